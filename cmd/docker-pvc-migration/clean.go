@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/migration"
+)
+
+// runClean deletes the migration pods that interrupted runs left behind
+func runClean(engine *migration.Engine, olderThan time.Duration, dryRun bool) {
+	engine.SetCleanOptions(olderThan, dryRun)
+
+	logger.Println("Looking for leftover migration pods...")
+	pods, err := engine.CleanMigrationPods()
+	if err != nil {
+		logger.Printf("Clean failed: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch {
+	case len(pods) == 0:
+		logger.Println("No leftover migration pods found")
+	case dryRun:
+		logger.Printf("Dry run: %d migration pods would be deleted. Run without --dry-run to delete them.\n", len(pods))
+	default:
+		logger.Printf("Deleted %d migration pods\n", len(pods))
+	}
+}
+
+// validateDryRun rejects --dry-run for every command but clean, where it would
+// otherwise be ignored; a migration is only a dry run without --execute
+func validateDryRun(command string, dryRun bool) error {
+	if dryRun && command != "clean" {
+		return fmt.Errorf("--dry-run only applies to clean; %s is a dry run unless --execute is given", command)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestValidateDryRun(t *testing.T) {
+	tests := []struct {
+		command string
+		dryRun  bool
+		wantErr bool
+	}{
+		{"clean", true, false},
+		{"clean", false, false},
+		{"./k8s", true, true},
+		{"./k8s", false, false},
+		{"list", true, true},
+		{"rollback", true, true},
+	}
+
+	for _, tt := range tests {
+		err := validateDryRun(tt.command, tt.dryRun)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateDryRun(%q, %v) error = %v, want error %v", tt.command, tt.dryRun, err, tt.wantErr)
+		}
+	}
+}
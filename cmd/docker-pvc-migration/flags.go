@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// stringSliceFlag is a flag that can be repeated and/or given comma-separated values
+type stringSliceFlag []string
+
+func (f *stringSliceFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *stringSliceFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*f = append(*f, item)
+		}
+	}
+	return nil
+}
+
+// daysDurationFlag is a duration flag that also accepts whole days, e.g. 30d
+type daysDurationFlag time.Duration
+
+func (f *daysDurationFlag) String() string {
+	return time.Duration(*f).String()
+}
+
+func (f *daysDurationFlag) Set(value string) error {
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid number of days %q", value)
+		}
+		*f = daysDurationFlag(time.Duration(n) * 24 * time.Hour)
+		return nil
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*f = daysDurationFlag(duration)
+	return nil
+}
+
+// flagPassed reports whether the flag called name was set on the command line
+func flagPassed(name string) bool {
+	passed := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			passed = true
+		}
+	})
+	return passed
+}
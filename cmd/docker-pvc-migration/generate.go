@@ -0,0 +1,25 @@
+package main
+
+import (
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/kubernetes"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/output"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// runGeneratePVCs writes a PVC manifest per Docker volume to outputDir and encodes
+// the paths of the written files
+func runGeneratePVCs(dockerVolumes map[string]*types.DockerVolumeInfo, namespace, storageClass, outputDir string, encoder output.Encoder) error {
+	logger.Printf("Generating PVC YAML files in %s...\n", outputDir)
+
+	generator := kubernetes.NewGenerator(namespace, storageClass)
+	written, err := generator.GenerateFiles(dockerVolumes, outputDir)
+	if err != nil {
+		return err
+	}
+
+	logger.Printf("✅ Generated %d PVC YAML files\n", len(written))
+	if written == nil {
+		written = []string{}
+	}
+	return encoder.Encode(written)
+}
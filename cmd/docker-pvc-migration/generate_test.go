@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/output"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestRunGeneratePVCsEncodesWrittenFiles(t *testing.T) {
+	outputDir := t.TempDir()
+	var stdout bytes.Buffer
+	encoder, err := output.NewEncoder(output.ModeJSON, &stdout)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	volumes := map[string]*types.DockerVolumeInfo{
+		"app_data": {Name: "app_data", Size: 1024},
+	}
+	if err := runGeneratePVCs(volumes, "default", "", outputDir, encoder); err != nil {
+		t.Fatal(err)
+	}
+
+	var written []string
+	if err := json.Unmarshal(stdout.Bytes(), &written); err != nil {
+		t.Fatalf("stdout is not a JSON list of files: %v\n%s", err, stdout.String())
+	}
+	if want := filepath.Join(outputDir, "app-data.yaml"); len(written) != 1 || written[0] != want {
+		t.Errorf("written = %v, want [%s]", written, want)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/docker"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/matcher"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/output"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// volumeInspection is the output of the inspect command
+type volumeInspection struct {
+	*types.DockerVolumeInfo
+	InUseBy []string       `json:"in_use_by"`
+	Compose []composeUsage `json:"compose,omitempty"`
+}
+
+// composeUsage is a compose service that mounts the inspected volume
+type composeUsage struct {
+	Service   string `json:"service"`
+	Volume    string `json:"volume"`
+	MountPath string `json:"mount_path"`
+}
+
+// runInspect shows the details of one Docker volume. When composeDir is set, the
+// services of the compose file in it that use the volume are listed too, with
+// composeEnv for its variables.
+func runInspect(dockerClient *docker.Client, name, composeDir string, composeEnv map[string]string, stdout io.Writer, encoder output.Encoder) error {
+	info, err := dockerClient.InspectVolume(name)
+	if err != nil {
+		return err
+	}
+
+	inspection := volumeInspection{DockerVolumeInfo: info, InUseBy: []string{}}
+	containers, err := dockerClient.GetVolumeContainerMounts(context.Background(), name)
+	if err != nil {
+		return err
+	}
+	inspection.InUseBy = append(inspection.InUseBy, containers...)
+
+	if composeDir != "" {
+		volumeMatcher := matcher.NewVolumeMatcher(map[string]*types.DockerVolumeInfo{name: info})
+		volumeMatcher.SetComposeEnv(composeEnv)
+		if err := volumeMatcher.LoadComposeContext(composeDir); err != nil {
+			return err
+		}
+		for _, mapping := range volumeMatcher.ComposeMappings(info) {
+			inspection.Compose = append(inspection.Compose, composeUsage{
+				Service:   mapping.ServiceName,
+				Volume:    mapping.VolumeName,
+				MountPath: mapping.MountPath,
+			})
+		}
+	}
+
+	if encoder != nil {
+		return encoder.Encode(inspection)
+	}
+
+	fmt.Fprintf(stdout, "Name:       %s\n", info.Name)
+	fmt.Fprintf(stdout, "Driver:     %s\n", info.Driver)
+	fmt.Fprintf(stdout, "Mountpoint: %s\n", info.Mountpoint)
+	if !info.CreatedAt.IsZero() {
+		fmt.Fprintf(stdout, "Created:    %s\n", info.CreatedAt.Format("2006-01-02 15:04:05"))
+	}
+	fmt.Fprintf(stdout, "Size:       %s\n", info.SizeHuman)
+	if len(inspection.InUseBy) > 0 {
+		fmt.Fprintf(stdout, "In use by:  %s\n", strings.Join(inspection.InUseBy, ", "))
+	} else {
+		fmt.Fprintln(stdout, "In use by:  -")
+	}
+
+	if len(info.Labels) > 0 {
+		fmt.Fprintln(stdout, "Labels:")
+		keys := make([]string, 0, len(info.Labels))
+		for key := range info.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(stdout, "  %s=%s\n", key, info.Labels[key])
+		}
+	}
+
+	if composeDir != "" {
+		if len(inspection.Compose) == 0 {
+			fmt.Fprintln(stdout, "Compose:    not used by any service")
+		} else {
+			fmt.Fprintln(stdout, "Compose:")
+			for _, usage := range inspection.Compose {
+				fmt.Fprintf(stdout, "  %s: %s -> %s\n", usage.Service, usage.Volume, usage.MountPath)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/matcher"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/output"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func runListVolumes(dockerVolumes map[string]*types.DockerVolumeInfo, encoder output.Encoder) error {
+	volumes := make([]*types.DockerVolumeInfo, 0, len(dockerVolumes))
+	for _, volume := range dockerVolumes {
+		volumes = append(volumes, volume)
+	}
+	sort.Slice(volumes, func(i, j int) bool {
+		return volumes[i].Name < volumes[j].Name
+	})
+
+	return encoder.Encode(volumes)
+}
+
+func runListPVCs(pvcs []*types.PVCInfo, encoder output.Encoder) error {
+	if pvcs == nil {
+		pvcs = []*types.PVCInfo{}
+	}
+	return encoder.Encode(pvcs)
+}
+
+// listMatchStrategy returns the match strategy of the list command, which never
+// prompts. The default interactive strategy becomes auto-best; asking for
+// interactive matching explicitly is an error.
+func listMatchStrategy(strategy string, explicit bool) (string, error) {
+	if strategy != "interactive" {
+		return strategy, nil
+	}
+	if explicit {
+		return "", fmt.Errorf("list does not prompt, use --match-strategy=auto-best, auto-exact or compose-only")
+	}
+	return "auto-best", nil
+}
+
+// pvcListEntry is one row of the list command
+type pvcListEntry struct {
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace"`
+	Size       string `json:"size"`
+	Volume     string `json:"volume,omitempty"`
+	VolumeSize int64  `json:"volume_size_bytes,omitempty"`
+	Score      int    `json:"score,omitempty"` // matcher.ScoreVolume of the matched volume
+}
+
+// runList prints the PVCs with their matched volume as a table, or encodes them
+// when encoder is set
+func runList(pvcs []*types.PVCInfo, stdout io.Writer, encoder output.Encoder) error {
+	entries := []pvcListEntry{}
+	for _, pvc := range pvcs {
+		entry := pvcListEntry{Name: pvc.Name, Namespace: pvc.Namespace, Size: pvc.RequestedSize}
+		if pvc.MatchedVolume != nil {
+			entry.Volume = pvc.MatchedVolume.Name
+			entry.VolumeSize = pvc.MatchedVolume.Size
+			entry.Score = matcher.ScoreVolume(pvc.Name, pvc.MatchedVolume)
+		}
+		entries = append(entries, entry)
+	}
+	if encoder != nil {
+		return encoder.Encode(entries)
+	}
+
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PVC\tNAMESPACE\tSIZE\tVOLUME\tVOLUME SIZE\tSCORE")
+	for i, entry := range entries {
+		if entry.Volume == "" {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t-\t-\t-\n", entry.Name, entry.Namespace, entry.Size)
+			continue
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%d\n", entry.Name, entry.Namespace, entry.Size,
+			entry.Volume, pvcs[i].MatchedVolume.SizeHuman, entry.Score)
+	}
+	return writer.Flush()
+}
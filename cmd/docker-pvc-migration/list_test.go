@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/kubernetes"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/matcher"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/output"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestListMatchStrategy(t *testing.T) {
+	tests := []struct {
+		strategy string
+		explicit bool
+		want     string
+		wantErr  bool
+	}{
+		{"interactive", false, "auto-best", false},
+		{"interactive", true, "", true},
+		{"auto-exact", true, "auto-exact", false},
+		{"compose-only", true, "compose-only", false},
+	}
+
+	for _, tt := range tests {
+		got, err := listMatchStrategy(tt.strategy, tt.explicit)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("listMatchStrategy(%q, %v) error = %v, want error %v", tt.strategy, tt.explicit, err, tt.wantErr)
+		}
+		if got != tt.want {
+			t.Errorf("listMatchStrategy(%q, %v) = %q, want %q", tt.strategy, tt.explicit, got, tt.want)
+		}
+	}
+}
+
+// listFixture writes PVC manifests and a compose file to a directory and returns the
+// directory with the Docker volumes the fixture expects
+func listFixture(t *testing.T) (string, map[string]*types.DockerVolumeInfo) {
+	t.Helper()
+	dir := t.TempDir()
+	files := map[string]string{
+		"app-data-persistentvolumeclaim.yaml": `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: app-data
+  namespace: shop
+spec:
+  accessModes: [ReadWriteOnce]
+  resources:
+    requests:
+      storage: 1Gi
+`,
+		"cache-persistentvolumeclaim.yaml": `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: cache
+  namespace: shop
+spec:
+  accessModes: [ReadWriteOnce]
+  resources:
+    requests:
+      storage: 512Mi
+`,
+		"docker-compose.yml": `services:
+  app:
+    image: shop/app
+    volumes:
+      - app-data:/data
+volumes:
+  app-data:
+`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	project := filepath.Base(dir)
+	volumes := map[string]*types.DockerVolumeInfo{
+		project + "_app-data": {Name: project + "_app-data", Size: 2 << 30, SizeHuman: "2.1GB"},
+		"unrelated":           {Name: "unrelated", Size: 1 << 20, SizeHuman: "1MB"},
+	}
+	return dir, volumes
+}
+
+// listPVCs runs the discovery of the list command on the fixture
+func listPVCs(t *testing.T, dir string, volumes map[string]*types.DockerVolumeInfo) []*types.PVCInfo {
+	t.Helper()
+	pvcs, parseErrors, err := kubernetes.NewParser().ParseYAMLFiles(dir)
+	if err != nil || len(parseErrors) > 0 {
+		t.Fatalf("ParseYAMLFiles() = %v, %v", parseErrors, err)
+	}
+
+	strategy, err := listMatchStrategy("interactive", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	volumeMatcher := matcher.NewVolumeMatcher(volumes)
+	if err := volumeMatcher.SetMatchStrategy(strategy); err != nil {
+		t.Fatal(err)
+	}
+	if err := volumeMatcher.LoadComposeContext(dir); err != nil {
+		t.Fatal(err)
+	}
+	return volumeMatcher.MatchVolumes(pvcs)
+}
+
+func TestRunListFixture(t *testing.T) {
+	dir, volumes := listFixture(t)
+	before := snapshotDir(t, dir)
+	pvcs := listPVCs(t, dir, volumes)
+	project := filepath.Base(dir)
+
+	t.Run("table", func(t *testing.T) {
+		var stdout bytes.Buffer
+		if err := runList(pvcs, &stdout, nil); err != nil {
+			t.Fatal(err)
+		}
+		lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("table has %d lines, want a header and 2 PVCs:\n%s", len(lines), stdout.String())
+		}
+		for _, want := range []string{"PVC", "app-data", project + "_app-data", "2.1GB", "cache"} {
+			if !strings.Contains(stdout.String(), want) {
+				t.Errorf("table does not contain %q:\n%s", want, stdout.String())
+			}
+		}
+	})
+
+	t.Run("json", func(t *testing.T) {
+		var stdout bytes.Buffer
+		encoder, err := output.NewEncoder(output.ModeJSON, &stdout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := runList(pvcs, &stdout, encoder); err != nil {
+			t.Fatal(err)
+		}
+
+		var entries []pvcListEntry
+		if err := json.Unmarshal(stdout.Bytes(), &entries); err != nil {
+			t.Fatalf("output is not JSON: %v\n%s", err, stdout.String())
+		}
+		byName := make(map[string]pvcListEntry)
+		for _, entry := range entries {
+			byName[entry.Name] = entry
+		}
+		if app := byName["app-data"]; app.Namespace != "shop" || app.Size != "1Gi" || app.Volume != project+"_app-data" || app.VolumeSize != 2<<30 {
+			t.Errorf("app-data = %+v, want the compose volume", app)
+		}
+		if cache, ok := byName["cache"]; !ok || cache.Volume != "" {
+			t.Errorf("cache = %+v, want it listed without a volume", cache)
+		}
+	})
+
+	// list only reads
+	if after := snapshotDir(t, dir); !maps.Equal(before, after) {
+		t.Error("list changed the fixture files")
+	}
+}
+
+// snapshotDir returns the content of every file in dir
+func snapshotDir(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = string(data)
+	}
+	return files
+}
@@ -0,0 +1,942 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/cloud"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/compose"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/config"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/docker"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/kubernetes"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/log"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/migration"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/notify"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/output"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/ui"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/yaml"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/client-go/rest"
+)
+
+var logger = log.New("main")
+
+func main() {
+	var execute = flag.Bool("execute", false, "Execute the migration (default is dry-run)")
+	var pvcNamespace = flag.String("pvc-namespace", "", `Namespace for all PVCs, overriding metadata.namespace in the YAML (default: the YAML namespace, or "default")`)
+	var targetNamespace = flag.String("target-namespace", "", "Namespace for migration pods (default: the namespace of the PVC they copy into)")
+	var migrationNamespace = flag.String("migration-namespace", "", "Deprecated: use --target-namespace")
+	var namespace = flag.String("namespace", "", "Deprecated: use --pvc-namespace")
+	var includeContainerData = flag.Bool("include-container-data", false, "Include container overlay data alongside named volumes")
+	var migrationTimeoutPerGB = flag.Duration("migration-timeout-per-gb", 2*time.Minute, "Migration pod timeout per GB of volume data (minimum 10m per PVC)")
+	var podTimeout = flag.Duration("pod-timeout", 0, "Migration pod timeout per PVC, overriding --migration-timeout-per-gb (0 = based on volume size)")
+	var pvcTimeout = flag.Duration("pvc-timeout", 5*time.Minute, "How long to wait for a created PVC to be bound")
+	var pvcPollInterval = flag.Duration("pvc-poll-interval", 5*time.Second, "How often to check whether a created PVC is bound")
+	var hostPathType = flag.String("migration-hostpath-type", "DirectoryOrCreate", "hostPath type for the Docker volume in the migration pod (Directory, DirectoryOrCreate, File, FileOrCreate, Socket, CharDevice, BlockDevice)")
+	var expandEnv = flag.Bool("expand-env", false, "Expand ${VAR} environment variable references in YAML files")
+	var noNormalize = flag.Bool("no-normalize", false, "Keep PVC names from the YAML as they are instead of making them valid Kubernetes names")
+	var noKustomize = flag.Bool("no-kustomize", false, "Parse the YAML files as they are, ignoring a kustomization.yaml in the YAML directory")
+	var yamlDepth = flag.Int("yaml-depth", -1, "How many subdirectory levels of the YAML directory to search for YAML files (0 for none, -1 for unlimited)")
+	var strictYAML = flag.Bool("strict-yaml", false, "Fail if any YAML file cannot be parsed")
+	var volumesCommand = flag.String("volumes-command", "", "Command that prints a JSON array of volumes to use instead of the Docker daemon")
+	var verifyType = flag.String("verify-type", "basic", "Filesystem check after copying (basic, postgres, mysql, mongo, auto)")
+	var dockerCACert = flag.String("docker-ca-cert", "", "Deprecated: use --docker-ca")
+	var dockerHost = flag.String("docker-host", "", "Docker daemon to connect to, e.g. tcp://host:2376 (default: $DOCKER_HOST)")
+	var dockerCA = flag.String("docker-ca", "", "PEM file with the CA certificate of a TLS-secured Docker daemon")
+	var dockerCert = flag.String("docker-cert", "", "PEM client certificate for a TLS-secured Docker daemon")
+	var dockerKey = flag.String("docker-key", "", "PEM client key for a TLS-secured Docker daemon")
+	var maxPVCs = flag.Int("max-pvcs", 0, "Refuse to run when more PVCs than this are found (0 = unlimited)")
+	var matchStrategy = flag.String("match-strategy", "interactive", "How to match volumes to PVCs (interactive, auto-best, auto-exact, compose-only)")
+	var minScore = flag.Int("min-score", 1, "Hide interactive match candidates that score lower than this")
+	var matchLabel = flag.String("match-label", "", "Match volumes labelled <key>=<pvc name> (or <namespace>/<pvc name>) before using --match-strategy")
+	var warnUnmatched = flag.Bool("warn-unmatched", true, "List Docker volumes that were not matched to any PVC")
+	var failOnUnmatched = flag.Bool("fail-on-unmatched", false, "Exit with an error when any Docker volume is not matched to a PVC")
+	var veleroBackup = flag.Bool("velero-backup", false, "Take a Velero backup of the namespace before migrating")
+	var overwriteExisting = flag.Bool("overwrite-existing", false, "Apply PVCs that already exist with another size, even when their storage class cannot expand them")
+	var annotate = flag.Bool("annotate", false, "Annotate created PVCs with their Docker volume, the migration date and the tool version")
+	var forceBound = flag.Bool("force-bound", false, "Migrate into ReadWriteOnce PVCs that are already bound and mounted by a pod")
+	var driverToStorageClass = flag.String("driver-to-storage-class", "", `JSON map of compose volume drivers to storage classes, e.g. {"nfs": "nfs-client"}`)
+	var excludePVCPattern = flag.String("exclude-pvc-pattern", "", "Skip PVCs whose name matches this glob pattern (e.g. test-*)")
+	var useEphemeralVolumes = flag.Bool("use-ephemeral-volumes", false, "Test the migration by copying into emptyDir volumes instead of creating PVCs")
+	var whatIf = flag.String("what-if", "", "Resolve PVCs, volumes, sizes and nodes and write the migration plan to this YAML file instead of migrating")
+	var fromPlan = flag.String("from-plan", "", "Migrate the PVCs of a plan written with --what-if, without matching or prompting")
+	var exportDir = flag.String("export-dir", "", "Export Docker volumes to <pvc>.tar.gz files in this directory and stream them into the PVCs, for clusters without access to the Docker host's filesystem")
+	var exportImage = flag.String("export-image", docker.DefaultExportImage, "Image the Docker host runs tar in for --export-dir; must contain tar with gzip support")
+	var cloudProvider = flag.String("cloud-provider", "none", "Add cloud-specific storage settings to PVCs (gcp, aws, azure, none)")
+	var envFile = flag.String("env-file", "", "Load variables for the compose file from this .env file; variables set in the environment take precedence")
+	var includeBindMounts = flag.Bool("include-bind-mounts", false, "Include bind-mounted host directories from the compose file as volumes")
+	var logFile = flag.String("log-file", "", "Also write every message as a JSON line to this file")
+	var noColor = flag.Bool("no-color", false, "Disable colored output (also disabled when stdout is not a terminal or NO_COLOR is set)")
+	var showDiff = flag.Bool("show-diff", false, "Show a colorized diff of every YAML file that is updated")
+	var fieldSelector = flag.String("field-selector", "", "Only migrate PVCs matching this kubectl-style field selector (e.g. metadata.namespace=production)")
+	var watch = flag.Bool("watch", false, "Keep running and automatically migrate new PVCs as matching Docker volumes appear")
+	var watchInterval = flag.Duration("watch-interval", 30*time.Second, "How often to check for new Docker volumes in watch mode")
+	var watchMaxAttempts = flag.Int("watch-max-attempts", 5, "Stop retrying a PVC in watch mode after this many failed migrations (0 = retry forever)")
+	var auditLog = flag.String("audit-log", "", "Append the watch mode audit log to this file (default: stderr)")
+	var skipVerifyTLS = flag.Bool("skip-verify-tls", false, "Skip TLS certificate verification for the Kubernetes API (insecure)")
+	var kubeCACert = flag.String("kube-ca-cert", "", "PEM file with the CA certificate of the Kubernetes API server")
+	var helmValues = flag.String("helm-values", "", "Helm values file to read PVC sizes from, overriding the sizes in the YAML files")
+	var helmKeyPattern = flag.String("helm-key-pattern", kubernetes.DefaultHelmKeyPattern, "Dot path of a PVC's size in --helm-values, with {pvcName} and {namespace} placeholders")
+	var pvcSource = flag.String("source", "yaml", "Where to read the PVCs to migrate into from (yaml, cluster, both); cluster PVCs already exist and are not created")
+	var sourceKubeconfig = flag.String("source-kubeconfig", "", "Kubeconfig of the cluster to read PVCs from, for migrating into a different cluster")
+	var destKubeconfig = flag.String("dest-kubeconfig", "", "Kubeconfig of the cluster to migrate into, overriding --kubeconfig")
+	var kubeconfig = flag.String("kubeconfig", "", "Kubeconfig file (default: in-cluster config, $KUBECONFIG or ~/.kube/config)")
+	var kubeContext = flag.String("kube-context", "", "Kubeconfig context of the cluster to migrate into (default: the current context)")
+	var listYAMLFiles = flag.Bool("list-yaml-files", false, "Print the YAML files that would be processed and exit")
+	var configFile = flag.String("config", "", "YAML file with pvcName/namespace/dockerVolume/newSize records to migrate without prompts")
+	var strict = flag.Bool("strict", false, "With --config, fail when a PVC is missing from the config instead of prompting for it")
+	var nonInteractive = flag.Bool("non-interactive", false, "Never prompt: use the suggested size and storage class of every PVC and the detected or best-fitting node")
+	var outputFormat = flag.String("output", "text", "Dry-run plan format (text, json); json also reports YAML changes as diffs instead of writing them")
+	var parallelism = flag.Int("parallelism", 1, "Number of PVCs to migrate at the same time")
+	var migrationImage = flag.String("migration-image", migration.DefaultMigrationImage, "Image for migration and verification pods; needs sh, cp, find, tar and sha256sum, and copies with rsync when it contains it")
+	var imagePullSecret = flag.String("image-pull-secret", "", "Secret for pulling --migration-image from a private registry")
+	var rsyncArgs = flag.String("rsync-args", "", "Extra options for the rsync data copy, e.g. \"--bwlimit=10m --exclude=cache\"; needs a --migration-image with rsync")
+	var verifyChecksums = flag.Bool("verify", false, "Compare SHA-256 checksums of every file in the Docker volume and the PVC after copying")
+	var backupDir = flag.String("backup-dir", "", `Back up YAML files to this directory before updating them ("inline" to write <file>.bak next to them)`)
+	var stsReplicas = flag.Int("sts-replicas", 1, "Number of PVCs to expect per StatefulSet volumeClaimTemplate (<template>-<statefulset>-0 up to N-1)")
+	var stateFile = flag.String("state-file", "migration-state.json", "JSON file, relative to the working directory, that records migration progress so an interrupted migration can be resumed; empty to not record it")
+	var resetState = flag.Bool("reset-state", false, "Forget the progress recorded in --state-file and start over")
+	var preHook = flag.String("pre-hook", "", "Shell command to run before any PVC is migrated; a non-zero exit aborts the migration")
+	var postHook = flag.String("post-hook", "", "Shell command to run after all PVCs were migrated successfully")
+	var prePVCHook = flag.String("pre-pvc-hook", "", "Shell command to run before each PVC, with $PVC_NAME and $PVC_NAMESPACE set")
+	var postPVCHook = flag.String("post-pvc-hook", "", "Shell command to run after each migrated PVC, with $PVC_NAME and $PVC_NAMESPACE set")
+	var webhookURL = flag.String("webhook-url", "", "URL to POST a JSON summary to when the migration succeeds or fails")
+	var webhookSecret = flag.String("webhook-secret", "", "Secret to sign webhook bodies with (HMAC-SHA256 in the X-Signature-256 header)")
+	var webhookTimeout = flag.Duration("webhook-timeout", 10*time.Second, "Timeout for the webhook request")
+	var reportFile = flag.String("report", "", "Write a report of the migration to this file, e.g. migration-report.md")
+	var reportFormat = flag.String("report-format", "", "Report format (markdown, json; default: from the --report file extension)")
+	var rollback = flag.Bool("rollback", false, "Delete the PVCs created by a previous (failed) migration instead of migrating")
+	var defaultStorageClass = flag.String("default-storage-class", "", "Storage class for PVCs whose YAML has no storageClassName")
+	var storageClass = flag.String("storage-class", "", "Storage class for generated PVCs (generate-pvcs)")
+	var cleanDryRun = flag.Bool("dry-run", false, "Only list the migration pods that would be deleted (clean)")
+	var cleanOlderThan = flag.Duration("older-than", 0, "Only delete migration pods that started longer ago than this, e.g. 1h (clean)")
+	var outputDir = flag.String("output-dir", ".", "Directory to write generated PVC YAML files to (generate-pvcs)")
+	var outputMode = flag.String("output-mode", "json", "Output format for list-volumes, list-pvcs and generate-pvcs (yaml, json, json-stream)")
+	var since daysDurationFlag
+	var cacheTTL = flag.Duration("cache-ttl", 10*time.Minute, "Reuse Docker volume sizes measured within this period; whether a volume is in use is always checked live (0 disables the cache)")
+	var refreshCache = flag.Bool("refresh-cache", false, "Measure Docker volume sizes again instead of using cached ones")
+	var sizeUnit = flag.String("size-unit", "", "Show volume sizes in si (kB, MB, GB) or iec (KiB, MiB, GiB) units (default: as reported by Docker)")
+	var skipInUse = flag.String("skip-in-use", docker.InUseWarn, "What to do with Docker volumes that are in use: warn (skip them), retry (wait until the migrated volumes are released) or error (fail when a migrated volume is in use)")
+	var inUseRetryInterval = flag.Duration("in-use-retry-interval", 30*time.Second, "How often to check whether in-use volumes were released, with --skip-in-use=retry")
+	var inUseRetryTimeout = flag.Duration("in-use-retry-timeout", 10*time.Minute, "How long to wait for in-use volumes to be released, with --skip-in-use=retry")
+	var estimatedThroughput = flag.String("estimated-throughput", "50Mi", "Expected copy throughput per second, used to estimate the migration time (e.g. 50Mi, 1Gi)")
+	var podCPURequest = flag.String("pod-cpu-request", migration.DefaultPodResources.CPURequest, "CPU request of the migration pod (empty for none)")
+	var podCPULimit = flag.String("pod-cpu-limit", migration.DefaultPodResources.CPULimit, "CPU limit of the migration pod, e.g. 500m (empty for none)")
+	var podMemoryRequest = flag.String("pod-memory-request", migration.DefaultPodResources.MemoryRequest, "Memory request of the migration pod (empty for none)")
+	var podMemoryLimit = flag.String("pod-memory-limit", migration.DefaultPodResources.MemoryLimit, "Memory limit of the migration pod, doubled on every restart after an OOMKill (empty for none)")
+	var useNodeAffinity = flag.Bool("use-node-affinity", false, "Schedule migration pods with a kubernetes.io/hostname node affinity instead of spec.nodeName")
+	var sizeHeadroom = flag.Float64("size-headroom", 20, "Percentage added to the Docker volume size for the suggested PVC size")
+	var maxPodRestarts = flag.Int("max-pod-restarts", 3, "Relaunch a migration pod this many times when it is OOMKilled (with twice the memory limit) or evicted")
+	var volumeLabels stringSliceFlag
+	var scaleDown stringSliceFlag
+	var extraVolumes stringSliceFlag
+	var preCreateDirs stringSliceFlag
+	var importVolumes stringSliceFlag
+	var excludeNamespaces stringSliceFlag
+	var composeProfiles stringSliceFlag
+	var excludeVolumes stringSliceFlag
+	var namespaceMaps stringSliceFlag
+	var excludeVolumePrefixes stringSliceFlag
+	flag.Var(&since, "since", "Only migrate volumes modified within this period, e.g. 30d or 12h")
+	flag.Var(&excludeNamespaces, "exclude-namespace", "Skip PVCs in this namespace (repeatable, comma-separated)")
+	flag.Var(&importVolumes, "import-volume", "Add a volume from a tar archive as name=path.tar.gz (repeatable)")
+	flag.Var(&composeProfiles, "compose-profile", "Active compose profile; services with other profiles are ignored (repeatable, comma-separated, * for all)")
+	flag.Var(&namespaceMaps, "namespace-map", "Move PVCs from YAML files in namespace source to namespace target, as source:target (repeatable, applied in order)")
+	flag.Var(&excludeVolumes, "exclude-volume", "Never match this Docker volume (repeatable, comma-separated)")
+	flag.Var(&excludeVolumePrefixes, "exclude-volume-prefix", "Never match Docker volumes whose name starts with this prefix (repeatable, comma-separated)")
+	flag.Var(&volumeLabels, "volume-label", "Only consider Docker volumes with this label, as key or key=value (repeatable, all labels must match)")
+	flag.Var(&scaleDown, "scale-down", "Scale this workload to zero while migrating and back up afterwards, respecting PodDisruptionBudgets, as deployment/name or statefulset/name (repeatable)")
+	flag.Var(&extraVolumes, "migration-extra-volume", "Mount an extra volume into the migration pod as secret:name=/path, configmap:name=/path or emptyDir:=/path (repeatable)")
+	flag.Var(&preCreateDirs, "pre-create-dirs", "Directories to create in each PVC before copying (repeatable, comma-separated)")
+	flag.Parse()
+
+	if *noColor {
+		color.SetEnabled(false)
+	}
+	if *logFile != "" {
+		if err := log.SetFile(*logFile); err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer log.Close()
+	}
+
+	if *dockerCA == "" {
+		*dockerCA = *dockerCACert
+	}
+	if *sizeUnit != "" && *sizeUnit != "si" && *sizeUnit != "iec" {
+		logger.Printf("Error: invalid --size-unit %q (valid: si, iec)\n", *sizeUnit)
+		os.Exit(1)
+	}
+
+	if *namespace != "" {
+		logger.Println("Warning: --namespace is deprecated, use --pvc-namespace (and --target-namespace for migration pods)")
+		*pvcNamespace = *namespace
+	}
+	if *migrationNamespace != "" {
+		logger.Println("Warning: --migration-namespace is deprecated, use --target-namespace")
+		if *targetNamespace == "" {
+			*targetNamespace = *migrationNamespace
+		}
+	}
+
+	// Like Docker Compose, variables from the .env file do not override the environment.
+	// They are only used for the compose file, not for --expand-env in the Kubernetes YAML.
+	var composeEnv map[string]string
+	if *envFile != "" {
+		env, err := compose.LoadEnvFile(*envFile)
+		if err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		composeEnv = env
+	}
+
+	var namespaceMappings []types.NamespaceMapping
+	for _, spec := range namespaceMaps {
+		mapping, err := types.ParseNamespaceMapping(spec)
+		if err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		namespaceMappings = append(namespaceMappings, mapping)
+	}
+	namespaceMapper, err := types.NewNamespaceMapper(namespaceMappings)
+	if err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// --pvc-namespace puts every PVC from the YAML in one namespace, overriding
+	// metadata.namespace (the parser and the YAML updater both apply it). Without it
+	// PVCs keep their YAML namespace, or "default" when they have none. Everything
+	// else namespaced (cluster PVCs, workloads, clean) uses defaultNamespace.
+	defaultNamespace := *pvcNamespace
+	if defaultNamespace == "" {
+		defaultNamespace = "default"
+	}
+	newParser := func() *kubernetes.Parser {
+		parser := kubernetes.NewParser()
+		parser.SetMaxDepth(*yamlDepth)
+		parser.SetKustomize(!*noKustomize)
+		parser.SetNormalizeNames(!*noNormalize)
+		parser.SetExpandEnv(*expandEnv)
+		parser.SetNamespaceOverride(*pvcNamespace)
+		parser.SetDefaultStorageClass(*defaultStorageClass)
+		parser.SetStatefulSetReplicas(*stsReplicas)
+		return parser
+	}
+
+	if len(flag.Args()) < 1 {
+		logger.Println("Usage: docker-pvc-migration [--execute] [--pvc-namespace=ns] [--target-namespace=ns] <yaml-directory>")
+		logger.Println("       docker-pvc-migration [--pvc-namespace=default] [--storage-class=name] [--output-dir=dir] [--output-mode=json] generate-pvcs")
+		logger.Println("       docker-pvc-migration [--output-mode=json] list-volumes")
+		logger.Println("       docker-pvc-migration [--output-mode=json] list-pvcs <yaml-directory>")
+		logger.Println("       docker-pvc-migration [--output=json] list <yaml-directory>")
+		logger.Println("       docker-pvc-migration [--output=json] verify <yaml-directory>")
+		logger.Println("       docker-pvc-migration [--output=json] inspect <volume> [compose-directory]")
+		logger.Println("       docker-pvc-migration [--pvc-namespace=ns] [--dry-run] [--older-than=1h] clean")
+		os.Exit(1)
+	}
+
+	if err := validateDryRun(flag.Args()[0], *cleanDryRun); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if command := flag.Args()[0]; command == "list-volumes" || command == "list-pvcs" {
+		// Keep stdout clean for the listing; progress messages go to stderr
+		log.SetOutput(os.Stderr)
+
+		encoder, err := output.NewEncoder(*outputMode, os.Stdout)
+		if err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		if command == "list-volumes" {
+			dockerClient, err := docker.NewClientWithTLS(*dockerHost, *dockerCA, *dockerCert, *dockerKey)
+			if err != nil {
+				logger.Printf("Error creating Docker client: %v\n", err)
+				os.Exit(1)
+			}
+			dockerClient.SetVolumeLabels(volumeLabels)
+			dockerClient.SetExclusions(excludeVolumes, excludeVolumePrefixes)
+			dockerClient.SetSizeCache(*cacheTTL, *refreshCache)
+			dockerVolumes, err := loadDockerVolumes(dockerClient, *volumesCommand, *includeContainerData, importVolumes, *sizeUnit)
+			dockerClient.Cleanup()
+			if err != nil {
+				logger.Printf("Error loading Docker volumes: %v\n", err)
+				os.Exit(1)
+			}
+			err = runListVolumes(dockerVolumes, encoder)
+		} else {
+			if len(flag.Args()) < 2 {
+				logger.Println("Usage: docker-pvc-migration [--output-mode=json] list-pvcs <yaml-directory>")
+				os.Exit(1)
+			}
+			pvcs, parseErrors, parseErr := newParser().ParseYAMLFiles(flag.Args()[1])
+			if parseErr != nil {
+				logger.Printf("Error parsing YAML files: %v\n", parseErr)
+				os.Exit(1)
+			}
+			for _, parseErr := range parseErrors {
+				logger.Printf("Warning: Failed to parse %v\n", parseErr)
+			}
+			namespaceMapper.Apply(pvcs)
+			err = runListPVCs(pvcs, encoder)
+		}
+		if err != nil {
+			logger.Printf("Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Args()[0] == "inspect" {
+		if len(flag.Args()) < 2 {
+			logger.Println("Usage: docker-pvc-migration [--output=json] inspect <volume> [compose-directory]")
+			os.Exit(1)
+		}
+		var composeDir string
+		if len(flag.Args()) > 2 {
+			composeDir = flag.Args()[2]
+		}
+
+		// Keep stdout clean for the volume details; progress messages go to stderr
+		log.SetOutput(os.Stderr)
+
+		var encoder output.Encoder
+		switch *outputFormat {
+		case "text":
+		case "json":
+			encoder, _ = output.NewEncoder(output.ModeJSON, os.Stdout)
+		default:
+			logger.Printf("Error: invalid --output %q (valid: text, json)\n", *outputFormat)
+			os.Exit(1)
+		}
+
+		dockerClient, err := docker.NewClientWithTLS(*dockerHost, *dockerCA, *dockerCert, *dockerKey)
+		if err != nil {
+			logger.Printf("Error creating Docker client: %v\n", err)
+			os.Exit(1)
+		}
+		dockerClient.SetSizeCache(*cacheTTL, *refreshCache)
+		if err := runInspect(dockerClient, flag.Args()[1], composeDir, composeEnv, os.Stdout, encoder); err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	if flag.Args()[0] == "generate-pvcs" {
+		// Keep stdout clean for the list of written files; progress messages go to stderr
+		log.SetOutput(os.Stderr)
+		encoder, err := output.NewEncoder(*outputMode, os.Stdout)
+		if err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		dockerClient, err := docker.NewClientWithTLS(*dockerHost, *dockerCA, *dockerCert, *dockerKey)
+		if err != nil {
+			logger.Printf("Error creating Docker client: %v\n", err)
+			os.Exit(1)
+		}
+		dockerClient.SetVolumeLabels(volumeLabels)
+		dockerClient.SetExclusions(excludeVolumes, excludeVolumePrefixes)
+		dockerClient.SetSizeCache(*cacheTTL, *refreshCache)
+		dockerVolumes, err := loadDockerVolumes(dockerClient, *volumesCommand, *includeContainerData, importVolumes, *sizeUnit)
+		dockerClient.Cleanup()
+		if err != nil {
+			logger.Printf("Error loading Docker volumes: %v\n", err)
+			os.Exit(1)
+		}
+		if err := runGeneratePVCs(dockerVolumes, defaultNamespace, *storageClass, *outputDir, encoder); err != nil {
+			logger.Printf("Error generating PVCs: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	yamlDir := flag.Args()[0]
+
+	// list runs the discovery of a migration and shows what it found, without
+	// prompting or changing any file or Kubernetes resource
+	listMode := yamlDir == "list"
+	if listMode {
+		if len(flag.Args()) < 2 {
+			logger.Println("Usage: docker-pvc-migration [--output=json] list <yaml-directory>")
+			os.Exit(1)
+		}
+		if *execute || *rollback || *watch || *resetState {
+			logger.Println("Error: list cannot be combined with --execute, --rollback, --watch or --reset-state")
+			os.Exit(1)
+		}
+		yamlDir = flag.Args()[1]
+		// Keep stdout clean for the listing; progress messages go to stderr
+		log.SetOutput(os.Stderr)
+
+		strategy, err := listMatchStrategy(*matchStrategy, flagPassed("match-strategy"))
+		if err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if strategy != *matchStrategy {
+			logger.Printf("Matching with --match-strategy=%s, since list never prompts\n", strategy)
+			*matchStrategy = strategy
+		}
+	}
+
+	// verify compares the Docker volumes with the PVCs of an earlier migration,
+	// without migrating anything
+	verifyMode := yamlDir == "verify"
+	if verifyMode {
+		if len(flag.Args()) < 2 {
+			logger.Println("Usage: docker-pvc-migration [--output=json] verify <yaml-directory>")
+			os.Exit(1)
+		}
+		if *execute || *rollback || *watch || *resetState {
+			logger.Println("Error: verify cannot be combined with --execute, --rollback, --watch or --reset-state")
+			os.Exit(1)
+		}
+		yamlDir = flag.Args()[1]
+		if *outputFormat == "json" {
+			log.SetOutput(os.Stderr)
+		}
+	}
+
+	if *whatIf != "" && (*fromPlan != "" || *execute || *rollback || *watch || listMode || verifyMode) {
+		logger.Println("Error: --what-if cannot be combined with --from-plan, --execute, --rollback, --watch, list or verify")
+		os.Exit(1)
+	}
+	if *fromPlan != "" && (*rollback || *watch || listMode || verifyMode) {
+		logger.Println("Error: --from-plan cannot be combined with --rollback, --watch, list or verify")
+		os.Exit(1)
+	}
+
+	if *listYAMLFiles {
+		yamlParser := kubernetes.NewParser()
+		yamlParser.SetMaxDepth(*yamlDepth)
+		files, err := yamlParser.FindYAMLFiles(yamlDir)
+		if err != nil {
+			logger.Printf("Error listing YAML files: %v\n", err)
+			os.Exit(1)
+		}
+		for _, file := range files {
+			logger.Println(file)
+		}
+		logger.Printf("Found %d YAML files matching pattern '%s' in %s\n", len(files), strings.Join(kubernetes.YAMLFilePatterns, ","), yamlDir)
+		return
+	}
+
+	// Configure the migration engine up front so invalid flags fail before any prompts
+	migrationEngine := migration.NewEngine(defaultNamespace, *targetNamespace, yamlDir)
+	migrationEngine.SetMigrationTimeoutPerGB(*migrationTimeoutPerGB)
+	migrationEngine.SetPodTimeout(*podTimeout)
+	if err := migrationEngine.SetPVCTimeout(*pvcTimeout); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := migrationEngine.SetPVCPollInterval(*pvcPollInterval); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	migrationEngine.SetExpandEnv(*expandEnv)
+	migrationEngine.SetYAMLDepth(*yamlDepth)
+	migrationEngine.SetPreCreateDirs(preCreateDirs)
+	migrationEngine.SetVeleroBackup(*veleroBackup)
+	migrationEngine.SetForceBound(*forceBound)
+	migrationEngine.SetAnnotate(*annotate)
+	migrationEngine.SetOverwriteExisting(*overwriteExisting)
+	migrationEngine.SetUseEphemeralVolumes(*useEphemeralVolumes)
+	migrationEngine.SetMaxPodRestarts(*maxPodRestarts)
+	migrationEngine.SetParallelism(*parallelism)
+	if err := migrationEngine.SetMigrationImage(*migrationImage); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	migrationEngine.SetImagePullSecret(*imagePullSecret)
+	migrationEngine.SetUseNodeAffinity(*useNodeAffinity)
+	if *webhookURL != "" {
+		migrationEngine.SetWebhook(&notify.Webhook{URL: *webhookURL, Secret: *webhookSecret, Timeout: *webhookTimeout})
+	}
+	if err := migrationEngine.SetReportFormat(*reportFormat); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	migrationEngine.SetHooks(migration.Hooks{
+		Pre:     *preHook,
+		Post:    *postHook,
+		PrePVC:  *prePVCHook,
+		PostPVC: *postPVCHook,
+	})
+	if err := migrationEngine.SetPodResources(migration.PodResources{
+		CPURequest:    *podCPURequest,
+		CPULimit:      *podCPULimit,
+		MemoryRequest: *podMemoryRequest,
+		MemoryLimit:   *podMemoryLimit,
+	}); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	migrationEngine.SetVerifyChecksums(*verifyChecksums)
+	if *stateFile != "" {
+		stateStore, err := migration.NewStateStore(*stateFile)
+		if err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if *resetState {
+			if err := stateStore.Reset(); err != nil {
+				logger.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			logger.Printf("Reset migration state in %s\n", *stateFile)
+		}
+		migrationEngine.SetStateStore(stateStore)
+	}
+	if err := migrationEngine.SetRsyncArgs(*rsyncArgs); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *rsyncArgs != "" && *migrationImage == migration.DefaultMigrationImage {
+		logger.Println("Error: --rsync-args needs a --migration-image that contains rsync")
+		os.Exit(1)
+	}
+	if *skipVerifyTLS && *kubeCACert != "" {
+		logger.Println("Error: --skip-verify-tls and --kube-ca-cert cannot be used together")
+		os.Exit(1)
+	}
+	if *skipVerifyTLS {
+		logger.Println("⚠️  WARNING: TLS certificate verification for the Kubernetes API is disabled (--skip-verify-tls)")
+	}
+	kubeOptions := kubernetes.RESTConfigOptions{
+		Kubeconfig:            *kubeconfig,
+		Context:               *kubeContext,
+		InsecureSkipTLSVerify: *skipVerifyTLS,
+		CAFile:                *kubeCACert,
+	}
+	if *destKubeconfig != "" {
+		kubeOptions.Kubeconfig = *destKubeconfig
+	}
+	if err := kubernetes.NewClientFactory(kubeOptions).ValidateContext(); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	migrationEngine.SetKubeOptions(kubeOptions)
+	migrationEngine.SetSourceKubeconfig(*sourceKubeconfig)
+
+	if yamlDir == "clean" {
+		runClean(migrationEngine, *cleanOlderThan, *cleanDryRun)
+		return
+	}
+
+	switch *pvcSource {
+	case "yaml", "cluster", "both":
+	default:
+		logger.Printf("Error: invalid --source %q (use yaml, cluster or both)\n", *pvcSource)
+		os.Exit(1)
+	}
+	if *pvcSource != "yaml" && *sourceKubeconfig != "" {
+		logger.Println("Error: --source cluster/both cannot be combined with --source-kubeconfig")
+		os.Exit(1)
+	}
+
+	// In a cross-cluster migration, PVCs are read from the source cluster instead of YAML files
+	var sourceConfig *rest.Config
+	if *sourceKubeconfig != "" {
+		sourceOptions := kubeOptions
+		sourceOptions.Kubeconfig = *sourceKubeconfig
+		sourceOptions.Context = ""
+		var err error
+		sourceConfig, err = kubernetes.NewRESTConfig(sourceOptions)
+		if err != nil {
+			logger.Printf("Error loading source cluster config: %v\n", err)
+			os.Exit(1)
+		}
+		destConfig, err := kubernetes.NewRESTConfig(kubeOptions)
+		if err != nil {
+			logger.Printf("Error loading destination cluster config: %v\n", err)
+			os.Exit(1)
+		}
+		logger.Printf("Source cluster:      %s\n", sourceConfig.Host)
+		logger.Printf("Destination cluster: %s\n", destConfig.Host)
+	}
+	var extraVolumeMounts []migration.ExtraVolumeMount
+	for _, spec := range extraVolumes {
+		extra, err := migration.ParseExtraVolumeMount(spec)
+		if err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		extraVolumeMounts = append(extraVolumeMounts, extra)
+	}
+	var workloads []types.WorkloadRef
+	for _, value := range scaleDown {
+		workload, err := migration.ParseWorkloadRef(value, defaultNamespace)
+		if err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		workloads = append(workloads, workload)
+	}
+	migrationEngine.SetScaleDownWorkloads(workloads)
+	if err := migrationEngine.SetExtraVolumes(extraVolumeMounts); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := migrationEngine.SetHostPathType(*hostPathType); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := migrationEngine.SetVerifyType(*verifyType); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	throughput, err := resource.ParseQuantity(*estimatedThroughput)
+	if err != nil || throughput.Value() <= 0 {
+		logger.Printf("Error: invalid --estimated-throughput %q\n", *estimatedThroughput)
+		os.Exit(1)
+	}
+	switch *outputFormat {
+	case "text":
+	case "json":
+		// Keep stdout clean for the plan; progress messages and prompts go to stderr
+		encoder, _ := output.NewEncoder(output.ModeJSON, os.Stdout)
+		migrationEngine.SetDryRunEncoder(encoder)
+		log.SetOutput(os.Stderr)
+	default:
+		logger.Printf("Error: invalid --output %q (valid: text, json)\n", *outputFormat)
+		os.Exit(1)
+	}
+	var batchConfig *config.Config
+	if *configFile != "" {
+		batchConfig, err = config.Load(*configFile)
+		if err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if *nonInteractive && *matchStrategy == "interactive" {
+		logger.Println("Error: --non-interactive needs a --match-strategy other than interactive")
+		os.Exit(1)
+	}
+	migrationEngine.SetNonInteractive(*nonInteractive)
+	provider, err := cloud.Lookup(*cloudProvider)
+	if err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Initialize Docker client
+	dockerClient, err := docker.NewClientWithTLS(*dockerHost, *dockerCA, *dockerCert, *dockerKey)
+	if err != nil {
+		logger.Printf("Error creating Docker client: %v\n", err)
+		os.Exit(1)
+	}
+	if *exportDir != "" {
+		if *execute {
+			if err := os.MkdirAll(*exportDir, 0755); err != nil {
+				logger.Printf("Error creating export directory: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		dockerClient.SetExportImage(*exportImage)
+		migrationEngine.SetExportDir(*exportDir, dockerClient)
+	}
+	dockerClient.SetSince(time.Duration(since))
+	dockerClient.SetVolumeLabels(volumeLabels)
+	dockerClient.SetExclusions(excludeVolumes, excludeVolumePrefixes)
+	dockerClient.SetSizeCache(*cacheTTL, *refreshCache)
+	if err := dockerClient.SetInUsePolicy(docker.RetryPolicy{
+		Mode:     *skipInUse,
+		Interval: *inUseRetryInterval,
+		Timeout:  *inUseRetryTimeout,
+	}); err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	k8sParser := newParser()
+
+	resolve := resolveOptions{
+		sourceConfig:         sourceConfig,
+		kubeOptions:          kubeOptions,
+		namespace:            defaultNamespace,
+		pvcSource:            *pvcSource,
+		yamlDir:              yamlDir,
+		strictYAML:           *strictYAML,
+		helmValues:           *helmValues,
+		helmKeyPattern:       *helmKeyPattern,
+		excludeNamespaces:    excludeNamespaces,
+		excludePVCPattern:    *excludePVCPattern,
+		fieldSelector:        *fieldSelector,
+		maxPVCs:              *maxPVCs,
+		batchConfig:          batchConfig,
+		configFile:           *configFile,
+		strict:               *strict,
+		rollback:             *rollback,
+		execute:              *execute,
+		volumesCommand:       *volumesCommand,
+		includeContainerData: *includeContainerData,
+		importVolumes:        importVolumes,
+		sizeUnit:             *sizeUnit,
+		excludeVolumes:       excludeVolumes,
+		excludePrefixes:      excludeVolumePrefixes,
+		matchLabel:           *matchLabel,
+		minScore:             *minScore,
+		matchStrategy:        *matchStrategy,
+		driverToStorageClass: *driverToStorageClass,
+		includeBindMounts:    *includeBindMounts,
+		composeProfiles:      composeProfiles,
+		composeEnv:           composeEnv,
+		listMode:             listMode,
+		verifyMode:           verifyMode,
+		outputFormat:         *outputFormat,
+		nonInteractive:       *nonInteractive,
+		sizeHeadroom:         *sizeHeadroom,
+		warnUnmatched:        *warnUnmatched,
+		failOnUnmatched:      *failOnUnmatched,
+		whatIf:               *whatIf,
+	}
+
+	if *watch {
+		options := watchOptions{
+			interval:    *watchInterval,
+			maxAttempts: *watchMaxAttempts,
+			resolve:     resolve,
+			audit:       os.Stderr,
+		}
+		// Watch mode only warns about scans that fail, so catch an invalid selector now
+		if *fieldSelector != "" {
+			if _, err := kubernetes.ParseFieldSelector(*fieldSelector); err != nil {
+				logger.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+		}
+		if *auditLog != "" {
+			file, err := os.OpenFile(*auditLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+			if err != nil {
+				logger.Printf("Error opening audit log: %v\n", err)
+				os.Exit(1)
+			}
+			defer file.Close()
+			options.audit = file
+		}
+
+		if err := runWatch(dockerClient, migrationEngine, k8sParser, namespaceMapper, options); err != nil {
+			logger.Printf("Error in watch mode: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// A plan from --what-if replaces PVC discovery, matching and the size prompts
+	var matchedPVCs []*types.PVCInfo
+	var userInterface *ui.Interface
+	if *fromPlan != "" {
+		plan, err := migration.NewPlanLoader(*fromPlan).Load()
+		if err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		logger.Printf("Loaded migration plan %s with %d PVCs (created %s)\n", *fromPlan, len(plan.PVCs), plan.CreatedAt.Format(time.RFC3339))
+		if err := resolvePlanVolumes(dockerClient, plan, *volumesCommand, *includeContainerData, importVolumes, *sizeUnit); err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		matchedPVCs = plan.PVCInfos()
+		migrationEngine.SetPlannedNodes(plan.Nodes())
+		migrationEngine.SetNonInteractive(true)
+		userInterface = ui.NewInterface()
+		userInterface.PrintSummary(matchedPVCs)
+	} else {
+		var done bool
+		matchedPVCs, userInterface, done = resolvePVCs(dockerClient, migrationEngine, k8sParser, namespaceMapper, resolve)
+		if done {
+			return
+		}
+	}
+
+	// Update YAML files with new sizes; PVCs from a source cluster get them when applied
+	if sourceConfig == nil {
+		yamlUpdater := yaml.NewUpdater()
+		yamlUpdater.SetMaxDepth(*yamlDepth)
+		yamlUpdater.SetExpandEnv(*expandEnv)
+		yamlUpdater.SetCloudProvider(provider)
+		yamlUpdater.SetShowDiff(*showDiff)
+		yamlUpdater.SetBackupDir(*backupDir)
+		yamlUpdater.SetNamespaceOverride(*pvcNamespace)
+		yamlUpdater.SetNamespaceMapper(namespaceMapper)
+		if !*execute && *outputFormat == "json" {
+			// A machine-readable dry run reports the YAML changes instead of making them
+			diffs, err := yamlUpdater.DiffYAMLFiles(yamlDir, matchedPVCs)
+			if err != nil {
+				logger.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			migrationEngine.SetYAMLDiffs(diffs)
+		} else if err := yamlUpdater.UpdateYAMLFiles(yamlDir, matchedPVCs); err != nil {
+			logger.Printf("Error updating YAML files: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// PVCs are created with their storage class, so check its size limits first
+	if !*useEphemeralVolumes {
+		if validationErrs := validatePVCSizes(kubeOptions, matchedPVCs); len(validationErrs) > 0 {
+			for _, validationErr := range validationErrs {
+				if *execute {
+					logger.Printf("Error: %v\n", validationErr)
+				} else {
+					logger.Printf("Warning: %v\n", validationErr)
+				}
+			}
+			if *execute {
+				os.Exit(1)
+			}
+		}
+	}
+
+	// Migration phase
+	if *execute {
+		// Only the volumes that are migrated have to be released, see --skip-in-use
+		if err := dockerClient.CheckVolumesInUse(matchedVolumeNames(matchedPVCs)); err != nil {
+			dockerClient.Cleanup()
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+
+		userInterface.PrintTimeEstimate(matchedPVCs, throughput.Value())
+		logger.Println("\n🚀 Starting actual migration...")
+		migrationErr := migrationEngine.StartMigration(matchedPVCs)
+		dockerClient.Cleanup()
+		if *reportFile != "" {
+			if err := migrationEngine.WriteReport(*reportFile, matchedPVCs); err != nil {
+				logger.Printf("Warning: %v\n", err)
+			}
+		}
+		if migrationErr != nil {
+			logger.Printf("Migration failed: %v\n", migrationErr)
+			os.Exit(1)
+		}
+	} else {
+		dockerClient.Cleanup()
+		if err := migrationEngine.DryRun(matchedPVCs); err != nil {
+			logger.Printf("Error writing migration plan: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	logger.Println("Process complete!")
+}
+
+// validatePVCSizes checks the PVC sizes against the size limits of their storage class
+// in the destination cluster. A cluster that cannot be reached is reported as a warning.
+func validatePVCSizes(options kubernetes.RESTConfigOptions, pvcs []*types.PVCInfo) []kubernetes.ValidationError {
+	config, err := kubernetes.NewRESTConfig(options)
+	if err != nil {
+		logger.Printf("Warning: Could not check PVC sizes against storage classes: %v\n", err)
+		return nil
+	}
+	validator, err := kubernetes.NewStorageClassValidatorForConfig(config)
+	if err != nil {
+		logger.Printf("Warning: Could not check PVC sizes against storage classes: %v\n", err)
+		return nil
+	}
+	return validator.ValidatePVCSizes(pvcs)
+}
+
+// matchedVolumeNames returns the names of the volumes matched to pvcs
+func matchedVolumeNames(pvcs []*types.PVCInfo) []string {
+	var names []string
+	for _, pvc := range pvcs {
+		if pvc.MatchedVolume != nil {
+			names = append(names, pvc.MatchedVolume.Name)
+		}
+	}
+	return names
+}
+
+func loadDockerVolumes(dockerClient *docker.Client, volumesCommand string, includeContainerData bool, importVolumes []string, sizeUnit string) (map[string]*types.DockerVolumeInfo, error) {
+	var dockerVolumes map[string]*types.DockerVolumeInfo
+	var err error
+	if volumesCommand != "" {
+		logger.Printf("Loading volumes from command: %s\n", volumesCommand)
+		dockerVolumes, err = dockerClient.LoadVolumesFromCommand(volumesCommand)
+	} else {
+		logger.Println("Loading Docker volumes...")
+		dockerVolumes, err = dockerClient.LoadVolumes()
+	}
+	if err != nil {
+		return nil, err
+	}
+	logger.Printf("Found %d Docker volumes\n", len(dockerVolumes))
+
+	if includeContainerData {
+		logger.Println("Loading Docker container data...")
+		containerMounts, err := dockerClient.LoadContainerMounts(context.Background(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, info := range containerMounts {
+			dockerVolumes[name] = info
+		}
+		logger.Printf("Found %d Docker containers with data\n", len(containerMounts))
+	}
+
+	for _, importVolume := range importVolumes {
+		name, tarPath, ok := strings.Cut(importVolume, "=")
+		if !ok || name == "" || tarPath == "" {
+			return nil, fmt.Errorf("invalid --import-volume %q, expected name=path.tar.gz", importVolume)
+		}
+
+		logger.Printf("Importing volume %s from %s...\n", name, tarPath)
+		info, err := dockerClient.LoadVolumeFromTar(tarPath)
+		if err != nil {
+			return nil, err
+		}
+		info.Name = name
+		dockerVolumes[name] = info
+	}
+
+	// Show all sizes in the same units, instead of as Docker reported them
+	for _, volume := range dockerVolumes {
+		switch sizeUnit {
+		case "si":
+			volume.SizeHuman = docker.FormatBytesSI(volume.Size)
+		case "iec":
+			volume.SizeHuman = docker.FormatBytesIEC(volume.Size)
+		}
+	}
+
+	return dockerVolumes, nil
+}
+
+// listDestinationPVCs reads the PVCs that already exist in the destination cluster
+func listDestinationPVCs(options kubernetes.RESTConfigOptions, namespace string) ([]*types.PVCInfo, error) {
+	config, err := kubernetes.NewRESTConfig(options)
+	if err != nil {
+		return nil, err
+	}
+	source, err := kubernetes.NewClusterSourceForConfig(config, namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	pvcs, err := source.ListPVCs(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs {
+		pvc.Existing = true
+	}
+	return pvcs, nil
+}
@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/config"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/docker"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/kubernetes"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/matcher"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/migration"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/output"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/ui"
+	"k8s.io/client-go/rest"
+)
+
+// resolveOptions are the discovery, matching and sizing flags of a run without --from-plan
+type resolveOptions struct {
+	sourceConfig      *rest.Config // Source cluster to read the PVCs from, nil to read YAML files
+	kubeOptions       kubernetes.RESTConfigOptions
+	namespace         string
+	pvcSource         string
+	yamlDir           string
+	strictYAML        bool
+	helmValues        string
+	helmKeyPattern    string
+	excludeNamespaces []string
+	excludePVCPattern string
+	fieldSelector     string
+	maxPVCs           int
+	batchConfig       *config.Config
+	configFile        string
+	strict            bool
+	rollback          bool
+	execute           bool
+
+	volumesCommand       string
+	includeContainerData bool
+	importVolumes        []string
+	sizeUnit             string
+	excludeVolumes       []string
+	excludePrefixes      []string
+	matchLabel           string
+	minScore             int
+	matchStrategy        string
+	driverToStorageClass string
+	includeBindMounts    bool
+	composeProfiles      []string
+	composeEnv           map[string]string
+
+	listMode        bool
+	verifyMode      bool
+	outputFormat    string
+	nonInteractive  bool
+	sizeHeadroom    float64
+	warnUnmatched   bool
+	failOnUnmatched bool
+	whatIf          string
+}
+
+// resolvePVCs discovers the PVCs, matches them to Docker volumes and sets their sizes.
+// done is true when the command is complete afterwards: --rollback, list, verify and
+// --what-if stop before migrating.
+func resolvePVCs(dockerClient *docker.Client, engine *migration.Engine, parser *kubernetes.Parser, mapper *types.NamespaceMapper, options resolveOptions) (matchedPVCs []*types.PVCInfo, userInterface *ui.Interface, done bool) {
+	pvcs, err := discoverPVCs(parser, mapper, options)
+	if err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if options.maxPVCs > 0 && len(pvcs) > options.maxPVCs {
+		logger.Printf("Error: Found %d PVCs but --max-pvcs is %d. Use --max-pvcs=%d to confirm you want to migrate this many PVCs.\n", len(pvcs), options.maxPVCs, len(pvcs))
+		os.Exit(1)
+	}
+
+	if options.batchConfig != nil && options.strict {
+		if missing := options.batchConfig.Missing(pvcs); len(missing) > 0 {
+			for _, pvc := range missing {
+				logger.Printf("  %s/%s\n", pvc.Namespace, pvc.Name)
+			}
+			logger.Printf("Error: %d PVCs are missing from %s (--strict)\n", len(missing), options.configFile)
+			os.Exit(1)
+		}
+	}
+
+	if options.rollback {
+		runRollback(engine, pvcs, options.execute)
+		return nil, nil, true
+	}
+
+	matchedPVCs, volumeMatcher, err := matchPVCs(dockerClient, pvcs, options)
+	if err != nil {
+		logger.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if options.listMode {
+		dockerClient.Cleanup()
+		var encoder output.Encoder
+		if options.outputFormat == "json" {
+			encoder, _ = output.NewEncoder(output.ModeJSON, os.Stdout)
+		}
+		if err := runList(matchedPVCs, os.Stdout, encoder); err != nil {
+			logger.Printf("Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+		return nil, nil, true
+	}
+
+	if options.verifyMode {
+		var encoder output.Encoder
+		if options.outputFormat == "json" {
+			encoder, _ = output.NewEncoder(output.ModeJSON, os.Stdout)
+		}
+		exitCode, err := runVerify(engine.VerifyMigration(matchedPVCs), os.Stdout, encoder)
+		if err != nil {
+			logger.Printf("Error writing output: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(exitCode)
+	}
+
+	// Interactive size configuration
+	userInterface = ui.NewInterface()
+	userInterface.SetBatchConfig(options.batchConfig)
+	userInterface.SetNonInteractive(options.nonInteractive)
+	userInterface.SetSizeHeadroom(options.sizeHeadroom)
+	if err := userInterface.InteractiveSetSizes(matchedPVCs); err != nil {
+		logger.Printf("Error during interactive setup: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Print summary
+	userInterface.PrintSummary(matchedPVCs)
+
+	unmatchedVolumes := volumeMatcher.GetUnmatchedVolumes(matchedPVCs)
+	if options.warnUnmatched || options.failOnUnmatched {
+		userInterface.PrintUnmatchedVolumes(unmatchedVolumes)
+	}
+	if options.failOnUnmatched && len(unmatchedVolumes) > 0 {
+		logger.Printf("Error: %d Docker volumes were not matched (--fail-on-unmatched)\n", len(unmatchedVolumes))
+		os.Exit(1)
+	}
+
+	if options.whatIf != "" {
+		plan := engine.BuildPlan(matchedPVCs)
+		if err := plan.Save(options.whatIf); err != nil {
+			logger.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		logger.Printf("Wrote migration plan for %d PVCs to %s; run it with --from-plan=%s --execute\n", len(plan.PVCs), options.whatIf, options.whatIf)
+		return nil, nil, true
+	}
+
+	return matchedPVCs, userInterface, false
+}
+
+// discoverPVCs reads the PVCs from the source cluster, the YAML files or the cluster,
+// as --source says, and applies --helm-values and the namespace, name and field filters.
+// Watch mode calls it on every scan, so both see the same PVCs.
+func discoverPVCs(parser *kubernetes.Parser, mapper *types.NamespaceMapper, options resolveOptions) ([]*types.PVCInfo, error) {
+	var pvcs []*types.PVCInfo
+	var err error
+	if options.sourceConfig != nil {
+		logger.Printf("Reading PVCs in namespace %s from the source cluster...\n", options.namespace)
+		pvcs, err = kubernetes.ListClusterPVCs(context.Background(), options.sourceConfig, options.namespace)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read PVCs from the source cluster: %v", err)
+		}
+		logger.Printf("Found %d PVCs in the source cluster\n", len(pvcs))
+	} else {
+		if options.pvcSource != "cluster" {
+			// Parse Kubernetes YAML files
+			logger.Printf("Parsing YAML files in %s...\n", options.yamlDir)
+			var parseErrors []kubernetes.ParseError
+			pvcs, parseErrors, err = parser.ParseYAMLFiles(options.yamlDir)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse YAML files: %v", err)
+			}
+			for _, parseErr := range parseErrors {
+				logger.Printf("Warning: Failed to parse %v\n", parseErr)
+			}
+			if options.strictYAML && len(parseErrors) > 0 {
+				return nil, fmt.Errorf("%d YAML files failed to parse (--strict-yaml)", len(parseErrors))
+			}
+			logger.Printf("Found %d PVCs in YAML files\n", len(pvcs))
+			mapper.Apply(pvcs)
+		}
+
+		if options.pvcSource != "yaml" {
+			clusterPVCs, err := listDestinationPVCs(options.kubeOptions, options.namespace)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read PVCs from the cluster: %v", err)
+			}
+			logger.Printf("Found %d PVCs in namespace %s of the cluster\n", len(clusterPVCs), options.namespace)
+			// PVCs defined in YAML files take precedence over the ones already in the cluster
+			pvcs = kubernetes.MergePVCs(pvcs, clusterPVCs)
+		}
+	}
+
+	if options.helmValues != "" {
+		logger.Printf("Reading PVC sizes from %s...\n", options.helmValues)
+		helmParser := kubernetes.NewHelmValuesParser()
+		helmParser.SetKeyPattern(options.helmKeyPattern)
+		if err := helmParser.ParseValuesFile(options.helmValues, pvcs); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(options.excludeNamespaces) > 0 || options.excludePVCPattern != "" {
+		var excluded int
+		pvcs, excluded, err = kubernetes.ExcludePVCs(pvcs, options.excludeNamespaces, options.excludePVCPattern)
+		if err != nil {
+			return nil, err
+		}
+		logger.Printf("Excluded %d PVCs by namespace/name filters, %d remaining\n", excluded, len(pvcs))
+	}
+
+	if options.fieldSelector != "" {
+		selector, err := kubernetes.ParseFieldSelector(options.fieldSelector)
+		if err != nil {
+			return nil, err
+		}
+
+		var selected []*types.PVCInfo
+		for _, pvc := range pvcs {
+			if selector.Matches(pvc) {
+				selected = append(selected, pvc)
+			}
+		}
+		logger.Printf("Field selector matched %d of %d PVCs\n", len(selected), len(pvcs))
+		pvcs = selected
+	}
+
+	return pvcs, nil
+}
+
+// matchPVCs loads the Docker volumes, bind mounts included when asked for, and matches
+// them to pvcs with the matching flags of options
+func matchPVCs(dockerClient *docker.Client, pvcs []*types.PVCInfo, options resolveOptions) ([]*types.PVCInfo, *matcher.VolumeMatcher, error) {
+	dockerVolumes, err := loadDockerVolumes(dockerClient, options.volumesCommand, options.includeContainerData, options.importVolumes, options.sizeUnit)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load Docker volumes: %v", err)
+	}
+
+	// Match Docker volumes to PVCs
+	logger.Println("Matching Docker volumes to PVCs...")
+	volumeMatcher := matcher.NewVolumeMatcher(dockerVolumes)
+	volumeMatcher.SetExclusions(options.excludeVolumes, options.excludePrefixes)
+	volumeMatcher.SetContainerMountLookup(dockerClient)
+	volumeMatcher.SetVolumeLabelLookup(dockerClient)
+	volumeMatcher.SetBatchConfig(options.batchConfig)
+	volumeMatcher.SetMatchLabel(options.matchLabel)
+	volumeMatcher.SetMinScore(options.minScore)
+	if err := volumeMatcher.SetMatchStrategy(options.matchStrategy); err != nil {
+		return nil, nil, err
+	}
+	if options.driverToStorageClass != "" {
+		driverClasses := make(map[string]string)
+		if err := json.Unmarshal([]byte(options.driverToStorageClass), &driverClasses); err != nil {
+			return nil, nil, fmt.Errorf("invalid --driver-to-storage-class: %v", err)
+		}
+		volumeMatcher.SetDriverStorageClasses(driverClasses)
+	}
+
+	// Load compose context for better matching
+	volumeMatcher.SetIncludeBindMounts(options.includeBindMounts)
+	volumeMatcher.SetComposeProfiles(options.composeProfiles)
+	volumeMatcher.SetComposeEnv(options.composeEnv)
+	if err := volumeMatcher.LoadComposeContext(options.yamlDir); err != nil {
+		logger.Printf("Warning: %v\n", err)
+	}
+
+	if options.includeBindMounts {
+		for _, hostPath := range volumeMatcher.GetBindMountPaths() {
+			info, err := dockerClient.CreateBindMountInfo(hostPath)
+			if err != nil {
+				logger.Printf("Warning: %v\n", err)
+				continue
+			}
+			volumeMatcher.AddVolume(info)
+		}
+	}
+
+	return volumeMatcher.MatchVolumes(pvcs), volumeMatcher, nil
+}
+
+// resolvePlanVolumes looks up the volumes of plan on this Docker host by name, since a
+// plan may be executed on another host than it was written on
+func resolvePlanVolumes(dockerClient *docker.Client, plan *migration.Plan, volumesCommand string, includeContainerData bool, importVolumes []string, sizeUnit string) error {
+	dockerVolumes, err := loadDockerVolumes(dockerClient, volumesCommand, includeContainerData, importVolumes, sizeUnit)
+	if err != nil {
+		return fmt.Errorf("failed to load Docker volumes: %v", err)
+	}
+
+	// Bind mounts are not Docker volumes; they are named by their host path
+	for _, planned := range plan.PVCs {
+		if planned.MatchedVolume.Driver != "bind" {
+			continue
+		}
+		info, err := dockerClient.CreateBindMountInfo(planned.MatchedVolume.Name)
+		if err != nil {
+			return err
+		}
+		dockerVolumes[info.Name] = info
+	}
+
+	return plan.ResolveVolumes(dockerVolumes)
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"os"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/migration"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// runRollback deletes the PVCs of a previous migration attempt. A new process
+// only knows which PVCs the earlier run created from the state file, so PVCs it
+// does not record are left alone.
+func runRollback(engine *migration.Engine, pvcs []*types.PVCInfo, execute bool) {
+	created := selectCreatedPVCs(engine, pvcs)
+	if len(created) == 0 {
+		logger.Println("Nothing to roll back: the state file records no PVCs created by a previous migration")
+		return
+	}
+
+	logger.Printf("Rollback will delete %d PVCs:\n", len(created))
+	for _, pvc := range created {
+		logger.Printf("  %s/%s\n", pvc.Namespace, pvc.Name)
+	}
+
+	if !execute {
+		logger.Println("Dry run: nothing was deleted. Run with --rollback --execute to delete these PVCs.")
+		return
+	}
+
+	if err := engine.Rollback(created); err != nil {
+		logger.Printf("Rollback failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// selectCreatedPVCs marks and returns the PVCs the state file records as created
+func selectCreatedPVCs(engine *migration.Engine, pvcs []*types.PVCInfo) []*types.PVCInfo {
+	var created []*types.PVCInfo
+	for _, pvc := range pvcs {
+		if !engine.CreatedPVC(pvc) {
+			logger.Printf("Skipping PVC %s/%s: not created by a previous migration\n", pvc.Namespace, pvc.Name)
+			continue
+		}
+		pvc.Created = true
+		created = append(created, pvc)
+	}
+	return created
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/migration"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestSelectCreatedPVCs(t *testing.T) {
+	store, err := migration.NewStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, phase := range map[string]migration.Phase{
+		"created":   migration.PhasePVCCreated,
+		"copied":    migration.PhaseDataCopied,
+		"completed": migration.PhaseCompleted,
+	} {
+		if err := store.Set("default", name, phase); err != nil {
+			t.Fatal(err)
+		}
+	}
+	engine := migration.NewEngine("default", "", t.TempDir())
+	engine.SetStateStore(store)
+
+	tests := []struct {
+		name    string
+		created bool
+	}{
+		{"created", true},
+		{"copied", true},
+		{"completed", true},
+		{"preexisting", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pvc := &types.PVCInfo{Name: tt.name, Namespace: "default"}
+			selected := selectCreatedPVCs(engine, []*types.PVCInfo{pvc})
+			if got := len(selected) == 1; got != tt.created {
+				t.Errorf("selected = %v, want %v", got, tt.created)
+			}
+			if pvc.Created != tt.created {
+				t.Errorf("Created = %v, want %v", pvc.Created, tt.created)
+			}
+		})
+	}
+}
+
+func TestSelectCreatedPVCsWithoutState(t *testing.T) {
+	engine := migration.NewEngine("default", "", t.TempDir())
+	pvc := &types.PVCInfo{Name: "data", Namespace: "default"}
+	if selected := selectCreatedPVCs(engine, []*types.PVCInfo{pvc}); len(selected) != 0 {
+		t.Errorf("selected %d PVCs without a state file, want none", len(selected))
+	}
+}
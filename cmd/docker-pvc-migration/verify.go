@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/migration"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/output"
+)
+
+// Exit codes of the verify command
+const (
+	verifyExitOK       = 0
+	verifyExitError    = 1 // A PVC could not be verified
+	verifyExitMismatch = 2 // A PVC does not hold the same data as its Docker volume
+)
+
+// runVerify reports the verification results as a table, or encodes them when
+// encoder is set, and returns the exit code of the verify command
+func runVerify(results []migration.VerificationResult, stdout io.Writer, encoder output.Encoder) (int, error) {
+	exitCode := verifyExitOK
+	for _, result := range results {
+		switch {
+		case result.Error != "":
+			exitCode = verifyExitError
+		case !result.Match && exitCode == verifyExitOK:
+			exitCode = verifyExitMismatch
+		}
+	}
+
+	if results == nil {
+		results = []migration.VerificationResult{}
+	}
+	if encoder != nil {
+		return exitCode, encoder.Encode(results)
+	}
+
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "PVC\tNAMESPACE\tVOLUME\tFILES\tBYTES\tRESULT")
+	for _, result := range results {
+		if result.Error != "" {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t-\t-\terror: %s\n", result.PVC, result.Namespace, result.Volume, result.Error)
+			continue
+		}
+		status := "ok"
+		if !result.Match {
+			status = "mismatch"
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d/%d\t%d/%d\t%s\n", result.PVC, result.Namespace, result.Volume,
+			result.SourceFiles, result.TargetFiles, result.SourceBytes, result.TargetBytes, status)
+	}
+	return exitCode, writer.Flush()
+}
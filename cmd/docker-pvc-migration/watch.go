@@ -0,0 +1,211 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/docker"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/kubernetes"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/migration"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchOptions configure watch mode. PVCs are discovered, filtered and matched with
+// resolve on every scan, the same way a single run does.
+type watchOptions struct {
+	interval    time.Duration
+	maxAttempts int // Give up on a PVC after this many failed migrations, 0 retries forever
+	resolve     resolveOptions
+	audit       io.Writer
+}
+
+// watchFailure tracks the failed migrations of one PVC for the retry backoff
+type watchFailure struct {
+	attempts  int
+	nextRetry time.Time
+}
+
+// runWatch migrates PVCs as soon as both their YAML and a matching Docker volume
+// exist, until interrupted. Matching is always automatic (auto-best).
+func runWatch(dockerClient *docker.Client, engine *migration.Engine, parser *kubernetes.Parser, mapper *types.NamespaceMapper, options watchOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	audit := log.New(options.audit, "audit: ", log.LstdFlags)
+	yamlDir := options.resolve.yamlDir
+	engine.SetNonInteractive(true)
+
+	// YAML changes trigger an immediate scan; new Docker volumes are picked up by polling
+	changes := make(chan struct{}, 1)
+	if watcher, err := fsnotify.NewWatcher(); err != nil {
+		logger.Printf("Warning: Could not watch %s, polling every %s: %v\n", yamlDir, options.interval, err)
+	} else {
+		defer watcher.Close()
+		if err := watcher.Add(yamlDir); err != nil {
+			logger.Printf("Warning: Could not watch %s, polling every %s: %v\n", yamlDir, options.interval, err)
+		}
+		go func() {
+			for event := range watcher.Events {
+				if strings.HasSuffix(event.Name, ".yaml") || strings.HasSuffix(event.Name, ".yml") {
+					select {
+					case changes <- struct{}{}:
+					default:
+					}
+				}
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(options.interval)
+	defer ticker.Stop()
+
+	scan := &watchScanner{
+		dockerClient: dockerClient,
+		engine:       engine,
+		parser:       parser,
+		mapper:       mapper,
+		options:      options,
+		audit:        audit,
+		migrated:     make(map[string]bool),
+		failures:     make(map[string]*watchFailure),
+		now:          time.Now,
+	}
+	logger.Printf("Watching %s and Docker volumes (Ctrl+C to stop)...\n", yamlDir)
+
+	for {
+		if err := scan.run(); err != nil {
+			logger.Printf("Warning: %v\n", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			logger.Println("Stopping watch mode")
+			return nil
+		case <-changes:
+		case <-ticker.C:
+		}
+	}
+}
+
+// watchScanner remembers across scans which PVCs were migrated or failed
+type watchScanner struct {
+	dockerClient *docker.Client
+	engine       *migration.Engine
+	parser       *kubernetes.Parser
+	mapper       *types.NamespaceMapper
+	options      watchOptions
+	audit        *log.Logger
+
+	migrated map[string]bool // Migrated in this run; earlier runs are known from the state file
+	failures map[string]*watchFailure
+	now      func() time.Time
+}
+
+func (s *watchScanner) run() error {
+	pending, err := s.candidates()
+	if err != nil || len(pending) == 0 {
+		return err
+	}
+
+	// Matching is always automatic in watch mode
+	options := s.options.resolve
+	options.matchStrategy = "auto-best"
+	defer s.dockerClient.Cleanup()
+	matched, _, err := matchPVCs(s.dockerClient, pending, options)
+	if err != nil {
+		return err
+	}
+
+	for _, pvc := range matched {
+		if pvc.MatchedVolume == nil {
+			continue
+		}
+		pvc.NewSize = pvc.RequestedSize
+
+		key := pvc.Namespace + "/" + pvc.Name
+		s.audit.Printf("auto-matched PVC %s to Docker volume %s", key, pvc.MatchedVolume.Name)
+
+		if err := s.dockerClient.CheckVolumesInUse([]string{pvc.MatchedVolume.Name}); err != nil {
+			s.recordFailure(key, err)
+			continue
+		}
+		if err := s.engine.StartMigration([]*types.PVCInfo{pvc}); err != nil {
+			s.recordFailure(key, err)
+			continue
+		}
+
+		delete(s.failures, key)
+		s.migrated[key] = true
+		s.audit.Printf("auto-migrated PVC %s from Docker volume %s", key, pvc.MatchedVolume.Name)
+	}
+
+	return nil
+}
+
+// candidates returns the PVCs that are still pending, discovered and filtered like a
+// single run does
+func (s *watchScanner) candidates() ([]*types.PVCInfo, error) {
+	pvcs, err := discoverPVCs(s.parser, s.mapper, s.options.resolve)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []*types.PVCInfo
+	for _, pvc := range pvcs {
+		if s.pending(pvc) {
+			pending = append(pending, pvc)
+		}
+	}
+	if maxPVCs := s.options.resolve.maxPVCs; maxPVCs > 0 && len(pending) > maxPVCs {
+		return nil, fmt.Errorf("found %d PVCs to migrate but --max-pvcs is %d, skipping this scan", len(pending), maxPVCs)
+	}
+	return pending, nil
+}
+
+// pending reports whether pvc still has to be migrated: not migrated before, not
+// given up on and not waiting for its next retry
+func (s *watchScanner) pending(pvc *types.PVCInfo) bool {
+	key := pvc.Namespace + "/" + pvc.Name
+	if s.migrated[key] || s.engine.Migrated(pvc) {
+		return false
+	}
+	failure := s.failures[key]
+	if failure == nil {
+		return true
+	}
+	if s.options.maxAttempts > 0 && failure.attempts >= s.options.maxAttempts {
+		return false
+	}
+	return !s.now().Before(failure.nextRetry)
+}
+
+// recordFailure schedules the next attempt for a failed PVC, doubling the wait
+// after every failure up to an hour
+func (s *watchScanner) recordFailure(key string, err error) {
+	failure := s.failures[key]
+	if failure == nil {
+		failure = &watchFailure{}
+		s.failures[key] = failure
+	}
+	failure.attempts++
+
+	if s.options.maxAttempts > 0 && failure.attempts >= s.options.maxAttempts {
+		s.audit.Printf("migration of PVC %s failed: %v; giving up after %d attempts", key, err, failure.attempts)
+		return
+	}
+
+	backoff := s.options.interval << (failure.attempts - 1)
+	if backoff <= 0 || backoff > time.Hour {
+		backoff = time.Hour
+	}
+	failure.nextRetry = s.now().Add(backoff)
+	s.audit.Printf("migration of PVC %s failed: %v; retrying in %s", key, err, backoff)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/kubernetes"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/migration"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestWatchScannerBackoff(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	scan := &watchScanner{
+		engine:   migration.NewEngine("default", "", t.TempDir()),
+		options:  watchOptions{interval: time.Minute, maxAttempts: 3},
+		audit:    log.New(io.Discard, "", 0),
+		migrated: make(map[string]bool),
+		failures: make(map[string]*watchFailure),
+		now:      func() time.Time { return now },
+	}
+	pvc := &types.PVCInfo{Name: "data", Namespace: "default"}
+	key := "default/data"
+
+	if !scan.pending(pvc) {
+		t.Fatal("new PVC is not pending")
+	}
+
+	// Each failure doubles the wait before the next attempt
+	for attempt, wait := range []time.Duration{time.Minute, 2 * time.Minute} {
+		scan.recordFailure(key, errors.New("copy failed"))
+		if scan.pending(pvc) {
+			t.Fatalf("attempt %d: PVC is pending right after failing", attempt+1)
+		}
+		now = now.Add(wait)
+		if !scan.pending(pvc) {
+			t.Fatalf("attempt %d: PVC is not pending after %s", attempt+1, wait)
+		}
+	}
+
+	// The third failure reaches maxAttempts
+	scan.recordFailure(key, errors.New("copy failed"))
+	now = now.Add(24 * time.Hour)
+	if scan.pending(pvc) {
+		t.Fatal("PVC is pending after reaching the maximum attempts")
+	}
+}
+
+func TestWatchScannerMigrated(t *testing.T) {
+	scan := &watchScanner{
+		engine:   migration.NewEngine("default", "", t.TempDir()),
+		migrated: map[string]bool{"default/data": true},
+		failures: make(map[string]*watchFailure),
+		now:      time.Now,
+	}
+	if scan.pending(&types.PVCInfo{Name: "data", Namespace: "default"}) {
+		t.Error("migrated PVC is pending")
+	}
+	if !scan.pending(&types.PVCInfo{Name: "other", Namespace: "default"}) {
+		t.Error("other PVC is not pending")
+	}
+}
+
+func TestWatchScannerStateFile(t *testing.T) {
+	store, err := migration.NewStateStore(t.TempDir() + "/state.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("default", "data", migration.PhaseCompleted); err != nil {
+		t.Fatal(err)
+	}
+	engine := migration.NewEngine("default", "", t.TempDir())
+	engine.SetStateStore(store)
+
+	scan := &watchScanner{
+		engine:   engine,
+		migrated: make(map[string]bool),
+		failures: make(map[string]*watchFailure),
+		now:      time.Now,
+	}
+	if scan.pending(&types.PVCInfo{Name: "data", Namespace: "default"}) {
+		t.Error("PVC completed in the state file is pending")
+	}
+}
+
+func TestWatchScannerFilters(t *testing.T) {
+	dir := t.TempDir()
+	var manifest string
+	for _, pvc := range []struct{ name, namespace string }{
+		{"data", "default"},
+		{"data", "kube-system"},
+		{"test-cache", "default"},
+	} {
+		manifest += "---\napiVersion: v1\nkind: PersistentVolumeClaim\nmetadata:\n  name: " + pvc.name +
+			"\n  namespace: " + pvc.namespace + "\nspec:\n  resources:\n    requests:\n      storage: 1Gi\n"
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pvcs.yaml"), []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	mapper, err := types.NewNamespaceMapper(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scan := &watchScanner{
+		engine: migration.NewEngine("default", "", t.TempDir()),
+		parser: kubernetes.NewParser(),
+		mapper: mapper,
+		options: watchOptions{resolve: resolveOptions{
+			pvcSource:         "yaml",
+			yamlDir:           dir,
+			excludeNamespaces: []string{"kube-system"},
+			excludePVCPattern: "test-*",
+		}},
+		migrated: make(map[string]bool),
+		failures: make(map[string]*watchFailure),
+		now:      time.Now,
+	}
+
+	pending, err := scan.candidates()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, pvc := range pending {
+		got = append(got, pvc.Namespace+"/"+pvc.Name)
+	}
+	if want := []string{"default/data"}; !slices.Equal(got, want) {
+		t.Errorf("candidates() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,216 @@
+// Package dockerpvcmigration migrates Docker volumes to Kubernetes PVCs.
+//
+// It exposes the same pipeline as the docker-pvc-migration CLI (load volumes,
+// parse PVC YAML, match, migrate) for embedding in operators or CI tools.
+package dockerpvcmigration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/docker"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/kubernetes"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/matcher"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/migration"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/yaml"
+)
+
+type (
+	DockerVolumeInfo = types.DockerVolumeInfo
+	PVCInfo          = types.PVCInfo
+)
+
+// VolumeLoader provides the Docker volumes that PVCs are matched against
+type VolumeLoader interface {
+	LoadVolumes() (map[string]*DockerVolumeInfo, error)
+}
+
+// Option configures a Migrator
+type Option func(*Migrator) error
+
+// WithNamespace sets the namespace for PVCs whose YAML has no metadata.namespace (default "default")
+func WithNamespace(namespace string) Option {
+	return func(m *Migrator) error {
+		m.namespace = namespace
+		return nil
+	}
+}
+
+// WithMigrationNamespace sets the namespace migration pods run in (default: the PVC's namespace)
+func WithMigrationNamespace(namespace string) Option {
+	return func(m *Migrator) error {
+		m.migrationNamespace = namespace
+		return nil
+	}
+}
+
+// WithMatchStrategy sets how volumes are matched to PVCs (default "auto-best").
+// The "interactive" strategy prompts on stdin and is not supported.
+func WithMatchStrategy(strategy string) Option {
+	return func(m *Migrator) error {
+		if strategy == "interactive" {
+			return fmt.Errorf("match strategy %q prompts on stdin and is not supported by the library", strategy)
+		}
+		m.matchStrategy = strategy
+		return nil
+	}
+}
+
+// WithDockerCACert trusts the CA certificate in caCertFile when connecting to the Docker daemon
+func WithDockerCACert(caCertFile string) Option {
+	return func(m *Migrator) error {
+		m.dockerCACert = caCertFile
+		return nil
+	}
+}
+
+// WithVolumeLoader replaces the Docker daemon as the source of volumes
+func WithVolumeLoader(loader VolumeLoader) Option {
+	return func(m *Migrator) error {
+		m.volumeLoader = loader
+		return nil
+	}
+}
+
+// Migrator plans and executes Docker volume to PVC migrations
+type Migrator struct {
+	namespace          string
+	migrationNamespace string
+	matchStrategy      string
+	dockerCACert       string
+	volumeLoader       VolumeLoader
+	dockerClient       *docker.Client
+}
+
+// ParseError is a YAML file that could not be parsed; its PVCs are missing from the plan
+type ParseError struct {
+	File string
+	Err  error
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e ParseError) Unwrap() error {
+	return e.Err
+}
+
+// MigrationPlan is the resolved set of PVCs and the volumes matched to them
+type MigrationPlan struct {
+	YAMLDirectory string
+	PVCs          []*PVCInfo
+	ParseErrors   []ParseError
+}
+
+// MigrationResult lists the PVCs that were migrated or skipped by Execute
+type MigrationResult struct {
+	Migrated []string
+	Skipped  []string
+}
+
+func NewMigrator(opts ...Option) (*Migrator, error) {
+	m := &Migrator{
+		namespace:     "default",
+		matchStrategy: "auto-best",
+	}
+
+	for _, opt := range opts {
+		if err := opt(m); err != nil {
+			return nil, err
+		}
+	}
+
+	dockerClient, err := docker.NewClient(m.dockerCACert)
+	if err != nil {
+		return nil, err
+	}
+	m.dockerClient = dockerClient
+
+	if m.volumeLoader == nil {
+		m.volumeLoader = dockerClient
+	}
+
+	return m, nil
+}
+
+// Plan loads Docker volumes and the PVCs in yamlDir, and matches them without
+// modifying any files or cluster resources. PVC sizes default to the size in the YAML.
+func (m *Migrator) Plan(ctx context.Context, yamlDir string) (*MigrationPlan, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	dockerVolumes, err := m.volumeLoader.LoadVolumes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Docker volumes: %v", err)
+	}
+
+	parser := kubernetes.NewParser()
+	parser.SetDefaultNamespace(m.namespace)
+	pvcs, parseErrors, err := parser.ParseYAMLFiles(yamlDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse YAML files: %v", err)
+	}
+
+	volumeMatcher := matcher.NewVolumeMatcher(dockerVolumes)
+	volumeMatcher.SetContainerMountLookup(m.dockerClient)
+	if err := volumeMatcher.SetMatchStrategy(m.matchStrategy); err != nil {
+		return nil, err
+	}
+	if err := volumeMatcher.LoadComposeContext(yamlDir); err != nil {
+		return nil, err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	pvcs = volumeMatcher.MatchVolumes(pvcs)
+	for _, pvc := range pvcs {
+		if pvc.NewSize == "" {
+			pvc.NewSize = pvc.RequestedSize
+		}
+	}
+
+	plan := &MigrationPlan{
+		YAMLDirectory: yamlDir,
+		PVCs:          pvcs,
+	}
+	for _, parseErr := range parseErrors {
+		plan.ParseErrors = append(plan.ParseErrors, ParseError{File: parseErr.File, Err: parseErr.Err})
+	}
+	return plan, nil
+}
+
+// Execute writes the planned sizes back to the YAML files and migrates every matched PVC.
+// It never prompts: the node for the migration pods is the detected or best-fitting node.
+// When ctx is done, no further PVCs are started and running requests are cancelled.
+func (m *Migrator) Execute(ctx context.Context, plan *MigrationPlan) (*MigrationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if err := yaml.NewUpdater().UpdateYAMLFiles(plan.YAMLDirectory, plan.PVCs); err != nil {
+		return nil, err
+	}
+
+	engine := migration.NewEngine(m.namespace, m.migrationNamespace, plan.YAMLDirectory)
+	engine.SetContext(ctx)
+	engine.SetNonInteractive(true)
+	if err := engine.StartMigration(plan.PVCs); err != nil {
+		return nil, err
+	}
+
+	result := &MigrationResult{}
+	for _, pvc := range plan.PVCs {
+		if pvc.MatchedVolume == nil {
+			result.Skipped = append(result.Skipped, pvc.Name)
+		} else {
+			result.Migrated = append(result.Migrated, pvc.Name)
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,48 @@
+package dockerpvcmigration
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWithMatchStrategy(t *testing.T) {
+	tests := []struct {
+		strategy string
+		wantErr  bool
+	}{
+		{"auto-best", false},
+		{"interactive", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			m := &Migrator{}
+			err := WithMatchStrategy(tt.strategy)(m)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WithMatchStrategy(%q) error = %v, wantErr %v", tt.strategy, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestExecuteCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := (&Migrator{}).Execute(ctx, &MigrationPlan{YAMLDirectory: t.TempDir()})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Execute() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestParseErrorUnwrap(t *testing.T) {
+	cause := errors.New("bad indentation")
+	err := error(ParseError{File: "pvc.yaml", Err: cause})
+	if !errors.Is(err, cause) {
+		t.Errorf("errors.Is(%v, cause) = false", err)
+	}
+	if got, want := err.Error(), "pvc.yaml: bad indentation"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,7 @@
+package cloud
+
+// AWS EFS: NFS-backed volumes through the EFS CSI driver
+const (
+	AWSProvisioner  = "efs.csi.aws.com"
+	AWSStorageClass = "efs-sc"
+)
@@ -0,0 +1,7 @@
+package cloud
+
+// Azure Files: SMB/NFS-backed volumes through the Azure Files CSI driver
+const (
+	AzureProvisioner  = "file.csi.azure.com"
+	AzureStorageClass = "azurefile-csi"
+)
@@ -0,0 +1,41 @@
+package cloud
+
+import "fmt"
+
+// Provider holds the PVC settings a cloud's file storage provisioner expects
+type Provider struct {
+	Name         string
+	StorageClass string
+	Annotations  map[string]string
+}
+
+// Lookup returns the provider settings for name, or nil for "none"
+func Lookup(name string) (*Provider, error) {
+	switch name {
+	case "", "none":
+		return nil, nil
+	case "gcp":
+		return &Provider{
+			Name:         name,
+			StorageClass: GCPStorageClass,
+			Annotations:  map[string]string{StorageProvisionerAnnotation: GCPProvisioner},
+		}, nil
+	case "aws":
+		return &Provider{
+			Name:         name,
+			StorageClass: AWSStorageClass,
+			Annotations:  map[string]string{StorageProvisionerAnnotation: AWSProvisioner},
+		}, nil
+	case "azure":
+		return &Provider{
+			Name:         name,
+			StorageClass: AzureStorageClass,
+			Annotations:  map[string]string{StorageProvisionerAnnotation: AzureProvisioner},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown cloud provider %q, must be one of: gcp, aws, azure, none", name)
+	}
+}
+
+// StorageProvisionerAnnotation tells Kubernetes which provisioner handles the PVC
+const StorageProvisionerAnnotation = "volume.beta.kubernetes.io/storage-provisioner"
@@ -0,0 +1,7 @@
+package cloud
+
+// GCP Filestore: NFS-backed, ReadWriteMany-capable volumes
+const (
+	GCPProvisioner  = "filestore.csi.storage.gke.io"
+	GCPStorageClass = "standard-rwx"
+)
@@ -0,0 +1,56 @@
+package color
+
+import (
+	"os"
+
+	"golang.org/x/term"
+)
+
+// ANSI escape codes for terminal output
+const (
+	Reset  = "\033[0m"
+	Red    = "\033[31m"
+	Green  = "\033[32m"
+	Yellow = "\033[33m"
+	Cyan   = "\033[36m"
+)
+
+var enabled = defaultEnabled()
+
+// defaultEnabled is false when stdout is not a terminal or NO_COLOR is set, see https://no-color.org
+func defaultEnabled() bool {
+	return os.Getenv("NO_COLOR") == "" && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// SetEnabled turns colors on or off, e.g. for --no-color
+func SetEnabled(on bool) {
+	enabled = on
+}
+
+// Colorize wraps text in the given color code, unless colors are disabled
+func Colorize(code, text string) string {
+	if !enabled {
+		return text
+	}
+	return code + text + Reset
+}
+
+// Success colors text for a step that succeeded
+func Success(text string) string {
+	return Colorize(Green, text)
+}
+
+// Warning colors text for a problem the migration continues after
+func Warning(text string) string {
+	return Colorize(Yellow, text)
+}
+
+// Error colors text for a failure
+func Error(text string) string {
+	return Colorize(Red, text)
+}
+
+// Header colors the title of a section
+func Header(text string) string {
+	return Colorize(Cyan, text)
+}
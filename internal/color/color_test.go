@@ -0,0 +1,57 @@
+package color
+
+import (
+	"os"
+	"testing"
+)
+
+func TestNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	if defaultEnabled() {
+		t.Error("colors are enabled with NO_COLOR=1")
+	}
+}
+
+func TestDefaultEnabledWithoutTerminal(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+
+	// Point stdout at a file, as when the output is redirected
+	file, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	stdout := os.Stdout
+	os.Stdout = file
+	defer func() { os.Stdout = stdout }()
+
+	if defaultEnabled() {
+		t.Error("colors are enabled when stdout is not a terminal")
+	}
+}
+
+func TestColorize(t *testing.T) {
+	defer SetEnabled(enabled)
+
+	tests := []struct {
+		enabled bool
+		color   func(string) string
+		want    string
+	}{
+		{false, Success, "done"},
+		{false, Warning, "done"},
+		{false, Error, "done"},
+		{false, Header, "done"},
+		{true, Success, Green + "done" + Reset},
+		{true, Warning, Yellow + "done" + Reset},
+		{true, Error, Red + "done" + Reset},
+		{true, Header, Cyan + "done" + Reset},
+	}
+
+	for _, tt := range tests {
+		SetEnabled(tt.enabled)
+		if got := tt.color("done"); got != tt.want {
+			t.Errorf("with colors enabled = %v got %q, want %q", tt.enabled, got, tt.want)
+		}
+	}
+}
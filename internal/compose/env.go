@@ -0,0 +1,183 @@
+package compose
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ExpandEnv resolves the variable substitutions of a compose file in data:
+// $VAR, ${VAR}, ${VAR:-default}, ${VAR-default}, ${VAR:?error} and ${VAR?error}.
+// $$ is a literal $. Variables are looked up in env, or in the process environment
+// when env is nil. An error is returned for a failed ${VAR:?error} check.
+func ExpandEnv(data []byte, env map[string]string) ([]byte, error) {
+	lookup := os.LookupEnv
+	if env != nil {
+		lookup = func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		}
+	}
+
+	expanded, err := expand(string(data), lookup)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(expanded), nil
+}
+
+// expandValues resolves the variable substitutions in every scalar value below node,
+// like Docker Compose does; keys and comments are left alone. A value that cannot be
+// expanded is kept as written and its error returned, so a failed ${VAR:?error} only
+// affects the values that use it.
+func expandValues(node *yaml.Node, lookup func(string) (string, bool)) []error {
+	var errs []error
+	switch node.Kind {
+	case yaml.ScalarNode:
+		if !strings.Contains(node.Value, "$") {
+			return nil
+		}
+		value, err := expand(node.Value, lookup)
+		if err != nil {
+			return []error{fmt.Errorf("line %d: %v", node.Line, err)}
+		}
+		node.Value = value
+		if node.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle|yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+			// Resolve the type of the expanded value, like for a value written out
+			node.Tag = ""
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			errs = append(errs, expandValues(node.Content[i], lookup)...)
+		}
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			errs = append(errs, expandValues(child, lookup)...)
+		}
+	}
+	return errs
+}
+
+// expand resolves the variable substitutions in input
+func expand(input string, lookup func(string) (string, bool)) (string, error) {
+	var out strings.Builder
+	for i := 0; i < len(input); i++ {
+		if input[i] != '$' || i+1 == len(input) {
+			out.WriteByte(input[i])
+			continue
+		}
+
+		switch next := input[i+1]; {
+		case next == '$':
+			out.WriteByte('$')
+			i++
+		case next == '{':
+			end := strings.IndexByte(input[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable substitution: %s", input[i:])
+			}
+			value, err := substitute(input[i+2:i+2+end], lookup)
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(value)
+			i += 2 + end
+		case isNameChar(next, true):
+			end := i + 2
+			for end < len(input) && isNameChar(input[end], false) {
+				end++
+			}
+			value, _ := lookup(input[i+1 : end])
+			out.WriteString(value)
+			i = end - 1
+		default:
+			out.WriteByte('$')
+		}
+	}
+
+	return out.String(), nil
+}
+
+// substitute evaluates the expression between ${ and }
+func substitute(expression string, lookup func(string) (string, bool)) (string, error) {
+	name := expression
+	operator, argument := "", ""
+	if i := strings.IndexAny(expression, ":-?"); i >= 0 {
+		name = expression[:i]
+		operator = expression[i : i+1]
+		argument = expression[i+1:]
+		if operator == ":" {
+			if argument == "" || (argument[0] != '-' && argument[0] != '?') {
+				return "", fmt.Errorf("invalid variable substitution: ${%s}", expression)
+			}
+			operator += argument[:1]
+			argument = argument[1:]
+		}
+	}
+	if name == "" {
+		return "", fmt.Errorf("invalid variable substitution: ${%s}", expression)
+	}
+
+	value, ok := lookup(name)
+	switch operator {
+	case ":-":
+		if value == "" {
+			return argument, nil
+		}
+	case "-":
+		if !ok {
+			return argument, nil
+		}
+	case ":?", "?":
+		if !ok || (operator == ":?" && value == "") {
+			if argument == "" {
+				argument = "required variable is not set or empty"
+			}
+			return "", fmt.Errorf("%s: %s", name, argument)
+		}
+	}
+	return value, nil
+}
+
+func isNameChar(c byte, first bool) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (!first && c >= '0' && c <= '9')
+}
+
+// LoadEnvFile reads KEY=VALUE lines from a .env file. Blank lines and lines
+// starting with # are skipped, and values may be quoted.
+func LoadEnvFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open env file: %v", err)
+	}
+	defer file.Close()
+
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNumber)
+		}
+		value = strings.TrimSpace(value)
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			value = value[1 : len(value)-1]
+		}
+		env[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read env file: %v", err)
+	}
+
+	return env, nil
+}
@@ -0,0 +1,142 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"NAME": "data", "EMPTY": ""}
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{"$NAME", "data", false},
+		{"${NAME}", "data", false},
+		{"${NAME}_db", "data_db", false},
+		{"${MISSING}", "", false},
+		{"${MISSING:-fallback}", "fallback", false},
+		{"${EMPTY:-fallback}", "fallback", false},
+		{"${MISSING-fallback}", "fallback", false},
+		{"${EMPTY-fallback}", "", false},
+		{"${NAME:-fallback}", "data", false},
+		{"${NAME:?must be set}", "data", false},
+		{"${MISSING:?must be set}", "", true},
+		{"${EMPTY:?must be set}", "", true},
+		{"${EMPTY?must be set}", "", false},
+		{"${MISSING?must be set}", "", true},
+		{"$$NAME", "$NAME", false},
+		{"cost: 5$", "cost: 5$", false},
+		{"${NAME", "", true},
+		{"${:-x}", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := ExpandEnv([]byte(tt.input), env)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExpandEnv(%q) error = %v, want error %v", tt.input, err, tt.wantErr)
+			}
+			if err == nil && string(got) != tt.want {
+				t.Errorf("ExpandEnv(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandEnvFromEnvironment(t *testing.T) {
+	t.Setenv("COMPOSE_TEST_VOLUME", "from-env")
+
+	got, err := ExpandEnv([]byte("${COMPOSE_TEST_VOLUME}"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "from-env" {
+		t.Errorf("ExpandEnv() = %q, want the process environment value", got)
+	}
+}
+
+func TestParseComposeFileExpandsValues(t *testing.T) {
+	t.Setenv("COMPOSE_TEST_OVERRIDE", "from-env")
+
+	dir := writeFixture(t, map[string]string{"compose.yml": `# ${UNRELATED:?comments are not expanded}
+services:
+  app:
+    image: ${IMAGE:-nginx}
+    volumes:
+      - ${DATA_VOLUME}:/data
+      - type: volume
+        source: ${COMPOSE_TEST_OVERRIDE}
+        target: /override
+        read_only: ${READ_ONLY:-true}
+  other:
+    image: ${OTHER_IMAGE:?the other image is required}
+`})
+
+	p := NewParser()
+	p.SetEnv(map[string]string{"DATA_VOLUME": "app-data", "COMPOSE_TEST_OVERRIDE": "from-file"})
+
+	compose, err := p.ParseComposeFile(filepath.Join(dir, "compose.yml"))
+	if err != nil {
+		t.Fatalf("ParseComposeFile() error = %v, want the failed check to only affect its value", err)
+	}
+
+	app := compose.Services["app"]
+	if app.Image != "nginx" {
+		t.Errorf("image = %q, want the default", app.Image)
+	}
+	want := []VolumeSpec{
+		{Type: "volume", Source: "app-data", Target: "/data"},
+		{Type: "volume", Source: "from-env", Target: "/override", ReadOnly: true},
+	}
+	if len(app.Volumes) != len(want) {
+		t.Fatalf("volumes = %+v, want %+v", app.Volumes, want)
+	}
+	for i := range want {
+		if app.Volumes[i] != want[i] {
+			t.Errorf("volume %d = %+v, want %+v", i, app.Volumes[i], want[i])
+		}
+	}
+
+	if image := compose.Services["other"].Image; image != "${OTHER_IMAGE:?the other image is required}" {
+		t.Errorf("other image = %q, want the value as written", image)
+	}
+
+	// The variables of the env file stay local to the parser
+	if _, ok := os.LookupEnv("DATA_VOLUME"); ok {
+		t.Error("SetEnv changed the process environment")
+	}
+}
+
+func TestLoadEnvFile(t *testing.T) {
+	dir := writeFixture(t, map[string]string{".env": `# comment
+
+NAME=data
+export EXPORTED=yes
+QUOTED="with spaces"
+SINGLE='single'
+SPACED = value
+`})
+
+	env, err := LoadEnvFile(filepath.Join(dir, ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"NAME": "data", "EXPORTED": "yes", "QUOTED": "with spaces", "SINGLE": "single", "SPACED": "value"}
+	if len(env) != len(want) {
+		t.Errorf("LoadEnvFile() = %v, want %v", env, want)
+	}
+	for name, value := range want {
+		if env[name] != value {
+			t.Errorf("%s = %q, want %q", name, env[name], value)
+		}
+	}
+
+	invalid := writeFixture(t, map[string]string{".env": "NAME\n"})
+	if _, err := LoadEnvFile(filepath.Join(invalid, ".env")); err == nil {
+		t.Error("LoadEnvFile accepted a line without =")
+	}
+}
@@ -0,0 +1,211 @@
+package compose
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// IncludeEntry is one item of the top-level include list. It is either a path or a
+// mapping whose path is a single file or a list of files.
+type IncludeEntry struct {
+	Paths []string
+}
+
+func (i *IncludeEntry) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		i.Paths = []string{node.Value}
+		return nil
+	}
+
+	var entry struct {
+		Path yaml.Node `yaml:"path"`
+	}
+	if err := node.Decode(&entry); err != nil {
+		return err
+	}
+	switch entry.Path.Kind {
+	case yaml.ScalarNode:
+		i.Paths = []string{entry.Path.Value}
+		return nil
+	case yaml.SequenceNode:
+		return entry.Path.Decode(&i.Paths)
+	}
+	return fmt.Errorf("include entry on line %d has no path", node.Line)
+}
+
+// Extends references the service a service is based on, in the same file when File is empty
+type Extends struct {
+	Service string `yaml:"service"`
+	File    string `yaml:"file"`
+}
+
+func (e *Extends) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		e.Service = node.Value
+		return nil
+	}
+	type plain Extends
+	return node.Decode((*plain)(e))
+}
+
+// loadComposeFile reads filePath and merges the services and volumes of the files
+// it includes. stack holds the files being loaded, to detect circular includes.
+func (p *Parser) loadComposeFile(filePath string, stack []string) (*ComposeFile, error) {
+	absPath, err := filepath.Abs(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, loading := range stack {
+		if loading == absPath {
+			return nil, fmt.Errorf("circular include: %s", strings.Join(append(stack, absPath), " -> "))
+		}
+	}
+	stack = append(stack, absPath)
+
+	data, err := os.ReadFile(absPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read compose file: %v", err)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse compose file %s: %v", filePath, err)
+	}
+	for _, err := range expandValues(&root, p.lookupEnv) {
+		logger.Warnf("Warning: %s: %v, using the value as written\n", filePath, err)
+	}
+
+	var compose ComposeFile
+	if root.Kind != 0 {
+		if err := root.Decode(&compose); err != nil {
+			return nil, fmt.Errorf("failed to parse compose file %s: %v", filePath, err)
+		}
+	}
+	if compose.Services == nil {
+		compose.Services = make(map[string]Service)
+	}
+	if compose.Volumes == nil {
+		compose.Volumes = make(map[string]VolumeDefinition)
+	}
+
+	dir := filepath.Dir(absPath)
+	p.rebaseBindMounts(&compose, dir)
+	for _, include := range compose.Include {
+		for _, includePath := range include.Paths {
+			if !filepath.IsAbs(includePath) {
+				includePath = filepath.Join(dir, includePath)
+			}
+			included, err := p.loadComposeFile(includePath, stack)
+			if err != nil {
+				return nil, err
+			}
+
+			// Definitions in the including file take precedence
+			for name, service := range included.Services {
+				if _, ok := compose.Services[name]; !ok {
+					compose.Services[name] = service
+				}
+			}
+			for name, volume := range included.Volumes {
+				if _, ok := compose.Volumes[name]; !ok {
+					compose.Volumes[name] = volume
+				}
+			}
+		}
+	}
+
+	for name := range compose.Services {
+		service, err := p.resolveExtends(&compose, dir, name, stack, nil)
+		if err != nil {
+			return nil, err
+		}
+		compose.Services[name] = service
+	}
+
+	return &compose, nil
+}
+
+// resolveExtends returns the service with the volumes (and image, if it has none) of
+// the service it extends merged in. seen holds the services of the extends chain.
+func (p *Parser) resolveExtends(compose *ComposeFile, dir, name string, stack, seen []string) (Service, error) {
+	service, ok := compose.Services[name]
+	if !ok {
+		return Service{}, fmt.Errorf("extended service %s not found", name)
+	}
+	if service.Extends == nil {
+		return service, nil
+	}
+
+	key := filepath.Join(dir, name)
+	for _, s := range seen {
+		if s == key {
+			return Service{}, fmt.Errorf("circular extends: %s", strings.Join(append(seen, key), " -> "))
+		}
+	}
+	seen = append(seen, key)
+
+	var base Service
+	var err error
+	if service.Extends.File == "" {
+		base, err = p.resolveExtends(compose, dir, service.Extends.Service, stack, seen)
+	} else {
+		basePath := service.Extends.File
+		if !filepath.IsAbs(basePath) {
+			basePath = filepath.Join(dir, basePath)
+		}
+		var baseFile *ComposeFile
+		baseFile, err = p.loadComposeFile(basePath, stack)
+		if err == nil {
+			base, err = p.resolveExtends(baseFile, filepath.Dir(basePath), service.Extends.Service, stack, seen)
+		}
+	}
+	if err != nil {
+		return Service{}, fmt.Errorf("service %s extends %s: %v", name, service.Extends.Service, err)
+	}
+
+	if service.Image == "" {
+		service.Image = base.Image
+	}
+	// Like docker compose, a volume of the service replaces the base volume with the same target
+	var volumes []VolumeSpec
+	for _, volume := range base.Volumes {
+		overridden := slices.ContainsFunc(service.Volumes, func(v VolumeSpec) bool { return v.Target == volume.Target })
+		if !overridden {
+			volumes = append(volumes, volume)
+		}
+	}
+	service.Volumes = append(volumes, service.Volumes...)
+	service.Extends = nil
+	return service, nil
+}
+
+// rebaseBindMounts rewrites the relative bind mount sources of a file in dir, which
+// are relative to that file, to paths relative to the main compose file
+func (p *Parser) rebaseBindMounts(compose *ComposeFile, dir string) {
+	mainDir, err := filepath.Abs(p.directory)
+	if err != nil || mainDir == dir {
+		return
+	}
+
+	for name, service := range compose.Services {
+		volumes := make([]VolumeSpec, len(service.Volumes))
+		for i, volume := range service.Volumes {
+			if volume.Type == "bind" && volume.Source != "" && !filepath.IsAbs(volume.Source) && !strings.HasPrefix(volume.Source, "~") {
+				if rel, err := filepath.Rel(mainDir, filepath.Join(dir, volume.Source)); err == nil {
+					if rel != ".." && !strings.HasPrefix(rel, "../") {
+						rel = "./" + rel
+					}
+					volume.Source = rel
+				}
+			}
+			volumes[i] = volume
+		}
+		service.Volumes = volumes
+		compose.Services[name] = service
+	}
+}
@@ -0,0 +1,180 @@
+package compose
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeFixture writes files (path relative to a temporary directory → content) and
+// returns the directory
+func writeFixture(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestParseComposeFileIncludeAndExtends(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		main    string // Compose file to parse, compose.yml when empty
+		service string
+		want    []VolumeSpec
+	}{
+		{
+			name: "extended volume overridden by target",
+			files: map[string]string{
+				"compose.yml": `services:
+  base:
+    image: postgres
+    volumes:
+      - db:/var/lib/postgresql/data
+      - logs:/logs
+  app:
+    extends: base
+    volumes:
+      - db-v2:/var/lib/postgresql/data
+`,
+			},
+			service: "app",
+			want: []VolumeSpec{
+				{Type: "volume", Source: "logs", Target: "/logs"},
+				{Type: "volume", Source: "db-v2", Target: "/var/lib/postgresql/data"},
+			},
+		},
+		{
+			name: "bind mount of included file",
+			files: map[string]string{
+				"compose.yml": `include:
+  - db/compose.yml
+services:
+  web:
+    image: nginx
+    volumes:
+      - ./html:/usr/share/nginx/html
+`,
+				"db/compose.yml": `services:
+  db:
+    image: postgres
+    volumes:
+      - ./data:/var/lib/postgresql/data
+      - type: bind
+        source: ../shared
+        target: /shared
+      - /srv/backups:/backups
+`,
+			},
+			service: "db",
+			want: []VolumeSpec{
+				{Type: "bind", Source: "./db/data", Target: "/var/lib/postgresql/data"},
+				{Type: "bind", Source: "./shared", Target: "/shared"},
+				{Type: "bind", Source: "/srv/backups", Target: "/backups"},
+			},
+		},
+		{
+			name: "bind mount of extended file",
+			files: map[string]string{
+				"app/compose.yml": `services:
+  app:
+    extends:
+      file: ../common/base.yml
+      service: base
+`,
+				"common/base.yml": `services:
+  base:
+    image: alpine
+    volumes:
+      - ./config:/config
+`,
+			},
+			main:    "app/compose.yml",
+			service: "app",
+			want: []VolumeSpec{
+				{Type: "bind", Source: "../common/config", Target: "/config"},
+			},
+		},
+		{
+			name: "main file bind mount unchanged",
+			files: map[string]string{
+				"compose.yml": `services:
+  web:
+    image: nginx
+    volumes:
+      - ./html:/usr/share/nginx/html
+`,
+			},
+			service: "web",
+			want: []VolumeSpec{
+				{Type: "bind", Source: "./html", Target: "/usr/share/nginx/html"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFixture(t, tt.files)
+			main := tt.main
+			if main == "" {
+				main = "compose.yml"
+			}
+
+			compose, err := NewParser().ParseComposeFile(filepath.Join(dir, main))
+			if err != nil {
+				t.Fatal(err)
+			}
+			service, ok := compose.Services[tt.service]
+			if !ok {
+				t.Fatalf("service %s not found", tt.service)
+			}
+			if !slices.Equal(service.Volumes, tt.want) {
+				t.Errorf("volumes = %+v, want %+v", service.Volumes, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseComposeFileCircular(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  string
+	}{
+		{
+			name: "include",
+			files: map[string]string{
+				"compose.yml": "include:\n  - other.yml\nservices: {}\n",
+				"other.yml":   "include:\n  - compose.yml\nservices: {}\n",
+			},
+			want: "circular include",
+		},
+		{
+			name: "extends",
+			files: map[string]string{
+				"compose.yml": "services:\n  a:\n    extends: b\n  b:\n    extends: a\n",
+			},
+			want: "circular extends",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeFixture(t, tt.files)
+			_, err := NewParser().ParseComposeFile(filepath.Join(dir, "compose.yml"))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
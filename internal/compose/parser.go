@@ -4,20 +4,78 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/log"
 	"gopkg.in/yaml.v3"
 )
 
+var logger = log.New("compose")
+
 type ComposeFile struct {
 	Version  string                      `yaml:"version"`
+	Include  []IncludeEntry              `yaml:"include"`
 	Services map[string]Service          `yaml:"services"`
 	Volumes  map[string]VolumeDefinition `yaml:"volumes"`
 }
 
 type Service struct {
-	Image   string   `yaml:"image"`
-	Volumes []string `yaml:"volumes"`
+	Image    string       `yaml:"image"`
+	Volumes  []VolumeSpec `yaml:"volumes"`
+	Extends  *Extends     `yaml:"extends"`
+	Profiles []string     `yaml:"profiles"`
+}
+
+// VolumeSpec is one volume of a service, written either in the short form
+// source:target[:options] or as a mapping with type, source and target
+type VolumeSpec struct {
+	Type     string `yaml:"type"` // volume, bind, tmpfs, ...
+	Source   string `yaml:"source"`
+	Target   string `yaml:"target"`
+	ReadOnly bool   `yaml:"read_only"`
+}
+
+func (v *VolumeSpec) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*v = parseShortVolumeSpec(node.Value)
+		return nil
+	}
+
+	type longSpec VolumeSpec
+	if err := node.Decode((*longSpec)(v)); err != nil {
+		return err
+	}
+	if v.Type == "" {
+		return fmt.Errorf("volume on line %d has no type", node.Line)
+	}
+	return nil
+}
+
+// parseShortVolumeSpec parses the short volume syntax:
+// - volume_name:/path/in/container
+// - /host/path:/path/in/container
+// - volume_name:/path/in/container:ro
+func parseShortVolumeSpec(spec string) VolumeSpec {
+	parts := strings.Split(spec, ":")
+	if len(parts) < 2 {
+		// An anonymous volume, which has no name to match
+		return VolumeSpec{Type: "volume", Target: spec}
+	}
+
+	volume := VolumeSpec{Type: "volume", Source: parts[0], Target: parts[1]}
+	if isHostPath(volume.Source) {
+		volume.Type = "bind"
+	}
+	if len(parts) > 2 {
+		volume.ReadOnly = slices.Contains(strings.Split(parts[2], ","), "ro")
+	}
+	return volume
+}
+
+func isHostPath(source string) bool {
+	return strings.HasPrefix(source, "/") || strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../")
 }
 
 type VolumeDefinition struct {
@@ -28,19 +86,65 @@ type VolumeDefinition struct {
 
 type VolumeMapping struct {
 	ServiceName  string
+	ServiceImage string
 	VolumeName   string
+	Driver       string // Volume driver from the top-level volumes section, if any
 	DockerVolume string // The actual Docker volume name
 	MountPath    string
+	HostPath     string // Absolute source directory for bind mounts, empty for named volumes
 }
 
 type Parser struct {
-	projectName string
+	projectName       string
+	directory         string
+	includeBindMounts bool
+	composeV2         bool              // Compose v2 names volumes {project}-{volume} instead of {project}_{volume}
+	profiles          []string          // Active compose profiles
+	env               map[string]string // Variables not set in the environment, see SetEnv
 }
 
 func NewParser() *Parser {
 	return &Parser{}
 }
 
+func (p *Parser) SetIncludeBindMounts(includeBindMounts bool) {
+	p.includeBindMounts = includeBindMounts
+}
+
+// SetEnv sets the variables for the substitutions in compose files that are not set
+// in the process environment, e.g. from LoadEnvFile. The environment is not changed.
+func (p *Parser) SetEnv(env map[string]string) {
+	p.env = env
+}
+
+// lookupEnv looks a variable up in the process environment and then in the SetEnv variables
+func (p *Parser) lookupEnv(name string) (string, bool) {
+	if value, ok := os.LookupEnv(name); ok {
+		return value, true
+	}
+	value, ok := p.env[name]
+	return value, ok
+}
+
+// SetProfiles sets the active compose profiles. Services with profiles are only
+// included when one of them is active, like with docker compose --profile.
+func (p *Parser) SetProfiles(profiles []string) {
+	p.profiles = profiles
+}
+
+// serviceEnabled reports whether service runs with the active profiles
+func (p *Parser) serviceEnabled(service Service) bool {
+	if len(service.Profiles) == 0 {
+		return true
+	}
+	for _, profile := range service.Profiles {
+		if slices.Contains(p.profiles, profile) || slices.Contains(p.profiles, "*") {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parser) FindComposeFile(directory string) (string, error) {
 	candidates := []string{
 		"docker-compose.yml",
@@ -63,27 +167,55 @@ func (p *Parser) ParseComposeFile(filePath string) (*ComposeFile, error) {
 	// Extract project name from directory
 	dir := filepath.Dir(filePath)
 	p.projectName = strings.ToLower(filepath.Base(dir))
+	p.directory = dir
 
-	data, err := os.ReadFile(filePath)
+	// Included and extended files are merged in, so callers see a single file
+	compose, err := p.loadComposeFile(filePath, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read compose file: %v", err)
+		return nil, err
 	}
 
-	var compose ComposeFile
-	if err := yaml.Unmarshal(data, &compose); err != nil {
-		return nil, fmt.Errorf("failed to parse compose file: %v", err)
+	p.composeV2 = isComposeV2(compose.Version)
+
+	return compose, nil
+}
+
+// isComposeV2 reports whether a compose file targets Docker Compose v2. Files with
+// version 3.x or below were written for the Python-based v1, newer files and files
+// without a version field for the Go-based v2 plugin.
+func isComposeV2(version string) bool {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return true
 	}
 
-	return &compose, nil
+	major, err := strconv.Atoi(strings.SplitN(version, ".", 2)[0])
+	if err != nil {
+		return true
+	}
+	return major > 3
+}
+
+func (p *Parser) volumeSeparators() (string, string) {
+	if p.composeV2 {
+		return "-", "_"
+	}
+	return "_", "-"
 }
 
 func (p *Parser) ExtractVolumeMappings(compose *ComposeFile) []VolumeMapping {
 	var mappings []VolumeMapping
 
 	for serviceName, service := range compose.Services {
+		if !p.serviceEnabled(service) {
+			logger.Printf("Skipping service %s: none of its profiles (%s) are active\n", serviceName, strings.Join(service.Profiles, ", "))
+			continue
+		}
 		for _, volumeSpec := range service.Volumes {
 			mapping := p.parseVolumeSpec(serviceName, volumeSpec)
 			if mapping != nil {
+				mapping.ServiceImage = service.Image
+				mapping.Driver = compose.Volumes[mapping.VolumeName].Driver
 				mappings = append(mappings, *mapping)
 			}
 		}
@@ -92,23 +224,39 @@ func (p *Parser) ExtractVolumeMappings(compose *ComposeFile) []VolumeMapping {
 	return mappings
 }
 
-func (p *Parser) parseVolumeSpec(serviceName, volumeSpec string) *VolumeMapping {
-	// Handle different volume specification formats:
-	// - volume_name:/path/in/container
-	// - /host/path:/path/in/container
-	// - volume_name:/path/in/container:ro
-
-	parts := strings.Split(volumeSpec, ":")
-	if len(parts) < 2 {
-		return nil // Invalid volume spec
+func (p *Parser) parseVolumeSpec(serviceName string, volumeSpec VolumeSpec) *VolumeMapping {
+	source := volumeSpec.Source
+	target := volumeSpec.Target
+	if source == "" || target == "" {
+		return nil // Anonymous volume or invalid volume spec
 	}
 
-	source := parts[0]
-	target := parts[1]
+	switch volumeSpec.Type {
+	case "volume":
+	case "bind":
+		// Bind mounts (host paths) are skipped unless explicitly included
+		if !p.includeBindMounts {
+			return nil
+		}
 
-	// Skip bind mounts (absolute paths)
-	if strings.HasPrefix(source, "/") || strings.HasPrefix(source, "./") || strings.HasPrefix(source, "../") {
-		return nil
+		// Relative paths are relative to the compose file
+		hostPath := source
+		if !filepath.IsAbs(hostPath) {
+			hostPath = filepath.Join(p.directory, hostPath)
+		}
+		if absPath, err := filepath.Abs(hostPath); err == nil {
+			hostPath = absPath
+		}
+
+		return &VolumeMapping{
+			ServiceName:  serviceName,
+			VolumeName:   source,
+			DockerVolume: hostPath,
+			MountPath:    target,
+			HostPath:     hostPath,
+		}
+	default:
+		return nil // tmpfs, npipe and cluster volumes hold no data to migrate
 	}
 
 	// This is a named volume
@@ -123,9 +271,10 @@ func (p *Parser) parseVolumeSpec(serviceName, volumeSpec string) *VolumeMapping
 }
 
 func (p *Parser) generateDockerVolumeName(volumeName string) string {
-	// Docker Compose typically creates volume names as: {project}_{volume}
+	// Docker Compose v1 creates volume names as {project}_{volume}, v2 as {project}-{volume}
 	// But there can be variations, so we'll try multiple patterns
-	return fmt.Sprintf("%s_%s", p.projectName, volumeName)
+	separator, _ := p.volumeSeparators()
+	return p.projectName + separator + volumeName
 }
 
 func (p *Parser) GetProjectName() string {
@@ -134,16 +283,17 @@ func (p *Parser) GetProjectName() string {
 
 // GetVolumeVariations returns possible Docker volume names for a given compose volume
 func (p *Parser) GetVolumeVariations(volumeName string) []string {
+	preferred, other := p.volumeSeparators()
 	variations := []string{
-		// Standard docker-compose naming
-		fmt.Sprintf("%s_%s", p.projectName, volumeName),
+		// Naming of the detected compose version
+		p.projectName + preferred + volumeName,
+		// Naming of the other compose version
+		p.projectName + other + volumeName,
 		// Without project prefix
 		volumeName,
-		// With directory name variations
-		fmt.Sprintf("%s-%s", p.projectName, volumeName),
 		// Uppercase project name
-		fmt.Sprintf("%s_%s", strings.ToUpper(p.projectName), volumeName),
-		fmt.Sprintf("%s-%s", strings.ToUpper(p.projectName), volumeName),
+		strings.ToUpper(p.projectName) + preferred + volumeName,
+		strings.ToUpper(p.projectName) + other + volumeName,
 	}
 
 	return variations
@@ -0,0 +1,144 @@
+package compose
+
+import (
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const profilesFixture = `services:
+  web:
+    image: nginx
+    volumes:
+      - web-data:/data
+  debug:
+    image: busybox
+    profiles: [debug]
+    volumes:
+      - debug-data:/data
+  backup:
+    image: restic
+    profiles: [ops, backup]
+    volumes:
+      - backup-data:/data
+volumes:
+  web-data:
+  debug-data:
+  backup-data:
+`
+
+func TestExtractVolumeMappingsProfiles(t *testing.T) {
+	tests := []struct {
+		name     string
+		profiles []string
+		want     []string // Services with mappings
+	}{
+		{"no active profiles", nil, []string{"web"}},
+		{"one profile", []string{"debug"}, []string{"debug", "web"}},
+		{"second profile of a service", []string{"backup"}, []string{"backup", "web"}},
+		{"several profiles", []string{"debug", "ops"}, []string{"backup", "debug", "web"}},
+		{"unknown profile", []string{"staging"}, []string{"web"}},
+		{"all profiles", []string{"*"}, []string{"backup", "debug", "web"}},
+	}
+
+	dir := writeFixture(t, map[string]string{"compose.yml": profilesFixture})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser()
+			p.SetProfiles(tt.profiles)
+			compose, err := p.ParseComposeFile(filepath.Join(dir, "compose.yml"))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var services []string
+			for _, mapping := range p.ExtractVolumeMappings(compose) {
+				services = append(services, mapping.ServiceName)
+			}
+			slices.Sort(services)
+			if !slices.Equal(services, tt.want) {
+				t.Errorf("services = %v, want %v", services, tt.want)
+			}
+		})
+	}
+}
+
+const volumeFormsFixture = `services:
+  app:
+    image: postgres
+    volumes:
+      - app-data:/data
+      - logs:/var/log:ro,z
+      - ./config:/etc/app
+      - /cache
+      - type: volume
+        source: db-data
+        target: /var/lib/postgresql
+        read_only: false
+      - type: bind
+        source: ./backups
+        target: /backups
+        read_only: true
+      - type: tmpfs
+        target: /tmp
+volumes:
+  app-data:
+  logs:
+  db-data:
+    driver: local
+`
+
+func TestVolumeFormsInOneService(t *testing.T) {
+	dir := writeFixture(t, map[string]string{"compose.yml": volumeFormsFixture})
+	p := NewParser()
+	p.SetIncludeBindMounts(true)
+	compose, err := p.ParseComposeFile(filepath.Join(dir, "compose.yml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantSpecs := []VolumeSpec{
+		{Type: "volume", Source: "app-data", Target: "/data"},
+		{Type: "volume", Source: "logs", Target: "/var/log", ReadOnly: true},
+		{Type: "bind", Source: "./config", Target: "/etc/app"},
+		{Type: "volume", Target: "/cache"},
+		{Type: "volume", Source: "db-data", Target: "/var/lib/postgresql"},
+		{Type: "bind", Source: "./backups", Target: "/backups", ReadOnly: true},
+		{Type: "tmpfs", Target: "/tmp"},
+	}
+	if got := compose.Services["app"].Volumes; !slices.Equal(got, wantSpecs) {
+		t.Errorf("volumes = %+v, want %+v", got, wantSpecs)
+	}
+
+	// Anonymous and tmpfs volumes hold no named data, so they have no mapping
+	wantMappings := []VolumeMapping{
+		{ServiceName: "app", VolumeName: "app-data", MountPath: "/data"},
+		{ServiceName: "app", VolumeName: "logs", MountPath: "/var/log"},
+		{ServiceName: "app", VolumeName: "./config", MountPath: "/etc/app", HostPath: filepath.Join(dir, "config")},
+		{ServiceName: "app", VolumeName: "db-data", MountPath: "/var/lib/postgresql", Driver: "local"},
+		{ServiceName: "app", VolumeName: "./backups", MountPath: "/backups", HostPath: filepath.Join(dir, "backups")},
+	}
+	mappings := p.ExtractVolumeMappings(compose)
+	if len(mappings) != len(wantMappings) {
+		t.Fatalf("mappings = %+v, want %+v", mappings, wantMappings)
+	}
+	for i, want := range wantMappings {
+		got := mappings[i]
+		if got.ServiceName != want.ServiceName || got.VolumeName != want.VolumeName || got.MountPath != want.MountPath ||
+			got.HostPath != want.HostPath || got.Driver != want.Driver || got.ServiceImage != "postgres" {
+			t.Errorf("mapping %d = %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestVolumeSpecLongFormWithoutType(t *testing.T) {
+	dir := writeFixture(t, map[string]string{"compose.yml": `services:
+  app:
+    volumes:
+      - source: data
+        target: /data
+`})
+	if _, err := NewParser().ParseComposeFile(filepath.Join(dir, "compose.yml")); err == nil {
+		t.Error("ParseComposeFile accepted a long-form volume without a type")
+	}
+}
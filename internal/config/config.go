@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// Record maps one PVC to the Docker volume it is migrated from
+type Record struct {
+	PVCName      string `yaml:"pvcName"`
+	Namespace    string `yaml:"namespace,omitempty"` // Empty matches the PVC in any namespace
+	DockerVolume string `yaml:"dockerVolume"`
+	NewSize      string `yaml:"newSize,omitempty"`
+}
+
+// Config is the mapping for a non-interactive batch migration
+type Config struct {
+	Records []Record
+}
+
+// MarshalYAML writes the config as a plain list of records
+func (c Config) MarshalYAML() (interface{}, error) {
+	return c.Records, nil
+}
+
+// UnmarshalYAML reads the config from a plain list of records
+func (c *Config) UnmarshalYAML(value *yaml.Node) error {
+	return value.Decode(&c.Records)
+}
+
+// Load reads and validates a batch config file
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s: %v", path, err)
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s: %v", path, err)
+	}
+
+	for i, record := range cfg.Records {
+		if record.PVCName == "" || record.DockerVolume == "" {
+			return nil, fmt.Errorf("config %s: record %d needs both pvcName and dockerVolume", path, i+1)
+		}
+	}
+
+	return &cfg, nil
+}
+
+// Lookup returns the record for pvc, if any
+func (c *Config) Lookup(pvc *types.PVCInfo) (Record, bool) {
+	for _, record := range c.Records {
+		if record.PVCName == pvc.Name && (record.Namespace == "" || record.Namespace == pvc.Namespace) {
+			return record, true
+		}
+	}
+	return Record{}, false
+}
+
+// Missing returns the PVCs that have no record in the config
+func (c *Config) Missing(pvcs []*types.PVCInfo) []*types.PVCInfo {
+	var missing []*types.PVCInfo
+	for _, pvc := range pvcs {
+		if _, ok := c.Lookup(pvc); !ok {
+			missing = append(missing, pvc)
+		}
+	}
+	return missing
+}
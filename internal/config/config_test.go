@@ -0,0 +1,86 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+func TestConfigRoundTrip(t *testing.T) {
+	cfg := Config{Records: []Record{
+		{PVCName: "data", Namespace: "apps", DockerVolume: "app_data", NewSize: "10Gi"},
+		{PVCName: "cache", DockerVolume: "app_cache"},
+	}}
+
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Records, cfg.Records) {
+		t.Errorf("loaded %+v, want %+v", loaded.Records, cfg.Records)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"missing volume", "- pvcName: data\n"},
+		{"missing pvc", "- dockerVolume: app_data\n"},
+		{"not a list", "pvcName: data\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); err == nil {
+				t.Errorf("Load() accepted %q", tt.content)
+			}
+		})
+	}
+}
+
+func TestLookup(t *testing.T) {
+	cfg := &Config{Records: []Record{
+		{PVCName: "data", Namespace: "apps", DockerVolume: "app_data"},
+		{PVCName: "cache", DockerVolume: "app_cache"},
+	}}
+
+	tests := []struct {
+		pvc        types.PVCInfo
+		wantVolume string
+	}{
+		{types.PVCInfo{Name: "data", Namespace: "apps"}, "app_data"},
+		{types.PVCInfo{Name: "data", Namespace: "other"}, ""},
+		{types.PVCInfo{Name: "cache", Namespace: "any"}, "app_cache"},
+	}
+
+	for _, tt := range tests {
+		record, _ := cfg.Lookup(&tt.pvc)
+		if record.DockerVolume != tt.wantVolume {
+			t.Errorf("Lookup(%s/%s) = %q, want %q", tt.pvc.Namespace, tt.pvc.Name, record.DockerVolume, tt.wantVolume)
+		}
+	}
+
+	pvcs := []*types.PVCInfo{{Name: "data", Namespace: "other"}, {Name: "cache", Namespace: "x"}}
+	if missing := cfg.Missing(pvcs); len(missing) != 1 || missing[0].Name != "data" {
+		t.Errorf("Missing() = %v, want only data", missing)
+	}
+}
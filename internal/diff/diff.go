@@ -0,0 +1,85 @@
+package diff
+
+import (
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/pmezard/go-difflib/difflib"
+	"golang.org/x/term"
+)
+
+// UnifiedDiff returns a plain unified diff between the original and updated content
+// of file, or "" when they are equal
+func UnifiedDiff(original, updated, file string) string {
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(original),
+		B:        difflib.SplitLines(updated),
+		FromFile: file,
+		ToFile:   file,
+		Context:  3,
+	})
+	if err != nil {
+		return ""
+	}
+	return text
+}
+
+// ColorizedUnifiedDiff returns a unified diff between original and updated with added
+// lines in green, removed lines in red and hunk headers in cyan. Lines longer than the
+// terminal are truncated.
+func ColorizedUnifiedDiff(original, updated string) string {
+	text, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(original),
+		B:        difflib.SplitLines(updated),
+		FromFile: "original",
+		ToFile:   "updated",
+		Context:  3,
+	})
+	if err != nil || text == "" {
+		return ""
+	}
+
+	maxWidth := 0
+	if width, _, err := term.GetSize(int(os.Stdout.Fd())); err == nil && width > 1 {
+		maxWidth = width - 1
+	}
+
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		line = truncateLine(line, maxWidth)
+
+		switch {
+		case strings.HasPrefix(line, "+++"), strings.HasPrefix(line, "---"):
+			b.WriteString(line)
+		case strings.HasPrefix(line, "@@"):
+			b.WriteString(color.Colorize(color.Cyan, line))
+		case strings.HasPrefix(line, "+"):
+			b.WriteString(color.Colorize(color.Green, line))
+		case strings.HasPrefix(line, "-"):
+			b.WriteString(color.Colorize(color.Red, line))
+		default:
+			b.WriteString(line)
+		}
+		b.WriteString("\n")
+	}
+
+	return b.String()
+}
+
+// truncateLine cuts line to at most maxWidth characters, never inside a multi-byte
+// character. A maxWidth of 0 leaves the line as it is.
+func truncateLine(line string, maxWidth int) string {
+	if maxWidth <= 0 || utf8.RuneCountInString(line) <= maxWidth {
+		return line
+	}
+	runes := 0
+	for i := range line {
+		if runes == maxWidth {
+			return line[:i]
+		}
+		runes++
+	}
+	return line
+}
@@ -0,0 +1,66 @@
+package diff
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateLine(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		maxWidth int
+		want     string
+	}{
+		{"no limit", "storage: 10Gi", 0, "storage: 10Gi"},
+		{"short", "storage: 10Gi", 20, "storage: 10Gi"},
+		{"exact", "abc", 3, "abc"},
+		{"ascii", "storage: 10Gi", 7, "storage"},
+		{"multi-byte", "+  # größe → 10Gi", 9, "+  # größ"},
+		{"cut before emoji", "+ 🚀🚀🚀", 3, "+ 🚀"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateLine(tt.line, tt.maxWidth)
+			if got != tt.want {
+				t.Errorf("truncateLine(%q, %d) = %q, want %q", tt.line, tt.maxWidth, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncateLine(%q, %d) returned invalid UTF-8", tt.line, tt.maxWidth)
+			}
+		})
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	original := "spec:\n  resources:\n    requests:\n      storage: 1Gi\n"
+
+	tests := []struct {
+		name    string
+		updated string
+		want    []string
+	}{
+		{"equal", original, nil},
+		{
+			name:    "changed storage",
+			updated: "spec:\n  resources:\n    requests:\n      storage: 5Gi\n",
+			want:    []string{"--- pvc.yaml\n", "+++ pvc.yaml\n", "-      storage: 1Gi\n", "+      storage: 5Gi\n"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff(original, tt.updated, "pvc.yaml")
+			if tt.want == nil && got != "" {
+				t.Errorf("UnifiedDiff() = %q, want no diff", got)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(got, want) {
+					t.Errorf("UnifiedDiff() does not contain %q:\n%s", want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package docker
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// sizeCacheFile is the file in the user cache directory that stores the volume sizes the daemon reported
+const sizeCacheFile = "docker-pvc-migration/volume-sizes.json"
+
+// sizeCacheEntry holds the volume sizes of one Docker host
+type sizeCacheEntry struct {
+	Timestamp time.Time                   `json:"timestamp"`
+	Volumes   map[string]cachedVolumeSize `json:"volumes"`
+}
+
+// cachedVolumeSize is the size of one volume. Whether a volume is in use is not
+// cached, since that changes as soon as a container starts.
+type cachedVolumeSize struct {
+	Bytes int64  `json:"bytes"`
+	Human string `json:"human"`
+}
+
+// SetSizeCache makes LoadVolumes reuse volume sizes from the daemon that are younger
+// than ttl, so the slow disk usage query is not run on every start. A ttl of 0
+// disables the cache; refresh ignores the cached sizes but still stores new ones.
+func (c *Client) SetSizeCache(ttl time.Duration, refresh bool) {
+	c.sizeCacheTTL = ttl
+	c.refreshSizeCache = refresh
+}
+
+// volumeSizes returns the sizes from the cache when they are fresh enough, and
+// otherwise asks the daemon and caches the result
+func (c *Client) volumeSizes() (map[string]volumeSize, error) {
+	if c.sizeCacheTTL <= 0 {
+		return c.daemonVolumeSizes()
+	}
+
+	path, err := sizeCachePath()
+	if err != nil {
+		logger.Warnf("Warning: Volume size cache disabled: %v\n", err)
+		return c.daemonVolumeSizes()
+	}
+
+	// daemonVolumeSizes queries this same daemon, so the key always matches the data
+	cache := readSizeCache(path)
+	host := c.client.DaemonHost()
+	if entry, ok := cache[host]; ok && !c.refreshSizeCache {
+		if age := c.now().Sub(entry.Timestamp); age >= 0 && age < c.sizeCacheTTL {
+			logger.Printf("Using volume sizes cached %s ago (use --refresh-cache to update them)\n", age.Round(time.Second))
+			sizes := make(map[string]volumeSize, len(entry.Volumes))
+			for name, size := range entry.Volumes {
+				sizes[name] = volumeSize{bytes: size.Bytes, human: size.Human}
+			}
+			return sizes, nil
+		}
+	}
+
+	sizes, err := c.daemonVolumeSizes()
+	if err != nil {
+		return nil, err
+	}
+
+	entry := sizeCacheEntry{Timestamp: c.now(), Volumes: make(map[string]cachedVolumeSize, len(sizes))}
+	for name, size := range sizes {
+		entry.Volumes[name] = cachedVolumeSize{Bytes: size.bytes, Human: size.human}
+	}
+	cache[host] = entry
+	if err := writeSizeCache(path, cache); err != nil {
+		logger.Warnf("Warning: Could not cache volume sizes: %v\n", err)
+	}
+	return sizes, nil
+}
+
+func sizeCachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, sizeCacheFile), nil
+}
+
+// readSizeCache returns the cached sizes per Docker host; a missing or corrupt
+// cache is treated as empty
+func readSizeCache(path string) map[string]sizeCacheEntry {
+	cache := make(map[string]sizeCacheEntry)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return make(map[string]sizeCacheEntry)
+	}
+	return cache
+}
+
+func writeSizeCache(path string, cache map[string]sizeCacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cache, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so concurrent runs never read a partial cache
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".volume-sizes-*.json")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
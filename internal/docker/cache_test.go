@@ -0,0 +1,218 @@
+package docker
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// sizingDaemon returns a client for a fake daemon that reports app_data with the next
+// of sizes on every disk usage query, repeating the last one
+func sizingDaemon(t *testing.T, sizes ...int64) *Client {
+	t.Helper()
+	calls := 0
+	return newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/system/df") {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(diskUsage(sizes[min(calls, len(sizes)-1)]))
+		calls++
+	})
+}
+
+func TestVolumeSizesCache(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	c := sizingDaemon(t, 1e9, 2e9, 3e9)
+	clock := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	c.now = func() time.Time { return clock }
+	c.SetSizeCache(10*time.Minute, false)
+
+	steps := []struct {
+		name    string
+		advance time.Duration
+		refresh bool
+		want    int64 // Size of app_data
+	}{
+		{"miss", 0, false, 1e9},
+		{"hit", 5 * time.Minute, false, 1e9},
+		{"hit just before expiry", 4*time.Minute + 59*time.Second, false, 1e9},
+		{"expired", time.Second, false, 2e9},
+		{"refresh", time.Minute, true, 3e9},
+		{"hit after refresh", time.Minute, false, 3e9},
+		{"clock moved back", -time.Hour, false, 3e9},
+	}
+
+	for _, step := range steps {
+		clock = clock.Add(step.advance)
+		c.refreshSizeCache = step.refresh
+
+		sizes, err := c.volumeSizes()
+		if err != nil {
+			t.Fatalf("%s: %v", step.name, err)
+		}
+		if got := sizes["app_data"].bytes; got != step.want {
+			t.Errorf("%s: size = %d, want %d", step.name, got, step.want)
+		}
+	}
+
+	path, err := sizeCachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "links") {
+		t.Errorf("the cache stores the in-use state:\n%s", data)
+	}
+}
+
+func TestVolumeSizesCacheDisabled(t *testing.T) {
+	cacheDir := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cacheDir)
+	c := sizingDaemon(t, 1e9, 2e9)
+
+	for _, want := range []int64{1e9, 2e9} {
+		sizes, err := c.volumeSizes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sizes["app_data"].bytes; got != want {
+			t.Errorf("size = %d, want %d", got, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cacheDir, sizeCacheFile)); !os.IsNotExist(err) {
+		t.Errorf("cache file written with the cache disabled: %v", err)
+	}
+}
+
+func TestLoadVolumesReadsInUseLive(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			json.NewEncoder(w).Encode(containersMounting("app_data"))
+		case strings.HasSuffix(r.URL.Path, "/volumes"):
+			json.NewEncoder(w).Encode(volume.ListResponse{Volumes: []*volume.Volume{{Name: "app_data"}, {Name: "idle"}}})
+		case strings.HasSuffix(r.URL.Path, "/system/df"):
+			// The disk usage still reports the volume as idle, as a cached result would
+			json.NewEncoder(w).Encode(diskUsage(1e9))
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	c.SetSizeCache(10*time.Minute, false)
+
+	volumes, err := c.LoadVolumes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := volumes["app_data"]; ok {
+		t.Error("LoadVolumes() kept app_data, which a container mounts")
+	}
+	if _, ok := volumes["idle"]; !ok {
+		t.Errorf("LoadVolumes() = %v, want idle", volumes)
+	}
+
+	// Other modes keep in-use volumes as candidates for CheckVolumesInUse
+	if err := c.SetInUsePolicy(RetryPolicy{Mode: InUseError}); err != nil {
+		t.Fatal(err)
+	}
+	volumes, err = c.LoadVolumes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := volumes["app_data"]; !ok {
+		t.Error("LoadVolumes() dropped app_data with --skip-in-use=error")
+	}
+}
+
+func TestLoadVolumesSizesFromDaemon(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	// The mountpoint exists on this machine, but the daemon is not local
+	mountpoint := t.TempDir()
+	if err := os.WriteFile(filepath.Join(mountpoint, "data"), make([]byte, 2048), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			json.NewEncoder(w).Encode([]container.Summary{})
+		case strings.HasSuffix(r.URL.Path, "/volumes"):
+			json.NewEncoder(w).Encode(volume.ListResponse{Volumes: []*volume.Volume{
+				{Name: "app_data", Mountpoint: mountpoint},
+				{Name: "unsized", Mountpoint: mountpoint},
+			}})
+		case strings.HasSuffix(r.URL.Path, "/system/df"):
+			usage := diskUsage(5e9)
+			usage.Volumes = append(usage.Volumes, &volume.Volume{Name: "unsized", UsageData: &volume.UsageData{Size: -1}})
+			json.NewEncoder(w).Encode(usage)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	volumes, err := c.LoadVolumes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := volumes["app_data"]; got.Size != 5e9 || got.SizeHuman != "5.0 GB" {
+		t.Errorf("app_data size = %d (%s), want the daemon's 5e9 (5.0 GB)", got.Size, got.SizeHuman)
+	}
+	if got := volumes["unsized"]; got.Size != 0 || got.SizeHuman != "unknown" {
+		t.Errorf("unsized size = %d (%s), want it unknown instead of walked", got.Size, got.SizeHuman)
+	}
+}
+
+func TestVolumeSizesCachePerDaemon(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	// DOCKER_HOST points at another daemon than --docker-host
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:1")
+
+	first := sizingDaemon(t, 1e9)
+	second := sizingDaemon(t, 2e9)
+	for _, c := range []*Client{first, second} {
+		c.SetSizeCache(10*time.Minute, false)
+	}
+
+	for _, step := range []struct {
+		c    *Client
+		want int64
+	}{{first, 1e9}, {second, 2e9}, {first, 1e9}} {
+		sizes, err := step.c.volumeSizes()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sizes["app_data"].bytes; got != step.want {
+			t.Errorf("%s: size = %d, want %d", step.c.client.DaemonHost(), got, step.want)
+		}
+	}
+
+	path, err := sizeCachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := readSizeCache(path)
+	for _, c := range []*Client{first, second} {
+		host := c.client.DaemonHost()
+		if host == "tcp://127.0.0.1:1" {
+			t.Fatalf("client uses DOCKER_HOST instead of its own host")
+		}
+		if _, ok := cache[host]; !ok {
+			t.Errorf("cache = %v, want an entry for %s", cache, host)
+		}
+	}
+	if _, ok := cache["tcp://127.0.0.1:1"]; ok {
+		t.Error("sizes cached under DOCKER_HOST, which was never queried")
+	}
+}
@@ -2,77 +2,264 @@ package docker
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
-	"regexp"
-	"strconv"
+	"slices"
 	"strings"
 	"syscall"
 	"time"
 
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/log"
 	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/filters"
 	"github.com/docker/docker/api/types/volume"
 	"github.com/docker/docker/client"
 )
 
+var logger = log.New("docker")
+
 type Client struct {
-	client *client.Client
+	client      *client.Client
+	since       time.Duration // Only load volumes modified within this period, 0 loads all
+	labels      []string      // Only load volumes with all of these labels (key or key=value)
+	inUsePolicy RetryPolicy   // What happens to volumes that are in use
+
+	excludeVolumes  []string // Volume names LoadVolumes leaves out
+	excludePrefixes []string // Volume name prefixes LoadVolumes leaves out
+
+	sizeCacheTTL     time.Duration // How long daemon volume sizes are reused, 0 to not cache them
+	refreshSizeCache bool          // Ignore cached volume sizes
+	now              func() time.Time
+
+	extractDirs []string // Directories LoadVolumeFromTar extracted archives to
+	exportImage string   // Image ExportVolumeTar runs tar in, see SetExportImage
+}
+
+// In-use modes of RetryPolicy
+const (
+	InUseWarn  = "warn"  // Skip volumes that are in use
+	InUseRetry = "retry" // Wait until the migrated volumes are no longer in use
+	InUseError = "error" // Fail when a migrated volume is in use
+)
+
+// RetryPolicy decides what happens to volumes that are mounted by a container. With
+// InUseWarn, LoadVolumes skips them; the other modes only apply to the volumes that
+// are migrated, see CheckVolumesInUse.
+type RetryPolicy struct {
+	Mode     string        // InUseWarn, InUseRetry or InUseError
+	Interval time.Duration // How often to check again in retry mode
+	Timeout  time.Duration // How long to wait for volumes to be released in retry mode
 }
 
 type volumeSize struct {
 	bytes int64
 	human string
-	links int
 }
 
-func NewClient() (*Client, error) {
-	dockerClient, err := client.NewClientWithOpts(client.FromEnv)
+func NewClient(caCertFile string) (*Client, error) {
+	return NewClientWithTLS("", caCertFile, "", "")
+}
+
+// NewClientWithTLS connects to the Docker daemon at host (DOCKER_HOST when empty). The
+// daemon certificate is checked against caFile, and certFile/keyFile are presented as
+// the client certificate. These files take precedence over DOCKER_CERT_PATH; when all
+// are empty the TLS settings from the environment are used.
+func NewClientWithTLS(host, caFile, certFile, keyFile string) (*Client, error) {
+	opts := []client.Opt{client.FromEnv}
+	if host != "" {
+		opts = append(opts, client.WithHost(host))
+	}
+	if caFile != "" || certFile != "" || keyFile != "" {
+		tlsConfig, err := newTLSConfig(caFile, certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		// Applied last, so DOCKER_CERT_PATH cannot replace the explicit certificates
+		opts = append(opts, withTLSConfig(tlsConfig))
+	}
+
+	dockerClient, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Docker client: %v", err)
 	}
 
-	return &Client{client: dockerClient}, nil
+	return &Client{client: dockerClient, inUsePolicy: RetryPolicy{Mode: InUseWarn}, now: time.Now}, nil
+}
+
+// withTLSConfig sets the TLS configuration of the transport the host options
+// configured, keeping its dialer
+func withTLSConfig(tlsConfig *tls.Config) client.Opt {
+	return func(c *client.Client) error {
+		transport, ok := c.HTTPClient().Transport.(*http.Transport)
+		if !ok {
+			return fmt.Errorf("cannot configure TLS on Docker client transport %T", c.HTTPClient().Transport)
+		}
+		transport.TLSClientConfig = tlsConfig
+		return nil
+	}
+}
+
+func newTLSConfig(caFile, certFile, keyFile string) (*tls.Config, error) {
+	tlsConfig := &tls.Config{}
+
+	if caFile != "" {
+		caCert, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read Docker CA certificate: %v", err)
+		}
+
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("no valid PEM certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("a Docker client certificate needs both a certificate and a key file")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Docker client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
+// SetSince limits LoadVolumes to volumes with files modified within since
+func (c *Client) SetSince(since time.Duration) {
+	c.since = since
+}
+
+// SetVolumeLabels limits LoadVolumes to volumes that have all of labels (key or key=value)
+func (c *Client) SetVolumeLabels(labels []string) {
+	c.labels = labels
+}
+
+// SetExclusions makes LoadVolumes leave out the volumes named in exact and the volumes
+// whose name starts with one of prefixes
+func (c *Client) SetExclusions(exact, prefixes []string) {
+	c.excludeVolumes = exact
+	c.excludePrefixes = prefixes
+}
+
+// excluded reports whether the volume called name is left out by SetExclusions
+func (c *Client) excluded(name string) bool {
+	if slices.Contains(c.excludeVolumes, name) {
+		return true
+	}
+	for _, prefix := range c.excludePrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetInUsePolicy sets what happens to volumes that are in use, see RetryPolicy
+func (c *Client) SetInUsePolicy(policy RetryPolicy) error {
+	switch policy.Mode {
+	case InUseWarn, InUseError:
+	case InUseRetry:
+		if policy.Interval <= 0 || policy.Timeout <= 0 {
+			return fmt.Errorf("the in-use retry interval and timeout must be positive")
+		}
+	default:
+		return fmt.Errorf("invalid in-use mode %q (valid: %s, %s, %s)", policy.Mode, InUseWarn, InUseRetry, InUseError)
+	}
+	c.inUsePolicy = policy
+	return nil
+}
+
+// labelFilters returns the Docker API filters for volumes that have all of labels
+func labelFilters(labels []string) filters.Args {
+	args := filters.NewArgs()
+	for _, label := range labels {
+		args.Add("label", label)
+	}
+	return args
 }
 
 func (c *Client) LoadVolumes() (map[string]*types.DockerVolumeInfo, error) {
-	volumes, err := c.client.VolumeList(context.Background(), volume.ListOptions{})
+	// The daemon filters the labels, so volumes from other sources are not affected
+	volumes, err := c.client.VolumeList(context.Background(), volume.ListOptions{Filters: labelFilters(c.labels)})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list Docker volumes: %v", err)
 	}
 
-	// Get volume sizes using docker system df -v
-	fmt.Println("Getting volume sizes (this may take a moment)...")
-	volumeSizes, err := c.getVolumeSizesFromDockerDF()
-	if err != nil {
-		fmt.Printf("Warning: Failed to get volume sizes from docker df, falling back to filesystem walk: %v\n", err)
+	// Excluded volumes are dropped up front, so they are neither sized nor checked for use
+	volumes.Volumes = slices.DeleteFunc(volumes.Volumes, func(vol *volume.Volume) bool {
+		return c.excluded(vol.Name)
+	})
+
+	// Get volume sizes from the daemon, like docker system df -v
+	logger.Println("Getting volume sizes (this may take a moment)...")
+	volumeSizes, err := c.volumeSizes()
+	if err != nil && c.localDaemon() {
+		logger.Warnf("Warning: Failed to get volume sizes from the daemon, falling back to filesystem walk: %v\n", err)
+	} else if err != nil {
+		logger.Warnf("Warning: Failed to get volume sizes from the daemon, sizes are unknown: %v\n", err)
+	}
+
+	// The in-use state changes too quickly to cache, so it is always read live
+	var links map[string]int
+	if c.inUsePolicy.Mode == InUseWarn {
+		links, err = c.volumeLinks(context.Background())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var cutoff time.Time
+	if c.since > 0 {
+		cutoff = time.Now().Add(-c.since)
 	}
 
 	result := make(map[string]*types.DockerVolumeInfo)
 	for _, volume := range volumes.Volumes {
 		var size int64
 		var sizeHuman string
-		var links int
 
-		// Try to get size from docker df first
+		// Try to get size from the daemon first
 		if volumeSizes != nil {
 			if dfSize, exists := volumeSizes[volume.Name]; exists {
 				size = dfSize.bytes
 				sizeHuman = dfSize.human
-				links = dfSize.links
 			}
 		}
 
-		// Skip volumes that are currently in use (links > 0)
-		if links > 0 {
-			fmt.Printf("Skipping volume %s (in use: %d links)\n", volume.Name, links)
+		// Skip volumes that are currently in use. In the other modes they are still
+		// candidates, and only checked once they are matched.
+		if n := links[volume.Name]; n > 0 {
+			logger.Printf("Skipping volume %s (in use: %d links)\n", volume.Name, n)
 			continue
 		}
 
-		// Fallback to filesystem walk if docker df didn't work
-		if size == 0 {
+		if !cutoff.IsZero() {
+			if mtime := c.getVolumeMtime(volume.Mountpoint); mtime.Before(cutoff) {
+				logger.Printf("Skipping volume %s (last modified %s, older than --since %s)\n",
+					volume.Name, mtime.Format("2006-01-02"), c.since)
+				continue
+			}
+		}
+
+		// Fallback to filesystem walk if the daemon didn't report a size. The mountpoint
+		// of a remote daemon is not on this machine, so it is not walked.
+		if size == 0 && c.localDaemon() {
 			size, sizeHuman = c.getVolumeSize(volume.Mountpoint)
+		} else if sizeHuman == "" {
+			sizeHuman = "unknown"
 		}
 
 		result[volume.Name] = &types.DockerVolumeInfo{
@@ -80,107 +267,301 @@ func (c *Client) LoadVolumes() (map[string]*types.DockerVolumeInfo, error) {
 			Mountpoint: volume.Mountpoint,
 			Size:       size,
 			SizeHuman:  sizeHuman,
+			CreatedAt:  c.parseCreatedAt(volume.CreatedAt),
+			Driver:     volume.Driver,
+			Labels:     volume.Labels,
 		}
 	}
 
 	return result, nil
 }
 
-func (c *Client) getVolumeSizesFromDockerDF() (map[string]volumeSize, error) {
-	// Set a generous timeout for docker system df -v since it can be slow
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
-	defer cancel()
+// InspectVolume returns the details of a single volume, whether or not LoadVolumes
+// would include it
+func (c *Client) InspectVolume(name string) (*types.DockerVolumeInfo, error) {
+	volume, err := c.client.VolumeInspect(context.Background(), name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect volume %s: %v", name, err)
+	}
 
-	cmd := exec.CommandContext(ctx, "docker", "system", "df", "-v")
-	output, err := cmd.Output()
+	info := &types.DockerVolumeInfo{
+		Name:       volume.Name,
+		Mountpoint: volume.Mountpoint,
+		CreatedAt:  c.parseCreatedAt(volume.CreatedAt),
+		Driver:     volume.Driver,
+		Labels:     volume.Labels,
+	}
+
+	volumeSizes, err := c.volumeSizes()
+	if size, ok := volumeSizes[volume.Name]; err == nil && ok {
+		info.Size, info.SizeHuman = size.bytes, size.human
+	} else if c.localDaemon() {
+		info.Size, info.SizeHuman = c.getVolumeSize(volume.Mountpoint)
+	} else {
+		info.SizeHuman = "unknown"
+	}
+
+	return info, nil
+}
+
+// CheckVolumesInUse applies the in-use policy to the volumes called names, the ones
+// that are migrated: InUseError fails when one of them is in use and InUseRetry waits
+// until they are all released. Volumes on other containers are not looked at.
+func (c *Client) CheckVolumesInUse(names []string) error {
+	if c.inUsePolicy.Mode == InUseWarn || len(names) == 0 {
+		return nil
+	}
+
+	links, err := c.volumeLinks(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("failed to run docker system df -v: %v", err)
+		return err
+	}
+	inUse := inUseVolumes(names, links)
+	if len(inUse) == 0 {
+		return nil
+	}
+	if c.inUsePolicy.Mode == InUseError {
+		return fmt.Errorf("volumes in use by a container: %s", strings.Join(inUse, ", "))
 	}
+	return c.waitForVolumesReleased(names, inUse)
+}
 
-	return c.parseDockerDFOutput(string(output))
+// volumeLinks returns the number of containers, running or not, that mount each
+// volume, like the LINKS column of docker system df. It always asks the daemon; the
+// in-use state is never cached.
+func (c *Client) volumeLinks(ctx context.Context) (map[string]int, error) {
+	containers, err := c.client.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to check whether volumes are in use: %v", err)
+	}
+
+	links := make(map[string]int)
+	for _, summary := range containers {
+		for _, mount := range summary.Mounts {
+			if mount.Type == "volume" {
+				links[mount.Name]++
+			}
+		}
+	}
+	return links, nil
 }
 
-func (c *Client) parseDockerDFOutput(output string) (map[string]volumeSize, error) {
-	lines := strings.Split(output, "\n")
-	volumeSizes := make(map[string]volumeSize)
+// inUseVolumes returns the names that are mounted by a container according to links
+func inUseVolumes(names []string, links map[string]int) []string {
+	var inUse []string
+	for _, name := range names {
+		if links[name] > 0 {
+			inUse = append(inUse, name)
+		}
+	}
+	return inUse
+}
 
-	// Look for lines that have the volume table format
-	// Based on your output: VOLUME NAME    LINKS    SIZE
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+// waitForVolumesReleased polls the containers until none of names is in use
+func (c *Client) waitForVolumesReleased(names, inUse []string) error {
+	deadline := time.Now().Add(c.inUsePolicy.Timeout)
+	for {
+		logger.Printf("Waiting for volumes to be released: %s (retrying in %s)\n", strings.Join(inUse, ", "), c.inUsePolicy.Interval)
+		time.Sleep(c.inUsePolicy.Interval)
 
-		// Skip header and empty lines
-		if line == "" || strings.HasPrefix(line, "VOLUME NAME") || strings.Contains(line, "Local Volumes") {
-			continue
+		links, err := c.volumeLinks(context.Background())
+		if err != nil {
+			return err
+		}
+		inUse = inUseVolumes(names, links)
+		if len(inUse) == 0 {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("volumes still in use after %s: %s", c.inUsePolicy.Timeout, strings.Join(inUse, ", "))
 		}
+	}
+}
+
+// LoadVolumesFromCommand runs an external command that prints a JSON array of
+// volumes, for setups where volumes are not managed by the Docker daemon.
+func (c *Client) LoadVolumesFromCommand(command string) (map[string]*types.DockerVolumeInfo, error) {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stderr = os.Stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to run volumes command: %v", err)
+	}
 
-		// Parse volume line: split by whitespace and expect at least 3 fields
-		fields := strings.Fields(line)
-		if len(fields) >= 3 {
-			volumeName := fields[0]
-			linksStr := fields[1]
-			sizeStr := fields[len(fields)-1] // Size is the last field
+	var volumes []*types.DockerVolumeInfo
+	if err := json.Unmarshal(output, &volumes); err != nil {
+		return nil, fmt.Errorf("failed to parse volumes command output: %v", err)
+	}
 
-			// Parse links (number of containers using this volume)
-			links, err := strconv.Atoi(linksStr)
-			if err != nil {
-				continue // Skip if we can't parse links
-			}
+	result := make(map[string]*types.DockerVolumeInfo)
+	for _, volume := range volumes {
+		if volume.Name == "" {
+			return nil, fmt.Errorf("volumes command returned a volume without a name")
+		}
+
+		// Measure the volume ourselves if the command didn't report a size
+		if volume.Size == 0 && volume.Mountpoint != "" {
+			volume.Size, volume.SizeHuman = c.getVolumeSize(volume.Mountpoint)
+		} else if volume.SizeHuman == "" {
+			volume.SizeHuman = c.formatBytes(volume.Size)
+		}
+
+		result[volume.Name] = volume
+	}
+
+	return result, nil
+}
+
+// VolumeLabelValues returns the value of labelKey for every volume that has the label,
+// keyed by volume name. The daemon does the filtering.
+func (c *Client) VolumeLabelValues(ctx context.Context, labelKey string) (map[string]string, error) {
+	volumes, err := c.client.VolumeList(ctx, volume.ListOptions{Filters: labelFilters([]string{labelKey})})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Docker volumes with label %s: %v", labelKey, err)
+	}
 
-			// Parse size (like "67.42MB", "291.7MB", "0B")
-			bytes, err := c.parseSizeString(sizeStr)
-			if err != nil {
-				continue // Skip this volume if we can't parse the size
+	values := make(map[string]string)
+	for _, vol := range volumes.Volumes {
+		if value := vol.Labels[labelKey]; value != "" {
+			values[vol.Name] = value
+		}
+	}
+	return values, nil
+}
+
+// GetVolumeContainerMounts returns the names of all containers (running or not) that mount volumeName
+func (c *Client) GetVolumeContainerMounts(ctx context.Context, volumeName string) ([]string, error) {
+	containers, err := c.client.ContainerList(ctx, container.ListOptions{All: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Docker containers: %v", err)
+	}
+
+	var names []string
+	for _, summary := range containers {
+		for _, mount := range summary.Mounts {
+			if mount.Type != "volume" || mount.Name != volumeName {
+				continue
 			}
 
-			volumeSizes[volumeName] = volumeSize{
-				bytes: bytes,
-				human: sizeStr,
-				links: links,
+			name := summary.ID
+			if len(summary.Names) > 0 {
+				name = strings.TrimPrefix(summary.Names[0], "/")
 			}
+			names = append(names, name)
+			break
 		}
 	}
 
-	return volumeSizes, nil
+	return names, nil
 }
 
-func (c *Client) parseSizeString(sizeStr string) (int64, error) {
-	// Handle docker df size format like "67.42MB", "291.7MB", "0B", etc.
-	re := regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([KMGTPE]?B)$`)
-	matches := re.FindStringSubmatch(strings.ToUpper(sizeStr))
+// CreateBindMountInfo describes a bind-mounted host directory as a volume so it can be migrated.
+// Its name is the absolute path, not the path as written in the compose file, so
+// the same relative path in two compose files never names the same volume.
+func (c *Client) CreateBindMountInfo(hostPath string) (*types.DockerVolumeInfo, error) {
+	info, err := os.Stat(hostPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat bind mount %s: %v", hostPath, err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("bind mount %s is not a directory", hostPath)
+	}
+
+	size, sizeHuman := c.getVolumeSize(hostPath)
+	return &types.DockerVolumeInfo{
+		Name:       hostPath,
+		Mountpoint: hostPath,
+		Size:       size,
+		SizeHuman:  sizeHuman,
+		CreatedAt:  info.ModTime(),
+		Driver:     "bind",
+	}, nil
+}
 
-	if len(matches) != 3 {
-		return 0, fmt.Errorf("invalid size format: %s", sizeStr)
+// LoadContainerMounts returns the writable overlay layer of every container
+// matching filter, so data stored outside of named volumes can be migrated too.
+func (c *Client) LoadContainerMounts(ctx context.Context, filter map[string]string) (map[string]*types.DockerVolumeInfo, error) {
+	args := filters.NewArgs()
+	for key, value := range filter {
+		args.Add(key, value)
 	}
 
-	value, err := strconv.ParseFloat(matches[1], 64)
+	containers, err := c.client.ContainerList(ctx, container.ListOptions{All: true, Filters: args})
 	if err != nil {
-		return 0, err
-	}
-
-	unit := matches[2]
-	var multiplier int64 = 1
-
-	switch unit {
-	case "B":
-		multiplier = 1
-	case "KB":
-		multiplier = 1000
-	case "MB":
-		multiplier = 1000 * 1000
-	case "GB":
-		multiplier = 1000 * 1000 * 1000
-	case "TB":
-		multiplier = 1000 * 1000 * 1000 * 1000
-	case "PB":
-		multiplier = 1000 * 1000 * 1000 * 1000 * 1000
-	case "EB":
-		multiplier = 1000 * 1000 * 1000 * 1000 * 1000 * 1000
-	default:
-		return 0, fmt.Errorf("unknown unit: %s", unit)
+		return nil, fmt.Errorf("failed to list Docker containers: %v", err)
 	}
 
-	return int64(value * float64(multiplier)), nil
+	result := make(map[string]*types.DockerVolumeInfo)
+	for _, summary := range containers {
+		inspect, err := c.client.ContainerInspect(ctx, summary.ID)
+		if err != nil {
+			logger.Warnf("Warning: Failed to inspect container %s: %v\n", summary.ID, err)
+			continue
+		}
+
+		// Only overlay-style drivers expose the container's writable layer as a directory
+		upperDir := inspect.GraphDriver.Data["UpperDir"]
+		if upperDir == "" {
+			logger.Printf("Skipping container %s (no overlay data path for driver %s)\n", inspect.Name, inspect.GraphDriver.Name)
+			continue
+		}
+
+		name := "container:" + strings.TrimPrefix(inspect.Name, "/")
+		size, sizeHuman := c.getVolumeSize(upperDir)
+
+		result[name] = &types.DockerVolumeInfo{
+			Name:       name,
+			Mountpoint: upperDir,
+			Size:       size,
+			SizeHuman:  sizeHuman,
+			CreatedAt:  c.parseCreatedAt(inspect.Created),
+		}
+	}
+
+	return result, nil
+}
+
+func (c *Client) parseCreatedAt(createdAt string) time.Time {
+	// Docker reports creation times in RFC 3339; older daemons may omit them
+	created, err := time.Parse(time.RFC3339, createdAt)
+	if err != nil {
+		return time.Time{}
+	}
+	return created
+}
+
+// daemonVolumeSizes asks the daemon for the size of every volume, like docker system
+// df -v does. Going through the API keeps --docker-host and the TLS settings.
+func (c *Client) daemonVolumeSizes() (map[string]volumeSize, error) {
+	// Set a generous timeout since sizing all volumes can be slow
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	usage, err := c.client.DiskUsage(ctx, dockertypes.DiskUsageOptions{Types: []dockertypes.DiskUsageObject{dockertypes.VolumeObject}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get volume disk usage: %v", err)
+	}
+
+	volumeSizes := make(map[string]volumeSize)
+	for _, vol := range usage.Volumes {
+		// The daemon reports -1 for volumes it could not size
+		if vol.UsageData == nil || vol.UsageData.Size < 0 {
+			continue
+		}
+		volumeSizes[vol.Name] = volumeSize{
+			bytes: vol.UsageData.Size,
+			human: FormatBytesSI(vol.UsageData.Size),
+		}
+	}
+
+	return volumeSizes, nil
+}
+
+// localDaemon reports whether the daemon runs on this machine, so volume mountpoints
+// can be read directly
+func (c *Client) localDaemon() bool {
+	host := c.client.DaemonHost()
+	return strings.HasPrefix(host, "unix://") || strings.HasPrefix(host, "npipe://")
 }
 
 func (c *Client) getVolumeSize(mountpoint string) (int64, string) {
@@ -207,6 +588,45 @@ func (c *Client) getVolumeSize(mountpoint string) (int64, string) {
 	return totalSize, c.formatBytes(totalSize)
 }
 
+// getVolumeMtime returns the most recent modification time of any file in the volume
+func (c *Client) getVolumeMtime(mountpoint string) time.Time {
+	var latest time.Time
+
+	filepath.Walk(mountpoint, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil // Skip errors, continue walking
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+		return nil
+	})
+
+	return latest
+}
+
+// FormatBytesSI formats bytes with 1000-based units: kB, MB, GB, ...
+func FormatBytesSI(bytes int64) string {
+	return formatBytesWithBase(bytes, 1000, "kMGTPE", "B")
+}
+
+// FormatBytesIEC formats bytes with 1024-based units: KiB, MiB, GiB, ...
+func FormatBytesIEC(bytes int64) string {
+	return formatBytesWithBase(bytes, 1024, "KMGTPE", "iB")
+}
+
+func formatBytesWithBase(bytes, base int64, prefixes, suffix string) string {
+	if bytes < base {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := base, 0
+	for n := bytes / base; n >= base; n /= base {
+		div *= base
+		exp++
+	}
+	return fmt.Sprintf("%.1f %c%s", float64(bytes)/float64(div), prefixes[exp], suffix)
+}
+
 func (c *Client) formatBytes(bytes int64) string {
 	const unit = 1024
 	if bytes < unit {
@@ -0,0 +1,391 @@
+package docker
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	dockertypes "github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/api/types/volume"
+)
+
+// newFakeDaemon starts a TLS server that answers volume inspections like the
+// Docker API, and returns its address and the PEM file of its certificate
+func newFakeDaemon(t *testing.T) (string, string) {
+	t.Helper()
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/volumes/data") {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(volume.Volume{Name: "data", Driver: "local", Mountpoint: "/var/lib/docker/volumes/data/_data"})
+	}))
+	t.Cleanup(server.Close)
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return "tcp://" + server.Listener.Addr().String(), caFile
+}
+
+// writeSelfSignedCert writes an unrelated self-signed certificate and key to dir
+func writeSelfSignedCert(t *testing.T, dir, certName, keyName string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "unrelated"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, certName), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, keyName), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNewClientWithTLS(t *testing.T) {
+	host, caFile := newFakeDaemon(t)
+
+	// DOCKER_CERT_PATH points at certificates the daemon does not trust
+	certPath := t.TempDir()
+	writeSelfSignedCert(t, certPath, "ca.pem", "ca-key.pem")
+	writeSelfSignedCert(t, certPath, "cert.pem", "key.pem")
+
+	tests := []struct {
+		name    string
+		caFile  string
+		wantErr bool
+	}{
+		{"explicit CA overrides environment", caFile, false},
+		{"environment CA only", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DOCKER_HOST", "")
+			t.Setenv("DOCKER_CERT_PATH", certPath)
+			t.Setenv("DOCKER_TLS_VERIFY", "1")
+
+			c, err := NewClientWithTLS(host, tt.caFile, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := c.client.VolumeInspect(context.Background(), "data")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("VolumeInspect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && info.Name != "data" {
+				t.Errorf("volume name = %q, want data", info.Name)
+			}
+		})
+	}
+}
+
+func TestNewTLSConfigNeedsCertAndKey(t *testing.T) {
+	if _, err := newTLSConfig("", "cert.pem", ""); err == nil {
+		t.Error("newTLSConfig accepted a certificate without a key")
+	}
+	if _, err := newTLSConfig(filepath.Join(t.TempDir(), "missing.pem"), "", ""); err == nil {
+		t.Error("newTLSConfig accepted a missing CA file")
+	}
+}
+
+func TestLabelFilters(t *testing.T) {
+	tests := []struct {
+		labels []string
+		want   []string
+	}{
+		{nil, nil},
+		{[]string{"backup"}, []string{"backup"}},
+		{[]string{"app=web", "tier=db"}, []string{"app=web", "tier=db"}},
+	}
+
+	for _, tt := range tests {
+		got := labelFilters(tt.labels).Get("label")
+		if !slices.Equal(sortedCopy(got), tt.want) {
+			t.Errorf("labelFilters(%v) = %v, want %v", tt.labels, got, tt.want)
+		}
+	}
+}
+
+func sortedCopy(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	values = slices.Clone(values)
+	slices.Sort(values)
+	return values
+}
+
+func TestLoadVolumesFiltersByLabel(t *testing.T) {
+	var gotFilters string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/containers/json") {
+			json.NewEncoder(w).Encode([]container.Summary{})
+			return
+		}
+		if !strings.HasSuffix(r.URL.Path, "/volumes") {
+			http.NotFound(w, r)
+			return
+		}
+		gotFilters = r.URL.Query().Get("filters")
+		json.NewEncoder(w).Encode(volume.ListResponse{Volumes: []*volume.Volume{{Name: "data", Labels: map[string]string{"backup": "true"}}}})
+	}))
+	defer server.Close()
+
+	// Without a docker binary the sizes fall back to walking the (missing) mountpoint
+	t.Setenv("PATH", t.TempDir())
+	t.Setenv("DOCKER_CERT_PATH", "")
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+
+	c, err := NewClientWithTLS("tcp://"+server.Listener.Addr().String(), "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.SetVolumeLabels([]string{"backup=true"})
+
+	volumes, err := c.LoadVolumes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := volumes["data"]; !ok || len(volumes) != 1 {
+		t.Errorf("LoadVolumes() = %v, want only data", volumes)
+	}
+	if !strings.Contains(gotFilters, `"label":{"backup=true":true}`) {
+		t.Errorf("volume list filters = %s, want the label filter", gotFilters)
+	}
+}
+
+func TestLoadVolumesExclusions(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			json.NewEncoder(w).Encode([]container.Summary{})
+		case strings.HasSuffix(r.URL.Path, "/volumes"):
+			json.NewEncoder(w).Encode(volume.ListResponse{Volumes: []*volume.Volume{
+				{Name: "app_data"}, {Name: "app_data_backup"}, {Name: "tmp_build"}, {Name: "tmp"}, {Name: "db_data"},
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	c.SetExclusions([]string{"db_data", "missing"}, []string{"tmp_", "app_data_"})
+
+	volumes, err := c.LoadVolumes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for name := range volumes {
+		names = append(names, name)
+	}
+	if got := sortedCopy(names); !slices.Equal(got, []string{"app_data", "tmp"}) {
+		t.Errorf("LoadVolumes() = %v, want app_data and tmp", got)
+	}
+}
+
+func TestVolumeLabelValues(t *testing.T) {
+	var gotFilters string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/volumes") {
+			http.NotFound(w, r)
+			return
+		}
+		gotFilters = r.URL.Query().Get("filters")
+		json.NewEncoder(w).Encode(volume.ListResponse{Volumes: []*volume.Volume{
+			{Name: "data", Labels: map[string]string{"migrate.pvc": "app-data"}},
+			{Name: "empty", Labels: map[string]string{"migrate.pvc": ""}},
+		}})
+	}))
+	defer server.Close()
+
+	t.Setenv("DOCKER_CERT_PATH", "")
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+
+	c, err := NewClientWithTLS("tcp://"+server.Listener.Addr().String(), "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values, err := c.VolumeLabelValues(context.Background(), "migrate.pvc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 1 || values["data"] != "app-data" {
+		t.Errorf("VolumeLabelValues() = %v, want only data=app-data", values)
+	}
+	if !strings.Contains(gotFilters, `"label":{"migrate.pvc":true}`) {
+		t.Errorf("volume list filters = %s, want the label filter", gotFilters)
+	}
+}
+
+// diskUsage returns the /system/df response of a daemon with one volume app_data of size bytes
+func diskUsage(size int64) dockertypes.DiskUsage {
+	return dockertypes.DiskUsage{Volumes: []*volume.Volume{{Name: "app_data", UsageData: &volume.UsageData{Size: size}}}}
+}
+
+// newTestClient returns a client for a plain HTTP fake daemon served by handler
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	t.Setenv("DOCKER_CERT_PATH", "")
+	t.Setenv("DOCKER_TLS_VERIFY", "")
+
+	c, err := NewClientWithTLS("tcp://"+server.Listener.Addr().String(), "", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// containersMounting returns containers that each mount one of volumes
+func containersMounting(volumes ...string) []container.Summary {
+	var containers []container.Summary
+	for i, name := range volumes {
+		containers = append(containers, container.Summary{
+			ID:     fmt.Sprintf("container-%d", i),
+			Mounts: []container.MountPoint{{Type: mount.TypeVolume, Name: name}},
+		})
+	}
+	return containers
+}
+
+func TestCheckVolumesInUse(t *testing.T) {
+	unrelatedInUse := containersMounting("other_db")
+	dataInUse := containersMounting("app_data", "other_db")
+
+	tests := []struct {
+		name       string
+		mode       string
+		containers [][]container.Summary // Answer to each container list, repeating the last one
+		wantErr    bool
+	}{
+		{"warn ignores in-use volumes", InUseWarn, [][]container.Summary{dataInUse}, false},
+		{"error ignores other volumes", InUseError, [][]container.Summary{unrelatedInUse}, false},
+		{"error on a migrated volume", InUseError, [][]container.Summary{dataInUse}, true},
+		{"retry until released", InUseRetry, [][]container.Summary{dataInUse, dataInUse, unrelatedInUse}, false},
+		{"retry times out", InUseRetry, [][]container.Summary{dataInUse}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasSuffix(r.URL.Path, "/containers/json") {
+					http.NotFound(w, r)
+					return
+				}
+				json.NewEncoder(w).Encode(tt.containers[min(calls, len(tt.containers)-1)])
+				calls++
+			})
+			if err := c.SetInUsePolicy(RetryPolicy{Mode: tt.mode, Interval: time.Millisecond, Timeout: 50 * time.Millisecond}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := c.CheckVolumesInUse([]string{"app_data"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CheckVolumesInUse() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := []struct {
+		bytes   int64
+		wantSI  string
+		wantIEC string
+	}{
+		{0, "0 B", "0 B"},
+		{999, "999 B", "999 B"},
+		{1000, "1.0 kB", "1000 B"},
+		{1023, "1.0 kB", "1023 B"},
+		{1024, "1.0 kB", "1.0 KiB"},
+		{1500 * 1000, "1.5 MB", "1.4 MiB"},
+		{3 << 19, "1.6 MB", "1.5 MiB"},
+		{2 * 1000 * 1000 * 1000, "2.0 GB", "1.9 GiB"},
+		{1 << 30, "1.1 GB", "1.0 GiB"},
+		{5 << 40, "5.5 TB", "5.0 TiB"},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.bytes), func(t *testing.T) {
+			if got := FormatBytesSI(tt.bytes); got != tt.wantSI {
+				t.Errorf("FormatBytesSI(%d) = %q, want %q", tt.bytes, got, tt.wantSI)
+			}
+			if got := FormatBytesIEC(tt.bytes); got != tt.wantIEC {
+				t.Errorf("FormatBytesIEC(%d) = %q, want %q", tt.bytes, got, tt.wantIEC)
+			}
+		})
+	}
+}
+
+func TestCreateBindMountInfo(t *testing.T) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+	if err := os.MkdirAll(data, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(data, "db.sqlite"), make([]byte, 2048), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{}
+	info, err := c.CreateBindMountInfo(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The absolute path names the volume, see CreateBindMountInfo
+	if info.Name != data || info.Mountpoint != data || info.Driver != "bind" {
+		t.Errorf("CreateBindMountInfo() = name %q, mountpoint %q, driver %q, want the absolute path as name and mountpoint", info.Name, info.Mountpoint, info.Driver)
+	}
+	if info.Size < 2048 {
+		t.Errorf("size = %d, want at least the size of the files in it", info.Size)
+	}
+
+	for _, path := range []string{filepath.Join(dir, "missing"), filepath.Join(dir, "file")} {
+		if _, err := c.CreateBindMountInfo(path); err == nil {
+			t.Errorf("CreateBindMountInfo(%s) returned no error", path)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package docker
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/image"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// LoadVolumeFromTar extracts a (optionally gzipped) volume export to a temporary
+// directory and returns it as a volume, so exported data can be migrated like any other.
+// The directory is removed by Cleanup.
+func (c *Client) LoadVolumeFromTar(tarPath string) (*types.DockerVolumeInfo, error) {
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tar archive: %v", err)
+	}
+	defer file.Close()
+
+	reader, err := c.tarReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", tarPath, err)
+	}
+
+	extractDir, err := os.MkdirTemp("", "docker-pvc-migration-")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create extraction directory: %v", err)
+	}
+
+	files, size, err := c.extractTar(tar.NewReader(reader), extractDir)
+	if err != nil {
+		os.RemoveAll(extractDir)
+		return nil, fmt.Errorf("failed to extract %s: %v", tarPath, err)
+	}
+
+	c.extractDirs = append(c.extractDirs, extractDir)
+	logger.Printf("Extracted %s to %s (%d files, %s)\n", tarPath, extractDir, files, c.formatBytes(size))
+
+	name := filepath.Base(tarPath)
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+
+	return &types.DockerVolumeInfo{
+		Name:       name,
+		Mountpoint: extractDir,
+		Size:       size,
+		SizeHuman:  c.formatBytes(size),
+		CreatedAt:  time.Now(),
+	}, nil
+}
+
+// Cleanup removes the directories LoadVolumeFromTar extracted archives to
+func (c *Client) Cleanup() {
+	for _, dir := range c.extractDirs {
+		if err := os.RemoveAll(dir); err != nil {
+			logger.Warnf("Warning: Could not remove %s: %v\n", dir, err)
+		}
+	}
+	c.extractDirs = nil
+}
+
+// DefaultExportImage is the image of the containers ExportVolumeTar runs, see SetExportImage
+const DefaultExportImage = "busybox:latest"
+
+// exportArchive is where the export container writes the tarball before it is copied out
+const exportArchive = "/tmp/volume.tar.gz"
+
+// SetExportImage sets the image ExportVolumeTar runs tar in, e.g. one from a private
+// registry, instead of DefaultExportImage. The image must contain tar with gzip support.
+func (c *Client) SetExportImage(image string) {
+	c.exportImage = image
+}
+
+// ExportVolumeTar writes the contents of a Docker volume (or bind-mounted host
+// directory) to destPath as a gzipped tarball. The archive is made by a temporary
+// container on the daemon and copied out through the API, so remote daemons work too.
+func (c *Client) ExportVolumeTar(volumeName, destPath string) error {
+	ctx := context.Background()
+	ref := c.exportImage
+	if ref == "" {
+		ref = DefaultExportImage
+	}
+	if err := c.ensureImage(ctx, ref); err != nil {
+		return err
+	}
+
+	source := mount.Mount{Type: mount.TypeVolume, Source: volumeName, Target: "/data", ReadOnly: true}
+	if filepath.IsAbs(volumeName) {
+		source.Type = mount.TypeBind
+	}
+	created, err := c.client.ContainerCreate(ctx,
+		&container.Config{Image: ref, Cmd: []string{"tar", "-czf", exportArchive, "-C", "/data", "."}},
+		&container.HostConfig{Mounts: []mount.Mount{source}}, nil, nil, "")
+	if err != nil {
+		return fmt.Errorf("failed to create export container for volume %s: %v", volumeName, err)
+	}
+	defer func() {
+		if err := c.client.ContainerRemove(ctx, created.ID, container.RemoveOptions{Force: true}); err != nil {
+			logger.Warnf("Warning: Could not remove export container %s: %v\n", created.ID, err)
+		}
+	}()
+
+	// Waiting is set up before the start, so a quick exit is not missed
+	waitC, errC := c.client.ContainerWait(ctx, created.ID, container.WaitConditionNextExit)
+	if err := c.client.ContainerStart(ctx, created.ID, container.StartOptions{}); err != nil {
+		return fmt.Errorf("failed to start export container for volume %s: %v", volumeName, err)
+	}
+	select {
+	case result := <-waitC:
+		if result.StatusCode != 0 {
+			return fmt.Errorf("failed to export volume %s: tar exited with status %d: %s", volumeName, result.StatusCode, c.containerOutput(ctx, created.ID))
+		}
+	case err := <-errC:
+		return fmt.Errorf("failed to wait for export container for volume %s: %v", volumeName, err)
+	}
+
+	return c.copyExportArchive(ctx, created.ID, destPath)
+}
+
+// ensureImage pulls image unless the daemon already has it, like docker run does
+func (c *Client) ensureImage(ctx context.Context, ref string) error {
+	if _, err := c.client.ImageInspect(ctx, ref); err == nil {
+		return nil
+	}
+	logger.Printf("Pulling %s...\n", ref)
+	progress, err := c.client.ImagePull(ctx, ref, image.PullOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to pull %s: %v", ref, err)
+	}
+	defer progress.Close()
+	if _, err := io.Copy(io.Discard, progress); err != nil {
+		return fmt.Errorf("failed to pull %s: %v", ref, err)
+	}
+	return nil
+}
+
+// copyExportArchive copies the tarball the export container made to destPath
+func (c *Client) copyExportArchive(ctx context.Context, containerID, destPath string) error {
+	// The daemon wraps the copied file in an uncompressed tar archive of its own
+	content, _, err := c.client.CopyFromContainer(ctx, containerID, exportArchive)
+	if err != nil {
+		return fmt.Errorf("failed to copy the export archive: %v", err)
+	}
+	defer content.Close()
+	reader := tar.NewReader(content)
+	if _, err := reader.Next(); err != nil {
+		return fmt.Errorf("failed to read the export archive: %v", err)
+	}
+
+	file, err := os.Create(destPath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %v", destPath, err)
+	}
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		os.Remove(destPath)
+		return fmt.Errorf("failed to write %s: %v", destPath, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write %s: %v", destPath, err)
+	}
+	return nil
+}
+
+// containerOutput returns the output of a stopped container, for error messages
+func (c *Client) containerOutput(ctx context.Context, containerID string) string {
+	logs, err := c.client.ContainerLogs(ctx, containerID, container.LogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return ""
+	}
+	defer logs.Close()
+	var output bytes.Buffer
+	stdcopy.StdCopy(&output, &output, logs)
+	return strings.TrimSpace(output.String())
+}
+
+func (c *Client) tarReader(file *os.File) (io.Reader, error) {
+	// Detect gzip by its magic bytes rather than trusting the file extension
+	buffered := bufio.NewReader(file)
+	magic, err := buffered.Peek(2)
+	if err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(buffered)
+	}
+	return buffered, nil
+}
+
+func (c *Client) extractTar(reader *tar.Reader, targetDir string) (int, int64, error) {
+	var files int
+	var totalSize int64
+
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return files, totalSize, err
+		}
+
+		// Refuse entries that would escape the target directory
+		target := filepath.Join(targetDir, header.Name)
+		if target != targetDir && !strings.HasPrefix(target, targetDir+string(os.PathSeparator)) {
+			return files, totalSize, fmt.Errorf("invalid path in archive: %s", header.Name)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, os.FileMode(header.Mode).Perm()); err != nil {
+				return files, totalSize, err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return files, totalSize, err
+			}
+			out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(header.Mode).Perm())
+			if err != nil {
+				return files, totalSize, err
+			}
+			written, err := io.Copy(out, reader)
+			out.Close()
+			if err != nil {
+				return files, totalSize, err
+			}
+			files++
+			totalSize += written
+		case tar.TypeSymlink:
+			// Links may only point at other entries of the archive, not elsewhere on the host
+			if filepath.IsAbs(header.Linkname) {
+				return files, totalSize, fmt.Errorf("invalid link in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			linkTarget := filepath.Join(filepath.Dir(target), header.Linkname)
+			if linkTarget != targetDir && !strings.HasPrefix(linkTarget, targetDir+string(os.PathSeparator)) {
+				return files, totalSize, fmt.Errorf("invalid link in archive: %s -> %s", header.Name, header.Linkname)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return files, totalSize, err
+			}
+			if err := os.Symlink(header.Linkname, target); err != nil {
+				return files, totalSize, err
+			}
+		default:
+			logger.Warnf("Warning: Skipping unsupported tar entry %s\n", header.Name)
+		}
+	}
+
+	return files, totalSize, nil
+}
@@ -0,0 +1,214 @@
+package docker
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+)
+
+// buildTar returns an archive with the given headers, regular files get their name as content
+func buildTar(t *testing.T, headers []*tar.Header) *tar.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	for _, header := range headers {
+		var content []byte
+		if header.Typeflag == tar.TypeReg {
+			content = []byte(header.Name)
+			header.Size = int64(len(content))
+		}
+		if err := writer.WriteHeader(header); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := writer.Write(content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return tar.NewReader(&buf)
+}
+
+func TestExtractTarLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		linkname string
+		wantErr  bool
+	}{
+		{"sibling", "file.txt", false},
+		{"subdirectory", "dir/../file.txt", false},
+		{"absolute", "/etc/passwd", true},
+		{"parent", "../outside", true},
+		{"nested parent", "dir/../../outside", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			targetDir := t.TempDir()
+			reader := buildTar(t, []*tar.Header{
+				{Name: "file.txt", Typeflag: tar.TypeReg, Mode: 0644},
+				{Name: "link", Typeflag: tar.TypeSymlink, Linkname: tt.linkname},
+			})
+
+			_, _, err := (&Client{}).extractTar(reader, targetDir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extractTar() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, statErr := os.Lstat(filepath.Join(targetDir, "link")); tt.wantErr && statErr == nil {
+				t.Errorf("link was created for %s", tt.linkname)
+			}
+		})
+	}
+}
+
+func TestLoadVolumeFromTarCleanup(t *testing.T) {
+	tarPath := filepath.Join(t.TempDir(), "data.tar")
+	var buf bytes.Buffer
+	writer := tar.NewWriter(&buf)
+	if err := writer.WriteHeader(&tar.Header{Name: "file.txt", Typeflag: tar.TypeReg, Mode: 0644, Size: 4}); err != nil {
+		t.Fatal(err)
+	}
+	writer.Write([]byte("data"))
+	writer.Close()
+	if err := os.WriteFile(tarPath, buf.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Client{}
+	info, err := c.LoadVolumeFromTar(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Name != "data" || info.Size != 4 {
+		t.Errorf("got volume %s of %d bytes, want data of 4 bytes", info.Name, info.Size)
+	}
+
+	c.Cleanup()
+	if _, err := os.Stat(info.Mountpoint); !os.IsNotExist(err) {
+		t.Errorf("extraction directory %s still exists after Cleanup", info.Mountpoint)
+	}
+}
+
+// exportDaemon fakes the API calls of ExportVolumeTar. The export container exits
+// with status and the daemon has the image unless pull is set.
+type exportDaemon struct {
+	status  int
+	pull    bool
+	archive []byte // Content of the tarball made by the container
+
+	created container.CreateRequest
+	pulled  string
+	removed bool
+}
+
+func (d *exportDaemon) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/json") && strings.Contains(path, "/images/"):
+		if d.pull {
+			http.Error(w, `{"message":"no such image"}`, http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("{}"))
+	case strings.HasSuffix(path, "/images/create"):
+		d.pulled = r.URL.Query().Get("fromImage") + ":" + r.URL.Query().Get("tag")
+		w.Write([]byte(`{"status":"Downloaded"}`))
+	case strings.HasSuffix(path, "/containers/create"):
+		json.NewDecoder(r.Body).Decode(&d.created)
+		json.NewEncoder(w).Encode(container.CreateResponse{ID: "export-1"})
+	case strings.HasSuffix(path, "/containers/export-1/wait"):
+		json.NewEncoder(w).Encode(container.WaitResponse{StatusCode: int64(d.status)})
+	case strings.HasSuffix(path, "/containers/export-1/start"):
+		w.WriteHeader(http.StatusNoContent)
+	case strings.HasSuffix(path, "/containers/export-1/logs"):
+		// A stderr frame of the multiplexed log stream
+		message := "tar: /data: Permission denied\n"
+		w.Write(append([]byte{2, 0, 0, 0, 0, 0, 0, byte(len(message))}, message...))
+	case strings.HasSuffix(path, "/containers/export-1/archive"):
+		if r.URL.Query().Get("path") != exportArchive {
+			http.NotFound(w, r)
+			return
+		}
+		stat, _ := json.Marshal(container.PathStat{Name: filepath.Base(exportArchive), Size: int64(len(d.archive))})
+		w.Header().Set("X-Docker-Container-Path-Stat", base64.StdEncoding.EncodeToString(stat))
+		writer := tar.NewWriter(w)
+		writer.WriteHeader(&tar.Header{Name: filepath.Base(exportArchive), Mode: 0644, Size: int64(len(d.archive)), Typeflag: tar.TypeReg})
+		writer.Write(d.archive)
+		writer.Close()
+	case r.Method == http.MethodDelete && strings.HasSuffix(path, "/containers/export-1"):
+		d.removed = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestExportVolumeTar(t *testing.T) {
+	tests := []struct {
+		name      string
+		volume    string
+		image     string
+		pull      bool
+		status    int
+		wantMount mount.Type
+		wantImage string
+		wantErr   string
+	}{
+		{name: "named volume", volume: "app_data", wantMount: mount.TypeVolume, wantImage: DefaultExportImage},
+		{name: "bind mount", volume: "/srv/app/data", wantMount: mount.TypeBind, wantImage: DefaultExportImage},
+		{name: "custom image is pulled", volume: "app_data", image: "registry.local/tools:1.0", pull: true, wantMount: mount.TypeVolume, wantImage: "registry.local/tools:1.0"},
+		{name: "tar fails", volume: "app_data", status: 2, wantMount: mount.TypeVolume, wantImage: DefaultExportImage, wantErr: "Permission denied"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemon := &exportDaemon{status: tt.status, pull: tt.pull, archive: []byte("gzipped volume")}
+			c := newTestClient(t, daemon.ServeHTTP)
+			if tt.image != "" {
+				c.SetExportImage(tt.image)
+			}
+
+			destPath := filepath.Join(t.TempDir(), "data.tar.gz")
+			err := c.ExportVolumeTar(tt.volume, destPath)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("ExportVolumeTar() error = %v, want it to contain %q", err, tt.wantErr)
+				}
+				if _, statErr := os.Stat(destPath); statErr == nil {
+					t.Error("ExportVolumeTar() left a tarball behind after failing")
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			} else if content, err := os.ReadFile(destPath); err != nil || string(content) != "gzipped volume" {
+				t.Errorf("tarball = %q, %v, want the archive made by the container", content, err)
+			}
+
+			if daemon.created.Image != tt.wantImage {
+				t.Errorf("container image = %q, want %q", daemon.created.Image, tt.wantImage)
+			}
+			if tt.pull && daemon.pulled != tt.wantImage {
+				t.Errorf("pulled %q, want %q", daemon.pulled, tt.wantImage)
+			}
+			if !tt.pull && daemon.pulled != "" {
+				t.Errorf("pulled %q although the daemon has the image", daemon.pulled)
+			}
+			mounts := daemon.created.HostConfig.Mounts
+			if len(mounts) != 1 || mounts[0].Type != tt.wantMount || mounts[0].Source != tt.volume || mounts[0].Target != "/data" || !mounts[0].ReadOnly {
+				t.Errorf("mounts = %+v, want %s mounted read-only as a %s", mounts, tt.volume, tt.wantMount)
+			}
+			if !daemon.removed {
+				t.Error("export container was not removed")
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// RESTConfigOptions selects the cluster and adjusts how its API server certificate is verified
+type RESTConfigOptions struct {
+	Kubeconfig            string // Explicit kubeconfig file, overriding in-cluster and default configs
+	Context               string // Kubeconfig context to use instead of the current context
+	InsecureSkipTLSVerify bool
+	CAFile                string
+}
+
+// ClientFactory builds the REST config for one kubeconfig file and context
+type ClientFactory struct {
+	options RESTConfigOptions
+}
+
+func NewClientFactory(options RESTConfigOptions) *ClientFactory {
+	return &ClientFactory{options: options}
+}
+
+// NewRESTConfig is a shorthand for NewClientFactory(options).RESTConfig()
+func NewRESTConfig(options RESTConfigOptions) (*rest.Config, error) {
+	return NewClientFactory(options).RESTConfig()
+}
+
+// clientConfig loads options.Kubeconfig, or $KUBECONFIG or ~/.kube/config when it is empty
+func (f *ClientFactory) clientConfig() clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.ExplicitPath = f.options.Kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: f.options.Context}
+	return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, overrides)
+}
+
+// ValidateContext returns an error when the configured context is not in the kubeconfig
+func (f *ClientFactory) ValidateContext() error {
+	if f.options.Context == "" {
+		return nil
+	}
+
+	raw, err := f.clientConfig().RawConfig()
+	if err != nil {
+		return fmt.Errorf("failed to load kubeconfig: %v", err)
+	}
+	if _, ok := raw.Contexts[f.options.Context]; !ok {
+		return fmt.Errorf("context %q not found in kubeconfig", f.options.Context)
+	}
+	return nil
+}
+
+// RESTConfig returns the config from options.Kubeconfig or options.Context when set,
+// the in-cluster config when running inside a pod, and otherwise the config from
+// $KUBECONFIG or ~/.kube/config.
+func (f *ClientFactory) RESTConfig() (*rest.Config, error) {
+	options := f.options
+
+	var config *rest.Config
+	var err error
+	useKubeconfig := options.Kubeconfig != "" || options.Context != ""
+	if !useKubeconfig {
+		config, err = rest.InClusterConfig()
+	}
+	if useKubeconfig || err != nil {
+		if err := f.ValidateContext(); err != nil {
+			return nil, err
+		}
+		config, err = f.clientConfig().ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load kubeconfig: %v", err)
+		}
+	}
+
+	if options.InsecureSkipTLSVerify {
+		config.TLSClientConfig.Insecure = true
+		config.TLSClientConfig.CAFile = ""
+		config.TLSClientConfig.CAData = nil
+	}
+	if options.CAFile != "" {
+		config.TLSClientConfig.CAFile = options.CAFile
+		config.TLSClientConfig.CAData = nil
+	}
+
+	return config, nil
+}
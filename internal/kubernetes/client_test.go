@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testKubeconfig = `apiVersion: v1
+kind: Config
+current-context: dev
+clusters:
+- name: dev
+  cluster:
+    server: https://dev.example.com:6443
+- name: prod
+  cluster:
+    server: https://prod.example.com:6443
+contexts:
+- name: dev
+  context:
+    cluster: dev
+    user: admin
+- name: prod
+  context:
+    cluster: prod
+    user: admin
+users:
+- name: admin
+  user:
+    token: secret
+`
+
+// writeKubeconfig writes the synthetic kubeconfig to a temp file and returns its path
+func writeKubeconfig(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(testKubeconfig), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestValidateContext(t *testing.T) {
+	kubeconfig := writeKubeconfig(t)
+
+	tests := []struct {
+		name    string
+		options RESTConfigOptions
+		wantErr bool
+	}{
+		{"current context", RESTConfigOptions{Kubeconfig: kubeconfig}, false},
+		{"existing context", RESTConfigOptions{Kubeconfig: kubeconfig, Context: "prod"}, false},
+		{"missing context", RESTConfigOptions{Kubeconfig: kubeconfig, Context: "staging"}, true},
+		{"missing kubeconfig", RESTConfigOptions{Kubeconfig: filepath.Join(t.TempDir(), "missing"), Context: "prod"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := NewClientFactory(tt.options).ValidateContext()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateContext() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClientFactoryRESTConfig(t *testing.T) {
+	kubeconfig := writeKubeconfig(t)
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+
+	tests := []struct {
+		name     string
+		options  RESTConfigOptions
+		wantHost string
+		wantErr  bool
+	}{
+		{name: "current context", options: RESTConfigOptions{Kubeconfig: kubeconfig}, wantHost: "https://dev.example.com:6443"},
+		{name: "selected context", options: RESTConfigOptions{Kubeconfig: kubeconfig, Context: "prod"}, wantHost: "https://prod.example.com:6443"},
+		{name: "missing context", options: RESTConfigOptions{Kubeconfig: kubeconfig, Context: "staging"}, wantErr: true},
+		{name: "insecure", options: RESTConfigOptions{Kubeconfig: kubeconfig, InsecureSkipTLSVerify: true}, wantHost: "https://dev.example.com:6443"},
+		{name: "ca file", options: RESTConfigOptions{Kubeconfig: kubeconfig, CAFile: caFile}, wantHost: "https://dev.example.com:6443"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config, err := NewClientFactory(tt.options).RESTConfig()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RESTConfig() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if config.Host != tt.wantHost {
+				t.Errorf("Host = %q, want %q", config.Host, tt.wantHost)
+			}
+			if config.BearerToken != "secret" {
+				t.Errorf("BearerToken = %q, want the token of the context's user", config.BearerToken)
+			}
+			if config.TLSClientConfig.Insecure != tt.options.InsecureSkipTLSVerify {
+				t.Errorf("Insecure = %v, want %v", config.TLSClientConfig.Insecure, tt.options.InsecureSkipTLSVerify)
+			}
+			if config.TLSClientConfig.CAFile != tt.options.CAFile {
+				t.Errorf("CAFile = %q, want %q", config.TLSClientConfig.CAFile, tt.options.CAFile)
+			}
+		})
+	}
+}
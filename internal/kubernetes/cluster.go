@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// Source provides the PVCs to migrate into, next to the YAML files read by Parser
+type Source interface {
+	ListPVCs(ctx context.Context) ([]*types.PVCInfo, error)
+}
+
+// ClusterSource reads the PVCs in a namespace from a cluster
+type ClusterSource struct {
+	client    clientset.Interface
+	namespace string
+}
+
+func NewClusterSource(client clientset.Interface, namespace string) *ClusterSource {
+	return &ClusterSource{client: client, namespace: namespace}
+}
+
+// NewClusterSourceForConfig creates a ClusterSource for the cluster of config
+func NewClusterSourceForConfig(config *rest.Config, namespace string) (*ClusterSource, error) {
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	return NewClusterSource(client, namespace), nil
+}
+
+// ListClusterPVCs reads the PVCs in namespace from a cluster instead of from YAML files
+func ListClusterPVCs(ctx context.Context, config *rest.Config, namespace string) ([]*types.PVCInfo, error) {
+	source, err := NewClusterSourceForConfig(config, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return source.ListPVCs(ctx)
+}
+
+func (s *ClusterSource) ListPVCs(ctx context.Context) ([]*types.PVCInfo, error) {
+	list, err := s.client.CoreV1().PersistentVolumeClaims(s.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PVCs: %v", err)
+	}
+
+	var pvcs []*types.PVCInfo
+	for _, item := range list.Items {
+		pvc := &types.PVCInfo{
+			Name:        item.Name,
+			Namespace:   item.Namespace,
+			Annotations: item.Annotations,
+		}
+		if item.Spec.StorageClassName != nil {
+			pvc.StorageClass = *item.Spec.StorageClassName
+		}
+		for _, mode := range item.Spec.AccessModes {
+			pvc.AccessModes = append(pvc.AccessModes, string(mode))
+		}
+		if storage, ok := item.Spec.Resources.Requests["storage"]; ok {
+			pvc.RequestedSize = storage.String()
+		}
+		pvcs = append(pvcs, pvc)
+	}
+
+	return pvcs, nil
+}
+
+// MergePVCs combines PVC lists, keeping the first PVC with each namespace and name
+func MergePVCs(lists ...[]*types.PVCInfo) []*types.PVCInfo {
+	var merged []*types.PVCInfo
+	seen := make(map[string]bool)
+	for _, pvcs := range lists {
+		for _, pvc := range pvcs {
+			key := pvc.Namespace + "/" + pvc.Name
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			merged = append(merged, pvc)
+		}
+	}
+	return merged
+}
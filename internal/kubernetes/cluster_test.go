@@ -0,0 +1,106 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestClusterSourceListPVCs(t *testing.T) {
+	storageClass := "fast"
+	client := fake.NewSimpleClientset(
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "shop", Annotations: map[string]string{"team": "web"}},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				StorageClassName: &storageClass,
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("5Gi")},
+				},
+			},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "bare", Namespace: "shop"},
+		},
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "other", Namespace: "billing"},
+		},
+	)
+
+	pvcs, err := NewClusterSource(client, "shop").ListPVCs(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byName := make(map[string]*types.PVCInfo)
+	for _, pvc := range pvcs {
+		byName[pvc.Name] = pvc
+	}
+	if len(byName) != 2 || byName["other"] != nil {
+		t.Fatalf("ListPVCs() = %v, want only the PVCs in shop", pvcs)
+	}
+
+	data := byName["data"]
+	if data.Namespace != "shop" || data.RequestedSize != "5Gi" || data.StorageClass != "fast" ||
+		!slices.Equal(data.AccessModes, []string{"ReadWriteOnce"}) || data.Annotations["team"] != "web" {
+		t.Errorf("data = %+v, want the fields of the live PVC", data)
+	}
+	if bare := byName["bare"]; bare.RequestedSize != "" || bare.StorageClass != "" || len(bare.AccessModes) != 0 {
+		t.Errorf("bare = %+v, want empty fields", bare)
+	}
+}
+
+func TestClusterSourceListError(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "persistentvolumeclaims", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("forbidden")
+	})
+
+	if _, err := NewClusterSource(client, "shop").ListPVCs(context.Background()); err == nil {
+		t.Error("ListPVCs() returned no error when listing fails")
+	}
+}
+
+func TestMergePVCs(t *testing.T) {
+	fromYAML := []*types.PVCInfo{
+		{Name: "data", Namespace: "shop", RequestedSize: "1Gi"},
+		{Name: "cache", Namespace: "shop"},
+	}
+	fromCluster := []*types.PVCInfo{
+		{Name: "data", Namespace: "shop", RequestedSize: "5Gi"},
+		{Name: "data", Namespace: "billing"},
+		{Name: "logs", Namespace: "shop"},
+	}
+
+	tests := []struct {
+		name  string
+		lists [][]*types.PVCInfo
+		want  []string // namespace/name/size of the merged PVCs
+	}{
+		{"none", nil, nil},
+		{"yaml only", [][]*types.PVCInfo{fromYAML}, []string{"shop/data/1Gi", "shop/cache/"}},
+		{"yaml first", [][]*types.PVCInfo{fromYAML, fromCluster}, []string{"shop/data/1Gi", "shop/cache/", "billing/data/", "shop/logs/"}},
+		{"cluster first", [][]*types.PVCInfo{fromCluster, fromYAML}, []string{"shop/data/5Gi", "billing/data/", "shop/logs/", "shop/cache/"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, pvc := range MergePVCs(tt.lists...) {
+				got = append(got, pvc.Namespace+"/"+pvc.Name+"/"+pvc.RequestedSize)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("MergePVCs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package kubernetes
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// FieldSelector filters PVCs with kubectl-style field selectors, e.g.
+// "metadata.namespace=production,metadata.name!=scratch"
+type FieldSelector struct {
+	selector fields.Selector
+}
+
+func ParseFieldSelector(expression string) (*FieldSelector, error) {
+	selector, err := fields.ParseSelector(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid field selector: %v", err)
+	}
+
+	// Like kubectl, reject fields we cannot evaluate instead of silently matching nothing
+	supported := pvcFields(&types.PVCInfo{})
+	for _, requirement := range selector.Requirements() {
+		if _, ok := supported[requirement.Field]; !ok {
+			var names []string
+			for name := range supported {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("field %q is not supported in field selectors, supported fields: %s", requirement.Field, strings.Join(names, ", "))
+		}
+	}
+
+	return &FieldSelector{selector: selector}, nil
+}
+
+func (s *FieldSelector) Matches(pvc *types.PVCInfo) bool {
+	return s.selector.Matches(pvcFields(pvc))
+}
+
+// pvcFields returns the fields a selector can use. The selector runs before volumes
+// are matched and sizes chosen, so fields like newSize or matchedVolume are left out
+// rather than always being empty.
+func pvcFields(pvc *types.PVCInfo) fields.Set {
+	return fields.Set{
+		"metadata.name":                   pvc.Name,
+		"metadata.namespace":              pvc.Namespace,
+		"spec.resources.requests.storage": pvc.RequestedSize,
+		"spec.storageClassName":           pvc.StorageClass,
+		"name":                            pvc.Name,
+		"namespace":                       pvc.Namespace,
+		"requestedSize":                   pvc.RequestedSize,
+		"storageClass":                    pvc.StorageClass,
+	}
+}
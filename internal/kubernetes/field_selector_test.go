@@ -0,0 +1,57 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestParseFieldSelector(t *testing.T) {
+	tests := []struct {
+		expression string
+		wantErr    bool
+	}{
+		{"metadata.namespace=production", false},
+		{"name!=scratch,storageClass=fast", false},
+		{"spec.resources.requests.storage=1Gi", false},
+		{"newSize=10Gi", true},
+		{"matchedVolume=data", true},
+		{"serviceImage=postgres", true},
+		{"metadata.labels=x", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			_, err := ParseFieldSelector(tt.expression)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseFieldSelector(%q) error = %v, wantErr %v", tt.expression, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestFieldSelectorMatches(t *testing.T) {
+	pvc := &types.PVCInfo{Name: "data", Namespace: "production", RequestedSize: "1Gi", StorageClass: "fast"}
+
+	tests := []struct {
+		expression string
+		want       bool
+	}{
+		{"metadata.namespace=production", true},
+		{"namespace=staging", false},
+		{"metadata.name!=scratch,storageClass=fast", true},
+		{"requestedSize=2Gi", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expression, func(t *testing.T) {
+			selector, err := ParseFieldSelector(tt.expression)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := selector.Matches(pvc); got != tt.want {
+				t.Errorf("Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
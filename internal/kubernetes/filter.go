@@ -0,0 +1,38 @@
+package kubernetes
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// ExcludePVCs drops PVCs in any of the given namespaces or whose name matches the
+// glob pattern (e.g. "test-*"). It returns the remaining PVCs and how many were excluded.
+func ExcludePVCs(pvcs []*types.PVCInfo, namespaces []string, namePattern string) ([]*types.PVCInfo, int, error) {
+	if namePattern != "" {
+		if _, err := path.Match(namePattern, ""); err != nil {
+			return nil, 0, fmt.Errorf("invalid PVC name pattern %q: %v", namePattern, err)
+		}
+	}
+
+	excludedNamespaces := make(map[string]bool)
+	for _, namespace := range namespaces {
+		excludedNamespaces[namespace] = true
+	}
+
+	var kept []*types.PVCInfo
+	for _, pvc := range pvcs {
+		if excludedNamespaces[pvc.Namespace] {
+			continue
+		}
+		if namePattern != "" {
+			if matched, _ := path.Match(namePattern, pvc.Name); matched {
+				continue
+			}
+		}
+		kept = append(kept, pvc)
+	}
+
+	return kept, len(pvcs) - len(kept), nil
+}
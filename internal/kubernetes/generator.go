@@ -0,0 +1,97 @@
+package kubernetes
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// sizeBuffer is the extra space added on top of the current volume size
+const sizeBuffer = 0.2
+
+// Generator writes PVC manifests for Docker volumes when no Kubernetes YAML exists yet
+type Generator struct {
+	namespace    string
+	storageClass string
+}
+
+func NewGenerator(namespace, storageClass string) *Generator {
+	if namespace == "" {
+		namespace = "default"
+	}
+	return &Generator{
+		namespace:    namespace,
+		storageClass: storageClass,
+	}
+}
+
+// GenerateFiles writes one <name>.yaml per volume to outputDir and returns the written paths
+func (g *Generator) GenerateFiles(volumes map[string]*types.DockerVolumeInfo, outputDir string) ([]string, error) {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %v", err)
+	}
+
+	var written []string
+	for _, volume := range volumes {
+		name := g.pvcName(volume.Name)
+		if name == "" {
+			logger.Printf("Skipping volume %s (cannot derive a valid PVC name)\n", volume.Name)
+			continue
+		}
+
+		content, err := yaml.Marshal(g.buildPVC(name, volume))
+		if err != nil {
+			return written, fmt.Errorf("failed to generate PVC for %s: %v", volume.Name, err)
+		}
+
+		path := filepath.Join(outputDir, name+".yaml")
+		if err := os.WriteFile(path, content, 0644); err != nil {
+			return written, fmt.Errorf("failed to write %s: %v", path, err)
+		}
+		written = append(written, path)
+	}
+
+	return written, nil
+}
+
+func (g *Generator) buildPVC(name string, volume *types.DockerVolumeInfo) map[string]interface{} {
+	spec := map[string]interface{}{
+		"accessModes": []string{"ReadWriteOnce"},
+		"resources": map[string]interface{}{
+			"requests": map[string]interface{}{
+				"storage": g.pvcSize(volume.Size),
+			},
+		},
+	}
+	if g.storageClass != "" {
+		spec["storageClassName"] = g.storageClass
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "PersistentVolumeClaim",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": g.namespace,
+		},
+		"spec": spec,
+	}
+}
+
+func (g *Generator) pvcName(volumeName string) string {
+	return types.NormalizePVCName(volumeName)
+}
+
+func (g *Generator) pvcSize(volumeBytes int64) string {
+	// Round the volume size plus buffer up to whole Gi, with a minimum of 1Gi
+	const gi = 1024 * 1024 * 1024
+	size := int64(math.Ceil(float64(volumeBytes) * (1 + sizeBuffer) / gi))
+	if size < 1 {
+		size = 1
+	}
+	return fmt.Sprintf("%dGi", size)
+}
@@ -0,0 +1,83 @@
+package kubernetes
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DefaultHelmKeyPattern is the values.yaml key holding the size of a PVC
+const DefaultHelmKeyPattern = "{pvcName}.storage"
+
+// HelmValuesParser reads PVC sizes from a Helm values file
+type HelmValuesParser struct {
+	keyPattern string // Dot path of the size, with {pvcName} and {namespace} placeholders
+}
+
+func NewHelmValuesParser() *HelmValuesParser {
+	return &HelmValuesParser{keyPattern: DefaultHelmKeyPattern}
+}
+
+// SetKeyPattern sets the dot path of the size of a PVC, e.g. "persistence.{pvcName}.size"
+func (h *HelmValuesParser) SetKeyPattern(pattern string) {
+	if pattern != "" {
+		h.keyPattern = pattern
+	}
+}
+
+// ParseValuesFile sets the requested size of every PVC whose key is in the values
+// file at path. PVCs without a key keep the size from their YAML.
+func (h *HelmValuesParser) ParseValuesFile(path string, pvcs []*types.PVCInfo) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read Helm values %s: %v", path, err)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(data, &values); err != nil {
+		return fmt.Errorf("failed to parse Helm values %s: %v", path, err)
+	}
+
+	for _, pvc := range pvcs {
+		key := strings.NewReplacer("{pvcName}", pvc.Name, "{namespace}", pvc.Namespace).Replace(h.keyPattern)
+		value, ok := lookupValue(values, key)
+		if !ok {
+			continue
+		}
+
+		size := fmt.Sprint(value)
+		if _, err := resource.ParseQuantity(size); err != nil {
+			return fmt.Errorf("invalid size %q for PVC %s at %s in %s", size, pvc.Name, key, path)
+		}
+		if size != pvc.RequestedSize {
+			logger.Printf("  %s/%s: size %s from %s (%s)\n", pvc.Namespace, pvc.Name, size, path, key)
+			pvc.RequestedSize = size
+		}
+	}
+	return nil
+}
+
+// lookupValue follows the dot path key through nested maps of values
+func lookupValue(values map[string]interface{}, key string) (interface{}, bool) {
+	var current interface{} = values
+	for _, part := range strings.Split(key, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	if current == nil {
+		return nil, false
+	}
+	if _, ok := current.(map[string]interface{}); ok {
+		return nil, false
+	}
+	return current, true
+}
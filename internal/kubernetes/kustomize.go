@@ -0,0 +1,192 @@
+package kubernetes
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// kustomizationFileNames are the file names kustomize accepts for a kustomization
+var kustomizationFileNames = []string{"kustomization.yaml", "kustomization.yml", "Kustomization"}
+
+// kustomization holds the fields of a kustomization.yaml that decide which PVCs it produces
+type kustomization struct {
+	Namespace             string   `yaml:"namespace"`
+	NamePrefix            string   `yaml:"namePrefix"`
+	NameSuffix            string   `yaml:"nameSuffix"`
+	Resources             []string `yaml:"resources"`
+	Bases                 []string `yaml:"bases"` // Deprecated alias of resources
+	Components            []string `yaml:"components"`
+	PatchesStrategicMerge []string `yaml:"patchesStrategicMerge"`
+	Patches               []struct {
+		Path string `yaml:"path"`
+	} `yaml:"patches"`
+}
+
+// FindKustomization returns the kustomization file in directory, or "" when it has none
+func FindKustomization(directory string) string {
+	for _, name := range kustomizationFileNames {
+		path := filepath.Join(directory, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path
+		}
+	}
+	return ""
+}
+
+// KustomizeParser reads the PVCs of a Kustomize tree. When the kustomize binary is
+// installed its build output is used, so patches are applied; otherwise the
+// resources of every kustomization are followed and only the namespace, namePrefix
+// and nameSuffix transformers are applied. Every PVC keeps the document it was read
+// from in Manifest; the YAML updater only finds PVCs whose name the kustomization kept.
+type KustomizeParser struct {
+	parser    *Parser
+	useBinary bool
+	files     map[string]bool // Files the last ParseDirectory read as resources or patches
+}
+
+// NewKustomizeParser creates a KustomizeParser that parses resource files with parser
+func NewKustomizeParser(parser *Parser) *KustomizeParser {
+	_, err := exec.LookPath("kustomize")
+	return &KustomizeParser{parser: parser, useBinary: err == nil}
+}
+
+// ParseDirectory returns the PVCs the kustomization in directory produces. The PVCs
+// are marked with the directory, since they are built rather than read from a file.
+func (k *KustomizeParser) ParseDirectory(directory string) ([]*types.PVCInfo, []ParseError, error) {
+	k.files = make(map[string]bool)
+
+	pvcs, parseErrors, err := k.follow(directory, make(map[string]bool))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if k.useBinary {
+		built, err := k.build(directory)
+		if err != nil {
+			logger.Printf("Warning: kustomize build failed, following resources instead: %v\n", err)
+		} else {
+			pvcs = built
+		}
+	}
+
+	for _, pvc := range pvcs {
+		pvc.Kustomization = directory
+	}
+	return pvcs, parseErrors, nil
+}
+
+// Files returns the absolute paths of the files the last ParseDirectory read as
+// resources or patches
+func (k *KustomizeParser) Files() map[string]bool {
+	return k.files
+}
+
+// build returns the PVCs in the output of kustomize build
+func (k *KustomizeParser) build(directory string) ([]*types.PVCInfo, error) {
+	output, err := exec.Command("kustomize", "build", directory).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("%v: %s", err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return k.parser.parseYAMLContent(string(output))
+}
+
+// follow returns the PVCs of the kustomization in directory and its resources.
+// visiting holds the kustomizations being followed, to stop reference cycles.
+func (k *KustomizeParser) follow(directory string, visiting map[string]bool) ([]*types.PVCInfo, []ParseError, error) {
+	path := FindKustomization(directory)
+	if path == "" {
+		return nil, nil, fmt.Errorf("no kustomization found in %s", directory)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if visiting[absPath] {
+		return nil, nil, fmt.Errorf("kustomization %s references itself", path)
+	}
+	visiting[absPath] = true
+	defer delete(visiting, absPath)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %v", path, err)
+	}
+	var kust kustomization
+	if err := yaml.Unmarshal(data, &kust); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	var pvcs []*types.PVCInfo
+	var parseErrors []ParseError
+	resources := append(append(append([]string{}, kust.Resources...), kust.Bases...), kust.Components...)
+	for _, resource := range resources {
+		if isRemoteResource(resource) {
+			logger.Printf("Warning: %s: skipping remote resource %s\n", path, resource)
+			continue
+		}
+
+		resourcePath := filepath.Join(directory, resource)
+		info, err := os.Stat(resourcePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: resource %s not found", path, resource)
+		}
+
+		if info.IsDir() {
+			resourcePVCs, resourceErrors, err := k.follow(resourcePath, visiting)
+			if err != nil {
+				return nil, nil, err
+			}
+			pvcs = append(pvcs, resourcePVCs...)
+			parseErrors = append(parseErrors, resourceErrors...)
+			continue
+		}
+
+		k.addFile(resourcePath)
+		filePVCs, err := k.parser.parseYAMLFile(resourcePath)
+		if err != nil {
+			parseErrors = append(parseErrors, ParseError{File: resourcePath, Err: err})
+			continue
+		}
+		pvcs = append(pvcs, filePVCs...)
+	}
+
+	// Patches are not applied, but they are not PVCs of their own either
+	for _, patch := range kust.PatchesStrategicMerge {
+		k.addFile(filepath.Join(directory, patch))
+	}
+	for _, patch := range kust.Patches {
+		if patch.Path != "" {
+			k.addFile(filepath.Join(directory, patch.Path))
+		}
+	}
+
+	for _, pvc := range pvcs {
+		if pvc.StatefulSet == "" {
+			pvc.Name = kust.NamePrefix + pvc.Name + kust.NameSuffix
+		}
+		if kust.Namespace != "" && k.parser.namespaceOverride == "" {
+			pvc.Namespace = kust.Namespace
+		}
+	}
+	return pvcs, parseErrors, nil
+}
+
+func (k *KustomizeParser) addFile(path string) {
+	if absPath, err := filepath.Abs(path); err == nil {
+		k.files[absPath] = true
+	}
+}
+
+// isRemoteResource reports whether a kustomization resource refers to a Git repository or URL
+func isRemoteResource(resource string) bool {
+	return strings.Contains(resource, "://") || strings.HasPrefix(resource, "github.com/") || strings.Contains(resource, "?ref=")
+}
@@ -0,0 +1,204 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeKustomizeFixture writes a base with one PVC and an overlay that renames it,
+// moves it to another namespace and adds a PVC of its own
+func writeKustomizeFixture(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	files := map[string]string{
+		"base/kustomization.yaml": "resources:\n  - pvc.yaml\n",
+		"base/pvc.yaml": `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  labels:
+    app: web
+spec:
+  accessModes: [ReadWriteOnce]
+  volumeMode: Filesystem
+  resources:
+    requests:
+      storage: 1Gi
+`,
+		"overlay/kustomization.yaml": `namespace: prod
+namePrefix: prod-
+resources:
+  - ../base
+  - cache.yaml
+patches:
+  - path: size.yaml
+`,
+		"overlay/cache.yaml": `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: cache
+spec:
+  resources:
+    requests:
+      storage: 2Gi
+`,
+		"overlay/size.yaml": `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+spec:
+  resources:
+    requests:
+      storage: 5Gi
+`,
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func TestKustomizeParserFollowsResources(t *testing.T) {
+	root := writeKustomizeFixture(t)
+
+	tests := []struct {
+		dir  string
+		want []string // namespace/name=size
+	}{
+		{"base", []string{"default/data=1Gi"}},
+		{"overlay", []string{"prod/prod-cache=2Gi", "prod/prod-data=1Gi"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			dir := filepath.Join(root, tt.dir)
+			k := NewKustomizeParser(NewParser())
+			k.useBinary = false
+
+			pvcs, parseErrors, err := k.ParseDirectory(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parseErrors) > 0 {
+				t.Fatalf("ParseDirectory() parse errors = %v", parseErrors)
+			}
+
+			var got []string
+			for _, pvc := range pvcs {
+				got = append(got, pvc.Namespace+"/"+pvc.Name+"="+pvc.RequestedSize)
+				if pvc.Kustomization != dir {
+					t.Errorf("PVC %s Kustomization = %q, want %q", pvc.Name, pvc.Kustomization, dir)
+				}
+				if pvc.Manifest == nil {
+					t.Errorf("PVC %s has no manifest", pvc.Name)
+				}
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseDirectory() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Resources and patches are marked, so the plain parser skips them
+	k := NewKustomizeParser(NewParser())
+	k.useBinary = false
+	if _, _, err := k.ParseDirectory(filepath.Join(root, "overlay")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"base/pvc.yaml", "overlay/cache.yaml", "overlay/size.yaml"} {
+		if path := filepath.Join(root, name); !k.Files()[path] {
+			t.Errorf("Files() does not contain %s", name)
+		}
+	}
+
+	// The base document is kept as it was read, labels and volumeMode included
+	pvcs, _, err := k.ParseDirectory(filepath.Join(root, "base"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	spec, _ := pvcs[0].Manifest["spec"].(map[string]interface{})
+	metadata, _ := pvcs[0].Manifest["metadata"].(map[string]interface{})
+	if spec["volumeMode"] != "Filesystem" || metadata["labels"] == nil {
+		t.Errorf("manifest = %v, want the labels and volumeMode of the base", pvcs[0].Manifest)
+	}
+}
+
+func TestKustomizeParserRejectsCycles(t *testing.T) {
+	root := t.TempDir()
+	for dir, other := range map[string]string{"a": "../b", "b": "../a"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		content := "resources:\n  - " + other + "\n"
+		if err := os.WriteFile(filepath.Join(root, dir, "kustomization.yaml"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	k := NewKustomizeParser(NewParser())
+	k.useBinary = false
+	if _, _, err := k.ParseDirectory(filepath.Join(root, "a")); err == nil {
+		t.Error("ParseDirectory() followed a reference cycle without an error")
+	}
+}
+
+func TestParseYAMLFilesWithKustomization(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // No kustomize binary, so resources are followed
+	root := writeKustomizeFixture(t)
+	overlay := filepath.Join(root, "overlay")
+	if err := os.WriteFile(filepath.Join(overlay, "extra.yaml"), []byte(`apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: extra
+spec:
+  resources:
+    requests:
+      storage: 3Gi
+`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		kustomize bool
+		want      []string
+	}{
+		// extra.yaml is not a resource, so it is parsed as a plain file
+		{"kustomize", true, []string{"default/extra", "prod/prod-cache", "prod/prod-data"}},
+		// The patch is a PVC document of its own when the kustomization is ignored
+		{"no kustomize", false, []string{"default/cache", "default/data", "default/extra"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser()
+			p.SetKustomize(tt.kustomize)
+
+			pvcs, parseErrors, err := p.ParseYAMLFiles(overlay)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(parseErrors) > 0 {
+				t.Fatalf("ParseYAMLFiles() parse errors = %v", parseErrors)
+			}
+
+			var got []string
+			for _, pvc := range pvcs {
+				got = append(got, pvc.Namespace+"/"+pvc.Name)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ParseYAMLFiles() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestParserNormalizesNames(t *testing.T) {
+	tests := []struct {
+		name         string
+		normalize    bool
+		wantName     string
+		wantOriginal string
+	}{
+		{"data", true, "data", ""},
+		{"App_Data", true, "app-data", "App_Data"},
+		{"my data@v2", true, "my-data-v2", "my data@v2"},
+		// Nothing usable is left, so the name is kept
+		{"__", true, "__", ""},
+		{"App_Data", false, "App_Data", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser()
+			p.SetNormalizeNames(tt.normalize)
+
+			pvcs, err := p.parseYAMLContent("apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata:\n  name: \"" + tt.name + "\"\nspec:\n  resources:\n    requests:\n      storage: 1Gi\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(pvcs) != 1 {
+				t.Fatalf("parsed %d PVCs, want 1", len(pvcs))
+			}
+			if pvcs[0].Name != tt.wantName || pvcs[0].OriginalName != tt.wantOriginal {
+				t.Errorf("name = %q (original %q), want %q (original %q)", pvcs[0].Name, pvcs[0].OriginalName, tt.wantName, tt.wantOriginal)
+			}
+		})
+	}
+}
+
+func TestGeneratorUsesNormalizedNames(t *testing.T) {
+	volumes := map[string]*types.DockerVolumeInfo{
+		"App_Data":   {Name: "App_Data", Size: 1024},
+		"my data@v2": {Name: "my data@v2", Size: 1024},
+		"__":         {Name: "__", Size: 1024},
+	}
+
+	dir := t.TempDir()
+	written, err := NewGenerator("default", "").GenerateFiles(volumes, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, path := range written {
+		got = append(got, filepath.Base(path))
+	}
+	slices.Sort(got)
+	// The volume without a usable character is skipped
+	want := []string{"app-data.yaml", "my-data-v2.yaml"}
+	if !slices.Equal(got, want) {
+		t.Errorf("GenerateFiles() = %v, want %v", got, want)
+	}
+	for _, name := range want {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Error(err)
+		}
+	}
+}
@@ -1,71 +1,252 @@
 package kubernetes
 
 import (
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/log"
 	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	internalyaml "github.com/LuukBlankenstijn/docker-pvc-migration/internal/yaml"
 	"k8s.io/apimachinery/pkg/util/yaml"
 )
 
-type Parser struct{}
+var logger = log.New("kubernetes")
+
+type Parser struct {
+	expandEnv           bool
+	defaultNamespace    string // Namespace for PVCs without metadata.namespace
+	namespaceOverride   string // Namespace for all PVCs, ignoring metadata.namespace
+	statefulSetReplicas int    // Number of PVCs generated per StatefulSet volumeClaimTemplate
+	defaultStorageClass string // Storage class for PVCs without spec.storageClassName
+	maxDepth            int    // Subdirectory levels to search for YAML files, negative for unlimited
+	kustomize           bool   // Read the PVCs of a kustomization.yaml in the directory, see KustomizeParser
+	normalizeNames      bool   // Turn PVC names into valid Kubernetes names, see types.NormalizePVCName
+}
 
 func NewParser() *Parser {
-	return &Parser{}
+	return &Parser{defaultNamespace: "default", statefulSetReplicas: 1, maxDepth: -1, kustomize: true, normalizeNames: true}
 }
 
-func (p *Parser) ParseYAMLFiles(directory string) ([]*types.PVCInfo, error) {
-	var pvcs []*types.PVCInfo
+func (p *Parser) SetDefaultNamespace(namespace string) {
+	if namespace != "" {
+		p.defaultNamespace = namespace
+	}
+}
+
+// SetNamespaceOverride puts every PVC in namespace, whatever its metadata.namespace says
+func (p *Parser) SetNamespaceOverride(namespace string) {
+	p.namespaceOverride = namespace
+}
+
+func (p *Parser) SetStatefulSetReplicas(replicas int) {
+	p.statefulSetReplicas = replicas
+}
+
+func (p *Parser) SetDefaultStorageClass(storageClass string) {
+	p.defaultStorageClass = storageClass
+}
+
+// SetMaxDepth limits how many subdirectory levels FindYAMLFiles searches; 0 only
+// searches the directory itself and a negative depth searches all subdirectories
+func (p *Parser) SetMaxDepth(depth int) {
+	p.maxDepth = depth
+}
+
+// SetKustomize makes ParseYAMLFiles read the kustomization in the directory, if it
+// has one, before parsing the remaining YAML files
+func (p *Parser) SetKustomize(kustomize bool) {
+	p.kustomize = kustomize
+}
+
+// SetNormalizeNames makes the parser rename PVCs whose name is not a valid
+// Kubernetes name with types.NormalizePVCName, keeping the name from the YAML in OriginalName
+func (p *Parser) SetNormalizeNames(normalize bool) {
+	p.normalizeNames = normalize
+}
+
+func (p *Parser) SetExpandEnv(expandEnv bool) {
+	p.expandEnv = expandEnv
+}
+
+// ParseError describes a YAML file that could not be parsed
+type ParseError struct {
+	File string
+	Err  error
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
 
-	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+// YAMLFilePatterns are the file name patterns ParseYAMLFiles processes
+var YAMLFilePatterns = []string{"*.yaml", "*.yml"}
+
+// FindYAMLFiles returns the paths of all YAML files in directory without parsing them
+func (p *Parser) FindYAMLFiles(directory string) ([]string, error) {
+	var files []string
+
+	err := internalyaml.WalkYAMLFiles(directory, p.maxDepth, func(path string) error {
+		files = append(files, path)
+		return nil
+	})
+
+	return files, err
+}
+
+// ParseYAMLFiles returns the PVCs found in directory. Files that fail to parse
+// are reported as ParseErrors so one broken file doesn't hide the others. When
+// the directory has a kustomization, its PVCs come first and the files it reads
+// are not parsed again.
+func (p *Parser) ParseYAMLFiles(directory string) ([]*types.PVCInfo, []ParseError, error) {
+	files, err := p.FindYAMLFiles(directory)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var kustomizePVCs []*types.PVCInfo
+	var parseErrors []ParseError
+	kustomized := make(map[string]bool)
+	if p.kustomize && FindKustomization(directory) != "" {
+		kustomizeParser := NewKustomizeParser(p)
+		kustomizePVCs, parseErrors, err = kustomizeParser.ParseDirectory(directory)
 		if err != nil {
-			return err
+			return nil, nil, err
 		}
+		kustomized = kustomizeParser.Files()
+	}
 
-		if !strings.HasSuffix(path, ".yaml") && !strings.HasSuffix(path, ".yml") {
-			return nil
+	var pvcs []*types.PVCInfo
+	for _, path := range files {
+		if absPath, err := filepath.Abs(path); err == nil && kustomized[absPath] {
+			continue
 		}
 
 		filePVCs, err := p.parseYAMLFile(path)
 		if err != nil {
-			return err
+			parseErrors = append(parseErrors, ParseError{File: path, Err: err})
+			continue
 		}
 
 		pvcs = append(pvcs, filePVCs...)
-		return nil
-	})
+	}
 
-	return pvcs, err
+	if len(kustomizePVCs) > 0 {
+		pvcs = MergePVCs(kustomizePVCs, pvcs)
+	}
+	return pvcs, parseErrors, nil
 }
 
 func (p *Parser) parseYAMLFile(filename string) ([]*types.PVCInfo, error) {
-	file, err := os.Open(filename)
+	data, err := os.ReadFile(filename)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
+	content := string(data)
+	if p.expandEnv {
+		if missing := internalyaml.MissingEnvVars(content); len(missing) > 0 {
+			logger.Printf("Warning: %s references unset environment variables: %s\n", filename, strings.Join(missing, ", "))
+		}
+		content = internalyaml.ExpandEnv(content)
+	}
+
+	return p.parseYAMLContent(content)
+}
+
+// parseYAMLContent returns the PVCs in a stream of YAML documents
+func (p *Parser) parseYAMLContent(content string) ([]*types.PVCInfo, error) {
 	var pvcs []*types.PVCInfo
-	decoder := yaml.NewYAMLToJSONDecoder(file)
+	decoder := yaml.NewYAMLToJSONDecoder(strings.NewReader(content))
 
 	for {
 		var obj map[string]interface{}
 		err := decoder.Decode(&obj)
+		if err == io.EOF {
+			break
+		}
 		if err != nil {
-			break // End of file or error
+			return nil, err
 		}
 
-		if kind, ok := obj["kind"].(string); ok && kind == "PersistentVolumeClaim" {
+		switch kind, _ := obj["kind"].(string); kind {
+		case "PersistentVolumeClaim":
 			if pvc := p.parsePVCFromObject(obj); pvc != nil {
 				pvcs = append(pvcs, pvc)
 			}
+		case "StatefulSet":
+			pvcs = append(pvcs, p.parseStatefulSetVolumeTemplates(obj)...)
 		}
 	}
 
 	return pvcs, nil
 }
 
+// parseStatefulSetVolumeTemplates returns the PVCs the StatefulSet controller will
+// create from spec.volumeClaimTemplates: <template>-<statefulset>-<ordinal> for the
+// first statefulSetReplicas ordinals
+func (p *Parser) parseStatefulSetVolumeTemplates(obj map[string]interface{}) []*types.PVCInfo {
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	statefulSet, ok := metadata["name"].(string)
+	if !ok {
+		return nil
+	}
+
+	spec, ok := obj["spec"].(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	templates, ok := spec["volumeClaimTemplates"].([]interface{})
+	if !ok {
+		return nil
+	}
+
+	var pvcs []*types.PVCInfo
+	for _, rawTemplate := range templates {
+		template, ok := rawTemplate.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		templateMetadata, ok := template["metadata"].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		templateName, ok := templateMetadata["name"].(string)
+		if !ok {
+			continue
+		}
+
+		for ordinal := 0; ordinal < p.statefulSetReplicas; ordinal++ {
+			// The PVCs live in the namespace of the StatefulSet
+			pvcMetadata := make(map[string]interface{}, len(templateMetadata)+1)
+			for key, value := range templateMetadata {
+				pvcMetadata[key] = value
+			}
+			pvcMetadata["name"] = fmt.Sprintf("%s-%s-%d", templateName, statefulSet, ordinal)
+			delete(pvcMetadata, "namespace")
+			if namespace, ok := metadata["namespace"]; ok {
+				pvcMetadata["namespace"] = namespace
+			}
+
+			pvc := p.parsePVCFromObject(map[string]interface{}{
+				"metadata": pvcMetadata,
+				"spec":     template["spec"],
+			})
+			if pvc != nil {
+				pvc.StatefulSet = statefulSet
+				pvcs = append(pvcs, pvc)
+			}
+		}
+	}
+
+	return pvcs
+}
+
 func (p *Parser) parsePVCFromObject(obj map[string]interface{}) *types.PVCInfo {
 	metadata, ok := obj["metadata"].(map[string]interface{})
 	if !ok {
@@ -77,10 +258,13 @@ func (p *Parser) parsePVCFromObject(obj map[string]interface{}) *types.PVCInfo {
 		return nil
 	}
 
-	namespace := "default"
+	namespace := p.defaultNamespace
 	if ns, ok := metadata["namespace"].(string); ok {
 		namespace = ns
 	}
+	if p.namespaceOverride != "" {
+		namespace = p.namespaceOverride
+	}
 
 	spec, ok := obj["spec"].(map[string]interface{})
 	if !ok {
@@ -102,9 +286,46 @@ func (p *Parser) parsePVCFromObject(obj map[string]interface{}) *types.PVCInfo {
 		return nil
 	}
 
+	annotations := make(map[string]string)
+	if rawAnnotations, ok := metadata["annotations"].(map[string]interface{}); ok {
+		for key, value := range rawAnnotations {
+			if str, ok := value.(string); ok {
+				annotations[key] = str
+			}
+		}
+	}
+
+	storageClass := p.defaultStorageClass
+	if class, ok := spec["storageClassName"].(string); ok && class != "" {
+		storageClass = class
+	}
+
+	var accessModes []string
+	if modes, ok := spec["accessModes"].([]interface{}); ok {
+		for _, mode := range modes {
+			if str, ok := mode.(string); ok {
+				accessModes = append(accessModes, str)
+			}
+		}
+	}
+
+	var originalName string
+	if normalized := types.NormalizePVCName(name); p.normalizeNames && normalized != name {
+		if normalized == "" {
+			logger.Printf("Warning: PVC name %q has no characters a Kubernetes name can use, keeping it as it is\n", name)
+		} else {
+			originalName, name = name, normalized
+		}
+	}
+
 	return &types.PVCInfo{
 		Name:          name,
+		OriginalName:  originalName,
 		Namespace:     namespace,
 		RequestedSize: storage,
+		Annotations:   annotations,
+		StorageClass:  storageClass,
+		AccessModes:   accessModes,
+		Manifest:      obj,
 	}
 }
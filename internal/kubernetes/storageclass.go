@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// StorageClass annotations with the smallest and largest PVC size the class provisions
+const (
+	MinSizeAnnotation = "docker-pvc-migration/min-size"
+	MaxSizeAnnotation = "docker-pvc-migration/max-size"
+
+	defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+)
+
+// ValidationError is a PVC whose size its storage class does not provision
+type ValidationError struct {
+	PVC          string
+	Namespace    string
+	StorageClass string
+	Message      string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("PVC %s/%s (storage class %s): %s", e.Namespace, e.PVC, e.StorageClass, e.Message)
+}
+
+// StorageClassValidator checks PVC sizes against the size limits of their storage class
+type StorageClassValidator struct {
+	client clientset.Interface
+}
+
+func NewStorageClassValidator(client clientset.Interface) *StorageClassValidator {
+	return &StorageClassValidator{client: client}
+}
+
+// NewStorageClassValidatorForConfig creates a StorageClassValidator for the cluster of config
+func NewStorageClassValidatorForConfig(config *rest.Config) (*StorageClassValidator, error) {
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	return NewStorageClassValidator(client), nil
+}
+
+// ValidatePVCSizes returns an error for every PVC whose new size (or requested size)
+// is below the MinSizeAnnotation or above the MaxSizeAnnotation of its storage class.
+// PVCs without a storage class use the default class. Classes without these
+// annotations accept any size.
+func (v *StorageClassValidator) ValidatePVCSizes(pvcs []*types.PVCInfo) []ValidationError {
+	classes, err := v.client.StorageV1().StorageClasses().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		logger.Printf("Warning: Could not check PVC sizes against storage classes: %v\n", err)
+		return nil
+	}
+
+	var errs []ValidationError
+	for _, pvc := range pvcs {
+		class := findStorageClass(classes.Items, pvc.StorageClass)
+		if class == nil {
+			continue
+		}
+
+		size := pvc.NewSize
+		if size == "" {
+			size = pvc.RequestedSize
+		}
+		quantity, err := resource.ParseQuantity(size)
+		if err != nil {
+			continue
+		}
+
+		fail := func(message string) {
+			errs = append(errs, ValidationError{PVC: pvc.Name, Namespace: pvc.Namespace, StorageClass: class.Name, Message: message})
+		}
+		if limit, ok, err := sizeAnnotation(class, MinSizeAnnotation); err != nil {
+			fail(err.Error())
+		} else if ok && quantity.Cmp(limit) < 0 {
+			fail(fmt.Sprintf("size %s is below the minimum of %s", size, limit.String()))
+		}
+		if limit, ok, err := sizeAnnotation(class, MaxSizeAnnotation); err != nil {
+			fail(err.Error())
+		} else if ok && quantity.Cmp(limit) > 0 {
+			fail(fmt.Sprintf("size %s is above the maximum of %s", size, limit.String()))
+		}
+	}
+	return errs
+}
+
+// findStorageClass returns the class called name, or the default class when name is empty
+func findStorageClass(classes []storagev1.StorageClass, name string) *storagev1.StorageClass {
+	for i, class := range classes {
+		if class.Name == name || (name == "" && class.Annotations[defaultStorageClassAnnotation] == "true") {
+			return &classes[i]
+		}
+	}
+	return nil
+}
+
+// sizeAnnotation parses the size in annotation of class, if it is set
+func sizeAnnotation(class *storagev1.StorageClass, annotation string) (resource.Quantity, bool, error) {
+	value, ok := class.Annotations[annotation]
+	if !ok {
+		return resource.Quantity{}, false, nil
+	}
+	quantity, err := resource.ParseQuantity(value)
+	if err != nil {
+		return resource.Quantity{}, false, fmt.Errorf("invalid %s annotation %q", annotation, value)
+	}
+	return quantity, true, nil
+}
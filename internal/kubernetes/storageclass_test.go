@@ -0,0 +1,78 @@
+package kubernetes
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	storagev1 "k8s.io/api/storage/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func storageClass(name string, annotations map[string]string) *storagev1.StorageClass {
+	return &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: annotations}}
+}
+
+func TestValidatePVCSizes(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		storageClass("limited", map[string]string{MinSizeAnnotation: "10Gi", MaxSizeAnnotation: "1Ti"}),
+		storageClass("min-only", map[string]string{MinSizeAnnotation: "1Gi"}),
+		storageClass("unlimited", nil),
+		storageClass("broken", map[string]string{MaxSizeAnnotation: "lots"}),
+		storageClass("standard", map[string]string{defaultStorageClassAnnotation: "true", MaxSizeAnnotation: "100Gi"}),
+	)
+	validator := NewStorageClassValidator(client)
+
+	tests := []struct {
+		name string
+		pvc  types.PVCInfo
+		want []string // Storage classes of the returned errors
+	}{
+		{"within limits", types.PVCInfo{StorageClass: "limited", NewSize: "20Gi"}, nil},
+		{"at the minimum", types.PVCInfo{StorageClass: "limited", NewSize: "10Gi"}, nil},
+		{"below the minimum", types.PVCInfo{StorageClass: "limited", NewSize: "5Gi"}, []string{"limited"}},
+		{"above the maximum", types.PVCInfo{StorageClass: "limited", NewSize: "2Ti"}, []string{"limited"}},
+		{"requested size without a new size", types.PVCInfo{StorageClass: "limited", RequestedSize: "1Gi"}, []string{"limited"}},
+		{"new size wins over requested size", types.PVCInfo{StorageClass: "limited", RequestedSize: "1Gi", NewSize: "10Gi"}, nil},
+		{"only a minimum", types.PVCInfo{StorageClass: "min-only", NewSize: "10Ti"}, nil},
+		{"no annotations", types.PVCInfo{StorageClass: "unlimited", NewSize: "1Mi"}, nil},
+		{"invalid annotation", types.PVCInfo{StorageClass: "broken", NewSize: "1Gi"}, []string{"broken"}},
+		{"default class", types.PVCInfo{NewSize: "200Gi"}, []string{"standard"}},
+		{"unknown class", types.PVCInfo{StorageClass: "missing", NewSize: "1Mi"}, nil},
+		{"invalid size", types.PVCInfo{StorageClass: "limited", NewSize: "big"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pvc := tt.pvc
+			pvc.Name, pvc.Namespace = "data", "default"
+
+			var got []string
+			for _, err := range validator.ValidatePVCSizes([]*types.PVCInfo{&pvc}) {
+				if err.PVC != "data" || err.Namespace != "default" || err.Message == "" {
+					t.Errorf("error = %+v, want it to name the PVC and the problem", err)
+				}
+				got = append(got, err.StorageClass)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ValidatePVCSizes() errors for classes %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePVCSizesWithoutAccess(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	client.PrependReactor("list", "storageclasses", func(k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, fmt.Errorf("forbidden")
+	})
+
+	pvcs := []*types.PVCInfo{{Name: "data", Namespace: "default", NewSize: "1Gi"}}
+	if errs := NewStorageClassValidator(client).ValidatePVCSizes(pvcs); len(errs) != 0 {
+		t.Errorf("ValidatePVCSizes() = %v, want no errors when the classes cannot be listed", errs)
+	}
+}
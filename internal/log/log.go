@@ -0,0 +1,137 @@
+// Package log prints messages to the console as they are, and additionally writes
+// them as JSON lines to a log file when one is set with SetFile.
+package log
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+var (
+	mu         sync.Mutex
+	console    io.Writer = os.Stdout
+	file       *os.File
+	fileLogger *slog.Logger
+)
+
+// ansiEscape matches the color and cursor codes printed for the console
+var ansiEscape = regexp.MustCompile(`\x1b\[[0-9;]*[A-Za-z]`)
+
+// SetOutput prints messages to w instead of stdout, e.g. stderr for commands that
+// write their results to stdout
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	console = w
+}
+
+// SetFile appends a JSON line with timestamp, level, component, message and optional
+// extra fields to the file at path for every message that is printed
+func SetFile(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file %s: %v", path, err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if file != nil {
+		file.Close()
+	}
+	file = f
+	fileLogger = slog.New(slog.NewJSONHandler(f, &slog.HandlerOptions{
+		Level:       slog.LevelDebug,
+		ReplaceAttr: renameAttr,
+	}))
+	return nil
+}
+
+// Close closes the log file, if any
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if file == nil {
+		return nil
+	}
+	err := file.Close()
+	file, fileLogger = nil, nil
+	return err
+}
+
+// renameAttr names the built-in slog attributes timestamp and message
+func renameAttr(groups []string, attr slog.Attr) slog.Attr {
+	if len(groups) > 0 {
+		return attr
+	}
+	switch attr.Key {
+	case slog.TimeKey:
+		attr.Key = "timestamp"
+	case slog.MessageKey:
+		attr.Key = "message"
+	}
+	return attr
+}
+
+// Logger prints the messages of one component
+type Logger struct {
+	component string
+	extra     map[string]any
+}
+
+func New(component string) *Logger {
+	return &Logger{component: component}
+}
+
+// With returns a logger that adds extra to the log file entry of every message
+func (l *Logger) With(extra map[string]any) *Logger {
+	return &Logger{component: l.component, extra: extra}
+}
+
+func (l *Logger) Print(args ...any) {
+	l.output(slog.LevelInfo, fmt.Sprint(args...))
+}
+
+func (l *Logger) Println(args ...any) {
+	l.output(slog.LevelInfo, fmt.Sprintln(args...))
+}
+
+func (l *Logger) Printf(format string, args ...any) {
+	l.output(slog.LevelInfo, fmt.Sprintf(format, args...))
+}
+
+// Warnf prints a message that is logged with level WARN
+func (l *Logger) Warnf(format string, args ...any) {
+	l.output(slog.LevelWarn, fmt.Sprintf(format, args...))
+}
+
+// Errorf prints a message that is logged with level ERROR
+func (l *Logger) Errorf(format string, args ...any) {
+	l.output(slog.LevelError, fmt.Sprintf(format, args...))
+}
+
+func (l *Logger) output(level slog.Level, text string) {
+	mu.Lock()
+	out, logger := console, fileLogger
+	mu.Unlock()
+
+	fmt.Fprint(out, text)
+	if logger == nil {
+		return
+	}
+
+	message := strings.TrimSpace(ansiEscape.ReplaceAllString(text, ""))
+	if message == "" {
+		return
+	}
+	attrs := []any{slog.String("component", l.component)}
+	if len(l.extra) > 0 {
+		attrs = append(attrs, slog.Any("extra", l.extra))
+	}
+	logger.Log(context.Background(), level, message, attrs...)
+}
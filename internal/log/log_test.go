@@ -0,0 +1,93 @@
+package log
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSetOutput(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(os.Stdout)
+
+	New("test").Printf("Found %d volumes\n", 2)
+
+	if got, want := buf.String(), "Found 2 volumes\n"; got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
+
+func TestSetFileWritesJSONLines(t *testing.T) {
+	var console bytes.Buffer
+	SetOutput(&console)
+	defer SetOutput(os.Stdout)
+
+	path := filepath.Join(t.TempDir(), "migration.log")
+	if err := SetFile(path); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	logger := New("engine")
+	logger.Printf("Copying %s\n", "data")
+	logger.Println("")
+	logger.Warnf("\033[33mWarning: %s\033[0m\n", "slow")
+	logger.With(map[string]any{"pvc": "data", "bytes": 42}).Errorf("Failed \"quoted\"\nsecond line\n")
+	if err := Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	type entry struct {
+		Timestamp time.Time      `json:"timestamp"`
+		Level     string         `json:"level"`
+		Component string         `json:"component"`
+		Message   string         `json:"message"`
+		Extra     map[string]any `json:"extra"`
+	}
+	var entries []entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var e entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", scanner.Text(), err)
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Empty messages are only printed to the console
+	want := []entry{
+		{Level: "INFO", Component: "engine", Message: "Copying data"},
+		{Level: "WARN", Component: "engine", Message: "Warning: slow"},
+		{Level: "ERROR", Component: "engine", Message: "Failed \"quoted\"\nsecond line", Extra: map[string]any{"pvc": "data", "bytes": float64(42)}},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("log file has %d lines, want %d: %+v", len(entries), len(want), entries)
+	}
+	for i, w := range want {
+		got := entries[i]
+		if got.Timestamp.IsZero() || got.Level != w.Level || got.Component != w.Component || got.Message != w.Message {
+			t.Errorf("line %d = %+v, want %+v", i, got, w)
+		}
+		if len(got.Extra) != len(w.Extra) || got.Extra["pvc"] != w.Extra["pvc"] || got.Extra["bytes"] != w.Extra["bytes"] {
+			t.Errorf("line %d extra = %v, want %v", i, got.Extra, w.Extra)
+		}
+	}
+
+	if console.Len() == 0 {
+		t.Error("nothing was printed to the console")
+	}
+}
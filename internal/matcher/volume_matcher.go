@@ -2,51 +2,208 @@ package matcher
 
 import (
 	"bufio"
+	"context"
 	"fmt"
 	"os"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/compose"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/config"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/log"
 	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
 )
 
+var logger = log.New("matcher")
+
+// matchStrategies are the supported --match-strategy values
+var matchStrategies = []string{"interactive", "auto-best", "auto-exact", "compose-only"}
+
+// ContainerMountLookup finds the containers that mount a Docker volume
+type ContainerMountLookup interface {
+	GetVolumeContainerMounts(ctx context.Context, volumeName string) ([]string, error)
+}
+
+// VolumeLabelLookup lists the values of a label on the Docker volumes, keyed by volume name
+type VolumeLabelLookup interface {
+	VolumeLabelValues(ctx context.Context, labelKey string) (map[string]string, error)
+}
+
 type VolumeMatcher struct {
 	dockerVolumes  map[string]*types.DockerVolumeInfo
 	volumeMappings []compose.VolumeMapping
 	composeParser  *compose.Parser
+	mountLookup    ContainerMountLookup
+	labelLookup    VolumeLabelLookup
+	matchStrategy  string
+	driverClasses  map[string]string // Compose volume driver -> Kubernetes storage class
+	batchConfig    *config.Config    // Predefined matches that replace the match strategy
+	matchLabel     string            // Volume label whose value names the PVC to migrate into
+	minScore       int               // Lowest ScoreVolume of the candidates offered interactively
 }
 
+// NewVolumeMatcher creates a matcher for dockerVolumes. Filtering the volumes by label
+// is up to the loader, see docker.Client.SetVolumeLabels.
 func NewVolumeMatcher(dockerVolumes map[string]*types.DockerVolumeInfo) *VolumeMatcher {
 	return &VolumeMatcher{
 		dockerVolumes: dockerVolumes,
 		composeParser: compose.NewParser(),
+		matchStrategy: "interactive",
+	}
+}
+
+func (vm *VolumeMatcher) SetMatchStrategy(strategy string) error {
+	for _, valid := range matchStrategies {
+		if strategy == valid {
+			vm.matchStrategy = strategy
+			return nil
+		}
 	}
+	return fmt.Errorf("invalid match strategy %q, must be one of: %s", strategy, strings.Join(matchStrategies, ", "))
+}
+
+func (vm *VolumeMatcher) SetContainerMountLookup(lookup ContainerMountLookup) {
+	vm.mountLookup = lookup
+}
+
+// SetVolumeLabelLookup makes MatchByLabel ask the Docker daemon which volumes carry
+// the label instead of relying on the labels the volumes were loaded with
+func (vm *VolumeMatcher) SetVolumeLabelLookup(lookup VolumeLabelLookup) {
+	vm.labelLookup = lookup
+}
+
+func (vm *VolumeMatcher) SetDriverStorageClasses(driverClasses map[string]string) {
+	vm.driverClasses = driverClasses
+}
+
+// SetBatchConfig makes PVCs listed in cfg use the configured volume; other PVCs
+// still go through the match strategy
+func (vm *VolumeMatcher) SetBatchConfig(cfg *config.Config) {
+	vm.batchConfig = cfg
+}
+
+// SetMinScore hides interactive candidates with a ScoreVolume below minScore. When
+// no candidate is left, all volumes are offered.
+func (vm *VolumeMatcher) SetMinScore(minScore int) {
+	vm.minScore = minScore
+}
+
+// SetMatchLabel makes volumes labelled labelKey=<pvc name> match that PVC before
+// the match strategy is tried
+func (vm *VolumeMatcher) SetMatchLabel(labelKey string) {
+	vm.matchLabel = labelKey
+}
+
+// MatchByLabel returns the volumes that carry labelKey, keyed by the label value.
+// When several volumes have the same value, the most recently created one wins.
+func (vm *VolumeMatcher) MatchByLabel(labelKey string) map[string]*types.DockerVolumeInfo {
+	values := vm.labelValues(labelKey)
+
+	matches := make(map[string]*types.DockerVolumeInfo)
+	for _, volume := range vm.getAllDockerVolumes() {
+		value := values[volume.Name]
+		if value == "" {
+			continue
+		}
+		if existing, ok := matches[value]; ok {
+			logger.Warnf("Warning: volumes %s and %s both have label %s=%s, using %s\n",
+				existing.Name, volume.Name, labelKey, value, existing.Name)
+			continue
+		}
+		matches[value] = volume
+	}
+	return matches
+}
+
+// labelValues returns the value of labelKey per volume name. Volumes that were not
+// loaded (excluded, or from another source) are ignored by MatchByLabel.
+func (vm *VolumeMatcher) labelValues(labelKey string) map[string]string {
+	if vm.labelLookup != nil {
+		values, err := vm.labelLookup.VolumeLabelValues(context.Background(), labelKey)
+		if err == nil {
+			return values
+		}
+		logger.Warnf("Warning: %v, using the labels the volumes were loaded with\n", err)
+	}
+
+	values := make(map[string]string)
+	for name, volume := range vm.dockerVolumes {
+		if value := volume.Labels[labelKey]; value != "" {
+			values[name] = value
+		}
+	}
+	return values
+}
+
+// SetExclusions removes the volumes named in exact and the volumes whose name starts
+// with one of prefixes from the candidates, wherever the volumes were loaded from
+func (vm *VolumeMatcher) SetExclusions(exact, prefixes []string) {
+	filtered := make(map[string]*types.DockerVolumeInfo)
+	for name, volume := range vm.dockerVolumes {
+		if slices.Contains(exact, name) || slices.ContainsFunc(prefixes, func(prefix string) bool {
+			return strings.HasPrefix(name, prefix)
+		}) {
+			continue
+		}
+		filtered[name] = volume
+	}
+	vm.dockerVolumes = filtered
+}
+
+// SetComposeProfiles sets the active compose profiles, see compose.Parser.SetProfiles
+func (vm *VolumeMatcher) SetComposeProfiles(profiles []string) {
+	vm.composeParser.SetProfiles(profiles)
+}
+
+// SetComposeEnv sets the variables for the compose file, see compose.Parser.SetEnv
+func (vm *VolumeMatcher) SetComposeEnv(env map[string]string) {
+	vm.composeParser.SetEnv(env)
+}
+
+func (vm *VolumeMatcher) SetIncludeBindMounts(includeBindMounts bool) {
+	vm.composeParser.SetIncludeBindMounts(includeBindMounts)
+}
+
+// GetBindMountPaths returns the host directories bind-mounted in the compose file
+func (vm *VolumeMatcher) GetBindMountPaths() []string {
+	var paths []string
+	for _, mapping := range vm.volumeMappings {
+		if mapping.HostPath != "" {
+			paths = append(paths, mapping.HostPath)
+		}
+	}
+	return paths
+}
+
+// AddVolume adds a synthetic volume (e.g. a bind mount) to the candidates
+func (vm *VolumeMatcher) AddVolume(volume *types.DockerVolumeInfo) {
+	vm.dockerVolumes[volume.Name] = volume
 }
 
 func (vm *VolumeMatcher) LoadComposeContext(directory string) error {
 	// Try to find and parse docker-compose file
 	composeFile, err := vm.composeParser.FindComposeFile(directory)
 	if err != nil {
-		fmt.Printf("Warning: %v - using basic matching\n", err)
+		logger.Warnf("Warning: %v - using basic matching\n", err)
 		return nil // Don't fail, just use basic matching
 	}
 
-	fmt.Printf("Found compose file: %s\n", composeFile)
+	logger.Printf("Found compose file: %s\n", composeFile)
 
 	compose, err := vm.composeParser.ParseComposeFile(composeFile)
 	if err != nil {
-		fmt.Printf("Warning: Failed to parse compose file: %v - using basic matching\n", err)
+		logger.Warnf("Warning: Failed to parse compose file: %v - using basic matching\n", err)
 		return nil
 	}
 
 	vm.volumeMappings = vm.composeParser.ExtractVolumeMappings(compose)
-	fmt.Printf("Found %d volume mappings in compose file\n", len(vm.volumeMappings))
+	logger.Printf("Found %d volume mappings in compose file\n", len(vm.volumeMappings))
 
 	// Debug: show the mappings
 	for _, mapping := range vm.volumeMappings {
-		fmt.Printf("  %s:%s -> %s (expected Docker volume: %s)\n",
+		logger.Printf("  %s:%s -> %s (expected Docker volume: %s)\n",
 			mapping.ServiceName, mapping.VolumeName, mapping.MountPath, mapping.DockerVolume)
 	}
 
@@ -54,23 +211,202 @@ func (vm *VolumeMatcher) LoadComposeContext(directory string) error {
 }
 
 func (vm *VolumeMatcher) MatchVolumes(pvcs []*types.PVCInfo) []*types.PVCInfo {
+	var labelled map[string]*types.DockerVolumeInfo
+	if vm.matchLabel != "" {
+		labelled = vm.MatchByLabel(vm.matchLabel)
+	}
+
 	for _, pvc := range pvcs {
-		fmt.Printf("\n--- Matching PVC: %s ---\n", pvc.Name)
+		logger.Printf("\n--- Matching PVC: %s ---\n", pvc.Name)
+
+		if volume := vm.labelMatch(labelled, pvc); volume != nil {
+			pvc.MatchedVolume = volume
+			logger.Printf("Selected: %s (label %s)\n", volume.Name, vm.matchLabel)
+			vm.applyComposeHints(pvc)
+			continue
+		}
+
+		if vm.batchConfig != nil {
+			if record, ok := vm.batchConfig.Lookup(pvc); ok {
+				pvc.MatchedVolume = vm.dockerVolumes[record.DockerVolume]
+				if pvc.MatchedVolume != nil {
+					logger.Printf("Selected: %s (config)\n", pvc.MatchedVolume.Name)
+				} else {
+					logger.Warnf("⚠️  Volume %s from config not found, skipping\n", record.DockerVolume)
+				}
+				vm.applyComposeHints(pvc)
+				continue
+			}
+		}
 
-		// Find all Docker volumes that contain parts of the PVC name
-		candidates := vm.findVolumesContainingPVCName(pvc)
+		switch vm.matchStrategy {
+		case "auto-best":
+			pvc.MatchedVolume = vm.autoBestMatch(pvc)
+		case "auto-exact":
+			pvc.MatchedVolume = vm.autoExactMatch(pvc)
+		case "compose-only":
+			pvc.MatchedVolume = vm.findComposeMatch(pvc)
+		default:
+			pvc.MatchedVolume = vm.interactiveMatch(pvc)
+		}
 
-		if len(candidates) == 0 {
-			fmt.Printf("No Docker volumes found containing '%s'\n", pvc.Name)
-			pvc.MatchedVolume = vm.interactiveVolumeSelection(pvc, vm.getAllDockerVolumes())
-		} else {
-			pvc.MatchedVolume = vm.interactiveVolumeSelection(pvc, candidates)
+		if vm.matchStrategy != "interactive" {
+			if pvc.MatchedVolume != nil {
+				logger.Printf("Selected: %s (%s)\n", pvc.MatchedVolume.Name, vm.matchStrategy)
+			} else {
+				logger.Printf("No match for '%s' (%s), skipping\n", pvc.Name, vm.matchStrategy)
+			}
 		}
+
+		vm.applyComposeHints(pvc)
 	}
 
 	return pvcs
 }
 
+// labelMatch looks pvc up in the MatchByLabel result, accepting both
+// "<name>" and "<namespace>/<name>" label values
+func (vm *VolumeMatcher) labelMatch(labelled map[string]*types.DockerVolumeInfo, pvc *types.PVCInfo) *types.DockerVolumeInfo {
+	if len(labelled) == 0 {
+		return nil
+	}
+	if pvc.Namespace != "" {
+		if volume, ok := labelled[pvc.Namespace+"/"+pvc.Name]; ok {
+			return volume
+		}
+	}
+	return labelled[pvc.Name]
+}
+
+// applyComposeHints copies what the compose file knows about the matched volume to the PVC
+func (vm *VolumeMatcher) applyComposeHints(pvc *types.PVCInfo) {
+	if pvc.MatchedVolume == nil {
+		return
+	}
+	if mapping := vm.findMappingForVolume(pvc.MatchedVolume); mapping != nil {
+		pvc.ServiceImage = mapping.ServiceImage
+		pvc.StorageClassHint = vm.driverClasses[mapping.Driver]
+	}
+}
+
+// GetUnmatchedVolumes returns the Docker volumes that no PVC was matched to
+func (vm *VolumeMatcher) GetUnmatchedVolumes(pvcs []*types.PVCInfo) []*types.DockerVolumeInfo {
+	matched := make(map[string]bool)
+	for _, pvc := range pvcs {
+		if pvc.MatchedVolume != nil {
+			matched[pvc.MatchedVolume.Name] = true
+		}
+	}
+
+	var unmatched []*types.DockerVolumeInfo
+	for _, volume := range vm.getAllDockerVolumes() {
+		if !matched[volume.Name] {
+			unmatched = append(unmatched, volume)
+		}
+	}
+
+	return unmatched
+}
+
+func (vm *VolumeMatcher) interactiveMatch(pvc *types.PVCInfo) *types.DockerVolumeInfo {
+	return vm.interactiveVolumeSelection(pvc, vm.interactiveCandidates(pvc))
+}
+
+// interactiveCandidates returns the volumes offered for pvc: those that contain parts
+// of its name and score at least minScore, or all volumes when there are none
+func (vm *VolumeMatcher) interactiveCandidates(pvc *types.PVCInfo) []*types.DockerVolumeInfo {
+	var candidates []*types.DockerVolumeInfo
+	for _, volume := range vm.findVolumesContainingPVCName(pvc) {
+		if ScoreVolume(pvc.Name, volume) >= vm.minScore {
+			candidates = append(candidates, volume)
+		}
+	}
+
+	if len(candidates) == 0 {
+		logger.Printf("No Docker volumes found containing '%s'\n", pvc.Name)
+		return vm.getAllDockerVolumes()
+	}
+	return candidates
+}
+
+func (vm *VolumeMatcher) autoBestMatch(pvc *types.PVCInfo) *types.DockerVolumeInfo {
+	// A compose mapping is the strongest signal we have
+	if volume := vm.findComposeMatch(pvc); volume != nil {
+		return volume
+	}
+
+	// Candidates are sorted newest first, so ties go to the most recent volume
+	var best *types.DockerVolumeInfo
+	bestScore := 0
+	for _, candidate := range vm.findVolumesContainingPVCName(pvc) {
+		score := ScoreVolume(pvc.Name, candidate)
+		if score > bestScore {
+			bestScore = score
+			best = candidate
+		}
+	}
+
+	return best
+}
+
+func (vm *VolumeMatcher) autoExactMatch(pvc *types.PVCInfo) *types.DockerVolumeInfo {
+	normalizedPVC := vm.normalizeName(pvc.Name)
+	for _, volume := range vm.dockerVolumes {
+		if vm.normalizeName(volume.Name) == normalizedPVC {
+			return volume
+		}
+	}
+
+	return nil
+}
+
+func (vm *VolumeMatcher) normalizeName(name string) string {
+	return strings.ReplaceAll(strings.ToLower(name), "_", "-")
+}
+
+// ScoreVolume rates how well the name of volume matches pvcName; higher is better.
+// Every part of the PVC name that is also a part of the volume name scores 1, and the
+// PVC name without its first part (usually the namespace) scores 3 when the volume
+// name contains it, 2 more when it ends with it and 4 more when it follows a separator.
+func ScoreVolume(pvcName string, volume *types.DockerVolumeInfo) int {
+	return calculateMatchScore(strings.Split(pvcName, "-"), volume.Name) +
+		calculateComposeMatchScore(stripNamespacePrefix(pvcName), volume.Name)
+}
+
+func stripNamespacePrefix(pvcName string) string {
+	if parts := strings.Split(pvcName, "-"); len(parts) > 1 {
+		return strings.Join(parts[1:], "-") // Remove first part (likely namespace)
+	}
+	return pvcName
+}
+
+// ComposeMappings returns the compose volume mappings that use volume
+func (vm *VolumeMatcher) ComposeMappings(volume *types.DockerVolumeInfo) []compose.VolumeMapping {
+	var mappings []compose.VolumeMapping
+	for _, mapping := range vm.volumeMappings {
+		if mapping.DockerVolume == volume.Name || slices.Contains(vm.composeParser.GetVolumeVariations(mapping.VolumeName), volume.Name) {
+			mappings = append(mappings, mapping)
+		}
+	}
+	return mappings
+}
+
+func (vm *VolumeMatcher) findMappingForVolume(volume *types.DockerVolumeInfo) *compose.VolumeMapping {
+	// Find the compose volume that corresponds to this Docker volume
+	for i, mapping := range vm.volumeMappings {
+		if mapping.DockerVolume == volume.Name {
+			return &vm.volumeMappings[i]
+		}
+		for _, variation := range vm.composeParser.GetVolumeVariations(mapping.VolumeName) {
+			if variation == volume.Name {
+				return &vm.volumeMappings[i]
+			}
+		}
+	}
+
+	return nil
+}
+
 func (vm *VolumeMatcher) findComposeMatch(pvc *types.PVCInfo) *types.DockerVolumeInfo {
 	// Try to match PVC name to compose volume mappings
 	for _, mapping := range vm.volumeMappings {
@@ -148,7 +484,7 @@ func (vm *VolumeMatcher) findFuzzyMatchForCompose(volumeName string) *types.Dock
 	bestScore := 0
 
 	for dockerVolumeName := range vm.dockerVolumes {
-		score := vm.calculateComposeMatchScore(volumeName, dockerVolumeName)
+		score := calculateComposeMatchScore(volumeName, dockerVolumeName)
 		if score > bestScore && score > 0 {
 			bestScore = score
 			bestMatch = dockerVolumeName
@@ -162,7 +498,7 @@ func (vm *VolumeMatcher) findFuzzyMatchForCompose(volumeName string) *types.Dock
 	return nil
 }
 
-func (vm *VolumeMatcher) calculateComposeMatchScore(volumeName, dockerVolumeName string) int {
+func calculateComposeMatchScore(volumeName, dockerVolumeName string) int {
 	score := 0
 
 	// Direct substring match
@@ -196,10 +532,7 @@ func (vm *VolumeMatcher) findVolumesContainingPVCName(pvc *types.PVCInfo) []*typ
 		}
 	}
 
-	// Sort by name for consistent display
-	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].Name < candidates[j].Name
-	})
+	vm.sortByCreatedAt(candidates)
 
 	return candidates
 }
@@ -249,36 +582,54 @@ func (vm *VolumeMatcher) getAllDockerVolumes() []*types.DockerVolumeInfo {
 		volumes = append(volumes, volume)
 	}
 
-	// Sort by name for consistent display
+	vm.sortByCreatedAt(volumes)
+
+	return volumes
+}
+
+func (vm *VolumeMatcher) sortByCreatedAt(volumes []*types.DockerVolumeInfo) {
+	// Most recently created first, since that is most likely the active volume.
+	// Fall back to name for volumes created at the same time (or without a date).
 	sort.Slice(volumes, func(i, j int) bool {
+		if !volumes[i].CreatedAt.Equal(volumes[j].CreatedAt) {
+			return volumes[i].CreatedAt.After(volumes[j].CreatedAt)
+		}
 		return volumes[i].Name < volumes[j].Name
 	})
-
-	return volumes
 }
 
 func (vm *VolumeMatcher) interactiveVolumeSelection(pvc *types.PVCInfo, candidates []*types.DockerVolumeInfo) *types.DockerVolumeInfo {
 	reader := bufio.NewReader(os.Stdin)
 
-	fmt.Printf("\nSelect Docker volume for PVC '%s':\n", pvc.Name)
-	fmt.Println("0. Skip (no volume)")
+	logger.Printf("\nSelect Docker volume for PVC '%s':\n", pvc.Name)
+	logger.Println("0. Skip (no volume)")
 
 	for i, volume := range candidates {
-		fmt.Printf("%d. %s (%s)\n", i+1, volume.Name, volume.SizeHuman)
+		line := fmt.Sprintf("%d. %s  %s  [score: %d]", i+1, volume.Name, volume.SizeHuman, ScoreVolume(pvc.Name, volume))
+		if !volume.CreatedAt.IsZero() {
+			line += fmt.Sprintf("  created %s", volume.CreatedAt.Format("2006-01-02"))
+		}
+		if volume.Driver == "bind" {
+			line += "  (bind mount)"
+		}
+		if containers := vm.getMountingContainers(volume); len(containers) > 0 {
+			line += fmt.Sprintf("  (mounted by: %s)", strings.Join(containers, ", "))
+		}
+		logger.Println(line)
 	}
 
 	for {
-		fmt.Print("Enter choice: ")
+		logger.Print("Enter choice: ")
 		input, err := reader.ReadString('\n')
 		if err != nil {
-			fmt.Printf("Error reading input: %v\n", err)
+			logger.Errorf("Error reading input: %v\n", err)
 			continue
 		}
 
 		input = strings.TrimSpace(input)
 		choice, err := strconv.Atoi(input)
 		if err != nil {
-			fmt.Println("Please enter a valid number")
+			logger.Println("Please enter a valid number")
 			continue
 		}
 
@@ -288,12 +639,25 @@ func (vm *VolumeMatcher) interactiveVolumeSelection(pvc *types.PVCInfo, candidat
 
 		if choice >= 1 && choice <= len(candidates) {
 			selected := candidates[choice-1]
-			fmt.Printf("Selected: %s\n", selected.Name)
+			logger.Printf("Selected: %s\n", selected.Name)
 			return selected
 		}
 
-		fmt.Printf("Invalid choice. Please enter 0-%d\n", len(candidates))
+		logger.Printf("Invalid choice. Please enter 0-%d\n", len(candidates))
+	}
+}
+
+func (vm *VolumeMatcher) getMountingContainers(volume *types.DockerVolumeInfo) []string {
+	if vm.mountLookup == nil {
+		return nil
+	}
+
+	// This is informational only, so lookup failures are ignored
+	containers, err := vm.mountLookup.GetVolumeContainerMounts(context.Background(), volume.Name)
+	if err != nil {
+		return nil
 	}
+	return containers
 }
 
 func (vm *VolumeMatcher) findExactMatch(name string) *types.DockerVolumeInfo {
@@ -319,7 +683,7 @@ func (vm *VolumeMatcher) findFuzzyMatch(pvcName string) *types.DockerVolumeInfo
 	bestScore := 0
 
 	for volumeName := range vm.dockerVolumes {
-		score := vm.calculateMatchScore(pvcParts, volumeName)
+		score := calculateMatchScore(pvcParts, volumeName)
 		if score > bestScore && score >= len(pvcParts)/2 {
 			bestScore = score
 			bestMatch = volumeName
@@ -333,7 +697,7 @@ func (vm *VolumeMatcher) findFuzzyMatch(pvcName string) *types.DockerVolumeInfo
 	return nil
 }
 
-func (vm *VolumeMatcher) calculateMatchScore(pvcParts []string, volumeName string) int {
+func calculateMatchScore(pvcParts []string, volumeName string) int {
 	volumeParts := strings.Split(volumeName, "_")
 	volumeParts = append(volumeParts, strings.Split(volumeName, "-")...)
 
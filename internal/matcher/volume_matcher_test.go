@@ -0,0 +1,301 @@
+package matcher
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// fakeLabelLookup answers VolumeLabelValues like a daemon with the given labels
+type fakeLabelLookup struct {
+	values map[string]string
+	err    error
+}
+
+func (f *fakeLabelLookup) VolumeLabelValues(ctx context.Context, labelKey string) (map[string]string, error) {
+	return f.values, f.err
+}
+
+func labelledVolumes() map[string]*types.DockerVolumeInfo {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return map[string]*types.DockerVolumeInfo{
+		"app_data":  {Name: "app_data", CreatedAt: created, Labels: map[string]string{"migrate.pvc": "app-data"}},
+		"app_old":   {Name: "app_old", CreatedAt: created.Add(-time.Hour), Labels: map[string]string{"migrate.pvc": "app-data"}},
+		"db_data":   {Name: "db_data", CreatedAt: created, Labels: map[string]string{"migrate.pvc": "prod/db"}},
+		"unlabeled": {Name: "unlabeled", CreatedAt: created},
+		"other":     {Name: "other", CreatedAt: created, Labels: map[string]string{"team": "web"}},
+	}
+}
+
+func TestMatchByLabel(t *testing.T) {
+	tests := []struct {
+		name   string
+		lookup VolumeLabelLookup
+		want   map[string]string // label value -> volume name
+	}{
+		{
+			name: "loaded labels",
+			want: map[string]string{"app-data": "app_data", "prod/db": "db_data"},
+		},
+		{
+			name:   "daemon lookup",
+			lookup: &fakeLabelLookup{values: map[string]string{"unlabeled": "web", "missing": "gone"}},
+			want:   map[string]string{"web": "unlabeled"},
+		},
+		{
+			name:   "lookup error falls back to loaded labels",
+			lookup: &fakeLabelLookup{err: fmt.Errorf("daemon unavailable")},
+			want:   map[string]string{"app-data": "app_data", "prod/db": "db_data"},
+		},
+		{
+			name:   "no volume has the label",
+			lookup: &fakeLabelLookup{values: map[string]string{}},
+			want:   map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := NewVolumeMatcher(labelledVolumes())
+			if tt.lookup != nil {
+				vm.SetVolumeLabelLookup(tt.lookup)
+			}
+
+			got := vm.MatchByLabel("migrate.pvc")
+			if len(got) != len(tt.want) {
+				t.Fatalf("MatchByLabel() = %v, want %v", got, tt.want)
+			}
+			for value, name := range tt.want {
+				if got[value] == nil || got[value].Name != name {
+					t.Errorf("MatchByLabel()[%q] = %v, want %s", value, got[value], name)
+				}
+			}
+		})
+	}
+}
+
+func TestMatchByLabelDuplicatesAreDeterministic(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	volumes := map[string]*types.DockerVolumeInfo{
+		"b": {Name: "b", CreatedAt: created, Labels: map[string]string{"migrate.pvc": "data"}},
+		"a": {Name: "a", CreatedAt: created, Labels: map[string]string{"migrate.pvc": "data"}},
+		"c": {Name: "c", CreatedAt: created.Add(-time.Hour), Labels: map[string]string{"migrate.pvc": "data"}},
+	}
+
+	// Same creation time falls back to the name, so a wins on every run
+	for i := 0; i < 20; i++ {
+		got := NewVolumeMatcher(volumes).MatchByLabel("migrate.pvc")
+		if got["data"] == nil || got["data"].Name != "a" {
+			t.Fatalf("MatchByLabel()[data] = %v, want a", got["data"])
+		}
+	}
+}
+
+func TestMatchVolumesByLabel(t *testing.T) {
+	tests := []struct {
+		pvc  *types.PVCInfo
+		want string
+	}{
+		{&types.PVCInfo{Name: "app-data", Namespace: "default"}, "app_data"},
+		{&types.PVCInfo{Name: "db", Namespace: "prod"}, "db_data"},
+		{&types.PVCInfo{Name: "db", Namespace: "staging"}, ""},
+		{&types.PVCInfo{Name: "cache", Namespace: "default"}, ""},
+	}
+
+	for _, tt := range tests {
+		vm := NewVolumeMatcher(labelledVolumes())
+		vm.SetMatchLabel("migrate.pvc")
+		if err := vm.SetMatchStrategy("auto-exact"); err != nil {
+			t.Fatal(err)
+		}
+
+		vm.MatchVolumes([]*types.PVCInfo{tt.pvc})
+		got := ""
+		if tt.pvc.MatchedVolume != nil {
+			got = tt.pvc.MatchedVolume.Name
+		}
+		if got != tt.want {
+			t.Errorf("PVC %s/%s matched %q, want %q", tt.pvc.Namespace, tt.pvc.Name, got, tt.want)
+		}
+	}
+}
+
+func TestScoreVolume(t *testing.T) {
+	tests := []struct {
+		pvcName    string
+		volumeName string
+		want       int
+	}{
+		{"myapp-data", "myapp_data", 11},
+		{"myapp-data", "MyApp_Data", 11},
+		{"myapp-data", "other_cache", 0},
+		{"data", "data", 6},
+		{"prod-db-data", "prod-db-data", 12},
+		{"prod-db-data", "myapp_db_data_backup", 2},
+		{"myapp-data", "datastore", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pvcName+"/"+tt.volumeName, func(t *testing.T) {
+			got := ScoreVolume(tt.pvcName, &types.DockerVolumeInfo{Name: tt.volumeName})
+			if got != tt.want {
+				t.Errorf("ScoreVolume(%q, %q) = %d, want %d", tt.pvcName, tt.volumeName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractiveCandidatesMinScore(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	volumes := map[string]*types.DockerVolumeInfo{
+		"myapp_data": {Name: "myapp_data", CreatedAt: created}, // score 11
+		"datastore":  {Name: "datastore", CreatedAt: created},  // score 3
+		"unrelated":  {Name: "unrelated", CreatedAt: created},  // not a candidate
+	}
+
+	tests := []struct {
+		minScore int
+		want     []string
+	}{
+		{0, []string{"datastore", "myapp_data"}},
+		{3, []string{"datastore", "myapp_data"}},
+		{4, []string{"myapp_data"}},
+		{11, []string{"myapp_data"}},
+		// No candidate is left, so every volume is offered
+		{12, []string{"datastore", "myapp_data", "unrelated"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.minScore), func(t *testing.T) {
+			vm := NewVolumeMatcher(volumes)
+			vm.SetMinScore(tt.minScore)
+
+			var got []string
+			for _, volume := range vm.interactiveCandidates(&types.PVCInfo{Name: "myapp-data"}) {
+				got = append(got, volume.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("candidates with --min-score=%d = %v, want %v", tt.minScore, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetExclusions(t *testing.T) {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	volumes := map[string]*types.DockerVolumeInfo{
+		"app-data":        {Name: "app-data", CreatedAt: created},
+		"app-data-backup": {Name: "app-data-backup", CreatedAt: created},
+		"app_data":        {Name: "app_data", CreatedAt: created},
+	}
+
+	tests := []struct {
+		name     string
+		exact    []string
+		prefixes []string
+		want     []string
+	}{
+		{"none", nil, nil, []string{"app-data", "app-data-backup", "app_data"}},
+		{"exact name", []string{"app-data"}, nil, []string{"app-data-backup", "app_data"}},
+		{"prefix", nil, []string{"app-data"}, []string{"app_data"}},
+		{"exact and prefix", []string{"app_data"}, []string{"app-data-"}, []string{"app-data"}},
+		{"everything", []string{"app_data"}, []string{"app"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vm := NewVolumeMatcher(volumes)
+			vm.SetExclusions(tt.exact, tt.prefixes)
+
+			var got []string
+			for _, volume := range vm.interactiveCandidates(&types.PVCInfo{Name: "app-data"}) {
+				got = append(got, volume.Name)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("candidates = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// An excluded volume is not matched even when its name equals the PVC name
+	vm := NewVolumeMatcher(volumes)
+	vm.SetExclusions([]string{"app-data"}, []string{"app_"})
+	if err := vm.SetMatchStrategy("auto-exact"); err != nil {
+		t.Fatal(err)
+	}
+	pvc := &types.PVCInfo{Name: "app-data", Namespace: "default"}
+	vm.MatchVolumes([]*types.PVCInfo{pvc})
+	if pvc.MatchedVolume != nil {
+		t.Errorf("PVC app-data matched excluded volume %s", pvc.MatchedVolume.Name)
+	}
+}
+
+func TestBindMountPathsAreResolved(t *testing.T) {
+	root := t.TempDir()
+	project := filepath.Join(root, "stacks", "app")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatal(err)
+	}
+	compose := `services:
+  app:
+    volumes:
+      - ./data:/data
+      - ../shared/config:/config
+      - /srv/media:/media
+      - type: bind
+        source: uploads
+        target: /uploads
+      - app-cache:/cache
+`
+	if err := os.WriteFile(filepath.Join(project, "compose.yml"), []byte(compose), 0644); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		filepath.Join(project, "data"),
+		filepath.Join(project, "uploads"),
+		filepath.Join(root, "stacks", "shared", "config"),
+		"/srv/media",
+	}
+	slices.Sort(want)
+
+	tests := []struct {
+		name string
+		dir  string // Directory passed to LoadComposeContext, relative to root
+	}{
+		{"absolute directory", project},
+		{"relative directory", filepath.Join("stacks", "app")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Chdir(root)
+			vm := NewVolumeMatcher(map[string]*types.DockerVolumeInfo{})
+			vm.SetIncludeBindMounts(true)
+			if err := vm.LoadComposeContext(tt.dir); err != nil {
+				t.Fatal(err)
+			}
+
+			got := vm.GetBindMountPaths()
+			slices.Sort(got)
+			if !slices.Equal(got, want) {
+				t.Errorf("GetBindMountPaths() = %v, want %v", got, want)
+			}
+		})
+	}
+
+	// Without --include-bind-mounts bind mounts are not candidates
+	vm := NewVolumeMatcher(map[string]*types.DockerVolumeInfo{})
+	if err := vm.LoadComposeContext(project); err != nil {
+		t.Fatal(err)
+	}
+	if got := vm.GetBindMountPaths(); len(got) != 0 {
+		t.Errorf("GetBindMountPaths() = %v without bind mounts included", got)
+	}
+}
@@ -0,0 +1,26 @@
+package migration
+
+import "fmt"
+
+func (e *Engine) SetUseNodeAffinity(useNodeAffinity bool) {
+	e.useNodeAffinity = useNodeAffinity
+}
+
+// buildNodeSelection pins a pod to nodeName, with spec.nodeName or, when node affinity
+// is enabled, a required affinity on the kubernetes.io/hostname label that still lets
+// the scheduler place the pod
+func (e *Engine) buildNodeSelection(nodeName string) string {
+	if !e.useNodeAffinity {
+		return fmt.Sprintf("  nodeName: %s\n", nodeName)
+	}
+	return fmt.Sprintf(`  affinity:
+    nodeAffinity:
+      requiredDuringSchedulingIgnoredDuringExecution:
+        nodeSelectorTerms:
+        - matchExpressions:
+          - key: kubernetes.io/hostname
+            operator: In
+            values:
+            - %s
+`, nodeName)
+}
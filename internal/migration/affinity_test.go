@@ -0,0 +1,52 @@
+package migration
+
+import (
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestBuildNodeSelection(t *testing.T) {
+	tests := []struct {
+		name            string
+		useNodeAffinity bool
+		wantNodeName    string
+		wantAffinity    *corev1.Affinity
+	}{
+		{
+			name:         "node name",
+			wantNodeName: "node-1",
+		},
+		{
+			name:            "node affinity",
+			useNodeAffinity: true,
+			wantAffinity: &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+					NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+						MatchExpressions: []corev1.NodeSelectorRequirement{{
+							Key:      "kubernetes.io/hostname",
+							Operator: corev1.NodeSelectorOpIn,
+							Values:   []string{"node-1"},
+						}},
+					}},
+				},
+			}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			e.SetUseNodeAffinity(tt.useNodeAffinity)
+
+			pod := renderMigrationPod(t, e, "")
+			if pod.Spec.NodeName != tt.wantNodeName {
+				t.Errorf("nodeName = %q, want %q", pod.Spec.NodeName, tt.wantNodeName)
+			}
+			if !reflect.DeepEqual(pod.Spec.Affinity, tt.wantAffinity) {
+				t.Errorf("affinity = %+v, want %+v", pod.Spec.Affinity, tt.wantAffinity)
+			}
+		})
+	}
+}
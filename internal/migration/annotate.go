@@ -0,0 +1,53 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8stypes "k8s.io/apimachinery/pkg/types"
+)
+
+// Annotations recording where a migrated PVC came from, see SetAnnotate
+const (
+	sourceVolumeAnnotation  = "pvc-migration/source-volume"
+	migrationDateAnnotation = "pvc-migration/migration-date"
+	toolVersionAnnotation   = "pvc-migration/tool-version"
+)
+
+// SetAnnotate makes createPVC annotate every PVC with its Docker volume, the
+// migration date and the version of this tool
+func (e *Engine) SetAnnotate(annotate bool) {
+	e.annotate = annotate
+}
+
+// annotatePVC adds the migration annotations to the PVC in the cluster
+func (e *Engine) annotatePVC(ctx context.Context, pvc *types.PVCInfo) error {
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
+
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": map[string]string{
+				sourceVolumeAnnotation:  pvc.MatchedVolume.Name,
+				migrationDateAnnotation: time.Now().UTC().Format(time.RFC3339),
+				toolVersionAnnotation:   toolVersion(),
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	namespace := e.namespaceFor(pvc)
+	_, err = client.CoreV1().PersistentVolumeClaims(namespace).Patch(ctx, pvc.Name, k8stypes.MergePatchType, patch, metav1.PatchOptions{FieldManager: fieldManager})
+	if err != nil {
+		return fmt.Errorf("failed to annotate PVC %s: %v", pvc.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestAnnotatePVC(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "apps", Annotations: map[string]string{"team": "web"}},
+	})
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = client
+
+	pvc := &types.PVCInfo{Name: "data", Namespace: "apps", MatchedVolume: &types.DockerVolumeInfo{Name: "app_data"}}
+	before := time.Now().UTC().Truncate(time.Second)
+	if err := e.annotatePVC(context.Background(), pvc); err != nil {
+		t.Fatal(err)
+	}
+	after := time.Now().UTC()
+
+	claim, err := client.CoreV1().PersistentVolumeClaims("apps").Get(context.Background(), "data", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	annotations := claim.Annotations
+
+	if got := annotations[sourceVolumeAnnotation]; got != "app_data" {
+		t.Errorf("%s = %q, want app_data", sourceVolumeAnnotation, got)
+	}
+	if got := annotations[toolVersionAnnotation]; got != toolVersion() {
+		t.Errorf("%s = %q, want %q", toolVersionAnnotation, got, toolVersion())
+	}
+	date, err := time.Parse(time.RFC3339, annotations[migrationDateAnnotation])
+	if err != nil {
+		t.Errorf("%s = %q, want an RFC 3339 date: %v", migrationDateAnnotation, annotations[migrationDateAnnotation], err)
+	} else if date.Before(before) || date.After(after) {
+		t.Errorf("%s = %s, want the time of the migration", migrationDateAnnotation, date)
+	}
+	if annotations["team"] != "web" {
+		t.Errorf("annotations = %v, want the existing annotations kept", annotations)
+	}
+}
+
+func TestAnnotatePVCMissing(t *testing.T) {
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = fake.NewSimpleClientset()
+
+	pvc := &types.PVCInfo{Name: "data", Namespace: "apps", MatchedVolume: &types.DockerVolumeInfo{Name: "app_data"}}
+	if err := e.annotatePVC(context.Background(), pvc); err == nil {
+		t.Error("annotatePVC() returned no error for a missing PVC")
+	}
+}
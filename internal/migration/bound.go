@@ -0,0 +1,74 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func (e *Engine) SetForceBound(forceBound bool) {
+	e.forceBound = forceBound
+}
+
+// checkPVCsNotInUse fails when a ReadWriteOnce PVC is already bound and mounted by
+// a pod, because the migration pod would either not start or write next to the
+// running workload. --force-bound turns this into a warning.
+func (e *Engine) checkPVCsNotInUse(pvcs []*types.PVCInfo) error {
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
+	ctx := e.ctx
+
+	var inUse []string
+	for _, pvc := range pvcs {
+		if pvc.MatchedVolume == nil {
+			continue
+		}
+
+		namespace := e.namespaceFor(pvc)
+		claim, err := client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvc.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get PVC %s: %v", pvc.Name, err)
+		}
+		if claim.Status.Phase != corev1.ClaimBound || !readWriteOnce(claim) {
+			continue
+		}
+
+		pods, err := e.podsUsingPVC(ctx, pvc.Name, namespace)
+		if err != nil {
+			return fmt.Errorf("failed to check pods using PVC %s: %v", pvc.Name, err)
+		}
+		if len(pods) > 0 {
+			logger.Warnf("%s\n", color.Warning(fmt.Sprintf("⚠️  PVC %s/%s is bound and mounted by pod(s) %s", namespace, pvc.Name, strings.Join(pods, ", "))))
+			inUse = append(inUse, namespace+"/"+pvc.Name)
+		}
+	}
+
+	if len(inUse) > 0 && !e.forceBound {
+		return fmt.Errorf("PVC(s) %s are in use; stop the pods using them or pass --force-bound", strings.Join(inUse, ", "))
+	}
+	return nil
+}
+
+// readWriteOnce reports whether claim can only be mounted by a single node or pod
+func readWriteOnce(claim *corev1.PersistentVolumeClaim) bool {
+	modes := claim.Status.AccessModes
+	if len(modes) == 0 {
+		modes = claim.Spec.AccessModes
+	}
+	for _, mode := range modes {
+		if mode == corev1.ReadWriteOnce || mode == corev1.ReadWriteOncePod {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,106 @@
+package migration
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func boundClaim(name string, mode corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{mode}},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	}
+}
+
+func podMounting(name, claimName string, phase corev1.PodPhase) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec: corev1.PodSpec{Volumes: []corev1.Volume{{
+			Name:         "data",
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claimName}},
+		}}},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+// terminatingPod returns a running pod mounting claimName that is being deleted
+func terminatingPod(name, claimName string) *corev1.Pod {
+	pod := podMounting(name, claimName, corev1.PodRunning)
+	deleted := metav1.Now()
+	pod.DeletionTimestamp = &deleted
+	pod.Finalizers = []string{"example.com/wait"}
+	return pod
+}
+
+func TestCheckPVCsNotInUse(t *testing.T) {
+	tests := []struct {
+		name       string
+		objects    []runtime.Object
+		forceBound bool
+		wantErr    bool
+	}{
+		{
+			name:    "bound and mounted by a running pod",
+			objects: []runtime.Object{boundClaim("data", corev1.ReadWriteOnce), podMounting("app", "data", corev1.PodRunning)},
+			wantErr: true,
+		},
+		{
+			name:       "bound and mounted with --force-bound",
+			objects:    []runtime.Object{boundClaim("data", corev1.ReadWriteOnce), podMounting("app", "data", corev1.PodRunning)},
+			forceBound: true,
+		},
+		{
+			name:    "mounted by a pending pod",
+			objects: []runtime.Object{boundClaim("data", corev1.ReadWriteOncePod), podMounting("app", "data", corev1.PodPending)},
+			wantErr: true,
+		},
+		{
+			name: "only terminated pods",
+			objects: []runtime.Object{
+				boundClaim("data", corev1.ReadWriteOnce),
+				podMounting("job-done", "data", corev1.PodSucceeded),
+				podMounting("job-failed", "data", corev1.PodFailed),
+			},
+		},
+		{
+			// A scaled down workload's pod is still Running while it terminates
+			name:    "only a terminating pod",
+			objects: []runtime.Object{boundClaim("data", corev1.ReadWriteOnce), terminatingPod("app", "data")},
+		},
+		{
+			name:    "ReadWriteMany",
+			objects: []runtime.Object{boundClaim("data", corev1.ReadWriteMany), podMounting("app", "data", corev1.PodRunning)},
+		},
+		{
+			name:    "pod mounts another PVC",
+			objects: []runtime.Object{boundClaim("data", corev1.ReadWriteOnce), podMounting("app", "other", corev1.PodRunning)},
+		},
+		{
+			name: "PVC does not exist yet",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			e.destKubeClient = fake.NewSimpleClientset(tt.objects...)
+			e.SetForceBound(tt.forceBound)
+
+			pvcs := []*types.PVCInfo{{Name: "data", Namespace: "default", MatchedVolume: &types.DockerVolumeInfo{Name: "app_data"}}}
+			err := e.checkPVCsNotInUse(pvcs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkPVCsNotInUse() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "default/data") {
+				t.Errorf("error = %v, want it to name the PVC", err)
+			}
+		})
+	}
+}
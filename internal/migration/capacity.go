@@ -0,0 +1,133 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	defaultStorageClassAnnotation = "storageclass.kubernetes.io/is-default-class"
+	// capacityHeadroom is how much more capacity than requested we want to see
+	capacityHeadroom = 1.5
+)
+
+// capacityParameters are StorageClass parameters that some provisioners (and
+// local-path-provisioner setups) use to advertise the total capacity
+var capacityParameters = []string{"capacity", "totalCapacity", "size"}
+
+// checkStorageCapacity warns for every storage class that does not have enough
+// room for the PVCs that are about to be created. It never fails the migration.
+func (e *Engine) checkStorageCapacity(ctx context.Context, pvcs []*types.PVCInfo) {
+	required := make(map[string]int64)
+	var order []string
+	for _, pvc := range pvcs {
+		if pvc.MatchedVolume == nil {
+			continue
+		}
+
+		size := pvc.NewSize
+		if size == "" {
+			size = pvc.RequestedSize
+		}
+		quantity, err := resource.ParseQuantity(size)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := required[pvc.StorageClass]; !ok {
+			order = append(order, pvc.StorageClass)
+		}
+		required[pvc.StorageClass] += quantity.Value()
+	}
+
+	for _, storageClass := range order {
+		ok, err := e.checkStorageClassCapacity(ctx, storageClass, required[storageClass])
+		if err != nil {
+			logger.Warnf("%s\n", color.Warning(fmt.Sprintf("Warning: Could not check capacity of storage class %q: %v", storageClass, err)))
+			continue
+		}
+		if !ok {
+			logger.Warnf("%s\n", color.Warning(fmt.Sprintf("⚠️  Storage class %q may not have enough capacity: %s required, want at least %.1fx available",
+				storageClass, resource.NewQuantity(required[storageClass], resource.BinarySI), capacityHeadroom)))
+		}
+	}
+}
+
+// checkStorageClassCapacity reports whether storageClass has at least 1.5x requiredBytes
+// available. Available capacity is the capacity hint in the StorageClass parameters minus
+// the capacity of the PersistentVolumes already provisioned from it. Classes without a
+// capacity hint are assumed to have enough room. An empty storageClass means the default class.
+func (e *Engine) checkStorageClassCapacity(ctx context.Context, storageClass string, requiredBytes int64) (bool, error) {
+	client, err := e.getClientset()
+	if err != nil {
+		return false, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	classes, err := client.StorageV1().StorageClasses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to list storage classes: %v", err)
+	}
+
+	var parameters map[string]string
+	found := false
+	for _, class := range classes.Items {
+		isDefault := class.Annotations[defaultStorageClassAnnotation] == "true"
+		if class.Name == storageClass || (storageClass == "" && isDefault) {
+			storageClass = class.Name
+			parameters = class.Parameters
+			found = true
+			break
+		}
+	}
+	if !found {
+		if storageClass == "" {
+			return false, fmt.Errorf("no default storage class found")
+		}
+		return false, fmt.Errorf("storage class %s not found", storageClass)
+	}
+
+	var totalBytes int64
+	for _, key := range capacityParameters {
+		if value, ok := parameters[key]; ok {
+			quantity, err := resource.ParseQuantity(value)
+			if err != nil {
+				return false, fmt.Errorf("invalid %s parameter %q: %v", key, value, err)
+			}
+			totalBytes = quantity.Value()
+			break
+		}
+	}
+	if totalBytes == 0 {
+		return true, nil
+	}
+
+	volumes, err := client.CoreV1().PersistentVolumes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return false, fmt.Errorf("failed to list persistent volumes: %v", err)
+	}
+
+	var usedBytes int64
+	for _, pv := range volumes.Items {
+		if pv.Spec.StorageClassName != storageClass {
+			continue
+		}
+		if capacity, ok := pv.Spec.Capacity["storage"]; ok {
+			usedBytes += capacity.Value()
+		}
+	}
+
+	available := totalBytes - usedBytes
+	logger.Printf("Storage class %s: %s available, %s required\n", storageClass,
+		resource.NewQuantity(available, resource.BinarySI), resource.NewQuantity(requiredBytes, resource.BinarySI))
+
+	return float64(available) >= capacityHeadroom*float64(requiredBytes), nil
+}
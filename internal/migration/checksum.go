@@ -0,0 +1,174 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// Markers that separate the checksums of both mounts in the logs of the checksum pod
+const (
+	sourceChecksumsMarker = "==> /docker-data"
+	targetChecksumsMarker = "==> /pvc-data"
+)
+
+func (e *Engine) SetVerifyChecksums(verifyChecksums bool) {
+	e.verifyChecksums = verifyChecksums
+}
+
+// verifyData compares the SHA-256 checksum of every file in the Docker volume with
+// the copy in the PVC, and fails when a file is missing or differs
+func (e *Engine) verifyData(pvc *types.PVCInfo) error {
+	script := fmt.Sprintf(`echo "%s"
+      cd /docker-data && find . -type f -exec sha256sum {} \; | sort
+      echo "%s"
+      cd /pvc-data && find . -type f -exec sha256sum {} \; | sort`, sourceChecksumsMarker, targetChecksumsMarker)
+
+	logger.Printf("  Comparing checksums of %s and PVC %s...\n", pvc.MatchedVolume.Name, pvc.Name)
+	output, err := e.runComparisonPod(pvc, "checksum", script)
+	if err != nil {
+		return err
+	}
+
+	source, target, err := splitChecksums(output)
+	if err != nil {
+		return err
+	}
+
+	differences := diffChecksums(source, target)
+	if len(differences) > 0 {
+		return fmt.Errorf("%d files differ between Docker volume %s and PVC %s:\n    %s",
+			len(differences), pvc.MatchedVolume.Name, pvc.Name, strings.Join(differences, "\n    "))
+	}
+
+	logger.Printf("    %s\n", color.Success(fmt.Sprintf("✅ All %d files match", len(source))))
+	return nil
+}
+
+// runComparisonPod runs script in a pod that mounts the Docker volume of pvc at
+// /docker-data and the PVC at /pvc-data, both read-only, and returns its logs.
+// name is used for the pod and its container.
+func (e *Engine) runComparisonPod(pvc *types.PVCInfo, name, script string) (string, error) {
+	namespace := e.namespaceFor(pvc)
+
+	e.mu.Lock()
+	nodeName := e.copyNodes[namespace+"/"+pvc.Name]
+	e.mu.Unlock()
+	if nodeName == "" {
+		// The data was copied by an earlier, resumed run
+		var err error
+		nodeName, err = e.getCurrentNodeName(pvc)
+		if err != nil {
+			return "", fmt.Errorf("failed to get current node name: %v", err)
+		}
+	}
+
+	podName := fmt.Sprintf("%s-%s-%d", name, pvc.Name, time.Now().Unix())
+	podYAML := fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: %s
+  namespace: %s
+  labels:
+    app.kubernetes.io/managed-by: docker-pvc-migration
+spec:
+  restartPolicy: Never
+%s%s  containers:
+  - name: %s
+    image: %s
+    command: ["/bin/sh", "-c"]
+    args:
+    - |
+      %s
+    volumeMounts:
+    - name: docker-volume
+      mountPath: /docker-data
+      readOnly: true
+    - name: pvc-volume
+      mountPath: /pvc-data
+      readOnly: true
+  volumes:
+  - name: docker-volume
+    hostPath:
+      path: %s
+  - name: pvc-volume
+    persistentVolumeClaim:
+      claimName: %s
+`, podName, namespace, e.buildNodeSelection(nodeName), e.buildImagePullSecrets(), name, e.migrationImage, script, pvc.MatchedVolume.Mountpoint, pvc.Name)
+
+	if err := e.createPod(podYAML); err != nil {
+		return "", fmt.Errorf("failed to create %s pod: %v", name, err)
+	}
+	defer func() {
+		if err := e.deletePod(podName, namespace); err != nil {
+			logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("Warning: Could not delete %s pod: %v", name, err)))
+		}
+	}()
+
+	// Reading all data again can take as long as the copy
+	ctx, cancel := context.WithTimeout(e.ctx, e.podTimeout(pvc))
+	defer cancel()
+
+	if err := e.waitForPodCompletion(ctx, podName, namespace, 0); err != nil {
+		return "", fmt.Errorf("%s pod failed: %v", name, err)
+	}
+
+	output, err := e.podLogs(ctx, podName, namespace)
+	if err != nil {
+		return "", fmt.Errorf("failed to read the logs of the %s pod: %v", name, err)
+	}
+	return output, nil
+}
+
+// splitChecksums parses the logs of the checksum pod into the sha256sum output
+// for the Docker volume and the PVC, both mapping file path to checksum
+func splitChecksums(logs string) (map[string]string, map[string]string, error) {
+	source := make(map[string]string)
+	target := make(map[string]string)
+
+	var current map[string]string
+	for _, line := range strings.Split(logs, "\n") {
+		switch line {
+		case sourceChecksumsMarker:
+			current = source
+			continue
+		case targetChecksumsMarker:
+			current = target
+			continue
+		}
+
+		// sha256sum prints "<checksum>  <path>"
+		checksum, file, ok := strings.Cut(line, "  ")
+		if !ok || current == nil {
+			continue
+		}
+		current[file] = checksum
+	}
+
+	if !strings.Contains(logs, targetChecksumsMarker) {
+		return nil, nil, fmt.Errorf("checksum pod output is incomplete")
+	}
+	return source, target, nil
+}
+
+// diffChecksums lists the files from source that are missing from target or have a
+// different checksum there. Files that only exist in target are ignored.
+func diffChecksums(source, target map[string]string) []string {
+	var differences []string
+	for file, checksum := range source {
+		targetChecksum, ok := target[file]
+		switch {
+		case !ok:
+			differences = append(differences, fmt.Sprintf("%s: missing from PVC", file))
+		case targetChecksum != checksum:
+			differences = append(differences, fmt.Sprintf("%s: checksum mismatch", file))
+		}
+	}
+	sort.Strings(differences)
+	return differences
+}
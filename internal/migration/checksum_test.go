@@ -0,0 +1,81 @@
+package migration
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestDiffChecksums(t *testing.T) {
+	tests := []struct {
+		name   string
+		source map[string]string
+		target map[string]string
+		want   []string
+	}{
+		{
+			name:   "identical",
+			source: map[string]string{"./a": "aaa", "./b": "bbb"},
+			target: map[string]string{"./a": "aaa", "./b": "bbb"},
+			want:   nil,
+		},
+		{
+			name:   "missing file",
+			source: map[string]string{"./a": "aaa", "./b": "bbb"},
+			target: map[string]string{"./a": "aaa"},
+			want:   []string{"./b: missing from PVC"},
+		},
+		{
+			name:   "changed file",
+			source: map[string]string{"./a": "aaa"},
+			target: map[string]string{"./a": "abc"},
+			want:   []string{"./a: checksum mismatch"},
+		},
+		{
+			name:   "extra file in PVC",
+			source: map[string]string{"./a": "aaa"},
+			target: map[string]string{"./a": "aaa", "./lost+found/x": "xxx"},
+			want:   nil,
+		},
+		{
+			name:   "sorted",
+			source: map[string]string{"./c": "ccc", "./a": "aaa", "./b": "bbb"},
+			target: map[string]string{"./b": "bad"},
+			want:   []string{"./a: missing from PVC", "./b: checksum mismatch", "./c: missing from PVC"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffChecksums(tt.source, tt.target); !slices.Equal(got, tt.want) {
+				t.Errorf("diffChecksums() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitChecksums(t *testing.T) {
+	logs := sourceChecksumsMarker + `
+aaa  ./a
+bbb  ./dir/file with spaces
+` + targetChecksumsMarker + `
+aaa  ./a
+ccc  ./dir/file with spaces
+`
+	source, target, err := splitChecksums(logs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(source) != 2 || source["./dir/file with spaces"] != "bbb" {
+		t.Errorf("source = %v", source)
+	}
+	if len(target) != 2 || target["./dir/file with spaces"] != "ccc" {
+		t.Errorf("target = %v", target)
+	}
+	if got := diffChecksums(source, target); !slices.Equal(got, []string{"./dir/file with spaces: checksum mismatch"}) {
+		t.Errorf("diffChecksums() = %q", got)
+	}
+
+	if _, _, err := splitChecksums(sourceChecksumsMarker + "\naaa  ./a\n"); err == nil {
+		t.Error("expected an error for output without the PVC checksums")
+	}
+}
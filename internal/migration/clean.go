@@ -0,0 +1,77 @@
+package migration
+
+import (
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// managedBySelector selects the pods the tool creates; every pod template carries this label
+const managedBySelector = "app.kubernetes.io/managed-by=" + fieldManager
+
+// SetCleanOptions makes CleanMigrationPods skip pods younger than olderThan, and
+// only report the pods it would delete when dryRun is set
+func (e *Engine) SetCleanOptions(olderThan time.Duration, dryRun bool) {
+	e.cleanOlderThan = olderThan
+	e.cleanDryRun = dryRun
+}
+
+// CleanMigrationPods deletes the pods left behind by interrupted runs in the
+// migration namespace (or the PVC namespace when none is set), and returns their
+// names. Only pods with the tool's label are deleted, and never ones a controller owns.
+func (e *Engine) CleanMigrationPods() ([]string, error) {
+	client, err := e.getClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	namespace := e.migrationNamespace
+	if namespace == "" {
+		namespace = e.pvcNamespace
+	}
+
+	ctx := e.ctx
+	podList, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: managedBySelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods in namespace %s: %v", namespace, err)
+	}
+
+	var deleted []string
+	for _, pod := range podList.Items {
+		// The tool creates bare pods, so an owned pod only borrowed the label
+		if len(pod.OwnerReferences) > 0 {
+			logger.Printf("  Skipping pod %s/%s, it is owned by %s %s\n", namespace, pod.Name, pod.OwnerReferences[0].Kind, pod.OwnerReferences[0].Name)
+			continue
+		}
+		age := podAge(&pod)
+		if age < e.cleanOlderThan {
+			continue
+		}
+
+		if e.cleanDryRun {
+			logger.Printf("  Would delete pod %s/%s (%s, %s old)\n", namespace, pod.Name, pod.Status.Phase, age.Round(time.Second))
+			deleted = append(deleted, pod.Name)
+			continue
+		}
+
+		logger.Printf("  Deleting pod %s/%s (%s, %s old)...\n", namespace, pod.Name, pod.Status.Phase, age.Round(time.Second))
+		err := client.CoreV1().Pods(namespace).Delete(ctx, pod.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return deleted, fmt.Errorf("failed to delete pod %s: %v", pod.Name, err)
+		}
+		deleted = append(deleted, pod.Name)
+	}
+	return deleted, nil
+}
+
+// podAge returns how long the pod has been running or, when it never started, how
+// long ago it was created
+func podAge(pod *corev1.Pod) time.Duration {
+	if pod.Status.StartTime != nil {
+		return time.Since(pod.Status.StartTime.Time)
+	}
+	return time.Since(pod.CreationTimestamp.Time)
+}
@@ -0,0 +1,113 @@
+package migration
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes/fake"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// leftoverPod returns a pod in namespace default that started age ago
+func leftoverPod(name string, podLabels map[string]string, age time.Duration, owners ...metav1.OwnerReference) *corev1.Pod {
+	started := metav1.NewTime(time.Now().Add(-age))
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: podLabels, OwnerReferences: owners},
+		Status:     corev1.PodStatus{Phase: corev1.PodSucceeded, StartTime: &started},
+	}
+}
+
+func TestCleanMigrationPods(t *testing.T) {
+	managed := map[string]string{"app.kubernetes.io/managed-by": "docker-pvc-migration"}
+	statefulSet := metav1.OwnerReference{APIVersion: "apps/v1", Kind: "StatefulSet", Name: "migration-db"}
+
+	tests := []struct {
+		name      string
+		olderThan time.Duration
+		dryRun    bool
+		want      []string
+	}{
+		{"all", 0, false, []string{"import-data-1", "migration-data-1", "migration-old-1"}},
+		{"older than", time.Hour, false, []string{"migration-old-1"}},
+		{"dry run", 0, true, []string{"import-data-1", "migration-data-1", "migration-old-1"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(
+				leftoverPod("migration-data-1", managed, time.Minute),
+				leftoverPod("migration-old-1", managed, 2*time.Hour),
+				leftoverPod("import-data-1", managed, time.Minute),
+				// A StatefulSet pod that only looks like a migration pod
+				leftoverPod("migration-db-0", map[string]string{"app": "db"}, 2*time.Hour),
+				// An owned pod is left alone, even with the label
+				leftoverPod("migration-web-0", managed, 2*time.Hour, statefulSet),
+			)
+			e := NewEngine("default", "", t.TempDir())
+			e.destKubeClient = client
+			e.SetCleanOptions(tt.olderThan, tt.dryRun)
+
+			got, err := e.CleanMigrationPods()
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("CleanMigrationPods() = %v, want %v", got, tt.want)
+			}
+
+			pods, err := client.CoreV1().Pods("default").List(context.Background(), metav1.ListOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var remaining []string
+			for _, pod := range pods.Items {
+				remaining = append(remaining, pod.Name)
+			}
+			for _, name := range got {
+				if slices.Contains(remaining, name) != tt.dryRun {
+					t.Errorf("pod %s remaining = %v after a dry run %v", name, !tt.dryRun, tt.dryRun)
+				}
+			}
+			for _, name := range []string{"migration-db-0", "migration-web-0"} {
+				if !slices.Contains(remaining, name) {
+					t.Errorf("pod %s was deleted", name)
+				}
+			}
+		})
+	}
+}
+
+func TestToolPodsAreLabelled(t *testing.T) {
+	selector, err := labels.Parse(managedBySelector)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewEngine("default", "", t.TempDir())
+	pvc := &types.PVCInfo{
+		Name:          "data",
+		Namespace:     "default",
+		MatchedVolume: &types.DockerVolumeInfo{Name: "app_data", Mountpoint: "/var/lib/docker/volumes/app_data/_data"},
+	}
+	pods := map[string]string{
+		"migration": e.buildMigrationPodYAML(pvc, "migration-data-1", "default", "node-1", "", "", ""),
+		"import":    e.buildImportPodYAML(pvc, "import-data-1", "default"),
+	}
+
+	for name, podYAML := range pods {
+		var pod corev1.Pod
+		if err := sigsyaml.UnmarshalStrict([]byte(podYAML), &pod); err != nil {
+			t.Fatalf("invalid %s pod YAML: %v\n%s", name, err, podYAML)
+		}
+		if !selector.Matches(labels.Set(pod.Labels)) {
+			t.Errorf("%s pod labels = %v, want them to match %s", name, pod.Labels, managedBySelector)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package migration
+
+import (
+	"fmt"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/kubernetes"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// Kubernetes API clients are created on first use, so dry runs never need to load
+// a kubeconfig. All clients talk to the destination cluster except
+// sourceKubeClient, which is only used for cross-cluster migrations.
+
+func (e *Engine) SetSourceKubeconfig(kubeconfig string) {
+	e.sourceKubeconfig = kubeconfig
+}
+
+func (e *Engine) SetDestKubeconfig(kubeconfig string) {
+	e.kubeOptions.Kubeconfig = kubeconfig
+}
+
+// crossCluster reports whether PVCs are read from a different cluster than they are migrated to
+func (e *Engine) crossCluster() bool {
+	return e.sourceKubeconfig != ""
+}
+
+func (e *Engine) getRESTConfig() (*rest.Config, error) {
+	e.clientsMu.Lock()
+	defer e.clientsMu.Unlock()
+	return e.loadRESTConfig()
+}
+
+// loadRESTConfig must be called with clientsMu held
+func (e *Engine) loadRESTConfig() (*rest.Config, error) {
+	if e.restConfig != nil {
+		return e.restConfig, nil
+	}
+
+	config, err := kubernetes.NewRESTConfig(e.kubeOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	e.restConfig = config
+	return config, nil
+}
+
+func (e *Engine) getDynamicClient() (dynamic.Interface, error) {
+	e.clientsMu.Lock()
+	defer e.clientsMu.Unlock()
+
+	if e.dynamicClient != nil {
+		return e.dynamicClient, nil
+	}
+
+	config, err := e.loadRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+
+	e.dynamicClient = dynamicClient
+	return dynamicClient, nil
+}
+
+func (e *Engine) getClientset() (clientset.Interface, error) {
+	e.clientsMu.Lock()
+	defer e.clientsMu.Unlock()
+
+	if e.destKubeClient != nil {
+		return e.destKubeClient, nil
+	}
+
+	config, err := e.loadRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := clientset.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+
+	e.destKubeClient = client
+	return client, nil
+}
+
+// getRESTMapper returns a mapper from kinds to API resources, discovering the
+// resources the destination cluster serves on first use
+func (e *Engine) getRESTMapper() (meta.RESTMapper, error) {
+	client, err := e.getClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	e.clientsMu.Lock()
+	defer e.clientsMu.Unlock()
+
+	if e.restMapper == nil {
+		e.restMapper = restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(client.Discovery()))
+	}
+	return e.restMapper, nil
+}
+
+func (e *Engine) getSourceKubeClient() (dynamic.Interface, error) {
+	e.clientsMu.Lock()
+	defer e.clientsMu.Unlock()
+
+	if e.sourceKubeClient != nil {
+		return e.sourceKubeClient, nil
+	}
+
+	options := e.kubeOptions
+	options.Kubeconfig = e.sourceKubeconfig
+	options.Context = ""
+	config, err := kubernetes.NewRESTConfig(options)
+	if err != nil {
+		return nil, fmt.Errorf("source cluster: %v", err)
+	}
+
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create source cluster client: %v", err)
+	}
+
+	e.sourceKubeClient = client
+	return client, nil
+}
@@ -0,0 +1,105 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// checkAlreadyMigrated reports whether the PVC already exists and is bound in the destination cluster
+func (e *Engine) checkAlreadyMigrated(ctx context.Context, pvc *types.PVCInfo) (bool, error) {
+	client, err := e.getClientset()
+	if err != nil {
+		return false, err
+	}
+
+	existing, err := client.CoreV1().PersistentVolumeClaims(e.namespaceFor(pvc)).Get(ctx, pvc.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return existing.Status.Phase == corev1.ClaimBound, nil
+}
+
+// createPVCFromSource copies the PVC definition from the source cluster into the
+// destination cluster, applying the new size and storage class
+func (e *Engine) createPVCFromSource(ctx context.Context, pvc *types.PVCInfo) error {
+	sourceClient, err := e.getSourceKubeClient()
+	if err != nil {
+		return err
+	}
+
+	source, err := sourceClient.Resource(pvcGVR).Namespace(pvc.Namespace).Get(ctx, pvc.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to read PVC %s/%s from source cluster: %v", pvc.Namespace, pvc.Name, err)
+	}
+
+	obj := portablePVC(source)
+	namespace := e.namespaceFor(pvc)
+	obj.SetNamespace(namespace)
+	if pvc.NewSize != "" {
+		if err := unstructured.SetNestedField(obj.Object, pvc.NewSize, "spec", "resources", "requests", "storage"); err != nil {
+			return err
+		}
+	}
+	if pvc.StorageClass != "" {
+		if err := unstructured.SetNestedField(obj.Object, pvc.StorageClass, "spec", "storageClassName"); err != nil {
+			return err
+		}
+	}
+
+	dynamicClient, err := e.getDynamicClient()
+	if err != nil {
+		return err
+	}
+
+	logger.Printf("    Applying PVC %s from the source cluster to namespace %s...\n", pvc.Name, namespace)
+	_, err = dynamicClient.Resource(pvcGVR).Namespace(namespace).Apply(ctx, pvc.Name, obj, metav1.ApplyOptions{
+		FieldManager: fieldManager,
+		Force:        true,
+	})
+	if err != nil {
+		return fmt.Errorf("server-side apply failed: %v", err)
+	}
+
+	return nil
+}
+
+// portablePVC strips everything from a PVC that ties it to the cluster it was read from
+func portablePVC(source *unstructured.Unstructured) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": source.GetAPIVersion(),
+		"kind":       source.GetKind(),
+	}}
+	obj.SetName(source.GetName())
+	obj.SetLabels(source.GetLabels())
+
+	annotations := make(map[string]string)
+	for key, value := range source.GetAnnotations() {
+		// Binding and provisioning state is managed by the source cluster's controllers
+		if strings.HasPrefix(key, "pv.kubernetes.io/") || strings.HasPrefix(key, "volume.") ||
+			key == "kubectl.kubernetes.io/last-applied-configuration" {
+			continue
+		}
+		annotations[key] = value
+	}
+	if len(annotations) > 0 {
+		obj.SetAnnotations(annotations)
+	}
+
+	if spec, ok, _ := unstructured.NestedMap(source.Object, "spec"); ok {
+		delete(spec, "volumeName")
+		obj.Object["spec"] = spec
+	}
+
+	return obj
+}
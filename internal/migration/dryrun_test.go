@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/output"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	internalyaml "github.com/LuukBlankenstijn/docker-pvc-migration/internal/yaml"
+)
+
+func TestDryRunJSON(t *testing.T) {
+	var buf bytes.Buffer
+	encoder, err := output.NewEncoder(output.ModeJSON, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewEngine("default", "", t.TempDir())
+	e.SetDryRunEncoder(encoder)
+	e.SetYAMLDiffs([]internalyaml.FileDiff{{File: "pvc.yaml", Diff: "-1Gi\n+5Gi\n", PVCs: []string{"apps/data"}}})
+
+	pvcs := []*types.PVCInfo{
+		{
+			Name:          "data",
+			Namespace:     "apps",
+			NewSize:       "5Gi",
+			MatchedVolume: &types.DockerVolumeInfo{Name: "app_data", Size: 1 << 30, Mountpoint: "/var/lib/docker/volumes/app_data/_data"},
+		},
+		{Name: "cache", Namespace: "apps", MatchedVolume: &types.DockerVolumeInfo{Name: "app_cache"}},
+		{Name: "unmatched", Namespace: "apps"},
+	}
+	if err := e.DryRun(pvcs); err != nil {
+		t.Fatal(err)
+	}
+
+	var plans []types.MigrationPlan
+	if err := json.Unmarshal(buf.Bytes(), &plans); err != nil {
+		t.Fatalf("dry run output is not a JSON plan list: %v\n%s", err, buf.String())
+	}
+
+	want := []types.MigrationPlan{
+		{
+			PVCName:      "data",
+			Namespace:    "apps",
+			SourceVolume: "app_data",
+			SourceSize:   1 << 30,
+			TargetSize:   "5Gi",
+			Mountpoint:   "/var/lib/docker/volumes/app_data/_data",
+			YAMLFile:     "pvc.yaml",
+			YAMLDiff:     "-1Gi\n+5Gi\n",
+		},
+		{PVCName: "cache", Namespace: "apps", SourceVolume: "app_cache", TargetSize: "0"},
+	}
+	if len(plans) != len(want) {
+		t.Fatalf("got %d plans, want %d: %+v", len(plans), len(want), plans)
+	}
+	for i := range want {
+		if plans[i] != want[i] {
+			t.Errorf("plan %d = %+v, want %+v", i, plans[i], want[i])
+		}
+	}
+}
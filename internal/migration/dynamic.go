@@ -0,0 +1,84 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// fieldManager identifies this tool as the owner of fields it applies server-side
+const fieldManager = "docker-pvc-migration"
+
+var pvcGVR = schema.GroupVersionResource{Version: "v1", Resource: "persistentvolumeclaims"}
+
+// applyManifests server-side applies every object in the YAML documents of content,
+// like kubectl apply. Namespaced objects without a namespace are put in namespace.
+func (e *Engine) applyManifests(ctx context.Context, content, namespace string) error {
+	objects, err := decodeManifests(content)
+	if err != nil {
+		return fmt.Errorf("failed to parse YAML: %v", err)
+	}
+
+	dynamicClient, err := e.getDynamicClient()
+	if err != nil {
+		return err
+	}
+	mapper, err := e.getRESTMapper()
+	if err != nil {
+		return err
+	}
+
+	for _, obj := range objects {
+		gvk := obj.GroupVersionKind()
+		mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+		if err != nil {
+			return fmt.Errorf("unknown resource type %s: %v", gvk, err)
+		}
+
+		resource := dynamicClient.Resource(mapping.Resource)
+		options := metav1.ApplyOptions{FieldManager: fieldManager, Force: true}
+		if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+			if obj.GetNamespace() == "" {
+				obj.SetNamespace(namespace)
+			}
+			_, err = resource.Namespace(obj.GetNamespace()).Apply(ctx, obj.GetName(), obj, options)
+		} else {
+			_, err = resource.Apply(ctx, obj.GetName(), obj, options)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to apply %s %s: %v", gvk.Kind, obj.GetName(), err)
+		}
+	}
+
+	return nil
+}
+
+// decodeManifests parses the YAML documents in content, skipping empty ones
+func decodeManifests(content string) ([]*unstructured.Unstructured, error) {
+	decoder := yaml.NewYAMLToJSONDecoder(strings.NewReader(content))
+
+	var objects []*unstructured.Unstructured
+	for {
+		obj := &unstructured.Unstructured{}
+		err := decoder.Decode(&obj.Object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(obj.Object) == 0 {
+			continue
+		}
+		objects = append(objects, obj)
+	}
+
+	return objects, nil
+}
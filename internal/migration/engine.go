@@ -3,110 +3,559 @@ package migration
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"path/filepath"
+	"path"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode"
 
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/kubernetes"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/log"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/notify"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/output"
 	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	internalyaml "github.com/LuukBlankenstijn/docker-pvc-migration/internal/yaml"
 	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/dynamic"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	sigsyaml "sigs.k8s.io/yaml"
 )
 
+// minPodTimeout is the lower bound for how long a migration pod may run
+const minPodTimeout = 10 * time.Minute
+
+// DefaultMigrationImage is the image of the migration pods, pinned so every run copies
+// with the same tools. Busybox has no rsync, so the data is copied with cp; an image
+// with rsync can be passed with --migration-image to resume failed copies.
+const DefaultMigrationImage = "busybox:1.37"
+
+// preCreateDirsAnnotation lists extra directories (comma-separated) to create in a PVC before copying
+const preCreateDirsAnnotation = "migration.tool/pre-create-dirs"
+
+// hostPathTypes are the hostPath volume types supported by Kubernetes
+var hostPathTypes = []string{"Directory", "DirectoryOrCreate", "File", "FileOrCreate", "Socket", "CharDevice", "BlockDevice"}
+
+var logger = log.New("migration")
+
 type Engine struct {
-	migrationNamespace string // Namespace for migration pods
-	yamlDirectory      string // Directory containing YAML files
+	pvcNamespace          string                       // Namespace for PVCs without one and for other namespaced resources
+	migrationNamespace    string                       // Namespace for migration pods, empty to use the PVC's namespace
+	yamlDirectory         string                       // Directory containing YAML files
+	yamlDepth             int                          // Subdirectory levels of yamlDirectory to search, negative for unlimited
+	migrationTimeoutPerGB time.Duration                // Copy time allowed per GB of source data
+	fixedPodTimeout       time.Duration                // Copy time allowed per PVC regardless of size, 0 to use migrationTimeoutPerGB
+	pvcTimeout            time.Duration                // How long to wait for a created PVC to be bound
+	pvcPollInterval       time.Duration                // How often to check whether a PVC is bound
+	hostPathType          string                       // hostPath type of the Docker volume in the migration pod
+	expandEnv             bool                         // Expand environment variables in YAML files before applying
+	verifyType            string                       // Kind of filesystem check to run after copying
+	watcher               *yamlWatcher                 // Tracks YAML files changed by other processes during migration
+	preCreateDirs         []string                     // Directories to create in every PVC before copying
+	veleroBackup          bool                         // Take a Velero backup of the namespace before migrating
+	forceBound            bool                         // Migrate into bound ReadWriteOnce PVCs that are mounted by a pod
+	annotate              bool                         // Annotate created PVCs with their source volume, see annotatePVC
+	overwriteExisting     bool                         // Apply existing PVCs with another size without checking volume expansion
+	useEphemeralVolumes   bool                         // Copy into emptyDir volumes instead of PVCs as a test run
+	nonInteractive        bool                         // Never prompt; use the best default node instead
+	kubeOptions           kubernetes.RESTConfigOptions // Destination cluster and TLS settings for the Kubernetes API
+	extraVolumes          []ExtraVolumeMount           // Additional volumes mounted into the migration pod
+	maxPodRestarts        int                          // Relaunches allowed after an OOMKilled or Error migration pod
+	podRestarts           map[string]int               // Migration pod restarts per PVC (namespace/name)
+	oomRestarts           map[string]int               // Restarts after an OOMKill per PVC, each doubles the memory limit
+	sourceKubeconfig      string                       // Cluster to read PVCs from in a cross-cluster migration
+	scaleDownWorkloads    []types.WorkloadRef          // Workloads to scale to zero before copying
+	scaledDown            []scaledWorkload             // Workloads scaled to zero by this migration, restored when it ends
+	dryRunEncoder         output.Encoder               // Machine-readable dry-run output, nil for text
+	yamlDiffs             []internalyaml.FileDiff      // YAML changes included in the machine-readable dry-run output
+	parallelism           int                          // Number of PVCs migrated at the same time
+	migrationImage        string                       // Image of all migration pods, see SetMigrationImage
+	rsyncArgs             []string                     // Extra rsync options for the data copy
+	imagePullSecret       string                       // Secret for pulling migrationImage from a private registry
+	podResources          PodResources                 // Resource requests and limits of the migration pod
+	useNodeAffinity       bool                         // Pin pods to a node with node affinity instead of nodeName
+	hooks                 Hooks                        // Commands run before and after the migration and each PVC
+	webhook               *notify.Webhook              // Receives the outcome of every migration, nil for none
+	lastResults           *migrationResults            // Outcome of the last StartMigration call, for WriteReport
+	reportFormat          string                       // Format of WriteReport, empty to pick it from the file extension
+	verifyChecksums       bool                         // Compare file checksums of the Docker volume and PVC after copying
+	copyNodes             map[string]string            // Node the data of each PVC (namespace/name) was copied on
+	stateStore            *StateStore                  // Progress of each PVC for resuming, nil to not track it
+	exportDir             string                       // Copy data through tarballs in this directory instead of hostPath volumes
+	exporter              VolumeExporter               // Exports Docker volumes to exportDir
+	plannedNodes          map[string]string            // Node to copy each PVC (namespace/name) on, from a plan
+	cleanOlderThan        time.Duration                // Minimum age of the pods CleanMigrationPods deletes
+	cleanDryRun           bool                         // Only report the pods CleanMigrationPods would delete
+
+	mu       sync.Mutex // Guards podRestarts, oomRestarts and copyNodes while PVCs are migrated in parallel
+	promptMu sync.Mutex // Serializes interactive prompts of parallel migrations
+
+	// Kubernetes API clients, created on first use under clientsMu
+	clientsMu        sync.Mutex
+	restConfig       *rest.Config
+	restMapper       meta.RESTMapper
+	dynamicClient    dynamic.Interface
+	destKubeClient   clientset.Interface
+	sourceKubeClient dynamic.Interface
+
+	ctx context.Context // Cancels the migration when done, see SetContext
+
+	migrateOne func(pvc *types.PVCInfo) error // Migrates a single PVC, migratePVC outside of tests
+}
+
+// NewEngine creates a migration engine. PVCs are created in their Namespace, or
+// pvcNamespace when it is empty. Migration pods run in migrationNamespace, or next
+// to their PVC when it is empty.
+func NewEngine(pvcNamespace, migrationNamespace, yamlDirectory string) *Engine {
+	if pvcNamespace == "" {
+		pvcNamespace = "default"
+	}
+	e := &Engine{
+		ctx:                   context.Background(),
+		pvcNamespace:          pvcNamespace,
+		migrationNamespace:    migrationNamespace,
+		yamlDirectory:         yamlDirectory,
+		yamlDepth:             -1,
+		migrationTimeoutPerGB: 2 * time.Minute,
+		pvcTimeout:            5 * time.Minute,
+		pvcPollInterval:       5 * time.Second,
+		hostPathType:          "DirectoryOrCreate",
+		verifyType:            "basic",
+		maxPodRestarts:        3,
+		parallelism:           1,
+		migrationImage:        DefaultMigrationImage,
+		podResources:          DefaultPodResources,
+	}
+	e.migrateOne = e.migratePVC
+	return e
+}
+
+func (e *Engine) SetMigrationTimeoutPerGB(timeout time.Duration) {
+	e.migrationTimeoutPerGB = timeout
+}
+
+// SetPodTimeout sets how long a migration pod may run, replacing the timeout
+// derived from the volume size. Zero restores the size-based timeout.
+func (e *Engine) SetPodTimeout(timeout time.Duration) {
+	e.fixedPodTimeout = timeout
+}
+
+// SetPVCTimeout sets how long to wait for a created PVC to be bound
+func (e *Engine) SetPVCTimeout(timeout time.Duration) error {
+	if timeout <= 0 {
+		return fmt.Errorf("invalid PVC timeout %s, must be positive", timeout)
+	}
+	e.pvcTimeout = timeout
+	return nil
+}
+
+// SetPVCPollInterval sets how often to check whether a created PVC is bound
+func (e *Engine) SetPVCPollInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid PVC poll interval %s, must be positive", interval)
+	}
+	e.pvcPollInterval = interval
+	return nil
+}
+
+// SetYAMLDepth limits how many subdirectory levels are searched for the YAML file of a PVC
+func (e *Engine) SetYAMLDepth(depth int) {
+	e.yamlDepth = depth
+}
+
+func (e *Engine) SetExpandEnv(expandEnv bool) {
+	e.expandEnv = expandEnv
+}
+
+func (e *Engine) SetPreCreateDirs(dirs []string) {
+	e.preCreateDirs = dirs
+}
+
+func (e *Engine) SetUseEphemeralVolumes(useEphemeralVolumes bool) {
+	e.useEphemeralVolumes = useEphemeralVolumes
+}
+
+// SetContext stops the migration when ctx is done: no further PVCs are started and
+// running Kubernetes requests and waits are cancelled
+func (e *Engine) SetContext(ctx context.Context) {
+	e.ctx = ctx
+}
+
+func (e *Engine) SetNonInteractive(nonInteractive bool) {
+	e.nonInteractive = nonInteractive
+}
+
+func (e *Engine) SetParallelism(parallelism int) {
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	e.parallelism = parallelism
+}
+
+// SetMigrationImage sets the image of the migration pod and the pods that verify
+// the copy. The image needs a POSIX shell with cp, find, tar and sha256sum; when it
+// also contains rsync, the data is copied with rsync instead of cp.
+func (e *Engine) SetMigrationImage(image string) error {
+	if image == "" || strings.ContainsFunc(image, unicode.IsSpace) {
+		return fmt.Errorf("invalid migration image %q", image)
+	}
+	e.migrationImage = image
+	return nil
+}
+
+// SetImagePullSecret sets the name of the secret used to pull the migration image
+func (e *Engine) SetImagePullSecret(secret string) {
+	e.imagePullSecret = secret
+}
+
+func (e *Engine) buildImagePullSecrets() string {
+	if e.imagePullSecret == "" {
+		return ""
+	}
+	return fmt.Sprintf(`  imagePullSecrets:
+  - name: %s
+`, e.imagePullSecret)
+}
+
+// SetRsyncArgs sets extra options that are passed to rsync, e.g. "--bwlimit=10m --exclude=cache".
+// They need a migration image with rsync, see buildCopyFallback.
+func (e *Engine) SetRsyncArgs(args string) error {
+	fields := strings.Fields(args)
+	for _, arg := range fields {
+		if strings.Contains(arg, "'") {
+			return fmt.Errorf("invalid rsync argument %q: single quotes are not supported", arg)
+		}
+	}
+	e.rsyncArgs = fields
+	return nil
+}
+
+func (e *Engine) SetKubeOptions(options kubernetes.RESTConfigOptions) {
+	e.kubeOptions = options
+}
+
+func (e *Engine) SetHostPathType(hostPathType string) error {
+	for _, valid := range hostPathTypes {
+		if hostPathType == valid {
+			e.hostPathType = hostPathType
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid hostPath type %q, must be one of: %s", hostPathType, strings.Join(hostPathTypes, ", "))
+}
+
+// namespaceFor returns the namespace the PVC is created in
+func (e *Engine) namespaceFor(pvc *types.PVCInfo) string {
+	if pvc.Namespace != "" {
+		return pvc.Namespace
+	}
+	return e.pvcNamespace
+}
+
+// podNamespaceFor returns the namespace of the migration pods for the PVC
+func (e *Engine) podNamespaceFor(pvc *types.PVCInfo) string {
+	if e.migrationNamespace != "" {
+		return e.migrationNamespace
+	}
+	return e.namespaceFor(pvc)
 }
 
-func NewEngine(migrationNamespace, yamlDirectory string) *Engine {
-	if migrationNamespace == "" {
-		migrationNamespace = "default"
+func (e *Engine) podTimeout(pvc *types.PVCInfo) time.Duration {
+	if e.fixedPodTimeout > 0 {
+		return e.fixedPodTimeout
 	}
-	return &Engine{
-		migrationNamespace: migrationNamespace,
-		yamlDirectory:      yamlDirectory,
+
+	sizeGB := float64(pvc.MatchedVolume.Size) / (1000 * 1000 * 1000)
+	timeout := time.Duration(sizeGB * float64(e.migrationTimeoutPerGB))
+	if timeout < minPodTimeout {
+		return minPodTimeout
 	}
+	return timeout
 }
 
-func (e *Engine) StartMigration(pvcs []*types.PVCInfo) error {
-	fmt.Println("\n=== Starting Migration Process ===")
+func (e *Engine) StartMigration(pvcs []*types.PVCInfo) (err error) {
+	logger.Println("\n" + color.Header("=== Starting Migration Process ==="))
+
+	results := newMigrationResults()
+	e.lastResults = results
+	defer func() { e.notifyWebhook(pvcs, results, err) }()
+
+	if err := e.runHook("pre", e.hooks.Pre); err != nil {
+		return err
+	}
+
+	if e.veleroBackup {
+		ctx, cancel := context.WithTimeout(e.ctx, time.Hour)
+		err := e.createVeleroBackup(ctx, e.pvcNamespaces(pvcs))
+		cancel()
+		if err != nil {
+			return fmt.Errorf("pre-migration backup failed: %v", err)
+		}
+	}
+
+	if !e.useEphemeralVolumes {
+		e.checkStorageCapacity(e.ctx, pvcs)
+	}
+
+	// Scaled down workloads get their replicas back however the migration ends
+	defer e.restoreScaledDownWorkloads()
+	for _, workload := range e.scaleDownWorkloads {
+		if err := e.scaleDownRespectingPDB(e.ctx, workload); err != nil {
+			return err
+		}
+	}
+
+	if !e.useEphemeralVolumes {
+		if err := e.checkPVCsNotInUse(pvcs); err != nil {
+			return err
+		}
+	}
+
+	// Watch for YAML changes made by other processes (e.g. GitOps) while we migrate
+	watcher, err := newYAMLWatcher(e.yamlDirectory)
+	if err != nil {
+		logger.Warnf("%s\n", color.Warning(fmt.Sprintf("Warning: Could not watch %s for changes: %v", e.yamlDirectory, err)))
+	} else {
+		e.watcher = watcher
+		defer func() {
+			watcher.Close()
+			e.watcher = nil
+		}()
+	}
+
+	semaphore := make(chan struct{}, e.parallelism)
+	var wg sync.WaitGroup
+	var errsMu sync.Mutex
+	var errs []error
 
 	for i, pvc := range pvcs {
 		if pvc.MatchedVolume == nil {
-			fmt.Printf("Skipping %s (no volume selected)\n", pvc.Name)
+			logger.Printf("Skipping %s (no volume selected)\n", pvc.Name)
 			continue
 		}
 
-		fmt.Printf("\n[%d/%d] Migrating PVC: %s\n", i+1, len(pvcs), pvc.Name)
+		if phase := e.statePhase(pvc); phase == PhaseCompleted {
+			logger.Printf("Skipping %s (already migrated according to the state file)\n", pvc.Name)
+			continue
+		}
 
-		if err := e.migratePVC(pvc); err != nil {
-			fmt.Printf("❌ Failed to migrate %s: %v\n", pvc.Name, err)
-			return fmt.Errorf("migration failed for PVC %s: %v", pvc.Name, err)
+		if e.crossCluster() {
+			migrated, err := e.checkAlreadyMigrated(e.ctx, pvc)
+			if err != nil {
+				wg.Wait()
+				return fmt.Errorf("failed to check destination cluster for PVC %s: %v", pvc.Name, err)
+			}
+			if migrated {
+				logger.Printf("Skipping %s (already bound in the destination cluster)\n", pvc.Name)
+				continue
+			}
+		}
+
+		if err := e.ctx.Err(); err != nil {
+			wg.Wait()
+			return err
 		}
 
-		fmt.Printf("✅ Successfully migrated %s\n", pvc.Name)
+		// Limit the number of PVCs migrated at the same time
+		semaphore <- struct{}{}
+		wg.Add(1)
+		go func(i int, pvc *types.PVCInfo) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			logger.Printf("\n[%d/%d] Migrating PVC: %s\n", i+1, len(pvcs), pvc.Name)
+
+			err := e.runPVCHook("pre-pvc", e.hooks.PrePVC, pvc)
+			if err == nil {
+				err = e.migrateOne(pvc)
+			}
+			if err == nil {
+				err = e.runPVCHook("post-pvc", e.hooks.PostPVC, pvc)
+			}
+			// Only completed once the post-pvc hook ran, so a resumed run repeats a failed hook
+			if err == nil && !e.useEphemeralVolumes {
+				e.recordPhase(pvc, PhaseCompleted)
+			}
+			results.record(pvc, err)
+			if err != nil {
+				logger.With(map[string]any{"pvc": pvc.Name, "volume": pvc.MatchedVolume.Name}).
+					Errorf("%s\n", color.Error(fmt.Sprintf("❌ Failed to migrate %s: %v", pvc.Name, err)))
+				errsMu.Lock()
+				errs = append(errs, fmt.Errorf("migration failed for PVC %s: %v", pvc.Name, err))
+				errsMu.Unlock()
+				return
+			}
+
+			logger.With(map[string]any{"pvc": pvc.Name, "volume": pvc.MatchedVolume.Name}).
+				Println(color.Success(fmt.Sprintf("✅ Successfully migrated %s", pvc.Name)))
+		}(i, pvc)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 
-	fmt.Println("\n🎉 Migration completed successfully!")
+	if err := e.runHook("post", e.hooks.Post); err != nil {
+		return err
+	}
+
+	logger.Println("\n🎉 Migration completed successfully!")
 	return nil
 }
 
 func (e *Engine) migratePVC(pvc *types.PVCInfo) error {
+	// A test migration copies into an emptyDir, so no PVC is created or verified
+	if e.useEphemeralVolumes {
+		logger.Printf("  Test-copying data from Docker volume %s into an ephemeral volume...\n", pvc.MatchedVolume.Name)
+		if err := e.copyData(pvc); err != nil {
+			return fmt.Errorf("failed to copy data: %v", err)
+		}
+		return nil
+	}
+
+	// Pods can only mount PVCs from their own namespace
+	if namespace := e.namespaceFor(pvc); e.podNamespaceFor(pvc) != namespace {
+		return fmt.Errorf("migration pods must run in namespace %s to mount PVC %s, but --target-namespace is %s",
+			namespace, pvc.Name, e.migrationNamespace)
+	}
+
+	// Resume an interrupted migration after the last step that completed
+	phase := e.statePhase(pvc)
+	if e.stateExisting(pvc) {
+		pvc.Existing = true
+	}
+
 	// Apply the specific YAML file for this PVC
-	fmt.Printf("  Applying YAML file for PVC %s to namespace %s...\n", pvc.Name, e.migrationNamespace)
-	if err := e.createPVC(pvc); err != nil {
-		return fmt.Errorf("failed to apply YAML file: %v", err)
+	if pvc.Existing {
+		logger.Printf("  PVC %s already exists in namespace %s, copying into it\n", pvc.Name, e.namespaceFor(pvc))
+	} else if phase.reached(PhasePVCCreated) {
+		logger.Printf("  PVC %s was already created, resuming\n", pvc.Name)
+	} else {
+		logger.Printf("  Applying YAML file for PVC %s to namespace %s...\n", pvc.Name, e.namespaceFor(pvc))
+		if err := e.createPVC(pvc); err != nil {
+			return fmt.Errorf("failed to apply YAML file: %v", err)
+		}
+		// A PVC that already existed was not created by us, so rollback leaves it alone
+		if !pvc.Existing {
+			e.recordPhase(pvc, PhasePVCCreated)
+		}
 	}
+	pvc.Created = !pvc.Existing
 
 	// Step 2: Wait for PVC to be bound
-	fmt.Printf("  Waiting for PVC %s to be bound...\n", pvc.Name)
+	logger.Printf("  Waiting for PVC %s to be bound...\n", pvc.Name)
 	if err := e.waitForPVCBound(pvc); err != nil {
 		return fmt.Errorf("PVC not bound: %v", err)
 	}
 
 	// Step 3: Copy data from Docker volume to PVC
-	fmt.Printf("  Copying data from Docker volume %s...\n", pvc.MatchedVolume.Name)
-	if err := e.copyData(pvc); err != nil {
-		return fmt.Errorf("failed to copy data: %v", err)
+	if phase.reached(PhaseDataCopied) {
+		logger.Printf("  Data of %s was already copied, resuming\n", pvc.Name)
+	} else {
+		logger.Printf("  Copying data from Docker volume %s...\n", pvc.MatchedVolume.Name)
+		if err := e.copyData(pvc); err != nil {
+			return fmt.Errorf("failed to copy data: %v", err)
+		}
+		e.recordPhase(pvc, PhaseDataCopied)
+	}
+
+	// Step 4: Verify the PVC contains the expected data
+	if err := e.verifyFilesystem(pvc); err != nil {
+		return fmt.Errorf("verification failed: %v", err)
+	}
+
+	// Step 5: Optionally compare the checksums of every file
+	if e.verifyChecksums {
+		if err := e.verifyData(pvc); err != nil {
+			return fmt.Errorf("checksum verification failed: %v", err)
+		}
 	}
 
 	return nil
 }
 
 func (e *Engine) createPVC(pvc *types.PVCInfo) error {
+	// Applying a PVC again could resize it, so only do so when that is possible
+	existed, upToDate, err := e.checkExistingPVC(e.ctx, pvc)
+	if err != nil {
+		return err
+	}
+	// An existing PVC stays the user's, even when it is applied again to resize it
+	if existed {
+		pvc.Existing = true
+	}
+	if upToDate {
+		return nil
+	}
+
+	if err := e.applyPVC(pvc); err != nil {
+		return err
+	}
+	if e.annotate {
+		return e.annotatePVC(e.ctx, pvc)
+	}
+	return nil
+}
+
+// applyPVC creates the PVC from the source cluster, its StatefulSet or its YAML file
+func (e *Engine) applyPVC(pvc *types.PVCInfo) error {
+	if e.crossCluster() {
+		return e.createPVCFromSource(e.ctx, pvc)
+	}
+	if pvc.StatefulSet != "" {
+		return e.createStatefulSetPVC(e.ctx, pvc)
+	}
+	if pvc.Kustomization != "" {
+		return e.createKustomizePVC(e.ctx, pvc)
+	}
+
 	// Find and apply only the YAML file containing this specific PVC
 	yamlFile, err := e.findYAMLFileForPVC(pvc)
 	if err != nil {
 		return fmt.Errorf("failed to find YAML file for PVC %s: %v", pvc.Name, err)
 	}
 
-	fmt.Printf("    Applying %s to namespace %s...\n", yamlFile, e.migrationNamespace)
+	// The file may have been rewritten (or the PVC moved) since we parsed it
+	if e.watcher != nil && e.watcher.takeModified(yamlFile) {
+		logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("⚠️  %s was modified by another process, re-reading it", yamlFile)))
+		yamlFile, err = e.findYAMLFileForPVC(pvc)
+		if err != nil {
+			return fmt.Errorf("failed to find YAML file for PVC %s after modification: %v", pvc.Name, err)
+		}
+	}
 
-	// Apply the specific YAML file to the specified namespace
-	cmd := exec.Command("kubectl", "apply", "-f", yamlFile, "-n", e.migrationNamespace)
-	output, err := cmd.CombinedOutput()
+	logger.Printf("    Applying %s to namespace %s...\n", yamlFile, e.namespaceFor(pvc))
+
+	content, err := e.readYAMLFile(yamlFile)
 	if err != nil {
-		return fmt.Errorf("kubectl apply failed: %v\nOutput: %s", err, string(output))
+		return fmt.Errorf("failed to read %s: %v", yamlFile, err)
 	}
 
-	return nil
+	// Apply the specific YAML file to the specified namespace
+	return e.applyWithRetry(e.ctx, content, e.namespaceFor(pvc), applyMaxRetries, applyBackoff)
 }
 
 func (e *Engine) findYAMLFileForPVC(pvc *types.PVCInfo) (string, error) {
-	// Search through YAML files to find the one containing this PVC
-	matches, err := filepath.Glob(filepath.Join(e.yamlDirectory, "*.yaml"))
+	// Search the same YAML files the parser found the PVC in
+	parser := kubernetes.NewParser()
+	parser.SetMaxDepth(e.yamlDepth)
+	yamlFiles, err := parser.FindYAMLFiles(e.yamlDirectory)
 	if err != nil {
 		return "", err
 	}
 
-	yamlFiles := matches
-	moreMatches, err := filepath.Glob(filepath.Join(e.yamlDirectory, "*.yml"))
-	if err == nil {
-		yamlFiles = append(yamlFiles, moreMatches...)
-	}
-
 	for _, file := range yamlFiles {
 		if e.fileContainsPVC(file, pvc) {
 			return file, nil
@@ -116,14 +565,26 @@ func (e *Engine) findYAMLFileForPVC(pvc *types.PVCInfo) (string, error) {
 	return "", fmt.Errorf("no YAML file found containing PVC %s", pvc.Name)
 }
 
-func (e *Engine) fileContainsPVC(filename string, pvc *types.PVCInfo) bool {
+func (e *Engine) readYAMLFile(filename string) (string, error) {
 	content, err := os.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+
+	if e.expandEnv {
+		return internalyaml.ExpandEnv(string(content)), nil
+	}
+	return string(content), nil
+}
+
+func (e *Engine) fileContainsPVC(filename string, pvc *types.PVCInfo) bool {
+	content, err := e.readYAMLFile(filename)
 	if err != nil {
 		return false
 	}
 
 	// Split content by document separator (---)
-	documents := strings.Split(string(content), "\n---\n")
+	documents := strings.Split(content, "\n---\n")
 
 	for _, doc := range documents {
 		if strings.TrimSpace(doc) == "" {
@@ -150,66 +611,134 @@ func (e *Engine) fileContainsPVC(filename string, pvc *types.PVCInfo) bool {
 }
 
 func (e *Engine) waitForPVCBound(pvc *types.PVCInfo) error {
-	timeout := 5 * time.Minute
-	interval := 5 * time.Second
+	timeout := e.pvcTimeout
+	interval := e.pvcPollInterval
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	ctx, cancel := context.WithTimeout(e.ctx, timeout)
 	defer cancel()
 
-	for {
-		select {
-		case <-ctx.Done():
-			return fmt.Errorf("timeout waiting for PVC %s to be bound", pvc.Name)
-		default:
-			cmd := exec.Command("kubectl", "get", "pvc", pvc.Name, "-n", e.migrationNamespace, "-o", "jsonpath={.status.phase}")
-			output, err := cmd.Output()
-			if err != nil {
-				fmt.Printf("    Error checking PVC status: %v\n", err)
-				time.Sleep(interval)
-				continue
-			}
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
 
-			phase := strings.TrimSpace(string(output))
-			fmt.Printf("    PVC status: %s\n", phase)
+	for {
+		claim, err := client.CoreV1().PersistentVolumeClaims(e.namespaceFor(pvc)).Get(ctx, pvc.Name, metav1.GetOptions{})
+		if err != nil {
+			logger.Warnf("    Error checking PVC status: %v\n", err)
+		} else {
+			phase := claim.Status.Phase
+			logger.Printf("    PVC status: %s\n", phase)
 
-			if phase == "Bound" {
-				fmt.Printf("    ✅ PVC is now bound!\n")
+			if phase == corev1.ClaimBound {
+				logger.Printf("    %s\n", color.Success("✅ PVC is now bound!"))
 				return nil
 			}
 
 			if phase == "Failed" {
 				return fmt.Errorf("PVC failed to bind")
 			}
+		}
 
-			// Don't proceed if PVC is not bound
-			time.Sleep(interval)
+		// Don't proceed if PVC is not bound; a cancelled migration stops waiting right away
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for PVC %s to be bound after %s", pvc.Name, timeout)
+		case <-ticker.C:
 		}
 	}
 }
 
 func (e *Engine) copyData(pvc *types.PVCInfo) error {
+	if e.exportDir != "" {
+		return e.exportAndImport(pvc)
+	}
+
 	// Get current node name to schedule migration pod on the same node
-	nodeName, err := e.getCurrentNodeName()
+	nodeName, err := e.getCurrentNodeName(pvc)
 	if err != nil {
 		return fmt.Errorf("failed to get current node name: %v", err)
 	}
 
-	// Create migration pod in the migration namespace (from --namespace flag)
-	podName := fmt.Sprintf("migration-%s-%d", pvc.Name, time.Now().Unix())
+	extraVolumes, extraMounts, err := e.buildExtraVolumesYAML()
+	if err != nil {
+		return fmt.Errorf("failed to build extra volumes: %v", err)
+	}
+
+	namespace := e.podNamespaceFor(pvc)
+	restartKey := e.namespaceFor(pvc) + "/" + pvc.Name
+
+	e.mu.Lock()
+	if e.copyNodes == nil {
+		e.copyNodes = make(map[string]string)
+	}
+	e.copyNodes[restartKey] = nodeName
+	e.mu.Unlock()
+	for {
+		podName := fmt.Sprintf("migration-%s-%d", pvc.Name, time.Now().Unix())
+		podYAML := e.buildMigrationPodYAML(pvc, podName, namespace, nodeName, e.podMemoryLimit(restartKey), extraVolumes, extraMounts)
+
+		// Create the migration pod
+		if err := e.createPod(podYAML); err != nil {
+			return fmt.Errorf("failed to create migration pod: %v", err)
+		}
+
+		logger.Printf("  Migration pod %s created in namespace %s, scheduled on node %s\n", podName, namespace, nodeName)
 
-	podYAML := fmt.Sprintf(`apiVersion: v1
+		// Wait for pod to complete, allowing more time for larger volumes
+		timeout := e.podTimeout(pvc)
+		ctx, cancel := context.WithTimeout(e.ctx, timeout)
+
+		logger.Printf("  Waiting for migration pod to complete (timeout %s)...\n", timeout)
+		err := e.watchPodForRestart(ctx, podName, namespace, e.progressTotal(pvc))
+		cancel()
+		if err == nil {
+			return e.finishMigrationPod(podName, namespace)
+		}
+
+		if !e.shouldRestartPod(restartKey, err) {
+			return fmt.Errorf("migration pod failed: %v", err)
+		}
+		if err := e.deletePod(podName, namespace); err != nil {
+			logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("Warning: Could not delete migration pod: %v", err)))
+		}
+	}
+}
+
+// finishMigrationPod shows the logs of a completed migration pod and removes it
+func (e *Engine) finishMigrationPod(podName, namespace string) error {
+	// Show pod logs
+	logger.Printf("  Migration pod logs:\n")
+	if err := e.showPodLogs(podName, namespace); err != nil {
+		logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("Warning: Could not retrieve pod logs: %v", err)))
+	}
+
+	// Clean up the migration pod
+	if err := e.deletePod(podName, namespace); err != nil {
+		logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("Warning: Could not delete migration pod: %v", err)))
+	}
+
+	return nil
+}
+
+func (e *Engine) buildMigrationPodYAML(pvc *types.PVCInfo, podName, namespace, nodeName, memoryLimit, extraVolumes, extraMounts string) string {
+	return fmt.Sprintf(`apiVersion: v1
 kind: Pod
 metadata:
   name: %s
   namespace: %s
+  labels:
+    app.kubernetes.io/managed-by: docker-pvc-migration
 spec:
   restartPolicy: Never
-  nodeName: %s
-  containers:
+%s%s%s  containers:
   - name: migration
-    image: busybox:latest
+    image: %s
     command: ["/bin/sh", "-c"]
-    args:
+%s    args:
     - |
       echo "Starting data copy..."
       echo "Source: /docker-data"
@@ -219,7 +748,26 @@ spec:
       
       if [ "$(ls -A /docker-data 2>/dev/null)" ]; then
         echo "Copying data..."
-        cp -av /docker-data/* /pvc-data/ 2>/dev/null || echo "No files to copy or copy failed"
+        total=$(find /docker-data -type f | wc -l)
+        echo "PROGRESS:0/$total"
+        if command -v rsync >/dev/null 2>&1; then
+          # rsync only transfers what differs, so a re-run after a failure resumes the copy.
+          # Every transferred file is counted for the PROGRESS:<copied>/<total> lines.
+          { rsync -av --checksum --out-format='FILE:%%n' %s/docker-data/ /pvc-data/; echo $? > /tmp/rsync-status; } | {
+            copied=0
+            while IFS= read -r line; do
+              case "$line" in
+                FILE:*/) ;;
+                FILE:*) copied=$((copied + 1)); echo "${line#FILE:}"; echo "PROGRESS:$copied/$total" ;;
+                *) echo "$line" ;;
+              esac
+            done
+          }
+          [ "$(cat /tmp/rsync-status)" = 0 ] || { echo "Copy failed"; exit 1; }
+        else
+%s
+        fi
+        echo "PROGRESS:$total/$total"
         echo "Copy completed"
       else
         echo "Source directory is empty"
@@ -233,55 +781,129 @@ spec:
       mountPath: /docker-data
     - name: pvc-volume
       mountPath: /pvc-data
-  volumes:
+%s  volumes:
   - name: docker-volume
     hostPath:
       path: %s
-      type: Directory
+      type: %s
   - name: pvc-volume
-    persistentVolumeClaim:
+%s%s`, podName, namespace, e.buildNodeSelection(nodeName), e.buildImagePullSecrets(), e.buildInitContainers(pvc), e.migrationImage, e.buildPodResources(memoryLimit), e.buildRsyncArgs(), e.buildCopyFallback(), extraMounts, pvc.MatchedVolume.Mountpoint, e.hostPathType, e.buildTargetVolume(pvc), extraVolumes)
+}
+
+// buildRsyncArgs returns the extra rsync options quoted for the shell, with a trailing space
+func (e *Engine) buildRsyncArgs() string {
+	var args string
+	for _, arg := range e.rsyncArgs {
+		args += fmt.Sprintf("'%s' ", arg)
+	}
+	return args
+}
+
+// buildCopyFallback returns the copy commands for images without rsync. Options from
+// --rsync-args cannot be honoured by cp, so the copy fails instead of ignoring them.
+func (e *Engine) buildCopyFallback() string {
+	if len(e.rsyncArgs) > 0 {
+		return `          echo "--rsync-args needs rsync, which the migration image does not contain (see --migration-image)"
+          exit 1`
+	}
+	return `          # cp copies everything again, so a re-run after a failure starts over
+          cp -a /docker-data/. /pvc-data/ || { echo "Copy failed"; exit 1; }`
+}
+
+func (e *Engine) buildTargetVolume(pvc *types.PVCInfo) string {
+	if !e.useEphemeralVolumes {
+		return fmt.Sprintf(`    persistentVolumeClaim:
       claimName: %s
-`, podName, e.migrationNamespace, nodeName, pvc.MatchedVolume.Mountpoint, pvc.Name)
+`, pvc.Name)
+	}
 
-	// Create the migration pod
-	cmd := exec.Command("kubectl", "apply", "-f", "-")
-	cmd.Stdin = strings.NewReader(podYAML)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("failed to create migration pod: %v\nOutput: %s", err, string(output))
+	size := pvc.NewSize
+	if size == "" {
+		size = pvc.RequestedSize
 	}
+	return fmt.Sprintf(`    emptyDir:
+      sizeLimit: %s
+`, size)
+}
 
-	fmt.Printf("  Migration pod %s created in namespace %s, scheduled on node %s\n", podName, e.migrationNamespace, nodeName)
+func (e *Engine) buildInitContainers(pvc *types.PVCInfo) string {
+	dirs := append([]string{}, e.preCreateDirs...)
+	if annotation, ok := pvc.Annotations[preCreateDirsAnnotation]; ok {
+		dirs = append(dirs, strings.Split(annotation, ",")...)
+	}
 
-	// Wait for pod to complete
-	fmt.Printf("  Waiting for migration pod to complete...\n")
-	if err := e.waitForPodCompletion(podName, e.migrationNamespace); err != nil {
-		return fmt.Errorf("migration pod failed: %v", err)
+	var targets []string
+	for _, dir := range dirs {
+		// Keep every directory inside the PVC mount
+		dir = path.Clean("/" + strings.TrimSpace(dir))
+		if dir == "/" || strings.ContainsAny(dir, "'\n") {
+			continue
+		}
+		targets = append(targets, fmt.Sprintf("'/pvc-data%s'", dir))
 	}
 
-	// Show pod logs
-	fmt.Printf("  Migration pod logs:\n")
-	if err := e.showPodLogs(podName, e.migrationNamespace); err != nil {
-		fmt.Printf("    Warning: Could not retrieve pod logs: %v\n", err)
+	if len(targets) == 0 {
+		return ""
 	}
 
-	// Clean up the migration pod
-	if err := e.deletePod(podName, e.migrationNamespace); err != nil {
-		fmt.Printf("    Warning: Could not delete migration pod: %v\n", err)
+	logger.Printf("  Pre-creating directories in PVC: %s\n", strings.Join(targets, " "))
+	return fmt.Sprintf(`  initContainers:
+  - name: pre-create-dirs
+    image: %s
+    command: ["/bin/sh", "-c"]
+    args:
+    - mkdir -p %s
+    volumeMounts:
+    - name: pvc-volume
+      mountPath: /pvc-data
+`, e.migrationImage, strings.Join(targets, " "))
+}
+
+func (e *Engine) createPod(podYAML string) error {
+	var pod corev1.Pod
+	if err := sigsyaml.Unmarshal([]byte(podYAML), &pod); err != nil {
+		return fmt.Errorf("invalid pod definition: %v", err)
 	}
 
-	return nil
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.CoreV1().Pods(pod.Namespace).Create(e.ctx, &pod, metav1.CreateOptions{FieldManager: fieldManager})
+	return err
 }
 
-func (e *Engine) getCurrentNodeName() (string, error) {
+func (e *Engine) getCurrentNodeName(pvc *types.PVCInfo) (string, error) {
+	if node, ok := e.plannedNodes[e.namespaceFor(pvc)+"/"+pvc.Name]; ok {
+		logger.Printf("  Using planned node: %s\n", node)
+		return node, nil
+	}
+
+	ctx, cancel := context.WithTimeout(e.ctx, 2*time.Minute)
+	defer cancel()
+
+	nodeName, err := e.detectVolumeNode(ctx, pvc.MatchedVolume.Mountpoint)
+	if err == nil {
+		logger.Printf("  Auto-detected node: %s (contains mountpoint %s)\n", nodeName, pvc.MatchedVolume.Mountpoint)
+		return nodeName, nil
+	}
+	logger.Printf("  Could not auto-detect node: %v\n", err)
+
 	// Get all available nodes
-	cmd := exec.Command("kubectl", "get", "nodes", "-o", "jsonpath={.items[*].metadata.name}")
-	output, err := cmd.Output()
+	client, err := e.getClientset()
+	if err != nil {
+		return "", err
+	}
+	nodeList, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
 	if err != nil {
 		return "", fmt.Errorf("failed to get node list: %v", err)
 	}
 
-	nodes := strings.Fields(string(output))
+	var nodes []string
+	for _, node := range nodeList.Items {
+		nodes = append(nodes, node.Name)
+	}
 	if len(nodes) == 0 {
 		return "", fmt.Errorf("no Kubernetes nodes found")
 	}
@@ -290,7 +912,14 @@ func (e *Engine) getCurrentNodeName() (string, error) {
 	hostname, _ := os.Hostname()
 	defaultNode := e.findBestDefaultNode(nodes, hostname)
 
+	if e.nonInteractive {
+		logger.Printf("  Using node %s\n", defaultNode)
+		return defaultNode, nil
+	}
+
 	// Interactive node selection
+	e.promptMu.Lock()
+	defer e.promptMu.Unlock()
 	return e.interactiveNodeSelection(nodes, defaultNode)
 }
 
@@ -314,7 +943,7 @@ func (e *Engine) findBestDefaultNode(nodes []string, hostname string) string {
 func (e *Engine) interactiveNodeSelection(nodes []string, defaultNode string) (string, error) {
 	reader := bufio.NewReader(os.Stdin)
 
-	fmt.Printf("\nSelect Kubernetes node for migration pods:\n")
+	logger.Printf("\nSelect Kubernetes node for migration pods:\n")
 
 	// Find default index
 	for i, node := range nodes {
@@ -322,11 +951,11 @@ func (e *Engine) interactiveNodeSelection(nodes []string, defaultNode string) (s
 		if node == defaultNode {
 			marker = "* "
 		}
-		fmt.Printf("%s%d. %s\n", marker, i+1, node)
+		logger.Printf("%s%d. %s\n", marker, i+1, node)
 	}
 
-	fmt.Printf("\nDefault: %s (press Enter to use default)\n", defaultNode)
-	fmt.Printf("Enter choice (number 1-%d or node name): ", len(nodes))
+	logger.Printf("\nDefault: %s (press Enter to use default)\n", defaultNode)
+	logger.Printf("Enter choice (number 1-%d or node name): ", len(nodes))
 
 	for {
 		input, err := reader.ReadString('\n')
@@ -338,7 +967,7 @@ func (e *Engine) interactiveNodeSelection(nodes []string, defaultNode string) (s
 
 		// If empty, use default
 		if input == "" {
-			fmt.Printf("Selected: %s (default)\n", defaultNode)
+			logger.Printf("Selected: %s (default)\n", defaultNode)
 			return defaultNode, nil
 		}
 
@@ -346,10 +975,10 @@ func (e *Engine) interactiveNodeSelection(nodes []string, defaultNode string) (s
 		if choice, err := strconv.Atoi(input); err == nil {
 			if choice >= 1 && choice <= len(nodes) {
 				selected := nodes[choice-1]
-				fmt.Printf("Selected: %s\n", selected)
+				logger.Printf("Selected: %s\n", selected)
 				return selected, nil
 			} else {
-				fmt.Printf("Invalid number. Enter 1-%d or node name: ", len(nodes))
+				logger.Printf("Invalid number. Enter 1-%d or node name: ", len(nodes))
 				continue
 			}
 		}
@@ -359,7 +988,7 @@ func (e *Engine) interactiveNodeSelection(nodes []string, defaultNode string) (s
 		for _, node := range nodes {
 			if strings.EqualFold(node, input) {
 				// Exact match
-				fmt.Printf("Selected: %s\n", node)
+				logger.Printf("Selected: %s\n", node)
 				return node, nil
 			}
 			if strings.Contains(strings.ToLower(node), strings.ToLower(input)) {
@@ -369,63 +998,154 @@ func (e *Engine) interactiveNodeSelection(nodes []string, defaultNode string) (s
 
 		if len(matches) == 1 {
 			// Single partial match
-			fmt.Printf("Selected: %s\n", matches[0])
+			logger.Printf("Selected: %s\n", matches[0])
 			return matches[0], nil
 		} else if len(matches) > 1 {
-			fmt.Printf("Multiple matches found: %s\n", strings.Join(matches, ", "))
-			fmt.Printf("Please be more specific. Enter choice (number 1-%d or node name): ", len(nodes))
+			logger.Printf("Multiple matches found: %s\n", strings.Join(matches, ", "))
+			logger.Printf("Please be more specific. Enter choice (number 1-%d or node name): ", len(nodes))
 			continue
 		}
 
 		// No matches
-		fmt.Printf("Node '%s' not found. Enter choice (number 1-%d or node name): ", input, len(nodes))
+		logger.Printf("Node '%s' not found. Enter choice (number 1-%d or node name): ", input, len(nodes))
 	}
 }
 
-func (e *Engine) waitForPodCompletion(podName, namespace string) error {
-	timeout := 10 * time.Minute
-	interval := 5 * time.Second
+// waitForPodCompletion watches the pod until it finishes. When totalBytes is set, the
+// amount of data copied into /pvc-data is shown while the pod is running.
+func (e *Engine) waitForPodCompletion(ctx context.Context, podName, namespace string, totalBytes int64) error {
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
+	progressShown := false
+	defer func() {
+		if progressShown && e.progressInPlace() {
+			logger.Println()
+		}
+	}()
 
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	phases := make(chan corev1.PodPhase)
+	go watchPodPhase(watchCtx, client, podName, namespace, phases)
+
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	// File counts from the pod logs are preferred over the byte counts of the kubelet,
+	// which are only refreshed about once a minute
+	progress := make(chan fileProgress)
+	followingLogs := false
+	fileProgressShown := false
+	lastPercent := -1
+
+	var phase corev1.PodPhase
 	for {
 		select {
 		case <-ctx.Done():
 			return fmt.Errorf("timeout waiting for pod %s to complete", podName)
-		default:
-			cmd := exec.Command("kubectl", "get", "pod", podName, "-n", namespace, "-o", "jsonpath={.status.phase}")
-			output, err := cmd.Output()
-			if err != nil {
-				time.Sleep(interval)
+		case next := <-phases:
+			switch next {
+			case corev1.PodSucceeded:
+				return nil
+			case corev1.PodFailed:
+				return fmt.Errorf("migration pod failed")
+			}
+			if next != phase {
+				logger.Printf("    Pod status: %s\n", next)
+				phase = next
+			}
+			if phase == corev1.PodRunning && !followingLogs {
+				followingLogs = true
+				go followCopyProgress(watchCtx, client, podName, namespace, progress)
+			}
+		case update := <-progress:
+			if update.total == 0 {
 				continue
 			}
-
-			phase := strings.TrimSpace(string(output))
-			if phase == "Succeeded" {
-				return nil
+			if percent := update.copied * 100 / update.total; percent != lastPercent {
+				e.printFileProgress(podName, update.copied, update.total)
+				lastPercent = percent
+				progressShown = true
+				fileProgressShown = true
 			}
-			if phase == "Failed" {
-				return fmt.Errorf("migration pod failed")
+		case <-ticker.C:
+			if phase != corev1.PodRunning || totalBytes == 0 || fileProgressShown {
+				continue
 			}
+			copied, err := e.copiedBytes(ctx, podName, namespace)
+			if apierrors.IsForbidden(err) {
+				// Asking again every tick would not help, so progress is off for this pod
+				logger.Warnf("    %s\n", color.Warning("Warning: No access to nodes/proxy, copy progress is not shown"))
+				totalBytes = 0
+				continue
+			}
+			if err == nil {
+				e.printCopyProgress(podName, copied, totalBytes)
+				progressShown = true
+			}
+		}
+	}
+}
+
+// watchPodPhase sends the phase of the pod to phases whenever the pod changes. The
+// watch is re-established when the API server closes it, until ctx is done.
+func watchPodPhase(ctx context.Context, client clientset.Interface, podName, namespace string, phases chan<- corev1.PodPhase) {
+	selector := fields.OneTermEqualSelector("metadata.name", podName).String()
+
+	for ctx.Err() == nil {
+		watcher, err := client.CoreV1().Pods(namespace).Watch(ctx, metav1.ListOptions{FieldSelector: selector})
+		if err != nil {
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+			continue
+		}
 
-			fmt.Printf("    Pod status: %s\n", phase)
-			time.Sleep(interval)
+		for event := range watcher.ResultChan() {
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
+			select {
+			case phases <- pod.Status.Phase:
+			case <-ctx.Done():
+			}
 		}
+		watcher.Stop()
+	}
+}
+
+// podLogs returns the logs of the migration container of the pod
+func (e *Engine) podLogs(ctx context.Context, podName, namespace string) (string, error) {
+	client, err := e.getClientset()
+	if err != nil {
+		return "", err
+	}
+
+	output, err := client.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{}).DoRaw(ctx)
+	if err != nil {
+		return "", err
 	}
+	return string(output), nil
 }
 
 func (e *Engine) showPodLogs(podName, namespace string) error {
-	cmd := exec.Command("kubectl", "logs", podName, "-n", namespace)
-	output, err := cmd.Output()
+	output, err := e.podLogs(e.ctx, podName, namespace)
 	if err != nil {
 		return err
 	}
 
-	lines := strings.Split(string(output), "\n")
+	lines := strings.Split(output, "\n")
 	for _, line := range lines {
+		if _, _, ok := parseProgressLine(line); ok {
+			continue
+		}
 		if strings.TrimSpace(line) != "" {
-			fmt.Printf("    %s\n", line)
+			logger.Printf("    %s\n", line)
 		}
 	}
 
@@ -433,27 +1153,62 @@ func (e *Engine) showPodLogs(podName, namespace string) error {
 }
 
 func (e *Engine) deletePod(podName, namespace string) error {
-	cmd := exec.Command("kubectl", "delete", "pod", podName, "-n", namespace, "--ignore-not-found")
-	_, err := cmd.CombinedOutput()
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
+
+	err = client.CoreV1().Pods(namespace).Delete(context.Background(), podName, metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
 	return err
 }
 
-func (e *Engine) DryRun(pvcs []*types.PVCInfo) {
-	fmt.Println("\n=== Dry Run - Migration Plan ===")
+// SetDryRunEncoder makes DryRun write the plan as []types.MigrationPlan to encoder
+// instead of printing it for humans
+func (e *Engine) SetDryRunEncoder(encoder output.Encoder) {
+	e.dryRunEncoder = encoder
+}
+
+// SetYAMLDiffs adds the YAML changes each PVC would get to the machine-readable dry-run plan
+func (e *Engine) SetYAMLDiffs(diffs []internalyaml.FileDiff) {
+	e.yamlDiffs = diffs
+}
+
+func (e *Engine) DryRun(pvcs []*types.PVCInfo) error {
+	if e.dryRunEncoder != nil {
+		plans := []types.MigrationPlan{}
+		for _, pvc := range pvcs {
+			if pvc.MatchedVolume != nil {
+				plan := types.NewMigrationPlan(pvc)
+				for _, fileDiff := range e.yamlDiffs {
+					if slices.Contains(fileDiff.PVCs, pvc.Namespace+"/"+pvc.Name) {
+						plan.YAMLFile = fileDiff.File
+						plan.YAMLDiff = fileDiff.Diff
+					}
+				}
+				plans = append(plans, plan)
+			}
+		}
+		return e.dryRunEncoder.Encode(plans)
+	}
+
+	logger.Println("\n" + color.Header("=== Dry Run - Migration Plan ==="))
 
 	for i, pvc := range pvcs {
 		if pvc.MatchedVolume == nil {
-			fmt.Printf("[%d] SKIP: %s (no volume selected)\n", i+1, pvc.Name)
+			logger.Printf("[%d] SKIP: %s (no volume selected)\n", i+1, pvc.Name)
 			continue
 		}
 
-		fmt.Printf("[%d] MIGRATE: %s\n", i+1, pvc.Name)
-		fmt.Printf("    Source: %s (%s)\n", pvc.MatchedVolume.Name, pvc.MatchedVolume.SizeHuman)
-		fmt.Printf("    Target: PVC %s/%s (%s)\n", pvc.Namespace, pvc.Name, pvc.NewSize)
-		fmt.Printf("    Path: %s → PVC mount\n", pvc.MatchedVolume.Mountpoint)
-		fmt.Println()
+		logger.Printf("[%d] MIGRATE: %s\n", i+1, pvc.Name)
+		logger.Printf("    Source: %s (%s)\n", pvc.MatchedVolume.Name, pvc.MatchedVolume.SizeHuman)
+		logger.Printf("    Target: PVC %s/%s (%s)\n", pvc.Namespace, pvc.Name, pvc.NewSize)
+		logger.Printf("    Path: %s → PVC mount\n", pvc.MatchedVolume.Mountpoint)
+		logger.Println()
 	}
 
-	fmt.Println("Use --execute to run the actual migration")
+	logger.Println("Use --execute to run the actual migration")
+	return nil
 }
-
@@ -0,0 +1,237 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestSetPVCTimeouts(t *testing.T) {
+	tests := []struct {
+		value   time.Duration
+		wantErr bool
+	}{
+		{time.Minute, false},
+		{time.Millisecond, false},
+		{0, true},
+		{-time.Second, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value.String(), func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			if err := e.SetPVCTimeout(tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("SetPVCTimeout(%s) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+			if err := e.SetPVCPollInterval(tt.value); (err != nil) != tt.wantErr {
+				t.Errorf("SetPVCPollInterval(%s) error = %v, want error %v", tt.value, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWaitForPVCBoundTimeout(t *testing.T) {
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "slow-data", Namespace: "default"},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	})
+	if err := e.SetPVCTimeout(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetPVCPollInterval(10 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	err := e.waitForPVCBound(&types.PVCInfo{Name: "slow-data", Namespace: "default"})
+	if err == nil || !strings.Contains(err.Error(), "slow-data") {
+		t.Errorf("error = %v, want a timeout naming the PVC", err)
+	}
+}
+
+func TestWaitForPVCBoundTimesOutBetweenPolls(t *testing.T) {
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "slow-data", Namespace: "default"},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	})
+	if err := e.SetPVCTimeout(50 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	// The timeout ends the wait, not the next poll
+	if err := e.SetPVCPollInterval(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	if err := e.waitForPVCBound(&types.PVCInfo{Name: "slow-data", Namespace: "default"}); err == nil {
+		t.Fatal("waitForPVCBound() bound a pending PVC")
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("waitForPVCBound() returned after %s, want it to stop at the timeout", elapsed)
+	}
+}
+
+func TestCreatePod(t *testing.T) {
+	client := fake.NewSimpleClientset()
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = client
+
+	pvc := &types.PVCInfo{
+		Name:          "data",
+		Namespace:     "default",
+		MatchedVolume: &types.DockerVolumeInfo{Name: "app_data", Mountpoint: "/var/lib/docker/volumes/app_data/_data"},
+	}
+	if err := e.createPod(e.buildMigrationPodYAML(pvc, "migration-data-1", "default", "node-1", "", "", "")); err != nil {
+		t.Fatal(err)
+	}
+
+	pod, err := client.CoreV1().Pods("default").Get(context.Background(), "migration-data-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pod.Spec.NodeName != "node-1" || pod.Spec.RestartPolicy != corev1.RestartPolicyNever {
+		t.Errorf("pod spec = node %q, restart policy %q", pod.Spec.NodeName, pod.Spec.RestartPolicy)
+	}
+}
+
+func TestWaitForPodCompletion(t *testing.T) {
+	tests := []struct {
+		phase   corev1.PodPhase
+		wantErr bool
+	}{
+		{corev1.PodSucceeded, false},
+		{corev1.PodFailed, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.phase), func(t *testing.T) {
+			// The fake watcher hands every event to the engine before the next one is sent
+			watcher := watch.NewFake()
+			client := fake.NewSimpleClientset()
+			client.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+
+			e := NewEngine("default", "", t.TempDir())
+			e.destKubeClient = client
+
+			pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "migration-data-1", Namespace: "default"}}
+			go func() {
+				for _, phase := range []corev1.PodPhase{corev1.PodPending, tt.phase} {
+					update := pod.DeepCopy()
+					update.Status.Phase = phase
+					watcher.Modify(update)
+				}
+			}()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			err := e.waitForPodCompletion(ctx, "migration-data-1", "default", 0)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("waitForPodCompletion() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPodLogs(t *testing.T) {
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "migration-data-1", Namespace: "default"},
+	})
+
+	// The fake clientset answers every log request with the same text
+	logs, err := e.podLogs(context.Background(), "migration-data-1", "default")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if logs != "fake logs" {
+		t.Errorf("podLogs() = %q, want the fake logs", logs)
+	}
+}
+
+func TestDeletePod(t *testing.T) {
+	client := fake.NewSimpleClientset(&corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "migration-data-1", Namespace: "default"},
+	})
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = client
+
+	if err := e.deletePod("migration-data-1", "default"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.CoreV1().Pods("default").Get(context.Background(), "migration-data-1", metav1.GetOptions{}); err == nil {
+		t.Error("pod still exists after deletePod")
+	}
+
+	// Deleting a pod that is already gone is not an error
+	if err := e.deletePod("migration-data-1", "default"); err != nil {
+		t.Errorf("deletePod() of a missing pod = %v, want nil", err)
+	}
+}
+
+func TestFindYAMLFileForPVCInSubdirectories(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"app.yaml":                 "kind: PersistentVolumeClaim\nmetadata:\n  name: top\n",
+		"one/db.yml":               "kind: PersistentVolumeClaim\nmetadata:\n  name: first\n",
+		"one/two/cache.yaml":       "kind: ConfigMap\nmetadata:\n  name: nested\n---\nkind: PersistentVolumeClaim\nmetadata:\n  name: second\n",
+		"one/two/three/media.yaml": "kind: PersistentVolumeClaim\nmetadata:\n  name: third\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		pvc   string
+		depth int
+		want  string // Relative to root, empty when the PVC is not found
+	}{
+		{"top", -1, "app.yaml"},
+		{"first", -1, "one/db.yml"},
+		{"second", -1, "one/two/cache.yaml"},
+		{"third", -1, "one/two/three/media.yaml"},
+		{"third", 3, "one/two/three/media.yaml"},
+		{"third", 2, ""},
+		{"second", 1, ""},
+		{"top", 0, "app.yaml"},
+		{"missing", -1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s/%d", tt.pvc, tt.depth), func(t *testing.T) {
+			e := NewEngine("default", "", root)
+			e.SetYAMLDepth(tt.depth)
+
+			got, err := e.findYAMLFileForPVC(&types.PVCInfo{Name: tt.pvc, Namespace: "default"})
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("findYAMLFileForPVC(%s) = %s, want an error", tt.pvc, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(root, tt.want); got != want {
+				t.Errorf("findYAMLFileForPVC(%s) = %s, want %s", tt.pvc, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SetOverwriteExisting makes createPVC apply PVCs that already exist with another
+// size, without checking whether their storage class can expand them
+func (e *Engine) SetOverwriteExisting(overwriteExisting bool) {
+	e.overwriteExisting = overwriteExisting
+}
+
+// checkExistingPVC reports whether pvc already exists, and whether it exists with
+// the size it would be created with so applying it can be skipped. An existing PVC
+// with another size is only applied again when its storage class allows volume
+// expansion or --overwrite-existing is set; it still is not a PVC this tool created.
+func (e *Engine) checkExistingPVC(ctx context.Context, pvc *types.PVCInfo) (existed, upToDate bool, err error) {
+	client, err := e.getClientset()
+	if err != nil {
+		return false, false, err
+	}
+
+	namespace := e.namespaceFor(pvc)
+	existing, err := client.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvc.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, false, nil
+	}
+	if err != nil {
+		return false, false, fmt.Errorf("failed to get PVC %s: %v", pvc.Name, err)
+	}
+
+	size := pvc.NewSize
+	if size == "" {
+		size = pvc.RequestedSize
+	}
+	if size == "" {
+		return true, false, nil
+	}
+	wanted, err := resource.ParseQuantity(size)
+	if err != nil {
+		return true, false, fmt.Errorf("invalid size %q for PVC %s: %v", size, pvc.Name, err)
+	}
+
+	// An unbound PVC has no capacity yet, only the size it requests. Provisioners may
+	// round the capacity up, so a PVC requesting the wanted size is correct as well.
+	requested := existing.Spec.Resources.Requests[corev1.ResourceStorage]
+	current, ok := existing.Status.Capacity[corev1.ResourceStorage]
+	if !ok {
+		current = requested
+	}
+	if current.Cmp(wanted) == 0 || requested.Cmp(wanted) == 0 {
+		logger.Printf("    PVC %s/%s already exists with correct size %s, skipping creation\n", namespace, pvc.Name, current.String())
+		return true, true, nil
+	}
+	if e.overwriteExisting {
+		return true, false, nil
+	}
+
+	if current.Cmp(wanted) > 0 {
+		return true, false, fmt.Errorf("PVC %s/%s already exists with size %s, larger than %s; PVCs cannot shrink (use --overwrite-existing to apply anyway)",
+			namespace, pvc.Name, current.String(), wanted.String())
+	}
+
+	expandable, err := e.storageClassAllowsExpansion(ctx, existing.Spec.StorageClassName)
+	if err != nil {
+		return true, false, err
+	}
+	if !expandable {
+		return true, false, fmt.Errorf("PVC %s/%s already exists with size %s instead of %s and its storage class does not allow volume expansion (use --overwrite-existing to apply anyway)",
+			namespace, pvc.Name, current.String(), wanted.String())
+	}
+	logger.Printf("    PVC %s/%s already exists with size %s, expanding it to %s\n", namespace, pvc.Name, current.String(), wanted.String())
+	return true, false, nil
+}
+
+// storageClassAllowsExpansion reports whether PVCs of the named storage class can be
+// resized. PVCs without a storage class cannot.
+func (e *Engine) storageClassAllowsExpansion(ctx context.Context, storageClassName *string) (bool, error) {
+	if storageClassName == nil || *storageClassName == "" {
+		return false, nil
+	}
+
+	client, err := e.getClientset()
+	if err != nil {
+		return false, err
+	}
+
+	storageClass, err := client.StorageV1().StorageClasses().Get(ctx, *storageClassName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get storage class %s: %v", *storageClassName, err)
+	}
+	return storageClass.AllowVolumeExpansion != nil && *storageClass.AllowVolumeExpansion, nil
+}
@@ -0,0 +1,166 @@
+package migration
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func TestMigratePVCKeepsExistingPVC(t *testing.T) {
+	store, err := NewStateStore(filepath.Join(t.TempDir(), "state.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+			},
+		},
+		Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+	})
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = client
+	e.SetStateStore(store)
+	// The PVC never binds, so the migration stops right after the creation step
+	if err := e.SetPVCTimeout(20 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.SetPVCPollInterval(5 * time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	pvc := &types.PVCInfo{Name: "data", Namespace: "default", RequestedSize: "1Gi", MatchedVolume: &types.DockerVolumeInfo{Name: "app_data"}}
+	if err := e.migratePVC(pvc); err == nil {
+		t.Fatal("migratePVC() succeeded for a PVC that never binds")
+	}
+
+	if !pvc.Existing || pvc.Created {
+		t.Errorf("Existing = %v, Created = %v, want the PVC marked as existing and not created", pvc.Existing, pvc.Created)
+	}
+	if e.CreatedPVC(pvc) {
+		t.Error("state file records the existing PVC as created")
+	}
+
+	// Later phases of the copy keep the PVC out of a rollback
+	e.recordPhase(pvc, PhaseDataCopied)
+	if e.CreatedPVC(pvc) {
+		t.Error("state file records the existing PVC as created after copying")
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "create" || action.GetVerb() == "patch" {
+			t.Errorf("migration did %s the existing PVC", action.GetVerb())
+		}
+	}
+
+	// A resumed run learns from the state file that the PVC existed
+	resumed := &types.PVCInfo{Name: "data", Namespace: "default", RequestedSize: "1Gi", MatchedVolume: &types.DockerVolumeInfo{Name: "app_data"}}
+	if err := e.migratePVC(resumed); err == nil {
+		t.Fatal("resumed migratePVC() succeeded for a PVC that never binds")
+	}
+	if !resumed.Existing || resumed.Created {
+		t.Errorf("resumed Existing = %v, Created = %v, want the PVC marked as existing", resumed.Existing, resumed.Created)
+	}
+}
+
+func TestMigratePVCReappliesExistingPVC(t *testing.T) {
+	const pvcYAML = "apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata:\n  name: data\nspec:\n  storageClassName: fast\n  resources:\n    requests:\n      storage: 2Gi\n"
+	expandable := true
+	className := "fast"
+
+	tests := []struct {
+		name      string
+		overwrite bool
+		class     *storagev1.StorageClass
+	}{
+		{"overwrite existing", true, nil},
+		{"expansion", false, &storagev1.StorageClass{ObjectMeta: metav1.ObjectMeta{Name: "fast"}, AllowVolumeExpansion: &expandable}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := NewStateStore(filepath.Join(t.TempDir(), "state.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			objects := []runtime.Object{&corev1.PersistentVolumeClaim{
+				ObjectMeta: metav1.ObjectMeta{Name: "data", Namespace: "default"},
+				Spec: corev1.PersistentVolumeClaimSpec{
+					StorageClassName: &className,
+					Resources: corev1.VolumeResourceRequirements{
+						Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+					},
+				},
+				Status: corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimPending},
+			}}
+			if tt.class != nil {
+				objects = append(objects, tt.class)
+			}
+			client := fake.NewSimpleClientset(objects...)
+
+			// Server-side apply is only recorded; the PVC keeps its old size and never binds
+			dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme())
+			applied := 0
+			dynamicClient.PrependReactor("patch", "persistentvolumeclaims", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				applied++
+				return true, &unstructured.Unstructured{Object: map[string]interface{}{"apiVersion": "v1", "kind": "PersistentVolumeClaim"}}, nil
+			})
+			mapper := meta.NewDefaultRESTMapper(nil)
+			mapper.Add(corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"), meta.RESTScopeNamespace)
+
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "pvc.yaml"), []byte(pvcYAML), 0644); err != nil {
+				t.Fatal(err)
+			}
+			e := NewEngine("default", "", dir)
+			e.destKubeClient = client
+			e.dynamicClient = dynamicClient
+			e.restMapper = mapper
+			e.SetStateStore(store)
+			e.SetOverwriteExisting(tt.overwrite)
+			if err := e.SetPVCTimeout(20 * time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+			if err := e.SetPVCPollInterval(5 * time.Millisecond); err != nil {
+				t.Fatal(err)
+			}
+
+			pvc := &types.PVCInfo{Name: "data", Namespace: "default", RequestedSize: "1Gi", NewSize: "2Gi", MatchedVolume: &types.DockerVolumeInfo{Name: "app_data"}}
+			if err := e.migratePVC(pvc); err == nil {
+				t.Fatal("migratePVC() succeeded for a PVC that never binds")
+			}
+
+			if applied != 1 {
+				t.Errorf("PVC applied %d times, want once", applied)
+			}
+			if !pvc.Existing || pvc.Created {
+				t.Errorf("Existing = %v, Created = %v, want the PVC marked as existing and not created", pvc.Existing, pvc.Created)
+			}
+			if e.statePhase(pvc).reached(PhasePVCCreated) || e.CreatedPVC(pvc) {
+				t.Error("state file records the existing PVC as created")
+			}
+
+			if err := e.Rollback([]*types.PVCInfo{pvc}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := client.CoreV1().PersistentVolumeClaims("default").Get(context.Background(), "data", metav1.GetOptions{}); err != nil {
+				t.Errorf("rollback deleted the existing PVC: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,219 @@
+package migration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// importScript waits for the tarball to be streamed into the import pod and
+// extracts it into the PVC
+const importScript = `echo "Waiting for archive..."
+while [ ! -f /import/ready ]; do sleep 1; done
+echo "Extracting archive..."
+tar -xzf /import/data.tar.gz -C /pvc-data || { echo "Extract failed"; exit 1; }
+echo "Import completed"
+echo "Final target contents:"
+ls -la /pvc-data/
+`
+
+// VolumeExporter writes a Docker volume to a gzipped tarball, see docker.Client.ExportVolumeTar
+type VolumeExporter interface {
+	ExportVolumeTar(volumeName, destPath string) error
+}
+
+// SetExportDir copies data through tarballs in dir instead of hostPath volumes, for
+// clusters that cannot reach the Docker host's filesystem. Each volume is exported
+// with exporter and then streamed into its PVC by an import pod.
+func (e *Engine) SetExportDir(dir string, exporter VolumeExporter) {
+	e.exportDir = dir
+	e.exporter = exporter
+}
+
+// exportTarPath returns the tarball the volume of pvc is exported to
+func (e *Engine) exportTarPath(pvc *types.PVCInfo) string {
+	return filepath.Join(e.exportDir, pvc.Name+".tar.gz")
+}
+
+// exportAndImport exports the Docker volume of pvc to a tarball and extracts it into the PVC
+func (e *Engine) exportAndImport(pvc *types.PVCInfo) error {
+	// Bind mounts are exported by their host path, docker run mounts those as is
+	source := pvc.MatchedVolume.Name
+	if pvc.MatchedVolume.Driver == "bind" {
+		source = pvc.MatchedVolume.Mountpoint
+	}
+
+	tarPath := e.exportTarPath(pvc)
+	logger.Printf("  Exporting volume %s to %s...\n", source, tarPath)
+	if err := e.exporter.ExportVolumeTar(source, tarPath); err != nil {
+		return err
+	}
+
+	return e.importFromTar(pvc, tarPath)
+}
+
+// importFromTar extracts the gzipped tarball at tarPath into the PVC. The extract
+// script is mounted from a ConfigMap and the tarball is streamed into the pod, so
+// the pod can run on any node.
+func (e *Engine) importFromTar(pvc *types.PVCInfo, tarPath string) error {
+	file, err := os.Open(tarPath)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %v", tarPath, err)
+	}
+	defer file.Close()
+
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
+
+	namespace := e.podNamespaceFor(pvc)
+	podName := fmt.Sprintf("import-%s-%d", pvc.Name, time.Now().Unix())
+	ctx := e.ctx
+
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: podName, Namespace: namespace},
+		Data:       map[string]string{"import.sh": importScript},
+	}
+	if _, err := client.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{FieldManager: fieldManager}); err != nil {
+		return fmt.Errorf("failed to create import script config map: %v", err)
+	}
+	defer func() {
+		if err := client.CoreV1().ConfigMaps(namespace).Delete(e.ctx, podName, metav1.DeleteOptions{}); err != nil {
+			logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("Warning: Could not delete import script config map: %v", err)))
+		}
+	}()
+
+	if err := e.createPod(e.buildImportPodYAML(pvc, podName, namespace)); err != nil {
+		return fmt.Errorf("failed to create import pod: %v", err)
+	}
+	logger.Printf("  Import pod %s created in namespace %s\n", podName, namespace)
+
+	timeout := e.podTimeout(pvc)
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := e.waitForPodRunning(ctx, podName, namespace); err != nil {
+		e.deletePod(podName, namespace)
+		return err
+	}
+
+	logger.Printf("  Streaming %s into the import pod...\n", tarPath)
+	if err := e.streamToPod(ctx, podName, namespace, file); err != nil {
+		e.deletePod(podName, namespace)
+		return fmt.Errorf("failed to upload %s: %v", tarPath, err)
+	}
+
+	logger.Printf("  Waiting for import pod to complete (timeout %s)...\n", timeout)
+	if err := e.waitForPodCompletion(ctx, podName, namespace, 0); err != nil {
+		e.deletePod(podName, namespace)
+		return fmt.Errorf("import pod failed: %v", err)
+	}
+	return e.finishMigrationPod(podName, namespace)
+}
+
+func (e *Engine) buildImportPodYAML(pvc *types.PVCInfo, podName, namespace string) string {
+	return fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: %s
+  namespace: %s
+  labels:
+    app.kubernetes.io/managed-by: docker-pvc-migration
+spec:
+  restartPolicy: Never
+%s  containers:
+  - name: migration
+    image: %s
+    command: ["/bin/sh", "/scripts/import.sh"]
+    volumeMounts:
+    - name: import-script
+      mountPath: /scripts
+    - name: import-data
+      mountPath: /import
+    - name: pvc-volume
+      mountPath: /pvc-data
+  volumes:
+  - name: import-script
+    configMap:
+      name: %s
+  - name: import-data
+    emptyDir: {}
+  - name: pvc-volume
+%s`, podName, namespace, e.buildImagePullSecrets(), e.migrationImage, podName, e.buildTargetVolume(pvc))
+}
+
+// waitForPodRunning waits until the containers of the pod have started
+func (e *Engine) waitForPodRunning(ctx context.Context, podName, namespace string) error {
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
+
+	watchCtx, stopWatch := context.WithCancel(ctx)
+	defer stopWatch()
+	phases := make(chan corev1.PodPhase)
+	go watchPodPhase(watchCtx, client, podName, namespace, phases)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for pod %s to start", podName)
+		case phase := <-phases:
+			switch phase {
+			case corev1.PodRunning:
+				return nil
+			case corev1.PodSucceeded, corev1.PodFailed:
+				return fmt.Errorf("pod %s exited before the archive was uploaded", podName)
+			}
+		}
+	}
+}
+
+// streamToPod writes data to /import/data.tar.gz in the import pod and marks it ready
+func (e *Engine) streamToPod(ctx context.Context, podName, namespace string, data *os.File) error {
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
+	config, err := e.getRESTConfig()
+	if err != nil {
+		return err
+	}
+
+	request := client.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(podName).
+		Namespace(namespace).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: "migration",
+			Command:   []string{"/bin/sh", "-c", "cat > /import/data.tar.gz && touch /import/ready"},
+			Stdin:     true,
+			Stdout:    true,
+			Stderr:    true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(config, "POST", request.URL())
+	if err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{Stdin: data, Stdout: &output, Stderr: &output})
+	if err != nil && output.Len() > 0 {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(output.String()))
+	}
+	return err
+}
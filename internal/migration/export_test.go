@@ -0,0 +1,70 @@
+package migration
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestExportTarPath(t *testing.T) {
+	tests := []struct {
+		dir  string
+		pvc  string
+		want string
+	}{
+		{"/exports", "data", "/exports/data.tar.gz"},
+		{"/exports/", "db-data", "/exports/db-data.tar.gz"},
+		{"exports", "data", "exports/data.tar.gz"},
+		{"./exports/../tarballs", "data", "tarballs/data.tar.gz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir+"/"+tt.pvc, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			e.SetExportDir(tt.dir, nil)
+			if got := e.exportTarPath(&types.PVCInfo{Name: tt.pvc}); got != filepath.FromSlash(tt.want) {
+				t.Errorf("exportTarPath() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// recordingExporter records what it is asked to export and fails, so no import pod is started
+type recordingExporter struct {
+	volume, destPath string
+}
+
+func (r *recordingExporter) ExportVolumeTar(volumeName, destPath string) error {
+	r.volume, r.destPath = volumeName, destPath
+	return fmt.Errorf("export failed")
+}
+
+func TestExportAndImportSource(t *testing.T) {
+	tests := []struct {
+		name   string
+		volume types.DockerVolumeInfo
+		want   string
+	}{
+		{"named volume", types.DockerVolumeInfo{Name: "app_data", Mountpoint: "/var/lib/docker/volumes/app_data/_data"}, "app_data"},
+		{"bind mount", types.DockerVolumeInfo{Name: "/srv/app/data", Mountpoint: "/srv/app/data", Driver: "bind"}, "/srv/app/data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exporter := &recordingExporter{}
+			e := NewEngine("default", "", t.TempDir())
+			e.SetExportDir("/exports", exporter)
+
+			volume := tt.volume
+			pvc := &types.PVCInfo{Name: "data", Namespace: "default", MatchedVolume: &volume}
+			if err := e.exportAndImport(pvc); err == nil {
+				t.Fatal("exportAndImport() returned no error for a failed export")
+			}
+			if exporter.volume != tt.want || exporter.destPath != filepath.FromSlash("/exports/data.tar.gz") {
+				t.Errorf("exported %s to %s, want %s to /exports/data.tar.gz", exporter.volume, exporter.destPath, tt.want)
+			}
+		})
+	}
+}
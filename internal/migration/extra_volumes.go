@@ -0,0 +1,105 @@
+package migration
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/yaml"
+)
+
+// ExtraVolumeMount is an additional volume mounted into the migration pod. Volume is
+// "secret:name", "configmap:name" or "emptyDir:"; a bare name is treated as a secret.
+type ExtraVolumeMount struct {
+	Volume    string
+	MountPath string
+}
+
+// ParseExtraVolumeMount parses a --migration-extra-volume value of the form volume=mountPath
+func ParseExtraVolumeMount(spec string) (ExtraVolumeMount, error) {
+	volume, mountPath, ok := strings.Cut(spec, "=")
+	if !ok || volume == "" || !path.IsAbs(mountPath) {
+		return ExtraVolumeMount{}, fmt.Errorf("invalid extra volume %q, expected secret:name=/mount/path", spec)
+	}
+	return ExtraVolumeMount{Volume: volume, MountPath: mountPath}, nil
+}
+
+func (e *Engine) SetExtraVolumes(extras []ExtraVolumeMount) error {
+	if _, _, err := buildExtraVolumes(extras); err != nil {
+		return err
+	}
+	e.extraVolumes = extras
+	return nil
+}
+
+// buildExtraVolumes converts the extra volume specifications into pod volumes and
+// matching mounts for the migration container
+func buildExtraVolumes(extras []ExtraVolumeMount) ([]corev1.Volume, []corev1.VolumeMount, error) {
+	var volumes []corev1.Volume
+	var mounts []corev1.VolumeMount
+
+	for i, extra := range extras {
+		kind, name, ok := strings.Cut(extra.Volume, ":")
+		if !ok {
+			kind, name = "secret", extra.Volume
+		}
+
+		volume := corev1.Volume{Name: fmt.Sprintf("extra-volume-%d", i)}
+		switch strings.ToLower(kind) {
+		case "secret":
+			if name == "" {
+				return nil, nil, fmt.Errorf("extra volume %q is missing the secret name", extra.Volume)
+			}
+			volume.Secret = &corev1.SecretVolumeSource{SecretName: name}
+		case "configmap":
+			if name == "" {
+				return nil, nil, fmt.Errorf("extra volume %q is missing the config map name", extra.Volume)
+			}
+			volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{Name: name},
+			}
+		case "emptydir":
+			volume.EmptyDir = &corev1.EmptyDirVolumeSource{}
+		default:
+			return nil, nil, fmt.Errorf("unsupported extra volume type %q (valid: secret, configmap, emptyDir)", kind)
+		}
+
+		volumes = append(volumes, volume)
+		mounts = append(mounts, corev1.VolumeMount{
+			Name:      volume.Name,
+			MountPath: extra.MountPath,
+			ReadOnly:  volume.EmptyDir == nil,
+		})
+	}
+
+	return volumes, mounts, nil
+}
+
+// buildExtraVolumesYAML renders the extra volumes as list items indented to fit the
+// volumes and volumeMounts sections of the migration pod YAML
+func (e *Engine) buildExtraVolumesYAML() (string, string, error) {
+	volumes, mounts, err := buildExtraVolumes(e.extraVolumes)
+	if err != nil || len(volumes) == 0 {
+		return "", "", err
+	}
+
+	volumesYAML, err := yaml.Marshal(volumes)
+	if err != nil {
+		return "", "", err
+	}
+	mountsYAML, err := yaml.Marshal(mounts)
+	if err != nil {
+		return "", "", err
+	}
+
+	return indentYAML(string(volumesYAML), "  "), indentYAML(string(mountsYAML), "    "), nil
+}
+
+func indentYAML(text, indent string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		b.WriteString(indent + line + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,43 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// Hooks are shell commands run around the migration, e.g. to scale an application
+// down and back up. Empty hooks are skipped.
+type Hooks struct {
+	Pre     string // Before any PVC is migrated
+	Post    string // After all PVCs were migrated successfully
+	PrePVC  string // Before each PVC, with $PVC_NAME and $PVC_NAMESPACE set
+	PostPVC string // After each successfully migrated PVC, with $PVC_NAME and $PVC_NAMESPACE set
+}
+
+func (e *Engine) SetHooks(hooks Hooks) {
+	e.hooks = hooks
+}
+
+// runHook runs command with sh. A hook that exits non-zero aborts the migration.
+func (e *Engine) runHook(name, command string, env ...string) error {
+	if command == "" {
+		return nil
+	}
+
+	logger.Printf("  Running %s hook: %s\n", name, command)
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Env = append(os.Environ(), env...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook %q failed: %v", name, command, err)
+	}
+	return nil
+}
+
+func (e *Engine) runPVCHook(name, command string, pvc *types.PVCInfo) error {
+	return e.runHook(name, command, "PVC_NAME="+pvc.Name, "PVC_NAMESPACE="+e.namespaceFor(pvc))
+}
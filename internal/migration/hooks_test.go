@@ -0,0 +1,79 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStartMigrationHooks(t *testing.T) {
+	tests := []struct {
+		name      string
+		postPVC   string
+		wantErr   bool
+		wantPhase Phase
+	}{
+		{"post-pvc hook succeeds", `touch "$DIR/post-$PVC_NAME"`, false, PhaseCompleted},
+		{"post-pvc hook fails", `touch "$DIR/post-$PVC_NAME"; exit 1`, true, PhaseDataCopied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("DIR", dir)
+			exists := func(name string) bool {
+				_, err := os.Stat(filepath.Join(dir, name))
+				return err == nil
+			}
+
+			store, err := NewStateStore(filepath.Join(dir, "state.json"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := NewEngine("default", "", t.TempDir())
+			e.destKubeClient = fake.NewSimpleClientset()
+			e.SetStateStore(store)
+			e.SetHooks(Hooks{
+				Pre:     `touch "$DIR/pre"`,
+				Post:    `touch "$DIR/post"`,
+				PrePVC:  `touch "$DIR/pre-$PVC_NAME"`,
+				PostPVC: tt.postPVC,
+			})
+
+			// Hook files that must, and must not, exist while the PVC is migrated
+			e.migrateOne = func(pvc *types.PVCInfo) error {
+				for _, name := range []string{"pre", "pre-data"} {
+					if !exists(name) {
+						t.Errorf("%s hook did not run before the migration", name)
+					}
+				}
+				for _, name := range []string{"post-data", "post"} {
+					if exists(name) {
+						t.Errorf("%s hook ran before the migration", name)
+					}
+				}
+				e.recordPhase(pvc, PhaseDataCopied)
+				return nil
+			}
+
+			pvc := &types.PVCInfo{Name: "data", Namespace: "default", MatchedVolume: &types.DockerVolumeInfo{Name: "data"}}
+			err = e.StartMigration([]*types.PVCInfo{pvc})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("StartMigration() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if !exists("post-data") {
+				t.Error("post-pvc hook did not run")
+			}
+			if exists("post") == tt.wantErr {
+				t.Errorf("post hook ran = %v, want %v", exists("post"), !tt.wantErr)
+			}
+			if got := store.Phase("default", "data"); got != tt.wantPhase {
+				t.Errorf("phase = %q, want %q", got, tt.wantPhase)
+			}
+		})
+	}
+}
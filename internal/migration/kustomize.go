@@ -0,0 +1,77 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/kubernetes"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// createKustomizePVC creates a PVC that is built by a kustomization. Its resources
+// may live outside the YAML directory and be patched, so the built PVC document is
+// applied instead of a file.
+func (e *Engine) createKustomizePVC(ctx context.Context, pvc *types.PVCInfo) error {
+	logger.Printf("    Creating PVC %s from kustomization %s in namespace %s...\n", pvc.Name, pvc.Kustomization, e.namespaceFor(pvc))
+
+	manifest, err := e.kustomizePVCManifest(pvc)
+	if err != nil {
+		return err
+	}
+	return e.applyWithRetry(ctx, manifest, e.namespaceFor(pvc), applyMaxRetries, applyBackoff)
+}
+
+// kustomizePVCManifest returns the built document of pvc with its name, namespace,
+// size and storage class set to the ones it is migrated with. Labels, annotations
+// and the rest of the spec are kept as the kustomization built them.
+func (e *Engine) kustomizePVCManifest(pvc *types.PVCInfo) (string, error) {
+	built := pvc.Manifest
+	if built == nil {
+		// PVCs read from a plan have no document, so build the kustomization again
+		var err error
+		if built, err = rebuildKustomizePVC(pvc); err != nil {
+			return "", err
+		}
+	}
+
+	obj := &unstructured.Unstructured{Object: runtime.DeepCopyJSON(built)}
+	unstructured.RemoveNestedField(obj.Object, "status")
+	obj.SetName(pvc.Name)
+	obj.SetNamespace(e.namespaceFor(pvc))
+
+	size := pvc.NewSize
+	if size == "" {
+		size = pvc.RequestedSize
+	}
+	if err := unstructured.SetNestedField(obj.Object, size, "spec", "resources", "requests", "storage"); err != nil {
+		return "", fmt.Errorf("failed to set the size of PVC %s: %v", pvc.Name, err)
+	}
+	if pvc.StorageClass != "" {
+		if err := unstructured.SetNestedField(obj.Object, pvc.StorageClass, "spec", "storageClassName"); err != nil {
+			return "", fmt.Errorf("failed to set the storage class of PVC %s: %v", pvc.Name, err)
+		}
+	}
+
+	manifest, err := yaml.Marshal(obj.Object)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode PVC %s: %v", pvc.Name, err)
+	}
+	return string(manifest), nil
+}
+
+// rebuildKustomizePVC returns the document of pvc in the output of its kustomization
+func rebuildKustomizePVC(pvc *types.PVCInfo) (map[string]interface{}, error) {
+	built, _, err := kubernetes.NewKustomizeParser(kubernetes.NewParser()).ParseDirectory(pvc.Kustomization)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build kustomization %s: %v", pvc.Kustomization, err)
+	}
+	for _, candidate := range built {
+		if candidate.Name == pvc.Name || candidate.Name == pvc.OriginalName {
+			return candidate.Manifest, nil
+		}
+	}
+	return nil, fmt.Errorf("kustomization %s no longer builds PVC %s", pvc.Kustomization, pvc.Name)
+}
@@ -0,0 +1,153 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"sigs.k8s.io/yaml"
+)
+
+const kustomizeBasePVC = `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  labels:
+    app: web
+  annotations:
+    backup: daily
+spec:
+  accessModes: [ReadWriteMany]
+  volumeMode: Block
+  selector:
+    matchLabels:
+      tier: db
+  dataSource:
+    kind: VolumeSnapshot
+    name: snapshot
+    apiGroup: snapshot.storage.k8s.io
+  resources:
+    requests:
+      storage: 1Gi
+status:
+  phase: Bound
+`
+
+func TestKustomizePVCManifest(t *testing.T) {
+	var built map[string]interface{}
+	if err := yaml.Unmarshal([]byte(kustomizeBasePVC), &built); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		pvc       *types.PVCInfo
+		wantSize  string
+		wantClass string
+	}{
+		{
+			name:      "new size and storage class",
+			pvc:       &types.PVCInfo{Name: "prod-data", Namespace: "prod", RequestedSize: "1Gi", NewSize: "5Gi", StorageClass: "fast", Manifest: built},
+			wantSize:  "5Gi",
+			wantClass: "fast",
+		},
+		{
+			name:     "requested size",
+			pvc:      &types.PVCInfo{Name: "prod-data", Namespace: "prod", RequestedSize: "1Gi", Manifest: built},
+			wantSize: "1Gi",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			manifest, err := e.kustomizePVCManifest(tt.pvc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got struct {
+				Metadata struct {
+					Name        string            `json:"name"`
+					Namespace   string            `json:"namespace"`
+					Labels      map[string]string `json:"labels"`
+					Annotations map[string]string `json:"annotations"`
+				} `json:"metadata"`
+				Spec struct {
+					StorageClassName string                 `json:"storageClassName"`
+					VolumeMode       string                 `json:"volumeMode"`
+					Selector         map[string]interface{} `json:"selector"`
+					DataSource       map[string]interface{} `json:"dataSource"`
+					Resources        struct {
+						Requests map[string]string `json:"requests"`
+					} `json:"resources"`
+				} `json:"spec"`
+				Status map[string]interface{} `json:"status"`
+			}
+			if err := yaml.Unmarshal([]byte(manifest), &got); err != nil {
+				t.Fatalf("manifest is not valid YAML: %v\n%s", err, manifest)
+			}
+
+			if got.Metadata.Name != "prod-data" || got.Metadata.Namespace != "prod" {
+				t.Errorf("metadata = %s/%s, want prod/prod-data", got.Metadata.Namespace, got.Metadata.Name)
+			}
+			if got.Spec.Resources.Requests["storage"] != tt.wantSize {
+				t.Errorf("storage = %q, want %q", got.Spec.Resources.Requests["storage"], tt.wantSize)
+			}
+			if got.Spec.StorageClassName != tt.wantClass {
+				t.Errorf("storageClassName = %q, want %q", got.Spec.StorageClassName, tt.wantClass)
+			}
+			if got.Metadata.Labels["app"] != "web" || got.Metadata.Annotations["backup"] != "daily" {
+				t.Errorf("labels = %v, annotations = %v, want the built ones", got.Metadata.Labels, got.Metadata.Annotations)
+			}
+			if got.Spec.VolumeMode != "Block" || got.Spec.Selector == nil || got.Spec.DataSource == nil {
+				t.Errorf("spec = %+v, want the built volumeMode, selector and dataSource", got.Spec)
+			}
+			if got.Status != nil {
+				t.Errorf("status = %v, want it removed", got.Status)
+			}
+		})
+	}
+
+	// The parsed document itself is left alone
+	spec := built["spec"].(map[string]interface{})
+	requests := spec["resources"].(map[string]interface{})["requests"].(map[string]interface{})
+	if requests["storage"] != "1Gi" || spec["storageClassName"] != nil {
+		t.Errorf("kustomizePVCManifest changed the parsed document: %v", built)
+	}
+}
+
+func TestKustomizePVCManifestRebuildsFromPlan(t *testing.T) {
+	t.Setenv("PATH", t.TempDir()) // No kustomize binary, so resources are followed
+	dir := t.TempDir()
+	files := map[string]string{
+		"kustomization.yaml": "namePrefix: prod-\nresources:\n  - pvc.yaml\n",
+		"pvc.yaml":           kustomizeBasePVC,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := NewEngine("default", "", dir)
+
+	// A PVC loaded from a plan only knows its kustomization
+	pvc := &types.PVCInfo{Name: "prod-data", Namespace: "prod", RequestedSize: "1Gi", NewSize: "2Gi", Kustomization: dir}
+	manifest, err := e.kustomizePVCManifest(pvc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := yaml.Unmarshal([]byte(manifest), &got); err != nil {
+		t.Fatal(err)
+	}
+	if spec := got["spec"].(map[string]interface{}); spec["volumeMode"] != "Block" {
+		t.Errorf("rebuilt manifest = %v, want the built volumeMode", got)
+	}
+
+	missing := &types.PVCInfo{Name: "gone", Namespace: "prod", RequestedSize: "1Gi", Kustomization: dir}
+	if _, err := e.kustomizePVCManifest(missing); err == nil {
+		t.Error("kustomizePVCManifest() found a PVC the kustomization does not build")
+	}
+}
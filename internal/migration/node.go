@@ -0,0 +1,78 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// volumePathLabel marks the nodes that host Docker volume directories. Label values
+// cannot contain slashes, so the value is the Docker data root with its slashes written
+// as dots, e.g. var.lib.docker for /var/lib/docker.
+const volumePathLabel = "docker.io/volume-path"
+
+// detectVolumeNode finds the Kubernetes node that has mountpoint on its filesystem.
+// A single node whose volumePathLabel covers mountpoint is preferred; otherwise each
+// node is probed over SSH.
+func (e *Engine) detectVolumeNode(ctx context.Context, mountpoint string) (string, error) {
+	client, err := e.getClientset()
+	if err != nil {
+		return "", err
+	}
+
+	nodes, err := client.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return "", fmt.Errorf("failed to list nodes: %v", err)
+	}
+
+	var labeled []string
+	for _, node := range nodes.Items {
+		if value, ok := node.Labels[volumePathLabel]; ok && labelCoversPath(value, mountpoint) {
+			labeled = append(labeled, node.Name)
+		}
+	}
+	if len(labeled) == 1 {
+		return labeled[0], nil
+	}
+
+	var found []string
+	for _, node := range nodes.Items {
+		if e.nodeHasPath(ctx, node.Name, mountpoint) {
+			found = append(found, node.Name)
+		}
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("no node has %s", mountpoint)
+	case 1:
+		return found[0], nil
+	default:
+		return "", fmt.Errorf("multiple nodes have %s: %s", mountpoint, strings.Join(found, ", "))
+	}
+}
+
+// labelCoversPath reports whether path is inside the directory that the volumePathLabel
+// value stands for
+func labelCoversPath(value, path string) bool {
+	if value == "" {
+		return false
+	}
+	encoded := strings.ReplaceAll(strings.Trim(path, "/"), "/", ".")
+	return encoded == value || strings.HasPrefix(encoded, value+".")
+}
+
+func (e *Engine) nodeHasPath(ctx context.Context, nodeName, path string) bool {
+	// ssh hands the command to the remote shell, so the path is quoted for it.
+	// BatchMode avoids hanging on password prompts for nodes we can't reach.
+	cmd := exec.CommandContext(ctx, "ssh", "-o", "BatchMode=yes", "-o", "ConnectTimeout=5", nodeName, "test -e "+shellQuote(path))
+	return cmd.Run() == nil
+}
+
+// shellQuote quotes s as a single word for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestDetectVolumeNodeByLabel(t *testing.T) {
+	node := func(name, root string) *corev1.Node {
+		return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{volumePathLabel: root}}}
+	}
+
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = fake.NewSimpleClientset(
+		node("node-a", "var.lib.docker"),
+		node("node-b", "data.docker"),
+		node("node-c", "data.docker-old"),
+	)
+
+	tests := []struct {
+		mountpoint string
+		want       string
+	}{
+		{"/var/lib/docker/volumes/app_data/_data", "node-a"},
+		{"/data/docker/volumes/app_data/_data", "node-b"},
+		{"/data/docker-old/volumes/app_data/_data", "node-c"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mountpoint, func(t *testing.T) {
+			got, err := e.detectVolumeNode(context.Background(), tt.mountpoint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("detectVolumeNode(%q) = %s, want %s", tt.mountpoint, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "it's $(touch injected) here")
+	if err := os.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command("sh", "-c", "test -e "+shellQuote(path))
+	cmd.Dir = dir
+	if err := cmd.Run(); err != nil {
+		t.Errorf("test -e %s: %v", shellQuote(path), err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "injected")); err == nil {
+		t.Error("the quoted path ran a command")
+	}
+}
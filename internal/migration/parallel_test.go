@@ -0,0 +1,64 @@
+package migration
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestStartMigrationParallelism(t *testing.T) {
+	tests := []struct {
+		parallelism int
+		pvcs        int
+	}{
+		{1, 4},
+		{3, 9},
+		{4, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("parallelism %d", tt.parallelism), func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			e.SetUseEphemeralVolumes(true) // Skip the cluster capacity and in-use checks
+			e.SetParallelism(tt.parallelism)
+
+			var running, maxRunning, migrated atomic.Int32
+			e.migrateOne = func(pvc *types.PVCInfo) error {
+				now := running.Add(1)
+				for {
+					seen := maxRunning.Load()
+					if now <= seen || maxRunning.CompareAndSwap(seen, now) {
+						break
+					}
+				}
+				time.Sleep(20 * time.Millisecond)
+				running.Add(-1)
+				migrated.Add(1)
+				return nil
+			}
+
+			var pvcs []*types.PVCInfo
+			for i := 0; i < tt.pvcs; i++ {
+				pvcs = append(pvcs, &types.PVCInfo{
+					Name:          fmt.Sprintf("data-%d", i),
+					Namespace:     "default",
+					MatchedVolume: &types.DockerVolumeInfo{Name: fmt.Sprintf("volume-%d", i)},
+				})
+			}
+			if err := e.StartMigration(pvcs); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := migrated.Load(); got != int32(tt.pvcs) {
+				t.Errorf("migrated %d PVCs, want %d", got, tt.pvcs)
+			}
+			want := min(tt.parallelism, tt.pvcs)
+			if got := maxRunning.Load(); got != int32(want) {
+				t.Errorf("at most %d PVCs migrated at once, want %d", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,289 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// pdbWaitTimeout is how long to wait for a blocking PodDisruptionBudget to allow disruptions
+const pdbWaitTimeout = 5 * time.Minute
+
+// scaleDownTimeout is how long to wait for the pods of a scaled down workload to terminate
+const scaleDownTimeout = 5 * time.Minute
+
+// scaleDownPollInterval is how often the pods of a scaled down workload are listed
+var scaleDownPollInterval = 2 * time.Second
+
+// scaledWorkload is a workload scaled to zero and the replicas it had before
+type scaledWorkload struct {
+	workload types.WorkloadRef
+	replicas int32
+}
+
+// ParseWorkloadRef parses a workload given as kind/name, e.g. deployment/web
+func ParseWorkloadRef(value, namespace string) (types.WorkloadRef, error) {
+	kind, name, ok := strings.Cut(value, "/")
+	if !ok || name == "" {
+		return types.WorkloadRef{}, fmt.Errorf("invalid workload %q, expected deployment/name or statefulset/name", value)
+	}
+
+	switch strings.ToLower(kind) {
+	case "deployment", "deploy":
+		kind = "Deployment"
+	case "statefulset", "sts":
+		kind = "StatefulSet"
+	default:
+		return types.WorkloadRef{}, fmt.Errorf("unsupported workload kind %q (valid: deployment, statefulset)", kind)
+	}
+
+	return types.WorkloadRef{Kind: kind, Name: name, Namespace: namespace}, nil
+}
+
+func (e *Engine) SetScaleDownWorkloads(workloads []types.WorkloadRef) {
+	e.scaleDownWorkloads = workloads
+}
+
+// scaleDownRespectingPDB scales a workload to zero replicas, but first makes sure no
+// PodDisruptionBudget covering its pods forbids taking them all down. A budget of
+// maxUnavailable: 0 fails at once; a minAvailable budget is waited on in case it is
+// relaxed. The workload is scaled back up by restoreScaledDownWorkloads.
+func (e *Engine) scaleDownRespectingPDB(ctx context.Context, workload types.WorkloadRef) error {
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
+
+	scale, selector, err := getWorkloadScale(ctx, client, workload)
+	if err != nil {
+		return err
+	}
+	if scale.Spec.Replicas == 0 {
+		logger.Printf("  %s is already scaled down\n", workload)
+		return nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, pdbWaitTimeout)
+	defer cancel()
+
+	for {
+		blocking, err := findBlockingPDB(waitCtx, client, workload.Namespace, selector, scale.Spec.Replicas)
+		if err != nil {
+			return err
+		}
+		if blocking == nil {
+			break
+		}
+		if zeroBudget(blocking, scale.Spec.Replicas) {
+			return fmt.Errorf("PodDisruptionBudget %s blocks scaling down %s: %s", blocking.Name, workload, describePDB(blocking, scale.Spec.Replicas))
+		}
+
+		select {
+		case <-waitCtx.Done():
+			return fmt.Errorf("PodDisruptionBudget %s blocks scaling down %s: %s", blocking.Name, workload, describePDB(blocking, scale.Spec.Replicas))
+		case <-time.After(10 * time.Second):
+			logger.Printf("  Waiting for PodDisruptionBudget %s to allow scaling down %s...\n", blocking.Name, workload)
+		}
+	}
+
+	logger.Printf("  Scaling down %s (%d replicas)...\n", workload, scale.Spec.Replicas)
+	replicas := scale.Spec.Replicas
+	if err := updateWorkloadScale(ctx, client, workload, scale, 0); err != nil {
+		return fmt.Errorf("failed to scale down %s: %v", workload, err)
+	}
+	e.scaledDown = append(e.scaledDown, scaledWorkload{workload: workload, replicas: replicas})
+
+	// The pods only release their volumes once they are gone
+	return waitForWorkloadPods(ctx, client, workload, selector)
+}
+
+// waitForWorkloadPods waits until no pod selected by the workload's selector is left
+// running, pending or terminating
+func waitForWorkloadPods(ctx context.Context, client clientset.Interface, workload types.WorkloadRef, selector labels.Selector) error {
+	ctx, cancel := context.WithTimeout(ctx, scaleDownTimeout)
+	defer cancel()
+
+	ticker := time.NewTicker(scaleDownPollInterval)
+	defer ticker.Stop()
+
+	for {
+		podList, err := client.CoreV1().Pods(workload.Namespace).List(ctx, metav1.ListOptions{LabelSelector: selector.String()})
+		if err != nil {
+			return fmt.Errorf("failed to list pods of %s: %v", workload, err)
+		}
+		remaining := 0
+		for _, pod := range podList.Items {
+			if pod.Status.Phase != corev1.PodSucceeded && pod.Status.Phase != corev1.PodFailed {
+				remaining++
+			}
+		}
+		if remaining == 0 {
+			return nil
+		}
+
+		logger.Printf("  Waiting for %d pod(s) of %s to terminate...\n", remaining, workload)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("pods of %s did not terminate after scaling down: %v", workload, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+// restoreScaledDownWorkloads scales the workloads scaled down by this migration back
+// to their replicas. It runs after the migration is cancelled too, so it does not use
+// the migration context.
+func (e *Engine) restoreScaledDownWorkloads() {
+	scaled := e.scaledDown
+	e.scaledDown = nil
+	if len(scaled) == 0 {
+		return
+	}
+
+	client, err := e.getClientset()
+	if err != nil {
+		logger.Warnf("  Warning: Could not restore scaled down workloads: %v\n", err)
+		return
+	}
+
+	ctx := context.Background()
+	for _, s := range scaled {
+		scale, _, err := getWorkloadScale(ctx, client, s.workload)
+		if err == nil {
+			err = updateWorkloadScale(ctx, client, s.workload, scale, s.replicas)
+		}
+		if err != nil {
+			logger.Warnf("  Warning: Could not scale %s back to %d replicas: %v\n", s.workload, s.replicas, err)
+			continue
+		}
+		logger.Printf("  Scaled %s back to %d replicas\n", s.workload, s.replicas)
+	}
+}
+
+func updateWorkloadScale(ctx context.Context, client clientset.Interface, workload types.WorkloadRef, scale *autoscalingv1.Scale, replicas int32) error {
+	scale.Spec.Replicas = replicas
+	var err error
+	switch workload.Kind {
+	case "Deployment":
+		_, err = client.AppsV1().Deployments(workload.Namespace).UpdateScale(ctx, workload.Name, scale, metav1.UpdateOptions{})
+	case "StatefulSet":
+		_, err = client.AppsV1().StatefulSets(workload.Namespace).UpdateScale(ctx, workload.Name, scale, metav1.UpdateOptions{})
+	default:
+		err = fmt.Errorf("unsupported workload kind %q", workload.Kind)
+	}
+	return err
+}
+
+func getWorkloadScale(ctx context.Context, client clientset.Interface, workload types.WorkloadRef) (*autoscalingv1.Scale, labels.Selector, error) {
+	var scale *autoscalingv1.Scale
+	var labelSelector *metav1.LabelSelector
+	var err error
+
+	switch workload.Kind {
+	case "Deployment":
+		deployment, getErr := client.AppsV1().Deployments(workload.Namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return nil, nil, fmt.Errorf("failed to get %s: %v", workload, getErr)
+		}
+		labelSelector = deployment.Spec.Selector
+		scale, err = client.AppsV1().Deployments(workload.Namespace).GetScale(ctx, workload.Name, metav1.GetOptions{})
+	case "StatefulSet":
+		statefulSet, getErr := client.AppsV1().StatefulSets(workload.Namespace).Get(ctx, workload.Name, metav1.GetOptions{})
+		if getErr != nil {
+			return nil, nil, fmt.Errorf("failed to get %s: %v", workload, getErr)
+		}
+		labelSelector = statefulSet.Spec.Selector
+		scale, err = client.AppsV1().StatefulSets(workload.Namespace).GetScale(ctx, workload.Name, metav1.GetOptions{})
+	default:
+		return nil, nil, fmt.Errorf("unsupported workload kind %q", workload.Kind)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get scale of %s: %v", workload, err)
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid selector on %s: %v", workload, err)
+	}
+	return scale, selector, nil
+}
+
+// findBlockingPDB returns a PodDisruptionBudget covering the workload's pods that does not
+// allow all replicas to be taken down, or nil when there is none
+func findBlockingPDB(ctx context.Context, client clientset.Interface, namespace string, podSelector labels.Selector, replicas int32) (*policyv1.PodDisruptionBudget, error) {
+	pdbs, err := client.PolicyV1().PodDisruptionBudgets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list PodDisruptionBudgets: %v", err)
+	}
+
+	for i := range pdbs.Items {
+		pdb := &pdbs.Items[i]
+		if !pdbCoversWorkload(pdb, podSelector) {
+			continue
+		}
+
+		if pdb.Status.DisruptionsAllowed > 0 {
+			continue
+		}
+		if pdb.Spec.MaxUnavailable != nil {
+			if maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, int(replicas), false); err == nil && maxUnavailable == 0 {
+				return pdb, nil
+			}
+		}
+		if pdb.Spec.MinAvailable != nil {
+			if minAvailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MinAvailable, int(replicas), true); err == nil && minAvailable >= int(replicas) {
+				return pdb, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// pdbCoversWorkload reports whether the PDB selects the pods of a workload, by
+// checking that the PDB selector matches the workload's own selector labels
+func pdbCoversWorkload(pdb *policyv1.PodDisruptionBudget, podSelector labels.Selector) bool {
+	if pdb.Spec.Selector == nil {
+		return false
+	}
+	pdbSelector, err := metav1.LabelSelectorAsSelector(pdb.Spec.Selector)
+	if err != nil || pdbSelector.Empty() {
+		return false
+	}
+
+	requirements, _ := podSelector.Requirements()
+	podLabels := labels.Set{}
+	for _, requirement := range requirements {
+		if values := requirement.Values().List(); len(values) == 1 {
+			podLabels[requirement.Key()] = values[0]
+		}
+	}
+	return pdbSelector.Matches(podLabels)
+}
+
+// zeroBudget reports whether the PDB never allows a disruption, whatever the state of
+// the pods, so waiting for it is pointless
+func zeroBudget(pdb *policyv1.PodDisruptionBudget, replicas int32) bool {
+	if pdb.Spec.MaxUnavailable == nil {
+		return false
+	}
+	maxUnavailable, err := intstr.GetScaledValueFromIntOrPercent(pdb.Spec.MaxUnavailable, int(replicas), false)
+	return err == nil && maxUnavailable == 0
+}
+
+func describePDB(pdb *policyv1.PodDisruptionBudget, replicas int32) string {
+	if pdb.Spec.MaxUnavailable != nil {
+		return fmt.Sprintf("maxUnavailable is %s", pdb.Spec.MaxUnavailable.String())
+	}
+	return fmt.Sprintf("minAvailable is %s with %d replicas", pdb.Spec.MinAvailable.String(), replicas)
+}
@@ -0,0 +1,153 @@
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newScaleClient returns a fake clientset with a deployment "web" of replicas pods,
+// serving its scale subresource, which the fake object tracker does not
+func newScaleClient(replicas int32, objects ...runtime.Object) (*fake.Clientset, *int32) {
+	labels := map[string]string{"app": "web"}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+		},
+	}
+	client := fake.NewSimpleClientset(append(objects, deployment)...)
+
+	current := replicas
+	client.PrependReactor("get", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		return true, &autoscalingv1.Scale{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+			Spec:       autoscalingv1.ScaleSpec{Replicas: current},
+		}, nil
+	})
+	client.PrependReactor("update", "deployments", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "scale" {
+			return false, nil, nil
+		}
+		scale := action.(k8stesting.UpdateAction).GetObject().(*autoscalingv1.Scale)
+		current = scale.Spec.Replicas
+		return true, scale, nil
+	})
+	return client, &current
+}
+
+func TestScaleDownRestoresReplicas(t *testing.T) {
+	client, current := newScaleClient(3)
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = client
+
+	workload := types.WorkloadRef{Kind: "Deployment", Name: "web", Namespace: "default"}
+	if err := e.scaleDownRespectingPDB(context.Background(), workload); err != nil {
+		t.Fatal(err)
+	}
+	if *current != 0 {
+		t.Fatalf("replicas after scale down = %d, want 0", *current)
+	}
+
+	e.restoreScaledDownWorkloads()
+	if *current != 3 {
+		t.Errorf("replicas after restore = %d, want 3", *current)
+	}
+}
+
+func TestScaleDownZeroBudgetFailsFast(t *testing.T) {
+	zero := intstr.FromInt32(0)
+	pdb := &policyv1.PodDisruptionBudget{
+		ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "default"},
+		Spec: policyv1.PodDisruptionBudgetSpec{
+			MaxUnavailable: &zero,
+			Selector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}},
+		},
+	}
+	client, current := newScaleClient(3, pdb)
+	e := NewEngine("default", "", t.TempDir())
+	e.destKubeClient = client
+
+	start := time.Now()
+	err := e.scaleDownRespectingPDB(context.Background(), types.WorkloadRef{Kind: "Deployment", Name: "web", Namespace: "default"})
+	if err == nil {
+		t.Fatal("scale down succeeded despite maxUnavailable: 0")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("scale down waited %s before failing", elapsed)
+	}
+	if *current != 3 {
+		t.Errorf("replicas = %d, want 3", *current)
+	}
+	if len(e.scaledDown) != 0 {
+		t.Errorf("scaledDown = %v, want none", e.scaledDown)
+	}
+}
+
+func TestScaleDownWaitsForPods(t *testing.T) {
+	interval := scaleDownPollInterval
+	scaleDownPollInterval = 5 * time.Millisecond
+	t.Cleanup(func() { scaleDownPollInterval = interval })
+
+	workload := types.WorkloadRef{Kind: "Deployment", Name: "web", Namespace: "default"}
+	webPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web-1", Namespace: "default", Labels: map[string]string{"app": "web"}},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	t.Run("pods terminate", func(t *testing.T) {
+		client, _ := newScaleClient(1, webPod())
+		e := NewEngine("default", "", t.TempDir())
+		e.destKubeClient = client
+
+		// Closed before the delete, so it is closed once the pod is seen to be gone
+		deleting := make(chan struct{})
+		go func() {
+			time.Sleep(30 * time.Millisecond)
+			close(deleting)
+			client.CoreV1().Pods("default").Delete(context.Background(), "web-1", metav1.DeleteOptions{})
+		}()
+
+		if err := e.scaleDownRespectingPDB(context.Background(), workload); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-deleting:
+		default:
+			t.Error("scale down returned while the pod was still there")
+		}
+	})
+
+	t.Run("cancelled", func(t *testing.T) {
+		client, _ := newScaleClient(1, webPod())
+		e := NewEngine("default", "", t.TempDir())
+		e.destKubeClient = client
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+		defer cancel()
+		if err := e.scaleDownRespectingPDB(ctx, workload); err == nil {
+			t.Error("scale down succeeded while the pod never terminated")
+		}
+		// Scaled down all the same, so the replicas are restored
+		if len(e.scaledDown) != 1 {
+			t.Errorf("scaledDown = %v, want the workload", e.scaledDown)
+		}
+	})
+}
@@ -0,0 +1,170 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// planSchemaVersion is the format version of plan files written by Plan.Save. It is
+// raised whenever the format changes, so a plan is never executed by a release that
+// reads it differently.
+const planSchemaVersion = 1
+
+// Plan is a fully resolved migration: the PVCs with their matched volumes, chosen
+// sizes and the node each volume is copied on. A plan written with --what-if is
+// executed later with --from-plan, without matching or prompting again. Volumes are
+// looked up again by name when executing, see ResolveVolumes.
+type Plan struct {
+	SchemaVersion int          `yaml:"schema_version"`
+	CreatedAt     time.Time    `yaml:"created_at"`
+	PVCs          []PlannedPVC `yaml:"pvcs"`
+}
+
+// PlannedPVC is the planned migration of one PVC
+type PlannedPVC struct {
+	types.PVCInfo `yaml:",inline"`
+	Node          string `yaml:"node,omitempty"` // Node the data is copied on, empty to pick one when executing
+}
+
+// BuildPlan resolves the migration of the PVCs with a matched volume into a plan.
+// The node of each volume is looked up (or asked for) now; nothing is changed in
+// the cluster.
+func (e *Engine) BuildPlan(pvcs []*types.PVCInfo) *Plan {
+	plan := &Plan{SchemaVersion: planSchemaVersion, CreatedAt: time.Now().UTC()}
+
+	for _, pvc := range pvcs {
+		if pvc.MatchedVolume == nil {
+			continue
+		}
+
+		planned := PlannedPVC{PVCInfo: *pvc}
+		planned.Namespace = e.namespaceFor(pvc)
+		planned.Created = false
+
+		// Copies through --export-dir do not mount the Docker volume, so they run on any node
+		if e.exportDir == "" {
+			logger.Printf("Resolving node for PVC %s/%s...\n", planned.Namespace, pvc.Name)
+			node, err := e.getCurrentNodeName(pvc)
+			if err != nil {
+				logger.Warnf("  %s\n", color.Warning(fmt.Sprintf("Warning: Could not resolve node, it is chosen when the plan is executed: %v", err)))
+			}
+			planned.Node = node
+		}
+
+		plan.PVCs = append(plan.PVCs, planned)
+	}
+	return plan
+}
+
+// SetPlannedNodes copies the data of each PVC (namespace/name) on the given node
+// instead of detecting or asking for it, see Plan.Nodes
+func (e *Engine) SetPlannedNodes(nodes map[string]string) {
+	e.plannedNodes = nodes
+}
+
+// Save writes the plan to path as YAML
+func (p *Plan) Save(path string) error {
+	data, err := yaml.Marshal(p)
+	if err != nil {
+		return fmt.Errorf("failed to encode plan: %v", err)
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write plan %s: %v", path, err)
+	}
+	return nil
+}
+
+// PVCInfos returns the planned PVCs for Engine.StartMigration
+func (p *Plan) PVCInfos() []*types.PVCInfo {
+	pvcs := make([]*types.PVCInfo, 0, len(p.PVCs))
+	for i := range p.PVCs {
+		pvcs = append(pvcs, &p.PVCs[i].PVCInfo)
+	}
+	return pvcs
+}
+
+// ResolveVolumes replaces the planned volume of every PVC with the volume of the same
+// name in volumes, so the plan is executed with the mountpoints and sizes of this
+// Docker host. A volume at another mountpoint was planned on another host, so its
+// planned node is dropped and chosen again when the plan is executed.
+func (p *Plan) ResolveVolumes(volumes map[string]*types.DockerVolumeInfo) error {
+	var missing []string
+	for i := range p.PVCs {
+		planned := &p.PVCs[i]
+		volume, ok := volumes[planned.MatchedVolume.Name]
+		if !ok {
+			missing = append(missing, planned.MatchedVolume.Name)
+			continue
+		}
+		if planned.Node != "" && volume.Mountpoint != planned.MatchedVolume.Mountpoint {
+			logger.Warnf("  %s\n", color.Warning(fmt.Sprintf("Warning: Volume %s moved from %s to %s since it was planned, ignoring planned node %s",
+				volume.Name, planned.MatchedVolume.Mountpoint, volume.Mountpoint, planned.Node)))
+			planned.Node = ""
+		}
+		planned.MatchedVolume = volume
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("planned Docker volumes not found: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Nodes returns the planned node of every PVC (namespace/name) that has one
+func (p *Plan) Nodes() map[string]string {
+	nodes := make(map[string]string)
+	for _, planned := range p.PVCs {
+		if planned.Node != "" {
+			nodes[planned.Namespace+"/"+planned.Name] = planned.Node
+		}
+	}
+	return nodes
+}
+
+// PlanLoader reads plan files written by Plan.Save
+type PlanLoader struct {
+	path string
+}
+
+func NewPlanLoader(path string) *PlanLoader {
+	return &PlanLoader{path: path}
+}
+
+// Load reads and validates the plan, so a damaged or edited plan fails before
+// anything is migrated
+func (l *PlanLoader) Load() (*Plan, error) {
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan %s: %v", l.path, err)
+	}
+
+	var plan Plan
+	if err := yaml.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan %s: %v", l.path, err)
+	}
+	switch plan.SchemaVersion {
+	case planSchemaVersion:
+	case 0:
+		return nil, fmt.Errorf("plan %s has no schema_version; write it again with --what-if", l.path)
+	default:
+		return nil, fmt.Errorf("plan %s has unsupported schema_version %d (expected %d); write it again with --what-if", l.path, plan.SchemaVersion, planSchemaVersion)
+	}
+
+	for _, planned := range plan.PVCs {
+		if planned.Name == "" || planned.Namespace == "" {
+			return nil, fmt.Errorf("plan %s contains a PVC without a name or namespace", l.path)
+		}
+		if planned.MatchedVolume == nil || planned.MatchedVolume.Name == "" {
+			return nil, fmt.Errorf("PVC %s/%s in plan %s has no source volume", planned.Namespace, planned.Name, l.path)
+		}
+		if planned.NewSize == "" {
+			return nil, fmt.Errorf("PVC %s/%s in plan %s has no size", planned.Namespace, planned.Name, l.path)
+		}
+	}
+	return &plan, nil
+}
@@ -0,0 +1,179 @@
+package migration
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func planPVCs() []*types.PVCInfo {
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return []*types.PVCInfo{
+		{
+			Name:          "data",
+			Namespace:     "apps",
+			RequestedSize: "1Gi",
+			NewSize:       "5Gi",
+			StorageClass:  "fast",
+			AccessModes:   []string{"ReadWriteOnce"},
+			Annotations:   map[string]string{"team": "web"},
+			Created:       true, // Not carried into the plan
+			MatchedVolume: &types.DockerVolumeInfo{
+				Name:       "app_data",
+				Mountpoint: "/var/lib/docker/volumes/app_data/_data",
+				Size:       1 << 30,
+				SizeHuman:  "1GiB",
+				CreatedAt:  created,
+				Labels:     map[string]string{"com.docker.compose.project": "app"},
+			},
+		},
+		{Name: "cache", NewSize: "1Gi", MatchedVolume: &types.DockerVolumeInfo{Name: "app_cache", Mountpoint: "/var/lib/docker/volumes/app_cache/_data"}},
+		{Name: "unmatched", Namespace: "apps", NewSize: "1Gi"},
+	}
+}
+
+func TestPlanRoundTrip(t *testing.T) {
+	e := NewEngine("default", "", t.TempDir())
+	// Copies through --export-dir need no node, so nothing is looked up in a cluster
+	e.SetExportDir(t.TempDir(), nil)
+
+	plan := e.BuildPlan(planPVCs())
+	plan.PVCs[0].Node = "node-1"
+	path := filepath.Join(t.TempDir(), "plan.yaml")
+	if err := plan.Save(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewPlanLoader(path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.SchemaVersion != planSchemaVersion || !loaded.CreatedAt.Equal(plan.CreatedAt) {
+		t.Errorf("loaded schema version %d created %s, want %d created %s", loaded.SchemaVersion, loaded.CreatedAt, planSchemaVersion, plan.CreatedAt)
+	}
+	if data, err := os.ReadFile(path); err != nil || !strings.HasPrefix(string(data), "schema_version: 1\n") {
+		t.Errorf("saved plan does not start with its schema version (%v):\n%s", err, data)
+	}
+
+	want := planPVCs()[:2]
+	want[0].Created = false
+	want[1].Namespace = "default" // The namespace the PVC is created in is resolved when planning
+	got := loaded.PVCInfos()
+	if len(got) != len(want) {
+		t.Fatalf("loaded %d PVCs, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if !reflect.DeepEqual(got[i], want[i]) {
+			t.Errorf("PVC %d = %+v (volume %+v), want %+v (volume %+v)", i, got[i], got[i].MatchedVolume, want[i], want[i].MatchedVolume)
+		}
+	}
+
+	if nodes := loaded.Nodes(); len(nodes) != 1 || nodes["apps/data"] != "node-1" {
+		t.Errorf("Nodes() = %v, want only apps/data on node-1", nodes)
+	}
+}
+
+func TestPlanLoaderRejectsInvalidPlans(t *testing.T) {
+	valid := `schema_version: 1
+pvcs:
+  - name: data
+    namespace: apps
+    new_size: 5Gi
+    matched_volume:
+      name: app_data
+`
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"valid without a mountpoint", valid, ""},
+		{"newer schema version", strings.Replace(valid, "schema_version: 1", "schema_version: 2", 1), "unsupported schema_version 2"},
+		{"no schema version", strings.Replace(valid, "schema_version: 1\n", "", 1), "no schema_version"},
+		{"old version key", strings.Replace(valid, "schema_version: 1", "version: 1", 1), "no schema_version"},
+		{"no name", strings.Replace(valid, "name: data", "name: \"\"", 1), "without a name"},
+		{"no namespace", strings.Replace(valid, "namespace: apps", "namespace: \"\"", 1), "without a name or namespace"},
+		{"no volume", strings.Replace(valid, "    matched_volume:\n      name: app_data\n", "", 1), "no source volume"},
+		{"no size", strings.Replace(valid, "    new_size: 5Gi\n", "", 1), "no size"},
+		{"not YAML", "pvcs: [", "failed to parse"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "plan.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			_, err := NewPlanLoader(path).Load()
+			if tt.wantErr == "" && err != nil {
+				t.Errorf("Load() error = %v", err)
+			}
+			if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("Load() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	if _, err := NewPlanLoader(filepath.Join(t.TempDir(), "missing.yaml")).Load(); err == nil {
+		t.Error("Load() returned no error for a missing plan")
+	}
+}
+
+func TestPlanResolveVolumes(t *testing.T) {
+	planned := func() *Plan {
+		return &Plan{SchemaVersion: planSchemaVersion, PVCs: []PlannedPVC{{
+			PVCInfo: types.PVCInfo{Name: "data", Namespace: "apps", NewSize: "5Gi", MatchedVolume: &types.DockerVolumeInfo{
+				Name: "app_data", Mountpoint: "/staging/volumes/app_data/_data", Size: 1,
+			}},
+			Node: "staging-1",
+		}}}
+	}
+
+	tests := []struct {
+		name     string
+		volumes  map[string]*types.DockerVolumeInfo
+		wantNode string
+		wantErr  bool
+	}{
+		{
+			name:     "same host",
+			volumes:  map[string]*types.DockerVolumeInfo{"app_data": {Name: "app_data", Mountpoint: "/staging/volumes/app_data/_data", Size: 2}},
+			wantNode: "staging-1",
+		},
+		{
+			name:    "other host",
+			volumes: map[string]*types.DockerVolumeInfo{"app_data": {Name: "app_data", Mountpoint: "/var/lib/docker/volumes/app_data/_data", Size: 2}},
+		},
+		{
+			name:    "volume missing",
+			volumes: map[string]*types.DockerVolumeInfo{"other": {Name: "other"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := planned()
+			err := plan.ResolveVolumes(tt.volumes)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveVolumes() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			volume := plan.PVCs[0].MatchedVolume
+			if volume != tt.volumes["app_data"] {
+				t.Errorf("volume = %+v, want the volume of this host", volume)
+			}
+			if node := plan.PVCs[0].Node; node != tt.wantNode {
+				t.Errorf("node = %q, want %q", node, tt.wantNode)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package migration
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	sigsyaml "sigs.k8s.io/yaml"
+)
+
+// renderMigrationPod builds the migration pod of e for a PVC "data" and parses it,
+// like createPod does, so invalid YAML fails the test
+func renderMigrationPod(t *testing.T, e *Engine, memoryLimit string) *corev1.Pod {
+	t.Helper()
+	pvc := &types.PVCInfo{
+		Name:          "data",
+		Namespace:     "default",
+		MatchedVolume: &types.DockerVolumeInfo{Name: "app_data", Mountpoint: "/var/lib/docker/volumes/app_data/_data"},
+	}
+	podYAML := e.buildMigrationPodYAML(pvc, "migration-data-1", "default", "node-1", memoryLimit, "", "")
+
+	var pod corev1.Pod
+	if err := sigsyaml.UnmarshalStrict([]byte(podYAML), &pod); err != nil {
+		t.Fatalf("invalid pod YAML: %v\n%s", err, podYAML)
+	}
+	return &pod
+}
+
+func TestSetMigrationImage(t *testing.T) {
+	tests := []struct {
+		image   string
+		wantErr bool
+	}{
+		{"registry.example.com/tools/rsync:3.2", false},
+		{"rsync@sha256:0123456789abcdef", false},
+		{"", true},
+		{"rsync latest", true},
+		{"rsync\t", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.image, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			err := e.SetMigrationImage(tt.image)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetMigrationImage(%q) error = %v, want error %v", tt.image, err, tt.wantErr)
+			}
+			want := DefaultMigrationImage
+			if !tt.wantErr {
+				want = tt.image
+			}
+			if got := renderMigrationPod(t, e, "").Spec.Containers[0].Image; got != want {
+				t.Errorf("image = %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestMigrationPodImagePullSecrets(t *testing.T) {
+	tests := []struct {
+		secret string
+		want   []corev1.LocalObjectReference
+	}{
+		{"", nil},
+		{"registry-credentials", []corev1.LocalObjectReference{{Name: "registry-credentials"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.secret, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			e.SetImagePullSecret(tt.secret)
+
+			got := renderMigrationPod(t, e, "").Spec.ImagePullSecrets
+			if len(got) != len(tt.want) || (len(got) == 1 && got[0] != tt.want[0]) {
+				t.Errorf("imagePullSecrets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// runCopyScript runs the script of the migration pod of e against local directories,
+// with only the tools busybox has on PATH, and returns its output
+func runCopyScript(t *testing.T, e *Engine, source, target string) (string, error) {
+	t.Helper()
+	bin := t.TempDir()
+	for _, tool := range []string{"sh", "ls", "find", "wc", "cp", "cat"} {
+		path, err := exec.LookPath(tool)
+		if err != nil {
+			t.Skipf("%s not found: %v", tool, err)
+		}
+		if err := os.Symlink(path, filepath.Join(bin, tool)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	script := renderMigrationPod(t, e, "").Spec.Containers[0].Args[0]
+	script = strings.NewReplacer("/docker-data", source, "/pvc-data", target, "/tmp/", t.TempDir()+"/").Replace(script)
+	cmd := exec.Command(filepath.Join(bin, "sh"), "-c", script)
+	cmd.Env = []string{"PATH=" + bin}
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+func TestMigrationPodCopiesWithoutRsync(t *testing.T) {
+	source, target := t.TempDir(), t.TempDir()
+	if err := os.MkdirAll(filepath.Join(source, "db"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(source, "db", "data.sqlite"), []byte("rows"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	e := NewEngine("default", "", t.TempDir())
+	output, err := runCopyScript(t, e, source, target)
+	if err != nil {
+		t.Fatalf("copy script failed: %v\n%s", err, output)
+	}
+	if data, err := os.ReadFile(filepath.Join(target, "db", "data.sqlite")); err != nil || string(data) != "rows" {
+		t.Errorf("copied file = %q, %v, want rows\n%s", data, err, output)
+	}
+	if !strings.Contains(output, "PROGRESS:1/1") {
+		t.Errorf("output has no final progress line:\n%s", output)
+	}
+
+	// cp cannot honour rsync options, so the copy fails instead of dropping them
+	if err := e.SetRsyncArgs("--bwlimit=10m"); err != nil {
+		t.Fatal(err)
+	}
+	if output, err := runCopyScript(t, e, source, t.TempDir()); err == nil {
+		t.Errorf("copy script with --rsync-args succeeded without rsync:\n%s", output)
+	}
+}
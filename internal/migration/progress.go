@@ -0,0 +1,190 @@
+package migration
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"golang.org/x/term"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+)
+
+// kubeletSummary is the part of the kubelet's /stats/summary response with volume usage
+type kubeletSummary struct {
+	Pods []struct {
+		PodRef struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"podRef"`
+		Volumes []struct {
+			Name      string `json:"name"`
+			UsedBytes int64  `json:"usedBytes"`
+		} `json:"volume"`
+	} `json:"pods"`
+}
+
+// copiedBytes returns how much data the migration pod has written to /pvc-data so far.
+// The usage comes from the volume statistics of the kubelet (through the nodes/proxy
+// API), which are refreshed about once a minute. Without access to nodes/proxy the
+// Forbidden error is returned as is.
+func (e *Engine) copiedBytes(ctx context.Context, podName, namespace string) (int64, error) {
+	client, err := e.getClientset()
+	if err != nil {
+		return 0, err
+	}
+
+	pod, err := client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if err != nil {
+		return 0, err
+	}
+	if pod.Spec.NodeName == "" {
+		return 0, fmt.Errorf("pod %s is not scheduled yet", podName)
+	}
+
+	output, err := client.CoreV1().RESTClient().Get().
+		AbsPath("/api/v1/nodes", pod.Spec.NodeName, "proxy", "stats", "summary").
+		DoRaw(ctx)
+	if apierrors.IsForbidden(err) {
+		return 0, err
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read volume statistics of node %s: %v", pod.Spec.NodeName, err)
+	}
+
+	var summary kubeletSummary
+	if err := json.Unmarshal(output, &summary); err != nil {
+		return 0, fmt.Errorf("unexpected volume statistics: %v", err)
+	}
+	for _, podStats := range summary.Pods {
+		if podStats.PodRef.Name != podName || podStats.PodRef.Namespace != namespace {
+			continue
+		}
+		for _, volume := range podStats.Volumes {
+			if volume.Name == "pvc-volume" {
+				return volume.UsedBytes, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("no volume statistics for pod %s yet", podName)
+}
+
+// progressTotal returns the number of bytes the migration pod for pvc is expected to
+// copy, or 0 when progress cannot be measured. The kubelet does not report usage of
+// the emptyDir volumes used instead of PVCs in test runs.
+func (e *Engine) progressTotal(pvc *types.PVCInfo) int64 {
+	if e.useEphemeralVolumes || pvc.MatchedVolume == nil {
+		return 0
+	}
+	return pvc.MatchedVolume.Size
+}
+
+// progressInPlace reports whether progress can be redrawn on a single terminal line.
+// Parallel migrations would overwrite each other's line, so they print plain lines.
+func (e *Engine) progressInPlace() bool {
+	return e.parallelism == 1 && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// printCopyProgress prints how much of totalBytes has been copied
+func (e *Engine) printCopyProgress(podName string, copied, totalBytes int64) {
+	percent := float64(copied) / float64(totalBytes) * 100
+	if percent > 100 {
+		percent = 100
+	}
+
+	line := fmt.Sprintf("    %s: copied %s of %s (%.0f%%)", podName, formatBytes(copied), formatBytes(totalBytes), percent)
+	if e.progressInPlace() {
+		// Return to the start of the line and clear it before redrawing
+		logger.Printf("\r\033[K%s", line)
+		return
+	}
+	logger.Println(line)
+}
+
+// fileProgress is the number of files the migration pod has copied out of total
+type fileProgress struct {
+	copied int
+	total  int
+}
+
+// parseProgressLine parses a PROGRESS:<copied>/<total> line printed by the migration pod
+func parseProgressLine(line string) (copied, total int, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(line), "PROGRESS:")
+	if !found {
+		return 0, 0, false
+	}
+	copiedStr, totalStr, found := strings.Cut(rest, "/")
+	if !found {
+		return 0, 0, false
+	}
+
+	copied, err := strconv.Atoi(strings.TrimSpace(copiedStr))
+	if err != nil || copied < 0 {
+		return 0, 0, false
+	}
+	total, err = strconv.Atoi(strings.TrimSpace(totalStr))
+	if err != nil || total < 0 {
+		return 0, 0, false
+	}
+	return copied, total, true
+}
+
+// followCopyProgress follows the logs of the migration pod and sends every progress
+// line to progress, until the container exits or ctx is done. Without access to the
+// logs no progress is sent.
+func followCopyProgress(ctx context.Context, client clientset.Interface, podName, namespace string, progress chan<- fileProgress) {
+	stream, err := client.CoreV1().Pods(namespace).GetLogs(podName, &corev1.PodLogOptions{Follow: true}).Stream(ctx)
+	if err != nil {
+		return
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	// rsync prints long file names, so allow lines longer than the default 64KiB
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		copied, total, ok := parseProgressLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		select {
+		case progress <- fileProgress{copied: copied, total: total}:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// printFileProgress prints how many of total files have been copied
+func (e *Engine) printFileProgress(podName string, copied, total int) {
+	if copied > total {
+		copied = total
+	}
+
+	line := fmt.Sprintf("    %s: copied %d of %d files (%d%%)", podName, copied, total, copied*100/total)
+	if e.progressInPlace() {
+		logger.Printf("\r\033[K%s", line)
+		return
+	}
+	logger.Println(line)
+}
+
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
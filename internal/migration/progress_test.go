@@ -0,0 +1,112 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clientset "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// newStatsServer starts an API server that knows one scheduled pod and answers the
+// kubelet statistics request of its node with status and summary
+func newStatsServer(t *testing.T, status int, summary string) clientset.Interface {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.URL.Path == "/api/v1/namespaces/default/pods/migration-data-1":
+			json.NewEncoder(w).Encode(&corev1.Pod{
+				TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
+				ObjectMeta: metav1.ObjectMeta{Name: "migration-data-1", Namespace: "default"},
+				Spec:       corev1.PodSpec{NodeName: "node-1"},
+			})
+		case strings.HasSuffix(r.URL.Path, "/nodes/node-1/proxy/stats/summary"):
+			w.WriteHeader(status)
+			w.Write([]byte(summary))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := clientset.NewForConfig(&rest.Config{Host: server.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestCopiedBytes(t *testing.T) {
+	summary := `{"pods":[{"podRef":{"name":"migration-data-1","namespace":"default"},"volume":[{"name":"pvc-volume","usedBytes":4096}]}]}`
+
+	tests := []struct {
+		name          string
+		status        int
+		summary       string
+		want          int64
+		wantErr       bool
+		wantForbidden bool
+	}{
+		{name: "usage", status: http.StatusOK, summary: summary, want: 4096},
+		{name: "no statistics yet", status: http.StatusOK, summary: `{"pods":[]}`, wantErr: true},
+		{name: "no nodes/proxy access", status: http.StatusForbidden, summary: `{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Forbidden","code":403}`, wantErr: true, wantForbidden: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			e.destKubeClient = newStatsServer(t, tt.status, tt.summary)
+
+			got, err := e.copiedBytes(context.Background(), "migration-data-1", "default")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("copiedBytes() error = %v, want error %v", err, tt.wantErr)
+			}
+			if apierrors.IsForbidden(err) != tt.wantForbidden {
+				t.Errorf("copiedBytes() error = %v, want Forbidden %v", err, tt.wantForbidden)
+			}
+			if got != tt.want {
+				t.Errorf("copiedBytes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseProgressLine(t *testing.T) {
+	tests := []struct {
+		line       string
+		wantCopied int
+		wantTotal  int
+		wantOK     bool
+	}{
+		{"PROGRESS:3/10", 3, 10, true},
+		{"PROGRESS:0/0", 0, 0, true},
+		{"  PROGRESS: 7 / 12 \r", 7, 12, true},
+		{"PROGRESS:10/10", 10, 10, true},
+		{"PROGRESS:3", 0, 0, false},
+		{"PROGRESS:a/10", 0, 0, false},
+		{"PROGRESS:3/b", 0, 0, false},
+		{"PROGRESS:-1/10", 0, 0, false},
+		{"PROGRESS:1/-10", 0, 0, false},
+		{"progress:3/10", 0, 0, false},
+		{"sending incremental file list", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.line, func(t *testing.T) {
+			copied, total, ok := parseProgressLine(tt.line)
+			if copied != tt.wantCopied || total != tt.wantTotal || ok != tt.wantOK {
+				t.Errorf("parseProgressLine(%q) = %d, %d, %v, want %d, %d, %v",
+					tt.line, copied, total, ok, tt.wantCopied, tt.wantTotal, tt.wantOK)
+			}
+		})
+	}
+}
@@ -0,0 +1,147 @@
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// Report formats for WriteReport
+const (
+	ReportFormatMarkdown = "markdown"
+	ReportFormatJSON     = "json"
+)
+
+// Report documents the outcome of a migration
+type Report struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Version   string        `json:"version"`
+	PVCs      []ReportEntry `json:"pvcs"`
+}
+
+type ReportEntry struct {
+	Name         string `json:"name"`
+	Namespace    string `json:"namespace"`
+	SourceVolume string `json:"source_volume,omitempty"`
+	SourceSize   int64  `json:"source_size_bytes"`
+	Size         string `json:"size"`
+	Status       string `json:"status"` // "success", "failed" or "skipped"
+	Error        string `json:"error,omitempty"`
+}
+
+// SetReportFormat sets the format of WriteReport; empty picks it from the file extension
+func (e *Engine) SetReportFormat(format string) error {
+	switch format {
+	case "", ReportFormatJSON:
+		e.reportFormat = format
+	case ReportFormatMarkdown, "md":
+		e.reportFormat = ReportFormatMarkdown
+	default:
+		return fmt.Errorf("invalid report format %q, must be one of: markdown, json", format)
+	}
+	return nil
+}
+
+// WriteReport writes the outcome of the last StartMigration call for pvcs to path
+func (e *Engine) WriteReport(path string, pvcs []*types.PVCInfo) error {
+	report := Report{Timestamp: time.Now().UTC(), Version: toolVersion(), PVCs: []ReportEntry{}}
+
+	results := e.lastResults
+	if results == nil {
+		results = newMigrationResults()
+	}
+	results.mu.Lock()
+	for _, pvc := range pvcs {
+		entry := ReportEntry{Name: pvc.Name, Namespace: e.namespaceFor(pvc), Size: pvc.NewSize, Status: "skipped"}
+		if entry.Size == "" {
+			entry.Size = pvc.RequestedSize
+		}
+		if pvc.MatchedVolume != nil {
+			entry.SourceVolume = pvc.MatchedVolume.Name
+			entry.SourceSize = pvc.MatchedVolume.Size
+		}
+		if err, ok := results.failed[pvc]; ok {
+			entry.Status = "failed"
+			entry.Error = err.Error()
+		} else if results.migrated[pvc] {
+			entry.Status = "success"
+		}
+		report.PVCs = append(report.PVCs, entry)
+	}
+	results.mu.Unlock()
+
+	format := e.reportFormat
+	if format == "" {
+		format = ReportFormatMarkdown
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			format = ReportFormatJSON
+		}
+	}
+
+	var content []byte
+	if format == ReportFormatJSON {
+		data, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return err
+		}
+		content = append(data, '\n')
+	} else {
+		content = []byte(report.markdown())
+	}
+
+	if err := os.WriteFile(path, content, 0644); err != nil {
+		return fmt.Errorf("failed to write report %s: %v", path, err)
+	}
+	logger.Printf("Migration report written to %s\n", path)
+	return nil
+}
+
+func (r Report) markdown() string {
+	counts := make(map[string]int)
+	for _, entry := range r.PVCs {
+		counts[entry.Status]++
+	}
+
+	var b strings.Builder
+	b.WriteString("# Migration report\n\n")
+	fmt.Fprintf(&b, "- Date: %s\n", r.Timestamp.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Tool version: %s\n", r.Version)
+	fmt.Fprintf(&b, "- Result: %d succeeded, %d failed, %d skipped\n\n", counts["success"], counts["failed"], counts["skipped"])
+
+	b.WriteString("## Summary\n\n")
+	b.WriteString("| PVC | Namespace | Source volume | Source size | PVC size | Status |\n")
+	b.WriteString("|-----|-----------|---------------|-------------|----------|--------|\n")
+	for _, entry := range r.PVCs {
+		sourceVolume := entry.SourceVolume
+		if sourceVolume == "" {
+			sourceVolume = "-"
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s | %s |\n",
+			entry.Name, entry.Namespace, sourceVolume, formatBytes(entry.SourceSize), entry.Size, entry.Status)
+	}
+
+	if counts["failed"] > 0 {
+		b.WriteString("\n## Errors\n\n")
+		for _, entry := range r.PVCs {
+			if entry.Error != "" {
+				fmt.Fprintf(&b, "- **%s/%s**: %s\n", entry.Namespace, entry.Name, strings.ReplaceAll(entry.Error, "\n", " "))
+			}
+		}
+	}
+
+	return b.String()
+}
+
+// toolVersion returns the module version this binary was built from
+func toolVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "unknown"
+}
@@ -0,0 +1,123 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// reportPVCs runs a migration in which "data" succeeds, "logs" fails and "cache"
+// has no volume, and returns its PVCs
+func reportPVCs(t *testing.T, e *Engine) []*types.PVCInfo {
+	t.Helper()
+	pvcs := []*types.PVCInfo{
+		{Name: "data", Namespace: "apps", NewSize: "10Gi", MatchedVolume: &types.DockerVolumeInfo{Name: "app_data", Size: 2 << 30}},
+		{Name: "logs", Namespace: "apps", RequestedSize: "1Gi", MatchedVolume: &types.DockerVolumeInfo{Name: "app_logs"}},
+		{Name: "cache", Namespace: "apps", RequestedSize: "1Gi"},
+	}
+	e.SetUseEphemeralVolumes(true)
+	e.migrateOne = func(pvc *types.PVCInfo) error {
+		if pvc.Name == "logs" {
+			return errors.New("copy failed")
+		}
+		return nil
+	}
+	if err := e.StartMigration(pvcs); err == nil {
+		t.Fatal("expected the migration of logs to fail")
+	}
+	return pvcs
+}
+
+func TestWriteReportMarkdown(t *testing.T) {
+	e := NewEngine("default", "", t.TempDir())
+	pvcs := reportPVCs(t, e)
+
+	path := filepath.Join(t.TempDir(), "report.md")
+	if err := e.WriteReport(path, pvcs); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := string(data)
+
+	for _, want := range []string{
+		"# Migration report",
+		"- Tool version: ",
+		"- Result: 1 succeeded, 1 failed, 1 skipped",
+		"## Summary",
+		"| PVC | Namespace | Source volume | Source size | PVC size | Status |",
+		"| data | apps | app_data | 2.0 GB | 10Gi | success |",
+		"| logs | apps | app_logs | ",
+		"| cache | apps | - | ",
+		"## Errors",
+		"- **apps/logs**: ",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q:\n%s", want, report)
+		}
+	}
+}
+
+func TestWriteReportJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		format string
+	}{
+		{"extension", "report.json", ""},
+		{"flag", "report.txt", ReportFormatJSON},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			if err := e.SetReportFormat(tt.format); err != nil {
+				t.Fatal(err)
+			}
+			pvcs := reportPVCs(t, e)
+
+			path := filepath.Join(t.TempDir(), tt.file)
+			if err := e.WriteReport(path, pvcs); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var report Report
+			if err := json.Unmarshal(data, &report); err != nil {
+				t.Fatalf("report is not JSON: %v\n%s", err, data)
+			}
+			if report.Timestamp.IsZero() || report.Version == "" {
+				t.Errorf("report has no timestamp or version: %+v", report)
+			}
+			want := map[string]string{"data": "success", "logs": "failed", "cache": "skipped"}
+			if len(report.PVCs) != len(want) {
+				t.Fatalf("report has %d PVCs, want %d", len(report.PVCs), len(want))
+			}
+			for _, entry := range report.PVCs {
+				if entry.Status != want[entry.Name] {
+					t.Errorf("status of %s = %q, want %q", entry.Name, entry.Status, want[entry.Name])
+				}
+				if entry.Namespace != "apps" || entry.Size == "" {
+					t.Errorf("entry %+v has no namespace or size", entry)
+				}
+			}
+		})
+	}
+}
+
+func TestSetReportFormat(t *testing.T) {
+	e := NewEngine("default", "", t.TempDir())
+	if err := e.SetReportFormat("html"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,83 @@
+package migration
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// PodResources are the resource requests and limits of the migration pod. Empty
+// values are left out of the pod spec.
+type PodResources struct {
+	CPURequest    string
+	CPULimit      string
+	MemoryRequest string
+	MemoryLimit   string // Doubled on every restart of an OOMKilled migration pod
+}
+
+// DefaultPodResources reserve a little CPU and memory for the copy without
+// limiting either
+var DefaultPodResources = PodResources{
+	CPURequest:    "100m",
+	MemoryRequest: "256Mi",
+}
+
+// SetPodResources validates and sets the resources of the migration pod
+func (e *Engine) SetPodResources(resources PodResources) error {
+	quantities := []struct {
+		name, request, limit string
+	}{
+		{"cpu", resources.CPURequest, resources.CPULimit},
+		{"memory", resources.MemoryRequest, resources.MemoryLimit},
+	}
+
+	for _, q := range quantities {
+		var request, limit resource.Quantity
+		var err error
+		if q.request != "" {
+			if request, err = resource.ParseQuantity(q.request); err != nil {
+				return fmt.Errorf("invalid %s request %q: %v", q.name, q.request, err)
+			}
+		}
+		if q.limit != "" {
+			if limit, err = resource.ParseQuantity(q.limit); err != nil {
+				return fmt.Errorf("invalid %s limit %q: %v", q.name, q.limit, err)
+			}
+		}
+		if q.request != "" && q.limit != "" && request.Cmp(limit) > 0 {
+			return fmt.Errorf("%s request %s is larger than the %s limit %s", q.name, q.request, q.name, q.limit)
+		}
+	}
+
+	e.podResources = resources
+	return nil
+}
+
+// buildPodResources returns the resources stanza of the migration container
+func (e *Engine) buildPodResources(memoryLimit string) string {
+	var requests, limits string
+	if e.podResources.CPURequest != "" {
+		requests += fmt.Sprintf("        cpu: %s\n", e.podResources.CPURequest)
+	}
+	if e.podResources.MemoryRequest != "" {
+		requests += fmt.Sprintf("        memory: %s\n", e.podResources.MemoryRequest)
+	}
+	if e.podResources.CPULimit != "" {
+		limits += fmt.Sprintf("        cpu: %s\n", e.podResources.CPULimit)
+	}
+	if memoryLimit != "" {
+		limits += fmt.Sprintf("        memory: %s\n", memoryLimit)
+	}
+
+	if requests == "" && limits == "" {
+		return ""
+	}
+	stanza := "    resources:\n"
+	if requests != "" {
+		stanza += "      requests:\n" + requests
+	}
+	if limits != "" {
+		stanza += "      limits:\n" + limits
+	}
+	return stanza
+}
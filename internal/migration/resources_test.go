@@ -0,0 +1,92 @@
+package migration
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestSetPodResources(t *testing.T) {
+	tests := []struct {
+		name      string
+		resources PodResources
+		wantErr   bool
+	}{
+		{"defaults", DefaultPodResources, false},
+		{"none", PodResources{}, false},
+		{"limits", PodResources{CPURequest: "250m", CPULimit: "1", MemoryRequest: "128Mi", MemoryLimit: "1Gi"}, false},
+		{"invalid quantity", PodResources{CPULimit: "fast"}, true},
+		{"request above limit", PodResources{MemoryRequest: "2Gi", MemoryLimit: "1Gi"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			if err := e.SetPodResources(tt.resources); (err != nil) != tt.wantErr {
+				t.Errorf("SetPodResources() error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMigrationPodResources(t *testing.T) {
+	tests := []struct {
+		name         string
+		resources    PodResources
+		memoryLimit  string // Limit after OOM restarts, see podMemoryLimit
+		wantRequests corev1.ResourceList
+		wantLimits   corev1.ResourceList
+	}{
+		{
+			name:      "defaults",
+			resources: DefaultPodResources,
+			wantRequests: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("100m"),
+				corev1.ResourceMemory: resource.MustParse("256Mi"),
+			},
+		},
+		{
+			name:      "none",
+			resources: PodResources{},
+		},
+		{
+			name:        "limits",
+			resources:   PodResources{CPURequest: "250m", CPULimit: "1", MemoryLimit: "512Mi"},
+			memoryLimit: "1Gi",
+			wantRequests: corev1.ResourceList{
+				corev1.ResourceCPU: resource.MustParse("250m"),
+			},
+			wantLimits: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse("1"),
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			if err := e.SetPodResources(tt.resources); err != nil {
+				t.Fatal(err)
+			}
+
+			got := renderMigrationPod(t, e, tt.memoryLimit).Spec.Containers[0].Resources
+			assertResources(t, "requests", got.Requests, tt.wantRequests)
+			assertResources(t, "limits", got.Limits, tt.wantLimits)
+		})
+	}
+}
+
+func assertResources(t *testing.T, name string, got, want corev1.ResourceList) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("%s = %v, want %v", name, got, want)
+		return
+	}
+	for key, quantity := range want {
+		if value := got[key]; value.Cmp(quantity) != 0 {
+			t.Errorf("%s %s = %s, want %s", name, key, value.String(), quantity.String())
+		}
+	}
+}
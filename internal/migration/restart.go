@@ -0,0 +1,122 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+)
+
+// restartablePodError is returned when a migration pod was OOMKilled or evicted, so
+// a relaunch (with more memory after an OOMKill) may succeed
+type restartablePodError struct {
+	podName string
+	reason  string
+}
+
+func (e *restartablePodError) Error() string {
+	return fmt.Sprintf("migration pod %s failed (%s)", e.podName, e.reason)
+}
+
+func (e *Engine) SetMaxPodRestarts(maxPodRestarts int) {
+	e.maxPodRestarts = maxPodRestarts
+}
+
+// watchPodForRestart waits for a migration pod to complete. When it fails because it
+// was OOMKilled or evicted, a *restartablePodError is returned so the caller can
+// relaunch it. Other failures, like rsync exiting with an error, are not retried.
+func (e *Engine) watchPodForRestart(ctx context.Context, podName, namespace string, totalBytes int64) error {
+	err := e.waitForPodCompletion(ctx, podName, namespace, totalBytes)
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	client, clientErr := e.getClientset()
+	if clientErr != nil {
+		return err
+	}
+	pod, getErr := client.CoreV1().Pods(namespace).Get(ctx, podName, metav1.GetOptions{})
+	if getErr != nil {
+		return err
+	}
+
+	if reason := restartReason(pod); reason != "" {
+		return &restartablePodError{podName: podName, reason: reason}
+	}
+	return err
+}
+
+// restartReason returns why a failed pod may be relaunched ("Evicted" or
+// "OOMKilled"), or "" when it failed for another reason
+func restartReason(pod *corev1.Pod) string {
+	if pod.Status.Reason == "Evicted" {
+		return pod.Status.Reason
+	}
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Terminated != nil && status.State.Terminated.Reason == "OOMKilled" {
+			return status.State.Terminated.Reason
+		}
+	}
+	return ""
+}
+
+// shouldRestartPod reports whether the migration pod for key may be relaunched after
+// err, and records the restart when it may
+func (e *Engine) shouldRestartPod(key string, err error) bool {
+	var restartable *restartablePodError
+	if !errors.As(err, &restartable) {
+		return false
+	}
+	e.mu.Lock()
+	restarts := e.podRestarts[key]
+	canRestart := restarts < e.maxPodRestarts
+	if canRestart {
+		if e.podRestarts == nil {
+			e.podRestarts = make(map[string]int)
+		}
+		restarts++
+		e.podRestarts[key] = restarts
+		if restartable.reason == "OOMKilled" {
+			if e.oomRestarts == nil {
+				e.oomRestarts = make(map[string]int)
+			}
+			e.oomRestarts[key]++
+		}
+	}
+	e.mu.Unlock()
+
+	if !canRestart {
+		logger.Errorf("  %s\n", color.Error(fmt.Sprintf("❌ Migration pod failed %d times, giving up", restarts+1)))
+		return false
+	}
+
+	if limit := e.podMemoryLimit(key); limit != "" && restartable.reason == "OOMKilled" {
+		logger.Warnf("  %s\n", color.Warning(fmt.Sprintf("⚠️  %v, restarting with %s memory (attempt %d of %d)",
+			restartable, limit, restarts, e.maxPodRestarts)))
+	} else {
+		logger.Warnf("  %s\n", color.Warning(fmt.Sprintf("⚠️  %v, restarting (attempt %d of %d)", restartable, restarts, e.maxPodRestarts)))
+	}
+	return true
+}
+
+// podMemoryLimit returns the memory limit for the next migration pod of key. Every
+// restart after an OOMKill doubles the configured limit; without a limit there is
+// nothing to raise.
+func (e *Engine) podMemoryLimit(key string) string {
+	if e.podResources.MemoryLimit == "" {
+		return ""
+	}
+
+	e.mu.Lock()
+	oomKills := e.oomRestarts[key]
+	e.mu.Unlock()
+
+	limit := resource.MustParse(e.podResources.MemoryLimit)
+	limit.Set(limit.Value() << oomKills)
+	return limit.String()
+}
@@ -0,0 +1,79 @@
+package migration
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestRestartReason(t *testing.T) {
+	terminated := func(reason string) corev1.PodStatus {
+		return corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: reason}},
+		}}}
+	}
+
+	tests := []struct {
+		name   string
+		status corev1.PodStatus
+		want   string
+	}{
+		{"oom killed", terminated("OOMKilled"), "OOMKilled"},
+		{"evicted", corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"}, "Evicted"},
+		{"rsync error", terminated("Error"), ""},
+		{"running", corev1.PodStatus{Phase: corev1.PodRunning}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := restartReason(&corev1.Pod{Status: tt.status}); got != tt.want {
+				t.Errorf("restartReason() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShouldRestartPod(t *testing.T) {
+	tests := []struct {
+		name        string
+		memoryLimit string
+		reasons     []string
+		wantLimit   string
+	}{
+		{"oom doubles limit", "512Mi", []string{"OOMKilled", "OOMKilled"}, "2Gi"},
+		{"eviction keeps limit", "512Mi", []string{"Evicted"}, "512Mi"},
+		{"no limit stays unset", "", []string{"OOMKilled"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEngine("default", "", t.TempDir())
+			e.podResources.MemoryLimit = tt.memoryLimit
+			for _, reason := range tt.reasons {
+				if !e.shouldRestartPod("default/data", &restartablePodError{podName: "pod", reason: reason}) {
+					t.Fatalf("pod is not restarted after %s", reason)
+				}
+			}
+			if got := e.podMemoryLimit("default/data"); got != tt.wantLimit {
+				t.Errorf("podMemoryLimit() = %q, want %q", got, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestShouldRestartPodLimits(t *testing.T) {
+	e := NewEngine("default", "", t.TempDir())
+	e.SetMaxPodRestarts(1)
+
+	if e.shouldRestartPod("default/data", errors.New("rsync failed")) {
+		t.Error("pod is restarted after a non-restartable error")
+	}
+	oom := &restartablePodError{podName: "pod", reason: "OOMKilled"}
+	if !e.shouldRestartPod("default/data", oom) {
+		t.Error("pod is not restarted after the first OOMKill")
+	}
+	if e.shouldRestartPod("default/data", oom) {
+		t.Error("pod is restarted more often than --max-pod-restarts")
+	}
+}
@@ -0,0 +1,59 @@
+package migration
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	applyMaxRetries = 5
+	applyBackoff    = 2 * time.Second
+)
+
+// retryableApplyErrors are messages of transient connection problems with the API
+// server, such as during a rolling restart of the control plane. Anything else
+// (invalid YAML, unauthorized, ...) will not go away by retrying.
+var retryableApplyErrors = []string{
+	"connection refused",
+	"connection reset by peer",
+	"i/o timeout",
+	"TLS handshake timeout",
+	"the server is currently unable to handle the request",
+	"the server was unable to return a response in the time allotted",
+	"the server has received too many requests",
+	"Timeout: request did not complete",
+	"etcdserver: request timed out",
+}
+
+// applyWithRetry applies yamlContent in namespace, retrying transient failures up
+// to maxRetries times with an exponentially growing backoff
+func (e *Engine) applyWithRetry(ctx context.Context, yamlContent, namespace string, maxRetries int, backoff time.Duration) error {
+	for attempt := 0; ; attempt++ {
+		err := e.applyManifests(ctx, yamlContent, namespace)
+		if err == nil {
+			return nil
+		}
+
+		if attempt >= maxRetries || !isRetryableApplyError(err) {
+			return err
+		}
+
+		logger.Warnf("    Apply failed (attempt %d of %d), retrying in %s...\n", attempt+1, maxRetries+1, backoff)
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+func isRetryableApplyError(err error) bool {
+	for _, message := range retryableApplyErrors {
+		if strings.Contains(err.Error(), message) {
+			return true
+		}
+	}
+	return false
+}
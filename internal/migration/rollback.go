@@ -0,0 +1,95 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Rollback deletes the PVCs that a (failed) migration created, so the migration
+// can be retried from a clean state. PVCs that are still mounted by a pod are
+// left alone and reported as an error.
+func (e *Engine) Rollback(pvcs []*types.PVCInfo) error {
+	logger.Println("\n" + color.Header("=== Rolling Back Migration ==="))
+
+	client, err := e.getClientset()
+	if err != nil {
+		return err
+	}
+	ctx := e.ctx
+
+	var errs []error
+	for _, pvc := range pvcs {
+		if !pvc.Created {
+			continue
+		}
+
+		namespace := e.namespaceFor(pvc)
+		pods, err := e.podsUsingPVC(ctx, pvc.Name, namespace)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to check pods using PVC %s: %v", pvc.Name, err))
+			continue
+		}
+		if len(pods) > 0 {
+			errs = append(errs, fmt.Errorf("PVC %s/%s is still used by pod(s) %s; delete or scale them down first",
+				namespace, pvc.Name, strings.Join(pods, ", ")))
+			continue
+		}
+
+		logger.Printf("  Deleting PVC %s/%s...\n", namespace, pvc.Name)
+		err = client.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, pvc.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			errs = append(errs, fmt.Errorf("failed to delete PVC %s: %v", pvc.Name, err))
+			continue
+		}
+		pvc.Created = false
+		if e.stateStore != nil {
+			if err := e.stateStore.Delete(namespace, pvc.Name); err != nil {
+				logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("Warning: Could not update migration state: %v", err)))
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
+	}
+
+	logger.Println("Rollback completed")
+	return nil
+}
+
+// podsUsingPVC returns the names of the running or pending pods in namespace that
+// mount claimName. Terminated pods no longer hold the volume, and pods that are
+// being deleted, e.g. by a scale down, are about to release it.
+func (e *Engine) podsUsingPVC(ctx context.Context, claimName, namespace string) ([]string, error) {
+	client, err := e.getClientset()
+	if err != nil {
+		return nil, err
+	}
+
+	podList, err := client.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var pods []string
+	for _, pod := range podList.Items {
+		if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || pod.DeletionTimestamp != nil {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+				pods = append(pods, pod.Name)
+				break
+			}
+		}
+	}
+	return pods, nil
+}
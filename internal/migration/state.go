@@ -0,0 +1,219 @@
+package migration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// Phase is how far the migration of a PVC got
+type Phase string
+
+const (
+	PhasePVCCreated Phase = "pvc-created"
+	PhaseDataCopied Phase = "data-copied"
+	PhaseCompleted  Phase = "completed"
+)
+
+// phaseOrder lists the phases in the order a migration passes them
+var phaseOrder = []Phase{PhasePVCCreated, PhaseDataCopied, PhaseCompleted}
+
+// reached reports whether a migration in phase p has passed target
+func (p Phase) reached(target Phase) bool {
+	current := slices.Index(phaseOrder, p)
+	return current >= 0 && current >= slices.Index(phaseOrder, target)
+}
+
+// StateEntry records the last phase a PVC reached
+type StateEntry struct {
+	PVCName   string    `json:"pvcName"`
+	Namespace string    `json:"namespace"`
+	Phase     Phase     `json:"phase"`
+	Existing  bool      `json:"existing,omitempty"` // The PVC existed before the migration, so it is never rolled back
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// StateStore persists migration progress to a JSON file, so an interrupted
+// migration can be resumed where it stopped
+type StateStore struct {
+	path    string
+	mu      sync.Mutex
+	entries []StateEntry
+}
+
+// NewStateStore loads the state file at path; a missing file is an empty state
+func NewStateStore(path string) (*StateStore, error) {
+	store := &StateStore{path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file %s: %v", path, err)
+	}
+	if len(data) == 0 {
+		return store, nil
+	}
+	if err := json.Unmarshal(data, &store.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %v", path, err)
+	}
+	return store, nil
+}
+
+// Phase returns the last recorded phase of a PVC, or "" when it was never started
+func (s *StateStore) Phase(namespace, name string) Phase {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		if entry.Namespace == namespace && entry.PVCName == name {
+			return entry.Phase
+		}
+	}
+	return ""
+}
+
+// Existing reports whether a PVC was recorded with SetExisting
+func (s *StateStore) Existing(namespace, name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.entries {
+		if entry.Namespace == namespace && entry.PVCName == name {
+			return entry.Existing
+		}
+	}
+	return false
+}
+
+// Set records that a PVC reached phase and writes the state file
+func (s *StateStore) Set(namespace, name string, phase Phase) error {
+	return s.set(namespace, name, phase, false)
+}
+
+// SetExisting is Set for a PVC that existed before the migration. Later phases keep
+// the mark, so a rollback never deletes the PVC.
+func (s *StateStore) SetExisting(namespace, name string, phase Phase) error {
+	return s.set(namespace, name, phase, true)
+}
+
+func (s *StateStore) set(namespace, name string, phase Phase, existing bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := StateEntry{PVCName: name, Namespace: namespace, Phase: phase, Existing: existing, Timestamp: time.Now().UTC()}
+	for i := range s.entries {
+		if s.entries[i].Namespace == namespace && s.entries[i].PVCName == name {
+			entry.Existing = entry.Existing || s.entries[i].Existing
+			s.entries[i] = entry
+			return s.save()
+		}
+	}
+	s.entries = append(s.entries, entry)
+	return s.save()
+}
+
+// Delete forgets a PVC, e.g. after it was rolled back
+func (s *StateStore) Delete(namespace, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.entries {
+		if s.entries[i].Namespace == namespace && s.entries[i].PVCName == name {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return s.save()
+		}
+	}
+	return nil
+}
+
+// Reset forgets all PVCs and truncates the state file
+func (s *StateStore) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = nil
+	if err := os.Truncate(s.path, 0); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to reset state file %s: %v", s.path, err)
+	}
+	return nil
+}
+
+// save must be called with mu held. The file is replaced atomically so a crash
+// never leaves a half-written state behind.
+func (s *StateStore) save() error {
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to write state file %s: %v", s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state file %s: %v", s.path, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state file %s: %v", s.path, err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to write state file %s: %v", s.path, err)
+	}
+	return nil
+}
+
+func (e *Engine) SetStateStore(store *StateStore) {
+	e.stateStore = store
+}
+
+// Migrated reports whether the state file records pvc as completely migrated
+func (e *Engine) Migrated(pvc *types.PVCInfo) bool {
+	return e.statePhase(pvc) == PhaseCompleted
+}
+
+// CreatedPVC reports whether the state file records that a migration created pvc,
+// so a later run can roll it back without touching PVCs it did not create
+func (e *Engine) CreatedPVC(pvc *types.PVCInfo) bool {
+	return e.statePhase(pvc).reached(PhasePVCCreated) && !e.stateExisting(pvc)
+}
+
+// stateExisting reports whether the state file records that pvc existed before the migration
+func (e *Engine) stateExisting(pvc *types.PVCInfo) bool {
+	return e.stateStore != nil && e.stateStore.Existing(e.namespaceFor(pvc), pvc.Name)
+}
+
+// statePhase returns the recorded phase of pvc, or "" without a state store
+func (e *Engine) statePhase(pvc *types.PVCInfo) Phase {
+	if e.stateStore == nil {
+		return ""
+	}
+	return e.stateStore.Phase(e.namespaceFor(pvc), pvc.Name)
+}
+
+// recordPhase saves that pvc reached phase. Failing to save does not fail the
+// migration, it only means a resumed run repeats the step.
+func (e *Engine) recordPhase(pvc *types.PVCInfo, phase Phase) {
+	if e.stateStore == nil {
+		return
+	}
+	set := e.stateStore.Set
+	if pvc.Existing {
+		set = e.stateStore.SetExisting
+	}
+	if err := set(e.namespaceFor(pvc), pvc.Name, phase); err != nil {
+		logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("Warning: Could not save migration state: %v", err)))
+	}
+}
@@ -0,0 +1,149 @@
+package migration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestStateStoreReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, err := NewStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, step := range []struct {
+		name  string
+		phase Phase
+	}{
+		{"a", PhasePVCCreated},
+		{"b", PhasePVCCreated},
+		{"a", PhaseDataCopied},
+		{"a", PhaseCompleted},
+	} {
+		if err := store.Set("default", step.name, step.phase); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A crash while saving leaves a temporary file next to the state file
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), ".state.json.tmp-1"), []byte("[{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	reloaded, err := NewStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		namespace, name string
+		want            Phase
+	}{
+		{"default", "a", PhaseCompleted},
+		{"default", "b", PhasePVCCreated},
+		{"other", "a", ""},
+		{"default", "c", ""},
+	}
+	for _, tt := range tests {
+		if got := reloaded.Phase(tt.namespace, tt.name); got != tt.want {
+			t.Errorf("Phase(%s, %s) = %q, want %q", tt.namespace, tt.name, got, tt.want)
+		}
+	}
+
+	if err := reloaded.SetExisting("default", "d", PhasePVCCreated); err != nil {
+		t.Fatal(err)
+	}
+	// Recording a later phase keeps the PVC marked as existing
+	if err := reloaded.Set("default", "d", PhaseDataCopied); err != nil {
+		t.Fatal(err)
+	}
+	existing, err := NewStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !existing.Existing("default", "d") || existing.Existing("default", "a") {
+		t.Error("Existing() does not match the PVCs recorded with SetExisting")
+	}
+
+	if err := reloaded.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	reset, err := NewStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := reset.Phase("default", "a"); got != "" {
+		t.Errorf("Phase after reset = %q, want none", got)
+	}
+}
+
+func TestStartMigrationResumesAfterCrash(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	pvcs := func() []*types.PVCInfo {
+		return []*types.PVCInfo{
+			{Name: "done", Namespace: "default", MatchedVolume: &types.DockerVolumeInfo{Name: "done"}},
+			{Name: "copied", Namespace: "default", MatchedVolume: &types.DockerVolumeInfo{Name: "copied"}},
+		}
+	}
+
+	// The first run migrates "done" and is killed after copying the data of "copied"
+	store, err := NewStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := NewEngine("default", "", t.TempDir())
+	first.SetUseEphemeralVolumes(true) // Skip the cluster checks
+	first.SetStateStore(store)
+	first.migrateOne = func(pvc *types.PVCInfo) error {
+		first.recordPhase(pvc, PhasePVCCreated)
+		first.recordPhase(pvc, PhaseDataCopied)
+		if pvc.Name == "copied" {
+			return errors.New("killed")
+		}
+		first.recordPhase(pvc, PhaseCompleted)
+		return nil
+	}
+	if err := first.StartMigration(pvcs()); err == nil {
+		t.Fatal("expected the first run to fail")
+	}
+
+	// The second run reads the state file again, like a new process
+	store, err = NewStateStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := fake.NewSimpleClientset(&corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Name: "copied", Namespace: "default"},
+		Status:     corev1.PersistentVolumeClaimStatus{Phase: corev1.ClaimBound},
+	})
+	second := NewEngine("default", "", t.TempDir())
+	second.destKubeClient = client
+	second.SetStateStore(store)
+
+	var migrated []string
+	second.migrateOne = func(pvc *types.PVCInfo) error {
+		migrated = append(migrated, pvc.Name)
+		return second.migratePVC(pvc)
+	}
+	if err := second.StartMigration(pvcs()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(migrated) != 1 || migrated[0] != "copied" {
+		t.Errorf("migrated %v, want only the interrupted PVC", migrated)
+	}
+	for _, action := range client.Actions() {
+		if action.GetVerb() == "create" {
+			t.Errorf("resumed migration created a %s, want the completed steps skipped", action.GetResource().Resource)
+		}
+	}
+	if got := store.Phase("default", "copied"); got != PhaseCompleted {
+		t.Errorf("phase of resumed PVC = %q, want %q", got, PhaseCompleted)
+	}
+}
@@ -0,0 +1,48 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// createStatefulSetPVC creates a PVC that is defined by a StatefulSet volumeClaimTemplate.
+// There is no PVC document to apply, so it is built from the parsed template. The
+// StatefulSet controller adopts an existing PVC with the expected name.
+func (e *Engine) createStatefulSetPVC(ctx context.Context, pvc *types.PVCInfo) error {
+	logger.Printf("    Creating PVC %s for StatefulSet %s in namespace %s...\n", pvc.Name, pvc.StatefulSet, e.namespaceFor(pvc))
+	return e.applyWithRetry(ctx, e.buildPVCYAML(pvc), e.namespaceFor(pvc), applyMaxRetries, applyBackoff)
+}
+
+// buildPVCYAML returns a PVC document built from the parsed fields of pvc, for PVCs
+// that have no document of their own to apply
+func (e *Engine) buildPVCYAML(pvc *types.PVCInfo) string {
+	size := pvc.NewSize
+	if size == "" {
+		size = pvc.RequestedSize
+	}
+
+	accessModes := pvc.AccessModes
+	if len(accessModes) == 0 {
+		accessModes = []string{"ReadWriteOnce"}
+	}
+
+	var storageClass string
+	if pvc.StorageClass != "" {
+		storageClass = fmt.Sprintf("  storageClassName: %s\n", pvc.StorageClass)
+	}
+
+	return fmt.Sprintf(`apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: %s
+  namespace: %s
+spec:
+  accessModes: [%s]
+%s  resources:
+    requests:
+      storage: %s
+`, pvc.Name, e.namespaceFor(pvc), strings.Join(accessModes, ", "), storageClass, size)
+}
@@ -0,0 +1,88 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func (e *Engine) SetVeleroBackup(veleroBackup bool) {
+	e.veleroBackup = veleroBackup
+}
+
+// pvcNamespaces returns the namespaces the PVCs are created in, in order of first appearance
+func (e *Engine) pvcNamespaces(pvcs []*types.PVCInfo) []string {
+	var namespaces []string
+	seen := make(map[string]bool)
+	for _, pvc := range pvcs {
+		if namespace := e.namespaceFor(pvc); !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
+
+// createVeleroBackup takes a Velero backup of namespaces and waits until it has completed
+func (e *Engine) createVeleroBackup(ctx context.Context, namespaces []string) error {
+	backupName := fmt.Sprintf("pre-migration-%d", time.Now().Unix())
+	included := strings.Join(namespaces, ",")
+
+	logger.Printf("Creating Velero backup %s of namespaces %s...\n", backupName, included)
+	cmd := exec.CommandContext(ctx, "velero", "backup", "create", backupName, "--include-namespaces", included, "--wait")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("velero backup create failed: %v\nOutput: %s", err, string(output))
+	}
+
+	interval := 5 * time.Second
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for Velero backup %s to complete", backupName)
+		default:
+			phase, err := e.getVeleroBackupPhase(ctx, backupName)
+			if err != nil {
+				logger.Warnf("  Error checking backup status: %v\n", err)
+				time.Sleep(interval)
+				continue
+			}
+
+			logger.Printf("  Backup status: %s\n", phase)
+			switch phase {
+			case "Completed":
+				logger.Println(color.Success(fmt.Sprintf("✅ Velero backup %s completed", backupName)))
+				return nil
+			case "Failed", "PartiallyFailed", "FailedValidation":
+				return fmt.Errorf("velero backup %s finished with phase %s", backupName, phase)
+			}
+
+			time.Sleep(interval)
+		}
+	}
+}
+
+func (e *Engine) getVeleroBackupPhase(ctx context.Context, backupName string) (string, error) {
+	cmd := exec.CommandContext(ctx, "velero", "backup", "describe", backupName)
+	output, err := cmd.Output()
+	if err != nil {
+		return "", err
+	}
+
+	// The describe output contains a line like "Phase:  Completed"
+	for _, line := range strings.Split(string(output), "\n") {
+		if phase, ok := strings.CutPrefix(strings.TrimSpace(line), "Phase:"); ok {
+			fields := strings.Fields(phase)
+			if len(fields) > 0 {
+				return fields[0], nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("no phase found in velero backup describe output")
+}
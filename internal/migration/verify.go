@@ -0,0 +1,199 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// verifyTypes are the supported --verify-type values
+var verifyTypes = []string{"basic", "postgres", "mysql", "mongo", "auto"}
+
+// verifyMarkers are files that must exist in a valid data directory for each verify type
+var verifyMarkers = map[string]string{
+	"postgres": "PG_VERSION",
+	"mysql":    "ibdata1",
+	"mongo":    "WiredTiger",
+}
+
+func (e *Engine) SetVerifyType(verifyType string) error {
+	for _, valid := range verifyTypes {
+		if verifyType == valid {
+			e.verifyType = verifyType
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid verify type %q, must be one of: %s", verifyType, strings.Join(verifyTypes, ", "))
+}
+
+func (e *Engine) detectVerifyType(pvc *types.PVCInfo) string {
+	if e.verifyType != "auto" {
+		return e.verifyType
+	}
+
+	// Detect the application from the compose service image, e.g. "postgres:16-alpine"
+	image := strings.ToLower(pvc.ServiceImage)
+	switch {
+	case strings.Contains(image, "postgres"):
+		return "postgres"
+	case strings.Contains(image, "mysql"), strings.Contains(image, "mariadb"):
+		return "mysql"
+	case strings.Contains(image, "mongo"):
+		return "mongo"
+	default:
+		return "basic"
+	}
+}
+
+func (e *Engine) verifyFilesystem(pvc *types.PVCInfo) error {
+	verifyType := e.detectVerifyType(pvc)
+
+	var check string
+	if marker, ok := verifyMarkers[verifyType]; ok {
+		// Data directories are often nested one level deep (e.g. PGDATA=/var/lib/postgresql/data/pgdata)
+		check = fmt.Sprintf(`if find /pvc-data -maxdepth 2 -name %s | grep -q .; then
+        echo "Found %s"
+      else
+        echo "Missing %s, this does not look like a valid %s data directory"
+        exit 1
+      fi`, marker, marker, marker, verifyType)
+	} else {
+		if pvc.MatchedVolume.Size == 0 {
+			logger.Printf("  Skipping verification (source volume is empty)\n")
+			return nil
+		}
+		check = `count=$(find /pvc-data -type f | wc -l)
+      echo "Found $count files"
+      if [ "$count" -eq 0 ]; then
+        echo "PVC contains no files"
+        exit 1
+      fi`
+	}
+
+	podName := fmt.Sprintf("verify-%s-%d", pvc.Name, time.Now().Unix())
+	podYAML := fmt.Sprintf(`apiVersion: v1
+kind: Pod
+metadata:
+  name: %s
+  namespace: %s
+  labels:
+    app.kubernetes.io/managed-by: docker-pvc-migration
+spec:
+  restartPolicy: Never
+%s  containers:
+  - name: verify
+    image: %s
+    command: ["/bin/sh", "-c"]
+    args:
+    - |
+      %s
+    volumeMounts:
+    - name: pvc-volume
+      mountPath: /pvc-data
+      readOnly: true
+  volumes:
+  - name: pvc-volume
+    persistentVolumeClaim:
+      claimName: %s
+`, podName, e.namespaceFor(pvc), e.buildImagePullSecrets(), e.migrationImage, check, pvc.Name)
+
+	logger.Printf("  Verifying PVC %s (%s)...\n", pvc.Name, verifyType)
+	if err := e.createPod(podYAML); err != nil {
+		return fmt.Errorf("failed to create verification pod: %v", err)
+	}
+	defer func() {
+		if err := e.deletePod(podName, e.namespaceFor(pvc)); err != nil {
+			logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("Warning: Could not delete verification pod: %v", err)))
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(e.ctx, 5*time.Minute)
+	defer cancel()
+
+	podErr := e.waitForPodCompletion(ctx, podName, e.namespaceFor(pvc), 0)
+	if err := e.showPodLogs(podName, e.namespaceFor(pvc)); err != nil {
+		logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("Warning: Could not retrieve pod logs: %v", err)))
+	}
+	if podErr != nil {
+		return fmt.Errorf("%s verification failed: %v", verifyType, podErr)
+	}
+
+	return nil
+}
+
+// VerificationResult compares the files in a Docker volume with the files in the PVC
+// it was migrated to
+type VerificationResult struct {
+	PVC         string `json:"pvc"`
+	Namespace   string `json:"namespace"`
+	Volume      string `json:"volume"`
+	SourceFiles int64  `json:"source_files"`
+	TargetFiles int64  `json:"target_files"`
+	SourceBytes int64  `json:"source_bytes"`
+	TargetBytes int64  `json:"target_bytes"`
+	Match       bool   `json:"match"`
+	Error       string `json:"error,omitempty"` // Set when the comparison could not be made
+}
+
+// VerifyMigration compares the file count and total size of every matched Docker
+// volume with its PVC, without migrating anything. PVCs without a matched volume
+// are skipped.
+func (e *Engine) VerifyMigration(pvcs []*types.PVCInfo) []VerificationResult {
+	logger.Println("\n" + color.Header("=== Verifying Migrated Data ==="))
+
+	var results []VerificationResult
+	for _, pvc := range pvcs {
+		if pvc.MatchedVolume == nil {
+			logger.Printf("Skipping %s (no volume selected)\n", pvc.Name)
+			continue
+		}
+
+		result := VerificationResult{PVC: pvc.Name, Namespace: e.namespaceFor(pvc), Volume: pvc.MatchedVolume.Name}
+		logger.Printf("  Comparing %s with PVC %s/%s...\n", pvc.MatchedVolume.Name, result.Namespace, pvc.Name)
+		if err := e.compareFileTotals(pvc, &result); err != nil {
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// compareFileTotals counts the files and bytes on both sides of pvc into result
+func (e *Engine) compareFileTotals(pvc *types.PVCInfo, result *VerificationResult) error {
+	// Every line is "<mount> <files> <bytes>"
+	script := `for dir in /docker-data /pvc-data; do
+        files=$(find "$dir" -type f | wc -l)
+        bytes=$(find "$dir" -type f -exec stat -c %s {} + | awk '{ total += $1 } END { print total + 0 }')
+        echo "$dir $files $bytes"
+      done`
+
+	output, err := e.runComparisonPod(pvc, "verify", script)
+	if err != nil {
+		return err
+	}
+
+	var sawSource, sawTarget bool
+	for _, line := range strings.Split(output, "\n") {
+		var dir string
+		var files, bytes int64
+		if _, err := fmt.Sscanf(line, "%s %d %d", &dir, &files, &bytes); err != nil {
+			continue
+		}
+		switch dir {
+		case "/docker-data":
+			result.SourceFiles, result.SourceBytes, sawSource = files, bytes, true
+		case "/pvc-data":
+			result.TargetFiles, result.TargetBytes, sawTarget = files, bytes, true
+		}
+	}
+	if !sawSource || !sawTarget {
+		return fmt.Errorf("verify pod output is incomplete")
+	}
+
+	result.Match = result.SourceFiles == result.TargetFiles && result.SourceBytes == result.TargetBytes
+	return nil
+}
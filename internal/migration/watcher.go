@@ -0,0 +1,92 @@
+package migration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/fsnotify/fsnotify"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+)
+
+// yamlWatcher records YAML files that are changed by other processes (e.g. GitOps
+// tooling) while a migration is running, so they can be re-read before applying.
+type yamlWatcher struct {
+	watcher  *fsnotify.Watcher
+	mu       sync.Mutex
+	modified map[string]bool
+}
+
+func newYAMLWatcher(directory string) (*yamlWatcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// fsnotify is not recursive, so watch every subdirectory explicitly
+	err = filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return watcher.Add(path)
+		}
+		return nil
+	})
+	if err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	w := &yamlWatcher{
+		watcher:  watcher,
+		modified: make(map[string]bool),
+	}
+	go w.run()
+
+	return w, nil
+}
+
+func (w *yamlWatcher) run() {
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			if !strings.HasSuffix(event.Name, ".yaml") && !strings.HasSuffix(event.Name, ".yml") {
+				continue
+			}
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			w.mu.Lock()
+			w.modified[filepath.Clean(event.Name)] = true
+			w.mu.Unlock()
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			logger.Warnf("    %s\n", color.Warning(fmt.Sprintf("Warning: YAML watcher error: %v", err)))
+		}
+	}
+}
+
+// takeModified reports whether path changed since the last call and clears its flag
+func (w *yamlWatcher) takeModified(path string) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	path = filepath.Clean(path)
+	modified := w.modified[path]
+	delete(w.modified, path)
+	return modified
+}
+
+func (w *yamlWatcher) Close() error {
+	return w.watcher.Close()
+}
@@ -0,0 +1,70 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/notify"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+// SetWebhook posts the outcome of every StartMigration call to webhook, nil to disable it
+func (e *Engine) SetWebhook(webhook *notify.Webhook) {
+	e.webhook = webhook
+}
+
+// migrationResults tracks the outcome of each PVC of a StartMigration call
+type migrationResults struct {
+	mu       sync.Mutex
+	migrated map[*types.PVCInfo]bool
+	failed   map[*types.PVCInfo]error
+}
+
+func newMigrationResults() *migrationResults {
+	return &migrationResults{
+		migrated: make(map[*types.PVCInfo]bool),
+		failed:   make(map[*types.PVCInfo]error),
+	}
+}
+
+func (r *migrationResults) record(pvc *types.PVCInfo, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		r.failed[pvc] = err
+	} else {
+		r.migrated[pvc] = true
+	}
+}
+
+// notifyWebhook posts the results; PVCs that were neither migrated nor failed were skipped
+func (e *Engine) notifyWebhook(pvcs []*types.PVCInfo, results *migrationResults, migrationErr error) {
+	if e.webhook == nil {
+		return
+	}
+
+	payload := notify.Payload{Status: "success", Timestamp: time.Now().UTC(), PVCs: []notify.PVCStatus{}}
+	if migrationErr != nil {
+		payload.Status = "failure"
+	}
+
+	results.mu.Lock()
+	for _, pvc := range pvcs {
+		status := notify.PVCStatus{Name: pvc.Name, Namespace: e.namespaceFor(pvc), Status: "skipped"}
+		if err, ok := results.failed[pvc]; ok {
+			status.Status = "failed"
+			status.Error = err.Error()
+		} else if results.migrated[pvc] {
+			status.Status = "migrated"
+		}
+		payload.PVCs = append(payload.PVCs, status)
+	}
+	results.mu.Unlock()
+
+	if err := e.webhook.Send(context.Background(), payload); err != nil {
+		logger.Warnf("%s\n", color.Warning(fmt.Sprintf("Warning: Could not send webhook notification: %v", err)))
+	}
+}
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SignatureHeader carries the HMAC-SHA256 of the body, like GitHub webhooks
+const SignatureHeader = "X-Signature-256"
+
+// Payload is the JSON body posted when a migration finishes
+type Payload struct {
+	Status    string      `json:"status"` // "success" or "failure"
+	Timestamp time.Time   `json:"timestamp"`
+	PVCs      []PVCStatus `json:"pvcs"`
+}
+
+type PVCStatus struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Status    string `json:"status"` // "migrated", "failed" or "skipped"
+	Error     string `json:"error,omitempty"`
+}
+
+// Webhook posts migration results to a URL
+type Webhook struct {
+	URL     string
+	Secret  string // Signs the body when set
+	Timeout time.Duration
+}
+
+// Sign returns the value of the signature header for body: "sha256=" and the hex HMAC
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send posts payload to the webhook URL and fails on a non-2xx response
+func (w *Webhook) Send(ctx context.Context, payload Payload) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	if w.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, w.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post webhook: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
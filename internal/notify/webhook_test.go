@@ -0,0 +1,96 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// Example from GitHub's webhook documentation
+	got := Sign("It's a Secret to Everybody", []byte("Hello, World!"))
+	want := "sha256=757107ea0eb2509fc211221cce984b8a37570b6d7586c22c46f4379c8b043e17"
+	if got != want {
+		t.Errorf("Sign() = %s, want %s", got, want)
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	tests := []struct {
+		name          string
+		secret        string
+		status        int
+		wantSignature bool
+		wantErr       bool
+	}{
+		{"signed", "s3cret", http.StatusOK, true, false},
+		{"unsigned", "", http.StatusNoContent, false, false},
+		{"server error", "s3cret", http.StatusInternalServerError, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header.Clone()
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			payload := Payload{
+				Status:    "failure",
+				Timestamp: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC),
+				PVCs: []PVCStatus{
+					{Name: "data", Namespace: "default", Status: "migrated"},
+					{Name: "logs", Namespace: "default", Status: "failed", Error: "copy failed"},
+				},
+			}
+			webhook := &Webhook{URL: server.URL, Secret: tt.secret, Timeout: 5 * time.Second}
+			err := webhook.Send(context.Background(), payload)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Send() error = %v, want error %v", err, tt.wantErr)
+			}
+
+			if got := header.Get("Content-Type"); got != "application/json" {
+				t.Errorf("Content-Type = %q", got)
+			}
+			signature := header.Get(SignatureHeader)
+			if !tt.wantSignature {
+				if signature != "" {
+					t.Errorf("unsigned webhook sent signature %q", signature)
+				}
+			} else if !hmac.Equal([]byte(signature), []byte(Sign(tt.secret, body))) {
+				t.Errorf("signature %q does not match the body", signature)
+			}
+
+			var received Payload
+			if err := json.Unmarshal(body, &received); err != nil {
+				t.Fatal(err)
+			}
+			if received.Status != payload.Status || !received.Timestamp.Equal(payload.Timestamp) || len(received.PVCs) != 2 || received.PVCs[1] != payload.PVCs[1] {
+				t.Errorf("received %+v, want %+v", received, payload)
+			}
+		})
+	}
+}
+
+func TestWebhookSendTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	webhook := &Webhook{URL: server.URL, Timeout: 50 * time.Millisecond}
+	if err := webhook.Send(context.Background(), Payload{Status: "success"}); err == nil {
+		t.Error("expected a timeout error")
+	}
+}
@@ -0,0 +1,83 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Output modes accepted by NewEncoder
+const (
+	ModeYAML       = "yaml"
+	ModeJSON       = "json"
+	ModeJSONStream = "json-stream"
+)
+
+// Encoder writes a value in one of the supported output formats
+type Encoder interface {
+	Encode(v interface{}) error
+}
+
+// NewEncoder returns the encoder for mode writing to w
+func NewEncoder(mode string, w io.Writer) (Encoder, error) {
+	switch mode {
+	case ModeYAML:
+		return &YAMLEncoder{w: w}, nil
+	case ModeJSON:
+		return &JSONEncoder{w: w}, nil
+	case ModeJSONStream:
+		return &JSONStreamEncoder{w: w}, nil
+	default:
+		return nil, fmt.Errorf("invalid output mode %q (valid: %s, %s, %s)", mode, ModeYAML, ModeJSON, ModeJSONStream)
+	}
+}
+
+// YAMLEncoder writes values as YAML documents
+type YAMLEncoder struct {
+	w io.Writer
+}
+
+func (e *YAMLEncoder) Encode(v interface{}) error {
+	encoder := yaml.NewEncoder(e.w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(v); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// JSONEncoder writes values as indented JSON, so slices become a single JSON array
+type JSONEncoder struct {
+	w io.Writer
+}
+
+func (e *JSONEncoder) Encode(v interface{}) error {
+	encoder := json.NewEncoder(e.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// JSONStreamEncoder writes one JSON object per line (JSON Lines). Slices are
+// written element by element so the output can be processed as a stream.
+type JSONStreamEncoder struct {
+	w io.Writer
+}
+
+func (e *JSONStreamEncoder) Encode(v interface{}) error {
+	encoder := json.NewEncoder(e.w)
+
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return encoder.Encode(v)
+	}
+
+	for i := 0; i < value.Len(); i++ {
+		if err := encoder.Encode(value.Index(i).Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package output
+
+import (
+	"io"
+	"os"
+)
+
+// Stdout is the special output path meaning "write to standard output"
+const Stdout = "-"
+
+type stdoutWriter struct {
+	io.Writer
+}
+
+// Close is a no-op so callers can always defer Close without closing os.Stdout
+func (stdoutWriter) Close() error {
+	return nil
+}
+
+// Open returns a writer for path, or standard output when path is "-"
+func Open(path string) (io.WriteCloser, error) {
+	if path == Stdout {
+		return stdoutWriter{os.Stdout}, nil
+	}
+	return os.Create(path)
+}
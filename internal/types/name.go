@@ -0,0 +1,25 @@
+package types
+
+import (
+	"regexp"
+	"strings"
+)
+
+// maxPVCNameLength is the longest name Kubernetes accepts for a PVC
+const maxPVCNameLength = 253
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// NormalizePVCName turns a name into a valid PVC name: lowercase, with every run of
+// other characters than letters, digits and dashes replaced by a dash, without
+// leading or trailing dashes and at most 253 characters long. The result is empty
+// when name contains no usable characters.
+func NormalizePVCName(name string) string {
+	name = strings.ToLower(name)
+	name = invalidNameChars.ReplaceAllString(name, "-")
+	name = strings.Trim(name, "-")
+	if len(name) > maxPVCNameLength {
+		name = strings.TrimRight(name[:maxPVCNameLength], "-")
+	}
+	return name
+}
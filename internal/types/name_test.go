@@ -0,0 +1,44 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizePVCName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"data", "data"},
+		{"app-data", "app-data"},
+		{"App_Data", "app-data"},
+		{"app.data", "app-data"},
+		{"app__data", "app-data"},
+		{"app_-_data", "app---data"}, // Dashes are valid and kept
+		{"my data", "my-data"},
+		{"user@host", "user-host"},
+		{"c++", "c"},
+		{"_data_", "data"},
+		{"-data-", "data"},
+		{"__", ""},
+		{"@@", ""},
+		{"", ""},
+		{"ÄppDäta", "ppd-ta"},
+		{strings.Repeat("a", 300), strings.Repeat("a", 253)},
+		// Truncating must not leave a trailing dash
+		{strings.Repeat("a", 252) + "_b", strings.Repeat("a", 252)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NormalizePVCName(tt.name)
+			if got != tt.want {
+				t.Errorf("NormalizePVCName(%q) = %q, want %q", tt.name, got, tt.want)
+			}
+			if again := NormalizePVCName(got); again != got {
+				t.Errorf("NormalizePVCName(%q) = %q, not stable", got, again)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package types
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NamespaceMapping moves the PVCs in namespace Source to namespace Target
+type NamespaceMapping struct {
+	Source string
+	Target string
+}
+
+// ParseNamespaceMapping parses a source:target mapping
+func ParseNamespaceMapping(spec string) (NamespaceMapping, error) {
+	source, target, ok := strings.Cut(spec, ":")
+	source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+	if !ok || source == "" || target == "" {
+		return NamespaceMapping{}, fmt.Errorf("invalid namespace mapping %q, expected source:target", spec)
+	}
+	return NamespaceMapping{Source: source, Target: target}, nil
+}
+
+// NamespaceMapper remaps the namespaces of PVCs, e.g. to move the PVCs of a compose
+// stack into the namespace of a tenant. A nil mapper leaves namespaces unchanged.
+type NamespaceMapper struct {
+	mappings []NamespaceMapping
+}
+
+// NewNamespaceMapper creates a mapper that applies mappings in order. Mapping the
+// same source namespace to two different targets is an error.
+func NewNamespaceMapper(mappings []NamespaceMapping) (*NamespaceMapper, error) {
+	targets := make(map[string]string)
+	for _, mapping := range mappings {
+		if target, ok := targets[mapping.Source]; ok && target != mapping.Target {
+			return nil, fmt.Errorf("namespace %s is mapped to both %s and %s", mapping.Source, target, mapping.Target)
+		}
+		targets[mapping.Source] = mapping.Target
+	}
+	return &NamespaceMapper{mappings: mappings}, nil
+}
+
+// Map returns the namespace PVCs in namespace are moved to
+func (m *NamespaceMapper) Map(namespace string) string {
+	if m == nil {
+		return namespace
+	}
+	for _, mapping := range m.mappings {
+		if namespace == mapping.Source {
+			namespace = mapping.Target
+		}
+	}
+	return namespace
+}
+
+// Apply moves every PVC to its mapped namespace
+func (m *NamespaceMapper) Apply(pvcs []*PVCInfo) {
+	for _, pvc := range pvcs {
+		pvc.Namespace = m.Map(pvc.Namespace)
+	}
+}
@@ -0,0 +1,95 @@
+package types
+
+import "testing"
+
+func TestNamespaceMapper(t *testing.T) {
+	tests := []struct {
+		name     string
+		mappings []NamespaceMapping
+		want     map[string]string // PVC namespace before -> after
+		wantErr  bool
+	}{
+		{
+			name:     "single mapping",
+			mappings: []NamespaceMapping{{Source: "default", Target: "tenant-a"}},
+			want:     map[string]string{"default": "tenant-a"},
+		},
+		{
+			name: "multiple mappings in order",
+			mappings: []NamespaceMapping{
+				{Source: "web", Target: "tenant-a"},
+				{Source: "db", Target: "tenant-b"},
+				{Source: "tenant-a", Target: "tenant-c"},
+			},
+			want: map[string]string{"web": "tenant-c", "db": "tenant-b", "tenant-a": "tenant-c"},
+		},
+		{
+			name:     "unmapped PVCs",
+			mappings: []NamespaceMapping{{Source: "web", Target: "tenant-a"}},
+			want:     map[string]string{"default": "default", "db": "db"},
+		},
+		{
+			name:     "same mapping twice",
+			mappings: []NamespaceMapping{{Source: "web", Target: "tenant-a"}, {Source: "web", Target: "tenant-a"}},
+			want:     map[string]string{"web": "tenant-a"},
+		},
+		{
+			name:     "conflicting mappings",
+			mappings: []NamespaceMapping{{Source: "web", Target: "tenant-a"}, {Source: "web", Target: "tenant-b"}},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mapper, err := NewNamespaceMapper(tt.mappings)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewNamespaceMapper() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			for before, after := range tt.want {
+				pvc := &PVCInfo{Name: "data", Namespace: before}
+				mapper.Apply([]*PVCInfo{pvc})
+				if pvc.Namespace != after {
+					t.Errorf("PVC in %s moved to %s, want %s", before, pvc.Namespace, after)
+				}
+			}
+		})
+	}
+}
+
+func TestNilNamespaceMapper(t *testing.T) {
+	var mapper *NamespaceMapper
+	if got := mapper.Map("web"); got != "web" {
+		t.Errorf("Map() = %s, want the namespace unchanged", got)
+	}
+}
+
+func TestParseNamespaceMapping(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    NamespaceMapping
+		wantErr bool
+	}{
+		{"web:tenant-a", NamespaceMapping{Source: "web", Target: "tenant-a"}, false},
+		{" web : tenant-a ", NamespaceMapping{Source: "web", Target: "tenant-a"}, false},
+		{"web", NamespaceMapping{}, true},
+		{":tenant-a", NamespaceMapping{}, true},
+		{"web:", NamespaceMapping{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := ParseNamespaceMapping(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseNamespaceMapping(%q) error = %v, want error %v", tt.spec, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseNamespaceMapping(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
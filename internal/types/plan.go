@@ -0,0 +1,37 @@
+package types
+
+import "encoding/json"
+
+// MigrationPlan is the planned migration of one Docker volume into a PVC
+type MigrationPlan struct {
+	PVCName      string `json:"pvcName"`
+	Namespace    string `json:"namespace"`
+	SourceVolume string `json:"sourcevolume"`
+	SourceSize   int64  `json:"sourceSize"` // Bytes
+	TargetSize   string `json:"targetSize"`
+	Mountpoint   string `json:"mountpoint"`
+	YAMLFile     string `json:"yamlFile,omitempty"` // File whose PVC definition would be updated
+	YAMLDiff     string `json:"yamlDiff,omitempty"` // Unified diff of the update to YAMLFile
+}
+
+// NewMigrationPlan returns the plan for a PVC with a matched volume
+func NewMigrationPlan(pvc *PVCInfo) MigrationPlan {
+	return MigrationPlan{
+		PVCName:      pvc.Name,
+		Namespace:    pvc.Namespace,
+		SourceVolume: pvc.MatchedVolume.Name,
+		SourceSize:   pvc.MatchedVolume.Size,
+		TargetSize:   pvc.NewSize,
+		Mountpoint:   pvc.MatchedVolume.Mountpoint,
+	}
+}
+
+// MarshalJSON falls back to "0" for a plan without a target size, so consumers
+// can always parse the field as a Kubernetes quantity
+func (p MigrationPlan) MarshalJSON() ([]byte, error) {
+	type plan MigrationPlan
+	if p.TargetSize == "" {
+		p.TargetSize = "0"
+	}
+	return json.Marshal(plan(p))
+}
@@ -1,16 +1,35 @@
 package types
 
+import "time"
+
 type DockerVolumeInfo struct {
-	Name       string
-	Mountpoint string
-	Size       int64
-	SizeHuman  string
+	Name       string            `json:"name" yaml:"name"`
+	Mountpoint string            `json:"mountpoint" yaml:"mountpoint"`
+	Size       int64             `json:"size_bytes" yaml:"size_bytes"`
+	SizeHuman  string            `json:"size_human,omitempty" yaml:"size_human,omitempty"`
+	CreatedAt  time.Time         `json:"created_at,omitempty" yaml:"created_at,omitempty"`
+	Driver     string            `json:"driver,omitempty" yaml:"driver,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
 }
 
 type PVCInfo struct {
-	Name          string
-	Namespace     string
-	RequestedSize string
-	MatchedVolume *DockerVolumeInfo
-	NewSize       string
+	Name          string            `json:"name" yaml:"name"`
+	OriginalName  string            `json:"original_name,omitempty" yaml:"original_name,omitempty"` // Name in the YAML, when NormalizePVCName changed it
+	Namespace     string            `json:"namespace" yaml:"namespace"`
+	RequestedSize string            `json:"requested_size" yaml:"requested_size"`
+	MatchedVolume *DockerVolumeInfo `json:"matched_volume,omitempty" yaml:"matched_volume,omitempty"`
+	NewSize       string            `json:"new_size,omitempty" yaml:"new_size,omitempty"`
+	ServiceImage  string            `json:"service_image,omitempty" yaml:"service_image,omitempty"` // Image of the compose service using the matched volume, if known
+	Annotations   map[string]string `json:"annotations,omitempty" yaml:"annotations,omitempty"`
+
+	StorageClass     string   `json:"storage_class,omitempty" yaml:"storage_class,omitempty"`
+	AccessModes      []string `json:"access_modes,omitempty" yaml:"access_modes,omitempty"`
+	StorageClassHint string   `json:"storage_class_hint,omitempty" yaml:"storage_class_hint,omitempty"` // Storage class suggested from the compose volume driver
+
+	StatefulSet   string `json:"stateful_set,omitempty" yaml:"stateful_set,omitempty"`   // StatefulSet whose volumeClaimTemplates define this PVC, if any
+	Kustomization string `json:"kustomization,omitempty" yaml:"kustomization,omitempty"` // Directory of the kustomization that builds this PVC, if any
+	Created       bool   `json:"created,omitempty" yaml:"created,omitempty"`             // Set once the PVC has been created in the cluster, see Engine.Rollback
+	Existing      bool   `json:"existing,omitempty" yaml:"existing,omitempty"`           // Read from the destination cluster, so the PVC is not created
+
+	Manifest map[string]interface{} `json:"-" yaml:"-"` // Parsed PVC document, applied for PVCs built by a kustomization
 }
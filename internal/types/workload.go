@@ -0,0 +1,12 @@
+package types
+
+// WorkloadRef identifies a Deployment or StatefulSet using a PVC
+type WorkloadRef struct {
+	Kind      string // Deployment or StatefulSet
+	Name      string
+	Namespace string
+}
+
+func (w WorkloadRef) String() string {
+	return w.Kind + "/" + w.Name
+}
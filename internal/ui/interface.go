@@ -5,40 +5,88 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"time"
 
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/config"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/log"
 	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
 
+var logger = log.New("ui")
+
 type Interface struct {
-	reader *bufio.Reader
+	reader         *bufio.Reader
+	batchConfig    *config.Config // Sizes for PVCs that should not be prompted for
+	sizeHeadroom   float64        // Percentage added to the volume size for the suggested PVC size
+	nonInteractive bool           // Use the suggested sizes instead of prompting
 }
 
 func NewInterface() *Interface {
 	return &Interface{
-		reader: bufio.NewReader(os.Stdin),
+		reader:       bufio.NewReader(os.Stdin),
+		sizeHeadroom: 20,
 	}
 }
 
+// SetBatchConfig makes InteractiveSetSizes take sizes from cfg instead of prompting
+// for every PVC listed in it
+func (ui *Interface) SetBatchConfig(cfg *config.Config) {
+	ui.batchConfig = cfg
+}
+
+// SetNonInteractive makes InteractiveSetSizes use the suggested size and storage class
+// of every PVC that is not in the batch config, instead of prompting
+func (ui *Interface) SetNonInteractive(nonInteractive bool) {
+	ui.nonInteractive = nonInteractive
+}
+
 func (ui *Interface) InteractiveSetSizes(pvcs []*types.PVCInfo) error {
-	fmt.Println("\n=== PVC Size Configuration ===")
-	fmt.Println("For each PVC, review the matched Docker volume and set the desired size.")
-	fmt.Println("Use formats like: 1Gi, 500Mi, 2Ti, etc.")
-	fmt.Println()
+	logger.Println("\n" + color.Header("=== PVC Size Configuration ==="))
+	logger.Println("For each PVC, review the matched Docker volume and set the desired size.")
+	logger.Println("Use formats like: 1Gi, 500Mi, 2Ti, etc.")
+	logger.Println()
 
 	for _, pvc := range pvcs {
-		fmt.Printf("PVC: %s (namespace: %s)\n", pvc.Name, pvc.Namespace)
-		fmt.Printf("  Kompose suggested size: %s\n", pvc.RequestedSize)
+		logger.Printf("PVC: %s (namespace: %s)\n", displayName(pvc), pvc.Namespace)
+		logger.Printf("  Kompose suggested size: %s\n", pvc.RequestedSize)
 
 		if pvc.MatchedVolume != nil {
-			fmt.Printf("  Matched Docker volume: %s\n", pvc.MatchedVolume.Name)
-			fmt.Printf("  Current volume size: %s\n", pvc.MatchedVolume.SizeHuman)
-			fmt.Printf("  Volume path: %s\n", pvc.MatchedVolume.Mountpoint)
+			logger.Printf("  Matched Docker volume: %s\n", pvc.MatchedVolume.Name)
+			logger.Printf("  Current volume size: %s\n", pvc.MatchedVolume.SizeHuman)
+			logger.Printf("  Volume path: %s\n", pvc.MatchedVolume.Mountpoint)
 		} else {
-			fmt.Printf("  ⚠️  No matching Docker volume found!\n")
+			logger.Printf("  %s\n", color.Warning("⚠️  No matching Docker volume found!"))
 		}
 
-		fmt.Print("  Enter desired PVC size (or press Enter to use suggested): ")
+		if ui.batchConfig != nil {
+			if record, ok := ui.batchConfig.Lookup(pvc); ok {
+				pvc.NewSize = pvc.RequestedSize
+				if record.NewSize != "" && ui.isValidSize(record.NewSize) {
+					pvc.NewSize = record.NewSize
+				} else if record.NewSize != "" {
+					logger.Printf("  %s\n", color.Warning(fmt.Sprintf("⚠️  Invalid size %q in config, using suggested: %s", record.NewSize, pvc.RequestedSize)))
+				}
+				if pvc.StorageClass == "" {
+					pvc.StorageClass = pvc.StorageClassHint
+				}
+				logger.Printf("  %s\n\n", color.Success(fmt.Sprintf("✅ Set PVC size to: %s (config)", pvc.NewSize)))
+				continue
+			}
+		}
+
+		suggested := ui.suggestedSize(pvc)
+		if ui.nonInteractive {
+			pvc.NewSize = suggested
+			if pvc.StorageClass == "" {
+				pvc.StorageClass = pvc.StorageClassHint
+			}
+			logger.Printf("  %s\n\n", color.Success(fmt.Sprintf("✅ Set PVC size to: %s (suggested)", pvc.NewSize)))
+			continue
+		}
+
+		logger.Printf("  Enter desired PVC size (or press Enter to use %s): ", suggested)
 		input, err := ui.reader.ReadString('\n')
 		if err != nil {
 			return fmt.Errorf("failed to read input: %v", err)
@@ -46,41 +94,129 @@ func (ui *Interface) InteractiveSetSizes(pvcs []*types.PVCInfo) error {
 
 		input = strings.TrimSpace(input)
 		if input == "" {
-			pvc.NewSize = pvc.RequestedSize
+			pvc.NewSize = suggested
 		} else {
 			if ui.isValidSize(input) {
 				pvc.NewSize = input
 			} else {
-				fmt.Printf("  ⚠️  Invalid size format, using suggested: %s\n", pvc.RequestedSize)
-				pvc.NewSize = pvc.RequestedSize
+				logger.Printf("  %s\n", color.Warning(fmt.Sprintf("⚠️  Invalid size format, using suggested: %s", suggested)))
+				pvc.NewSize = suggested
 			}
 		}
 
-		fmt.Printf("  ✅ Set PVC size to: %s\n", pvc.NewSize)
-		fmt.Println()
+		logger.Printf("  %s\n", color.Success(fmt.Sprintf("✅ Set PVC size to: %s", pvc.NewSize)))
+
+		if pvc.StorageClassHint != "" {
+			if pvc.StorageClass == "" {
+				pvc.StorageClass = pvc.StorageClassHint
+			}
+
+			logger.Printf("  Enter storage class (or press Enter to use %s): ", pvc.StorageClass)
+			input, err := ui.reader.ReadString('\n')
+			if err != nil {
+				return fmt.Errorf("failed to read input: %v", err)
+			}
+
+			if input = strings.TrimSpace(input); input != "" {
+				pvc.StorageClass = input
+			}
+			logger.Printf("  %s\n", color.Success(fmt.Sprintf("✅ Set storage class to: %s", pvc.StorageClass)))
+		}
+		logger.Println()
 	}
 
 	return nil
 }
 
+// displayName returns the name of the PVC, followed by the name in the YAML when it was normalized
+func displayName(pvc *types.PVCInfo) string {
+	if pvc.OriginalName != "" {
+		return fmt.Sprintf("%s (normalized from %s)", pvc.Name, pvc.OriginalName)
+	}
+	return pvc.Name
+}
+
 func (ui *Interface) isValidSize(size string) bool {
 	_, err := resource.ParseQuantity(size)
 	return err == nil
 }
 
 func (ui *Interface) PrintSummary(pvcs []*types.PVCInfo) {
-	fmt.Println("\n=== Migration Summary ===")
-	fmt.Printf("Found %d PVCs to migrate:\n\n", len(pvcs))
+	logger.Println("\n" + color.Header("=== Migration Summary ==="))
+	logger.Printf("Found %d PVCs to migrate:\n\n", len(pvcs))
 
 	for _, pvc := range pvcs {
-		fmt.Printf("PVC: %s/%s\n", pvc.Namespace, pvc.Name)
-		fmt.Printf("  Size: %s → %s\n", pvc.RequestedSize, pvc.NewSize)
+		logger.Printf("PVC: %s/%s\n", pvc.Namespace, displayName(pvc))
+		logger.Printf("  Size: %s → %s\n", pvc.RequestedSize, pvc.NewSize)
 
 		if pvc.MatchedVolume != nil {
-			fmt.Printf("  Source: %s (%s)\n", pvc.MatchedVolume.Name, pvc.MatchedVolume.SizeHuman)
+			logger.Printf("  Source: %s (%s)\n", pvc.MatchedVolume.Name, pvc.MatchedVolume.SizeHuman)
 		} else {
-			fmt.Printf("  Source: ⚠️  No matching volume found\n")
+			logger.Printf("  Source: %s\n", color.Warning("⚠️  No matching volume found"))
 		}
-		fmt.Println()
+		logger.Println()
+	}
+}
+
+func (ui *Interface) PrintUnmatchedVolumes(volumes []*types.DockerVolumeInfo) {
+	if len(volumes) == 0 {
+		return
+	}
+
+	logger.Println(color.Header("=== Unmatched volumes ==="))
+	logger.Printf("%d Docker volumes were not matched to any PVC:\n\n", len(volumes))
+
+	for _, volume := range volumes {
+		logger.Printf("  %s\n", color.Warning(fmt.Sprintf("⚠️  %s (%s)", volume.Name, volume.SizeHuman)))
+	}
+	logger.Println()
+}
+
+// PrintTimeEstimate prints how long copying each matched volume will take at the given throughput
+func (ui *Interface) PrintTimeEstimate(pvcs []*types.PVCInfo, throughputBytesPerSec int64) {
+	if throughputBytesPerSec <= 0 {
+		return
+	}
+
+	logger.Println("\n" + color.Header("=== Estimated Migration Time ==="))
+
+	var totalBytes int64
+	for _, pvc := range pvcs {
+		if pvc.MatchedVolume == nil {
+			continue
+		}
+
+		totalBytes += pvc.MatchedVolume.Size
+		estimate := time.Duration(pvc.MatchedVolume.Size/throughputBytesPerSec) * time.Second
+		logger.Printf("  %s/%s (%s): %s\n", pvc.Namespace, pvc.Name, pvc.MatchedVolume.SizeHuman, formatEstimate(estimate))
+	}
+
+	total := time.Duration(totalBytes/throughputBytesPerSec) * time.Second
+	logger.Printf("Total: %s\n\n", formatEstimate(total))
+}
+
+// formatEstimate formats a duration for humans, e.g. "~2 hours 15 minutes"
+func formatEstimate(d time.Duration) string {
+	if d < time.Minute {
+		return "less than a minute"
+	}
+
+	hours := int(d.Hours())
+	minutes := int(d.Minutes()) % 60
+
+	var parts []string
+	if hours > 0 {
+		parts = append(parts, pluralize(hours, "hour"))
+	}
+	if minutes > 0 {
+		parts = append(parts, pluralize(minutes, "minute"))
+	}
+	return "~" + strings.Join(parts, " ")
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
 	}
+	return fmt.Sprintf("%d %ss", n, unit)
 }
@@ -0,0 +1,31 @@
+package ui
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestInteractiveSetSizesNonInteractive(t *testing.T) {
+	ui := NewInterface()
+	ui.SetNonInteractive(true)
+	// Any read from stdin would fail the test
+	ui.reader = bufio.NewReader(strings.NewReader(""))
+
+	pvcs := []*types.PVCInfo{
+		{Name: "data", Namespace: "default", RequestedSize: "1Gi", StorageClassHint: "fast"},
+		{Name: "logs", Namespace: "default", RequestedSize: "5Gi", StorageClass: "standard", StorageClassHint: "fast"},
+	}
+	if err := ui.InteractiveSetSizes(pvcs); err != nil {
+		t.Fatal(err)
+	}
+
+	if pvcs[0].NewSize != "1Gi" || pvcs[0].StorageClass != "fast" {
+		t.Errorf("data: size %s class %s, want 1Gi fast", pvcs[0].NewSize, pvcs[0].StorageClass)
+	}
+	if pvcs[1].NewSize != "5Gi" || pvcs[1].StorageClass != "standard" {
+		t.Errorf("logs: size %s class %s, want 5Gi standard", pvcs[1].NewSize, pvcs[1].StorageClass)
+	}
+}
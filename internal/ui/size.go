@@ -0,0 +1,40 @@
+package ui
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const gibibyte = 1 << 30
+
+// SuggestSize returns volumeBytes plus headroomPct percent, rounded up to whole Gi
+// (at least 1Gi), e.g. "3Gi"
+func SuggestSize(volumeBytes int64, headroomPct float64) string {
+	gi := int64(math.Ceil(float64(volumeBytes) * (1 + headroomPct/100) / gibibyte))
+	if gi < 1 {
+		gi = 1
+	}
+	return fmt.Sprintf("%dGi", gi)
+}
+
+func (ui *Interface) SetSizeHeadroom(headroomPct float64) {
+	ui.sizeHeadroom = headroomPct
+}
+
+// suggestedSize is the default size offered for pvc: the matched volume size with
+// headroom, unless the YAML already requests more
+func (ui *Interface) suggestedSize(pvc *types.PVCInfo) string {
+	if pvc.MatchedVolume == nil {
+		return pvc.RequestedSize
+	}
+
+	suggested := SuggestSize(pvc.MatchedVolume.Size, ui.sizeHeadroom)
+	requested, err := resource.ParseQuantity(pvc.RequestedSize)
+	if err == nil && requested.Cmp(resource.MustParse(suggested)) > 0 {
+		return pvc.RequestedSize
+	}
+	return suggested
+}
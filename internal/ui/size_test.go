@@ -0,0 +1,57 @@
+package ui
+
+import (
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestSuggestSize(t *testing.T) {
+	tests := []struct {
+		name        string
+		volumeBytes int64
+		headroomPct float64
+		want        string
+	}{
+		{"empty volume", 0, 20, "1Gi"},
+		{"one byte", 1, 0, "1Gi"},
+		{"exactly 1Gi", gibibyte, 0, "1Gi"},
+		{"one byte over 1Gi", gibibyte + 1, 0, "2Gi"},
+		{"1Gi with headroom", gibibyte, 20, "2Gi"},
+		{"fractional Gi", gibibyte * 5 / 2, 0, "3Gi"},
+		{"headroom up to a whole Gi", gibibyte * 5 / 2, 20, "3Gi"},
+		{"headroom past a whole Gi", gibibyte * 5 / 2, 21, "4Gi"},
+		{"large volume", 100 * gibibyte, 50, "150Gi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SuggestSize(tt.volumeBytes, tt.headroomPct); got != tt.want {
+				t.Errorf("SuggestSize(%d, %v) = %s, want %s", tt.volumeBytes, tt.headroomPct, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSuggestedSize(t *testing.T) {
+	tests := []struct {
+		name string
+		pvc  types.PVCInfo
+		want string
+	}{
+		{"no volume", types.PVCInfo{RequestedSize: "100Mi"}, "100Mi"},
+		{"volume larger than request", types.PVCInfo{RequestedSize: "100Mi", MatchedVolume: &types.DockerVolumeInfo{Size: 2 * gibibyte}}, "3Gi"},
+		{"request larger than volume", types.PVCInfo{RequestedSize: "10Gi", MatchedVolume: &types.DockerVolumeInfo{Size: gibibyte}}, "10Gi"},
+		{"invalid request", types.PVCInfo{RequestedSize: "lots", MatchedVolume: &types.DockerVolumeInfo{Size: gibibyte}}, "2Gi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ui := NewInterface()
+			ui.SetSizeHeadroom(20)
+			if got := ui.suggestedSize(&tt.pvc); got != tt.want {
+				t.Errorf("suggestedSize() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// inlineBackupDir is the backup directory value that puts backups next to the original file
+const inlineBackupDir = "inline"
+
+// backupFile writes content, the original content of filePath, to the backup location
+func (u *Updater) backupFile(filePath string, content []byte) error {
+	if u.backupDir == "" {
+		return nil
+	}
+
+	backupPath := filePath + ".bak"
+	if u.backupDir != inlineBackupDir {
+		if err := os.MkdirAll(u.backupDir, 0755); err != nil {
+			return err
+		}
+		backupPath = filepath.Join(u.backupDir,
+			fmt.Sprintf("%s.bak.%s", filepath.Base(filePath), time.Now().Format("20060102-150405")))
+	}
+
+	if err := writeFileAtomic(backupPath, content, 0644); err != nil {
+		return err
+	}
+	logger.Printf("  Backed up %s to %s\n", filePath, backupPath)
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file and renames it to path, so an
+// interrupted write never leaves a partial file behind
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmpPath, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
@@ -0,0 +1,75 @@
+package yaml
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+)
+
+func TestUpdateYAMLFilesBackup(t *testing.T) {
+	// Odd spacing, comments and a missing final newline must all survive in the backup
+	original := []byte("# app data\nkind: PersistentVolumeClaim\nmetadata:\n  name:   data\nspec:\n  resources:\n    requests:\n      storage: 1Gi   # small")
+
+	tests := []struct {
+		name      string
+		backupDir func(dir string) string
+		pattern   func(dir string) string
+	}{
+		{
+			name:      "inline",
+			backupDir: func(string) string { return inlineBackupDir },
+			pattern:   func(dir string) string { return filepath.Join(dir, "yaml", "pvc.yaml.bak") },
+		},
+		{
+			name:      "directory",
+			backupDir: func(dir string) string { return filepath.Join(dir, "backups") },
+			pattern:   func(dir string) string { return filepath.Join(dir, "backups", "pvc.yaml.bak.*") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			yamlDir := filepath.Join(dir, "yaml")
+			if err := os.Mkdir(yamlDir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(yamlDir, "pvc.yaml")
+			if err := os.WriteFile(path, original, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			u := NewUpdater()
+			u.SetBackupDir(tt.backupDir(dir))
+			if err := u.UpdateYAMLFiles(yamlDir, []*types.PVCInfo{{Name: "data", Namespace: "default", NewSize: "2Gi"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			backups, err := filepath.Glob(tt.pattern(dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(backups) != 1 {
+				t.Fatalf("found backups %v, want one", backups)
+			}
+			backup, err := os.ReadFile(backups[0])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(backup, original) {
+				t.Errorf("backup differs from the original:\n%q\nwant:\n%q", backup, original)
+			}
+
+			updated, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bytes.Equal(updated, original) {
+				t.Error("file was not updated")
+			}
+		})
+	}
+}
@@ -0,0 +1,252 @@
+package yaml
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// documentEditor changes scalar values and adds keys in the text of a YAML
+// document at the positions of its parsed nodes. Everything it does not touch,
+// comments and formatting included, stays byte for byte the same.
+type documentEditor struct {
+	lines   []string         // Lines of the document, with their line endings
+	newline string           // Line ending of added lines
+	splices map[int][]splice // Replaced ranges per line index
+	inserts map[int][]string // Lines added before the line index
+	err     error            // First edit that could not be made
+}
+
+// splice replaces the bytes start:end of a line with text
+type splice struct {
+	start, end int
+	text       string
+}
+
+func newDocumentEditor(document string) *documentEditor {
+	newline := "\n"
+	if strings.Contains(document, "\r\n") {
+		newline = "\r\n"
+	}
+	return &documentEditor{
+		lines:   strings.SplitAfter(document, "\n"),
+		newline: newline,
+		splices: make(map[int][]splice),
+		inserts: make(map[int][]string),
+	}
+}
+
+// set sets key in mapping to a scalar and returns the previous value. parentKey
+// is the key node mapping is the value of; a missing key is added below it.
+// Every key is set at most once per document.
+func (ed *documentEditor) set(parentKey, mapping *yaml.Node, key, value string) string {
+	if existing := mappingValue(mapping, key); existing != nil {
+		if existing.Value != value {
+			ed.replaceScalar(existing, value)
+		}
+		return existing.Value
+	}
+
+	line, err := formatEntry(key, value)
+	if err != nil {
+		ed.fail(err)
+		return ""
+	}
+	ed.add(parentKey, mapping, key, line)
+	return ""
+}
+
+// setMapping adds the entries to the mapping under key in mapping, creating the
+// key when it is missing
+func (ed *documentEditor) setMapping(parentKey, mapping *yaml.Node, key string, keys []string, values map[string]string) {
+	keyNode, existing := mappingEntry(mapping, key)
+	if existing != nil {
+		if existing.Kind != yaml.MappingNode {
+			ed.fail(fmt.Errorf("%s is not a mapping", key))
+			return
+		}
+		for _, k := range keys {
+			ed.set(keyNode, existing, k, values[k])
+		}
+		return
+	}
+
+	lines := []string{key + ":"}
+	for _, k := range keys {
+		entry, err := formatEntry(k, values[k])
+		if err != nil {
+			ed.fail(err)
+			return
+		}
+		lines = append(lines, "  "+entry)
+	}
+	ed.add(parentKey, mapping, key, lines...)
+}
+
+// replaceScalar replaces the text of a single-line plain or quoted scalar, keeping
+// its quoting style
+func (ed *documentEditor) replaceScalar(node *yaml.Node, value string) {
+	if node.Kind != yaml.ScalarNode {
+		ed.fail(fmt.Errorf("line %d: expected a scalar", node.Line))
+		return
+	}
+	index := node.Line - 1
+	if index < 0 || index >= len(ed.lines) {
+		ed.fail(fmt.Errorf("line %d is outside the document", node.Line))
+		return
+	}
+	line := ed.lines[index]
+	start := columnOffset(line, node.Column)
+	end := -1
+	if start >= 0 {
+		end = scalarEnd(line, start, node)
+	}
+	if end < 0 {
+		ed.fail(fmt.Errorf("line %d: cannot edit value %q in place", node.Line, node.Value))
+		return
+	}
+
+	text, err := formatScalar(value, node.Style)
+	if err != nil {
+		ed.fail(err)
+		return
+	}
+	ed.splices[index] = append(ed.splices[index], splice{start: start, end: end, text: text})
+}
+
+// add inserts the lines of key as the first entries of a block mapping, right
+// below the key that holds the mapping
+func (ed *documentEditor) add(parentKey, mapping *yaml.Node, key string, lines ...string) {
+	if mapping.Kind != yaml.MappingNode || mapping.Style&yaml.FlowStyle != 0 || len(mapping.Content) == 0 ||
+		parentKey == nil || mapping.Line <= parentKey.Line || parentKey.Line >= len(ed.lines) {
+		ed.fail(fmt.Errorf("line %d: cannot add %s to this mapping in place", mapping.Line, key))
+		return
+	}
+
+	// Inserted before the line following the parent key, indented like the mapping's keys
+	indent := strings.Repeat(" ", mapping.Column-1)
+	for _, line := range lines {
+		ed.inserts[parentKey.Line] = append(ed.inserts[parentKey.Line], indent+line+ed.newline)
+	}
+}
+
+func (ed *documentEditor) fail(err error) {
+	if ed.err == nil {
+		ed.err = err
+	}
+}
+
+// String returns the edited document
+func (ed *documentEditor) String() string {
+	var b strings.Builder
+	for i, line := range ed.lines {
+		for _, inserted := range ed.inserts[i] {
+			b.WriteString(inserted)
+		}
+
+		// Later splices first, so the offsets of earlier ones stay valid
+		splices := ed.splices[i]
+		sort.Slice(splices, func(a, b int) bool { return splices[a].start > splices[b].start })
+		for _, s := range splices {
+			line = line[:s.start] + s.text + line[s.end:]
+		}
+		b.WriteString(line)
+	}
+	return b.String()
+}
+
+// columnOffset returns the byte offset of the 1-based character column in line, or -1
+func columnOffset(line string, column int) int {
+	n := 1
+	for offset := range line {
+		if n == column {
+			return offset
+		}
+		n++
+	}
+	return -1
+}
+
+// scalarEnd returns the byte offset in line where the scalar node starting at start
+// ends, or -1 when it is not a single-line plain or quoted scalar
+func scalarEnd(line string, start int, node *yaml.Node) int {
+	rest := line[start:]
+	switch node.Style {
+	case 0:
+		if node.Value == "" || !strings.HasPrefix(rest, node.Value) {
+			return -1
+		}
+		return start + len(node.Value)
+	case yaml.DoubleQuotedStyle:
+		for i := 1; i < len(rest); i++ {
+			switch rest[i] {
+			case '\\':
+				i++
+			case '"':
+				return start + i + 1
+			}
+		}
+	case yaml.SingleQuotedStyle:
+		for i := 1; i < len(rest); i++ {
+			if rest[i] != '\'' {
+				continue
+			}
+			if i+1 < len(rest) && rest[i+1] == '\'' {
+				i++
+				continue
+			}
+			return start + i + 1
+		}
+	}
+	return -1
+}
+
+// formatScalar renders value as a scalar in style, quoting a plain value that
+// would otherwise not be read back as the same string
+func formatScalar(value string, style yaml.Style) (string, error) {
+	out, err := yaml.Marshal(&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Style: style, Value: value})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %q: %v", value, err)
+	}
+	text := strings.TrimSuffix(string(out), "\n")
+	if strings.Contains(text, "\n") {
+		return "", fmt.Errorf("cannot write multi-line value %q in place", value)
+	}
+	return text, nil
+}
+
+// formatEntry renders a "key: value" mapping entry
+func formatEntry(key, value string) (string, error) {
+	k, err := formatScalar(key, 0)
+	if err != nil {
+		return "", err
+	}
+	v, err := formatScalar(value, 0)
+	if err != nil {
+		return "", err
+	}
+	return k + ": " + v, nil
+}
+
+// mappingEntry returns the key and value nodes of key in a mapping node, or nils
+// when node is not a mapping or has no such key
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// mappingValue returns the value of key in a mapping node, or nil when node is not
+// a mapping or has no such key
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	_, value := mappingEntry(node, key)
+	return value
+}
@@ -0,0 +1,33 @@
+package yaml
+
+import (
+	"os"
+	"regexp"
+)
+
+var envReferencePattern = regexp.MustCompile(`\$\{([^}]+)\}`)
+
+// ExpandEnv substitutes $VAR and ${VAR} references in content with values from the environment
+func ExpandEnv(content string) string {
+	return os.ExpandEnv(content)
+}
+
+// MissingEnvVars returns the ${VAR} references in content that ExpandEnv cannot resolve
+func MissingEnvVars(content string) []string {
+	var missing []string
+	seen := make(map[string]bool)
+
+	for _, match := range envReferencePattern.FindAllStringSubmatch(content, -1) {
+		name := match[1]
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		if _, ok := os.LookupEnv(name); !ok {
+			missing = append(missing, name)
+		}
+	}
+
+	return missing
+}
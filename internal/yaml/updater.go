@@ -4,154 +4,424 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/cloud"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/color"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/diff"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/log"
 	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
 	"gopkg.in/yaml.v3"
 )
 
-type Updater struct{}
+var logger = log.New("yaml")
+
+type Updater struct {
+	expandEnv     bool
+	cloudProvider *cloud.Provider
+	showDiff      bool
+	backupDir     string // Directory for backups of updated files, "inline" for next to the file, "" for none
+	namespace     string // Namespace written into every PVC, "" to keep the YAML namespace
+	maxDepth      int    // Subdirectory levels to search for YAML files, negative for unlimited
+
+	namespaceMapper *types.NamespaceMapper // Remaps the namespace after namespace is applied
+}
 
 func NewUpdater() *Updater {
-	return &Updater{}
+	return &Updater{maxDepth: -1}
+}
+
+func (u *Updater) SetExpandEnv(expandEnv bool) {
+	u.expandEnv = expandEnv
+}
+
+func (u *Updater) SetCloudProvider(provider *cloud.Provider) {
+	u.cloudProvider = provider
+}
+
+func (u *Updater) SetShowDiff(showDiff bool) {
+	u.showDiff = showDiff
+}
+
+// SetBackupDir makes the updater back up every file before changing it, to
+// <dir>/<filename>.bak.<timestamp>, or to <file>.bak when dir is "inline"
+func (u *Updater) SetBackupDir(dir string) {
+	u.backupDir = dir
+}
+
+// SetMaxDepth limits how many subdirectory levels are searched for YAML files, see WalkYAMLFiles
+func (u *Updater) SetMaxDepth(depth int) {
+	u.maxDepth = depth
+}
+
+// SetNamespaceOverride rewrites the metadata.namespace of every updated PVC that
+// declares a different namespace, matching kubernetes.Parser.SetNamespaceOverride
+func (u *Updater) SetNamespaceOverride(namespace string) {
+	u.namespace = namespace
+}
+
+// SetNamespaceMapper rewrites the metadata.namespace of every updated PVC to the namespace
+// mapper moves it to, matching the namespaces the mapper gave the parsed PVCs
+func (u *Updater) SetNamespaceMapper(mapper *types.NamespaceMapper) {
+	u.namespaceMapper = mapper
+}
+
+// FileDiff is the change UpdateYAMLFiles would make to one file
+type FileDiff struct {
+	File string   `json:"file"`
+	PVCs []string `json:"pvcs"` // namespace/name of the PVCs updated in the file
+	Diff string   `json:"diff"` // Unified diff of the file
 }
 
 func (u *Updater) UpdateYAMLFiles(directory string, pvcs []*types.PVCInfo) error {
-	fmt.Println("\nUpdating YAML files with new PVC sizes...")
+	logger.Println("\nUpdating YAML files with new PVC sizes...")
 
-	// Walk through all YAML files in the directory
-	err := filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+	updated := make(map[string]bool)
+	err := WalkYAMLFiles(directory, u.maxDepth, func(path string) error {
+		keys, err := u.updateYAMLFile(path, pvcs)
+		for _, key := range keys {
+			updated[key] = true
+		}
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update YAML files: %v", err)
+	}
+
+	// A kustomization can rename and patch its PVCs, so their documents are often
+	// not found under the name they are created with
+	for _, pvc := range pvcs {
+		if pvc.Kustomization != "" && !updated[pvc.Namespace+"/"+pvc.Name] {
+			logger.Warnf("  %s/%s: %s\n", pvc.Namespace, pvc.Name, color.Warning(fmt.Sprintf(
+				"Warning: Built by kustomization %s, its new size is not written back; set it in the base or an overlay patch", pvc.Kustomization)))
+		}
+	}
+
+	logger.Println(color.Success("✅ YAML files updated successfully!"))
+	return nil
+}
+
+// DiffYAMLFiles returns the changes UpdateYAMLFiles would make, without writing any file
+func (u *Updater) DiffYAMLFiles(directory string, pvcs []*types.PVCInfo) ([]FileDiff, error) {
+	var diffs []FileDiff
+	err := WalkYAMLFiles(directory, u.maxDepth, func(path string) error {
+		content, newContent, updated, err := u.renderYAMLFile(path, pvcs)
+		if err != nil || len(updated) == 0 {
+			return err
+		}
+		diffs = append(diffs, FileDiff{
+			File: path,
+			PVCs: updated,
+			Diff: diff.UnifiedDiff(content, newContent, path),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to diff YAML files: %v", err)
+	}
+	return diffs, nil
+}
+
+// WalkYAMLFiles calls fn for every .yaml and .yml file in directory and its
+// subdirectories up to maxDepth levels deep; 0 only walks directory itself and a
+// negative maxDepth walks all subdirectories
+func WalkYAMLFiles(directory string, maxDepth int, fn func(path string) error) error {
+	return filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		if info.IsDir() {
+			if maxDepth >= 0 && path != directory && directoryDepth(directory, path) > maxDepth {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		if !strings.HasSuffix(path, ".yaml") && !strings.HasSuffix(path, ".yml") {
 			return nil
 		}
 
-		return u.updateYAMLFile(path, pvcs)
+		return fn(path)
 	})
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to update YAML files: %v", err)
+// directoryDepth returns how many levels dir is below root
+func directoryDepth(root, dir string) int {
+	rel, err := filepath.Rel(root, dir)
+	if err != nil || rel == "." {
+		return 0
 	}
-
-	fmt.Println("✅ YAML files updated successfully!")
-	return nil
+	return strings.Count(rel, string(filepath.Separator)) + 1
 }
 
-func (u *Updater) updateYAMLFile(filePath string, pvcs []*types.PVCInfo) error {
-	// Read the file
+// renderYAMLFile returns the content of filePath before and after updating its
+// PVCs, and the namespace/name of the PVCs that changed
+func (u *Updater) renderYAMLFile(filePath string, pvcs []*types.PVCInfo) (string, string, []string, error) {
 	content, err := os.ReadFile(filePath)
 	if err != nil {
-		return fmt.Errorf("failed to read file %s: %v", filePath, err)
+		return "", "", nil, fmt.Errorf("failed to read file %s: %v", filePath, err)
 	}
 
 	// Split content by document separator (---)
 	documents := strings.Split(string(content), "\n---\n")
 	var updatedDocuments []string
-	hasUpdates := false
+	var updated []string
 
 	for _, doc := range documents {
 		if strings.TrimSpace(doc) == "" {
 			continue
 		}
 
-		updatedDoc, updated := u.updateDocumentIfPVC(doc, pvcs)
-		if updated {
-			hasUpdates = true
-			fmt.Printf("Updated PVC in %s\n", filePath)
+		updatedDoc, pvc := u.updateDocumentIfPVC(doc, pvcs)
+		if pvc != nil {
+			updated = append(updated, pvc.Namespace+"/"+pvc.Name)
+		} else {
+			var renamed []string
+			updatedDoc, renamed = u.updateClaimReferences(doc, pvcs)
+			updated = append(updated, renamed...)
 		}
 		updatedDocuments = append(updatedDocuments, updatedDoc)
 	}
 
+	// Join documents back with separator
+	return string(content), strings.Join(updatedDocuments, "\n---\n"), updated, nil
+}
+
+// updateYAMLFile updates the PVCs in filePath and returns the namespace/name of
+// the PVCs it changed
+func (u *Updater) updateYAMLFile(filePath string, pvcs []*types.PVCInfo) ([]string, error) {
+	content, newContent, updated, err := u.renderYAMLFile(filePath, pvcs)
+	if err != nil {
+		return nil, err
+	}
+
 	// Only write back if we made changes
-	if hasUpdates {
-		// Join documents back with separator
-		newContent := strings.Join(updatedDocuments, "\n---\n")
+	if len(updated) > 0 {
+		logger.Printf("Updated PVC in %s\n", filePath)
+
+		if u.showDiff {
+			logger.Printf("\n%s:\n%s", filePath, diff.ColorizedUnifiedDiff(content, newContent))
+		}
+
+		if err := u.backupFile(filePath, []byte(content)); err != nil {
+			return nil, fmt.Errorf("failed to back up %s: %v", filePath, err)
+		}
 
 		// Write back to file
 		err = os.WriteFile(filePath, []byte(newContent), 0644)
 		if err != nil {
-			return fmt.Errorf("failed to write file %s: %v", filePath, err)
+			return nil, fmt.Errorf("failed to write file %s: %v", filePath, err)
 		}
 	}
 
-	return nil
+	return updated, nil
 }
 
-func (u *Updater) updateDocumentIfPVC(document string, pvcs []*types.PVCInfo) (string, bool) {
-	// Parse the YAML document
-	var obj map[string]interface{}
-	err := yaml.Unmarshal([]byte(document), &obj)
-	if err != nil {
+func (u *Updater) updateDocumentIfPVC(document string, pvcs []*types.PVCInfo) (string, *types.PVCInfo) {
+	// Parse the original text, so ${VAR} references are written back as they are;
+	// values are only expanded to compare them with the parsed PVCs
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(document), &root); err != nil || len(root.Content) == 0 {
 		// If we can't parse it, return unchanged
-		return document, false
+		return document, nil
 	}
+	obj := root.Content[0]
 
 	// Check if this is a PVC
-	kind, ok := obj["kind"].(string)
-	if !ok || kind != "PersistentVolumeClaim" {
-		return document, false
+	kind := mappingValue(obj, "kind")
+	if kind == nil || u.value(kind) != "PersistentVolumeClaim" {
+		return document, nil
 	}
 
 	// Get the PVC name and namespace
-	metadata, ok := obj["metadata"].(map[string]interface{})
-	if !ok {
-		return document, false
-	}
-
-	name, ok := metadata["name"].(string)
-	if !ok {
-		return document, false
+	metadataKey, metadata := mappingEntry(obj, "metadata")
+	name := mappingValue(metadata, "name")
+	if name == nil || name.Kind != yaml.ScalarNode {
+		return document, nil
 	}
 
 	namespace := "default"
-	if ns, ok := metadata["namespace"].(string); ok {
-		namespace = ns
+	namespaceNode := mappingValue(metadata, "namespace")
+	if namespaceNode != nil {
+		namespace = u.value(namespaceNode)
 	}
+	// The override comes before the map, in the same order the parsed PVCs got them
+	if u.namespace != "" {
+		namespace = u.namespace
+	}
+	namespace = u.namespaceMapper.Map(namespace)
+	// PVCs without a namespace are applied with -n, so only a conflicting one is rewritten
+	namespaceChanged := namespaceNode != nil && u.value(namespaceNode) != namespace
 
 	// Find matching PVC from our list
 	var matchingPVC *types.PVCInfo
 	for _, pvc := range pvcs {
-		if pvc.Name == name && pvc.Namespace == namespace {
+		if (pvc.Name == u.value(name) || pvc.OriginalName == u.value(name)) && pvc.Namespace == namespace {
 			matchingPVC = pvc
 			break
 		}
 	}
 
-	if matchingPVC == nil || matchingPVC.NewSize == "" {
-		return document, false
+	if matchingPVC == nil {
+		return document, nil
 	}
 
-	// Update the storage size
-	spec, ok := obj["spec"].(map[string]interface{})
-	if !ok {
-		return document, false
+	specKey, spec := mappingEntry(obj, "spec")
+	if spec == nil || spec.Kind != yaml.MappingNode {
+		return document, nil
+	}
+
+	// The storage class may have been changed with --default-storage-class or the prompt
+	var currentStorageClass string
+	class := mappingValue(spec, "storageClassName")
+	if class != nil {
+		currentStorageClass = u.value(class)
+	}
+	storageClassChanged := matchingPVC.StorageClass != "" && currentStorageClass != matchingPVC.StorageClass
+	// A name that is not valid in Kubernetes is replaced by the normalized one
+	nameChanged := u.value(name) != matchingPVC.Name
+	if matchingPVC.NewSize == "" && !storageClassChanged && !namespaceChanged && !nameChanged {
+		return document, nil
+	}
+
+	requestsKey, requests := mappingEntry(mappingValue(spec, "resources"), "requests")
+	if requests == nil || requests.Kind != yaml.MappingNode {
+		return document, nil
 	}
 
-	resources, ok := spec["resources"].(map[string]interface{})
-	if !ok {
-		return document, false
+	// Only the changed values are replaced in the original text, so comments and
+	// formatting survive the update
+	editor := newDocumentEditor(document)
+
+	if nameChanged {
+		logger.Printf("  %s/%s: name %s → %s\n", namespace, matchingPVC.Name, name.Value, matchingPVC.Name)
+		editor.set(metadataKey, metadata, "name", matchingPVC.Name)
 	}
 
-	requests, ok := resources["requests"].(map[string]interface{})
-	if !ok {
-		return document, false
+	if namespaceChanged {
+		logger.Printf("  %s/%s: namespace %s → %s\n", namespace, name.Value, namespaceNode.Value, namespace)
+		editor.set(metadataKey, metadata, "namespace", namespace)
 	}
 
 	// Update the storage size
-	oldSize := requests["storage"]
-	requests["storage"] = matchingPVC.NewSize
+	if matchingPVC.NewSize != "" {
+		oldSize := editor.set(requestsKey, requests, "storage", matchingPVC.NewSize)
 
-	fmt.Printf("  %s/%s: %v → %s\n", namespace, name, oldSize, matchingPVC.NewSize)
+		logger.Printf("  %s/%s: %v → %s\n", namespace, name.Value, oldSize, matchingPVC.NewSize)
+	}
 
-	// Convert back to YAML
-	updatedYAML, err := yaml.Marshal(obj)
-	if err != nil {
-		// If we can't marshal, return unchanged
-		return document, false
+	var storageClass string
+	if u.cloudProvider != nil {
+		keys := make([]string, 0, len(u.cloudProvider.Annotations))
+		for key := range u.cloudProvider.Annotations {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		editor.setMapping(metadataKey, metadata, "annotations", keys, u.cloudProvider.Annotations)
+		storageClass = u.cloudProvider.StorageClass
+		logger.Printf("  %s/%s: %s settings (storage class %s)\n", namespace, name.Value, u.cloudProvider.Name, u.cloudProvider.StorageClass)
+	}
+
+	// The storage class from the YAML or an explicitly chosen one takes precedence over the cloud default
+	if matchingPVC.StorageClass != "" {
+		storageClass = matchingPVC.StorageClass
+		if storageClassChanged {
+			logger.Printf("  %s/%s: storage class %s\n", namespace, name.Value, matchingPVC.StorageClass)
+		}
+	}
+	if storageClass != "" && (class == nil || u.value(class) != storageClass) {
+		editor.set(specKey, spec, "storageClassName", storageClass)
+	}
+
+	if editor.err != nil {
+		logger.Warnf("  %s/%s: %s\n", namespace, name.Value, color.Warning(fmt.Sprintf("Warning: Could not update the YAML: %v", editor.err)))
+		return document, nil
+	}
+	return editor.String(), matchingPVC
+}
+
+// value returns the value of a scalar node, with environment variables expanded
+// when the PVCs were parsed that way
+// updateClaimReferences points the persistentVolumeClaim.claimName volumes of a
+// workload document at the normalized name of a PVC that was renamed, and returns
+// the namespace/name of those PVCs
+func (u *Updater) updateClaimReferences(document string, pvcs []*types.PVCInfo) (string, []string) {
+	var root yaml.Node
+	if err := yaml.Unmarshal([]byte(document), &root); err != nil || len(root.Content) == 0 {
+		return document, nil
+	}
+	obj := root.Content[0]
+	metadata := mappingValue(obj, "metadata")
+
+	// Same namespace rules as the PVC documents
+	namespace := "default"
+	if namespaceNode := mappingValue(metadata, "namespace"); namespaceNode != nil {
+		namespace = u.value(namespaceNode)
+	}
+	if u.namespace != "" {
+		namespace = u.namespace
+	}
+	namespace = u.namespaceMapper.Map(namespace)
+
+	renamed := make(map[string]*types.PVCInfo)
+	for _, pvc := range pvcs {
+		if pvc.OriginalName != "" && pvc.OriginalName != pvc.Name && pvc.Namespace == namespace {
+			renamed[pvc.OriginalName] = pvc
+		}
+	}
+	if len(renamed) == 0 {
+		return document, nil
+	}
+
+	workload := "-"
+	if name := mappingValue(metadata, "name"); name != nil {
+		workload = name.Value
+	}
+
+	editor := newDocumentEditor(document)
+	var updated []string
+	for _, claimName := range claimNameNodes(obj) {
+		pvc := renamed[u.value(claimName)]
+		if pvc == nil {
+			continue
+		}
+		logger.Printf("  %s/%s: claimName %s → %s\n", namespace, workload, claimName.Value, pvc.Name)
+		editor.replaceScalar(claimName, pvc.Name)
+		updated = append(updated, pvc.Namespace+"/"+pvc.Name)
+	}
+	if len(updated) == 0 {
+		return document, nil
 	}
+	if editor.err != nil {
+		logger.Warnf("  %s/%s: %s\n", namespace, workload, color.Warning(fmt.Sprintf("Warning: Could not update the claimName references: %v", editor.err)))
+		return document, nil
+	}
+	return editor.String(), updated
+}
 
-	return string(updatedYAML), true
+// claimNameNodes returns the persistentVolumeClaim.claimName scalars anywhere in node,
+// so the volumes of pods, pod templates and job templates are all found
+func claimNameNodes(node *yaml.Node) []*yaml.Node {
+	if node == nil {
+		return nil
+	}
+	var nodes []*yaml.Node
+	if claim := mappingValue(node, "persistentVolumeClaim"); claim != nil {
+		if claimName := mappingValue(claim, "claimName"); claimName != nil && claimName.Kind == yaml.ScalarNode {
+			nodes = append(nodes, claimName)
+		}
+	}
+	for _, child := range node.Content {
+		nodes = append(nodes, claimNameNodes(child)...)
+	}
+	return nodes
+}
+
+func (u *Updater) value(node *yaml.Node) string {
+	if u.expandEnv {
+		return ExpandEnv(node.Value)
+	}
+	return node.Value
 }
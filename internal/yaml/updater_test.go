@@ -0,0 +1,518 @@
+package yaml
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/cloud"
+	"github.com/LuukBlankenstijn/docker-pvc-migration/internal/types"
+	"gopkg.in/yaml.v3"
+)
+
+// renderTestFile writes content to a temporary file and renders it with the updater
+func renderTestFile(t *testing.T, u *Updater, content string, pvcs []*types.PVCInfo) (string, []string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pvc.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, updated, changed, err := u.renderYAMLFile(path, pvcs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return updated, changed
+}
+
+func TestUpdaterExpandEnvKeepsReferences(t *testing.T) {
+	t.Setenv("APP_NAME", "data")
+	t.Setenv("APP_NAMESPACE", "apps")
+
+	content := `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: ${APP_NAME}
+  namespace: ${APP_NAMESPACE}
+spec:
+  resources:
+    requests:
+      storage: 1Gi
+`
+	u := NewUpdater()
+	u.SetExpandEnv(true)
+	updated, changed := renderTestFile(t, u, content, []*types.PVCInfo{
+		{Name: "data", Namespace: "apps", NewSize: "5Gi"},
+	})
+
+	if len(changed) != 1 || changed[0] != "apps/data" {
+		t.Fatalf("changed = %v, want [apps/data]", changed)
+	}
+	for _, want := range []string{"name: ${APP_NAME}", "namespace: ${APP_NAMESPACE}", "storage: 5Gi"} {
+		if !strings.Contains(updated, want) {
+			t.Errorf("updated file does not contain %q:\n%s", want, updated)
+		}
+	}
+}
+
+func TestUpdaterPreservesComments(t *testing.T) {
+	content := `# PVC for the database
+apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data # keep in sync with the chart
+spec:
+  accessModes: [ReadWriteOnce]
+  resources:
+    requests:
+      # managed by helm
+      storage: "1Gi"   # initial size
+`
+	updated, changed := renderTestFile(t, NewUpdater(), content, []*types.PVCInfo{
+		{Name: "data", Namespace: "default", NewSize: "5Gi"},
+	})
+
+	if len(changed) != 1 {
+		t.Fatalf("changed = %v, want one PVC", changed)
+	}
+	want := strings.Replace(content, `"1Gi"`, `"5Gi"`, 1)
+	if updated != want {
+		t.Errorf("updated file:\n%s\nwant:\n%s", updated, want)
+	}
+}
+
+func TestUpdaterEdits(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		pvc      types.PVCInfo
+		provider *cloud.Provider
+		want     string
+	}{
+		{
+			name: "single quoted size",
+			content: `kind: PersistentVolumeClaim
+metadata:
+  name: data
+spec:
+  resources:
+    requests:
+      storage: '1Gi'
+`,
+			pvc: types.PVCInfo{Name: "data", Namespace: "default", NewSize: "2Gi"},
+			want: `kind: PersistentVolumeClaim
+metadata:
+  name: data
+spec:
+  resources:
+    requests:
+      storage: '2Gi'
+`,
+		},
+		{
+			name: "added storage class",
+			content: `kind: PersistentVolumeClaim
+metadata:
+  name: data
+spec:
+    # access
+    accessModes:
+    - ReadWriteOnce
+    resources:
+      requests:
+        storage: 1Gi
+`,
+			pvc: types.PVCInfo{Name: "data", Namespace: "default", StorageClass: "fast"},
+			want: `kind: PersistentVolumeClaim
+metadata:
+  name: data
+spec:
+    storageClassName: fast
+    # access
+    accessModes:
+    - ReadWriteOnce
+    resources:
+      requests:
+        storage: 1Gi
+`,
+		},
+		{
+			name: "cloud provider",
+			content: `kind: PersistentVolumeClaim
+metadata:
+  name: data
+  labels:
+    app: db
+spec:
+  storageClassName: standard
+  resources:
+    requests:
+      storage: 1Gi
+`,
+			pvc:      types.PVCInfo{Name: "data", Namespace: "default", NewSize: "3Gi"},
+			provider: &cloud.Provider{Name: "test", StorageClass: "filestore", Annotations: map[string]string{"example.com/provisioner": "nfs"}},
+			want: `kind: PersistentVolumeClaim
+metadata:
+  annotations:
+    example.com/provisioner: nfs
+  name: data
+  labels:
+    app: db
+spec:
+  storageClassName: filestore
+  resources:
+    requests:
+      storage: 3Gi
+`,
+		},
+		{
+			name: "value after multi-byte text",
+			content: `kind: PersistentVolumeClaim
+metadata: {name: data, labels: {owner: "Jürgen"}, namespace: default}
+spec:
+  resources:
+    requests: {storage: 1Gi}
+`,
+			pvc: types.PVCInfo{Name: "data", Namespace: "default", NewSize: "4Gi"},
+			want: `kind: PersistentVolumeClaim
+metadata: {name: data, labels: {owner: "Jürgen"}, namespace: default}
+spec:
+  resources:
+    requests: {storage: 4Gi}
+`,
+		},
+		{
+			name: "flow mapping is left alone",
+			content: `kind: PersistentVolumeClaim
+metadata:
+  name: data
+spec: {resources: {requests: {storage: 1Gi}}}
+`,
+			pvc: types.PVCInfo{Name: "data", Namespace: "default", StorageClass: "fast"},
+			want: `kind: PersistentVolumeClaim
+metadata:
+  name: data
+spec: {resources: {requests: {storage: 1Gi}}}
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewUpdater()
+			u.SetCloudProvider(tt.provider)
+			pvc := tt.pvc
+			updated, _ := renderTestFile(t, u, tt.content, []*types.PVCInfo{&pvc})
+			if updated != tt.want {
+				t.Errorf("updated file:\n%s\nwant:\n%s", updated, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatScalar(t *testing.T) {
+	tests := []struct {
+		value string
+		style yaml.Style
+		want  string
+	}{
+		{"10Gi", 0, "10Gi"},
+		{"true", 0, `"true"`},
+		{"10", 0, `"10"`},
+		{"10Gi", yaml.DoubleQuotedStyle, `"10Gi"`},
+		{"it's", yaml.SingleQuotedStyle, `'it''s'`},
+	}
+	for _, tt := range tests {
+		got, err := formatScalar(tt.value, tt.style)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("formatScalar(%q, %v) = %s, want %s", tt.value, tt.style, got, tt.want)
+		}
+	}
+}
+
+func TestDiffYAMLFiles(t *testing.T) {
+	dir := t.TempDir()
+	pvcFile := filepath.Join(dir, "pvc.yaml")
+	content := `apiVersion: v1
+kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: apps
+spec:
+  resources:
+    requests:
+      storage: 1Gi
+`
+	files := map[string]string{
+		pvcFile:                          content,
+		filepath.Join(dir, "other.yaml"): "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: config\n",
+	}
+	for path, data := range files {
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	diffs, err := NewUpdater().DiffYAMLFiles(dir, []*types.PVCInfo{{Name: "data", Namespace: "apps", NewSize: "5Gi"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diffs) != 1 {
+		t.Fatalf("DiffYAMLFiles() returned %d diffs, want only the PVC file", len(diffs))
+	}
+
+	d := diffs[0]
+	if d.File != pvcFile || len(d.PVCs) != 1 || d.PVCs[0] != "apps/data" {
+		t.Errorf("diff = file %s, PVCs %v, want %s with apps/data", d.File, d.PVCs, pvcFile)
+	}
+	for _, want := range []string{"-      storage: 1Gi\n", "+      storage: 5Gi\n", "--- " + pvcFile, "+++ " + pvcFile} {
+		if !strings.Contains(d.Diff, want) {
+			t.Errorf("diff does not contain %q:\n%s", want, d.Diff)
+		}
+	}
+
+	// Nothing is written while diffing
+	data, err := os.ReadFile(pvcFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != content {
+		t.Errorf("DiffYAMLFiles changed %s:\n%s", pvcFile, data)
+	}
+}
+
+func TestUpdaterNamespaceOverrideAndMap(t *testing.T) {
+	content := `kind: PersistentVolumeClaim
+metadata:
+  name: data
+  namespace: web
+spec:
+  resources:
+    requests:
+      storage: 1Gi
+`
+	tests := []struct {
+		name          string
+		override      string
+		mapper        *types.NamespaceMapper
+		pvcNamespace  string // Namespace the parser and mapper gave the PVC
+		wantNamespace string // Namespace written into the file, "" when unchanged
+	}{
+		{"no override or map", "", nil, "web", ""},
+		{"override", "apps", nil, "apps", "apps"},
+		{"map of the YAML namespace", "", mustMapper(t, "web", "tenant-a"), "tenant-a", "tenant-a"},
+		{"override then map", "apps", mustMapper(t, "apps", "tenant-a"), "tenant-a", "tenant-a"},
+		{"map does not apply to the override", "other", mustMapper(t, "web", "tenant-a"), "other", "other"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := NewUpdater()
+			u.SetNamespaceOverride(tt.override)
+			u.SetNamespaceMapper(tt.mapper)
+
+			pvc := &types.PVCInfo{Name: "data", Namespace: tt.pvcNamespace, NewSize: "2Gi"}
+			updated, changed := renderTestFile(t, u, content, []*types.PVCInfo{pvc})
+			if len(changed) != 1 || changed[0] != tt.pvcNamespace+"/data" {
+				t.Fatalf("changed PVCs = %v, want %s/data", changed, tt.pvcNamespace)
+			}
+
+			want := strings.Replace(content, "1Gi", "2Gi", 1)
+			if tt.wantNamespace != "" {
+				want = strings.Replace(want, "namespace: web", "namespace: "+tt.wantNamespace, 1)
+			}
+			if updated != want {
+				t.Errorf("updated file:\n%s\nwant:\n%s", updated, want)
+			}
+		})
+	}
+}
+
+func mustMapper(t *testing.T, source, target string) *types.NamespaceMapper {
+	t.Helper()
+	mapper, err := types.NewNamespaceMapper([]types.NamespaceMapping{{Source: source, Target: target}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mapper
+}
+
+func TestWalkYAMLFiles(t *testing.T) {
+	root := t.TempDir()
+	files := []string{
+		"top.yaml",
+		"notes.txt",
+		"one/first.yml",
+		"one/two/second.yaml",
+		"one/two/three/third.yaml",
+	}
+	for _, file := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("kind: PersistentVolumeClaim\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		maxDepth int
+		want     []string
+	}{
+		{0, []string{"top.yaml"}},
+		{1, []string{"one/first.yml", "top.yaml"}},
+		{2, []string{"one/first.yml", "one/two/second.yaml", "top.yaml"}},
+		{3, []string{"one/first.yml", "one/two/second.yaml", "one/two/three/third.yaml", "top.yaml"}},
+		{-1, []string{"one/first.yml", "one/two/second.yaml", "one/two/three/third.yaml", "top.yaml"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.maxDepth), func(t *testing.T) {
+			var got []string
+			err := WalkYAMLFiles(root, tt.maxDepth, func(path string) error {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				got = append(got, filepath.ToSlash(rel))
+				return nil
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			slices.Sort(got)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("WalkYAMLFiles(maxDepth=%d) = %v, want %v", tt.maxDepth, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateYAMLFilesKustomizedPVCs(t *testing.T) {
+	const base = "apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata:\n  name: data\nspec:\n  resources:\n    requests:\n      storage: 1Gi\n"
+
+	tests := []struct {
+		name string
+		pvc  *types.PVCInfo
+		want string // Size in the base file afterwards
+	}{
+		{"name kept", &types.PVCInfo{Name: "data", Namespace: "default", NewSize: "5Gi", Kustomization: "overlay"}, "5Gi"},
+		// Renamed by namePrefix, so the base document is not found and left alone
+		{"name prefixed", &types.PVCInfo{Name: "prod-data", Namespace: "default", NewSize: "5Gi", Kustomization: "overlay"}, "1Gi"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "pvc.yaml")
+			if err := os.WriteFile(path, []byte(base), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := NewUpdater().UpdateYAMLFiles(dir, []*types.PVCInfo{tt.pvc}); err != nil {
+				t.Fatal(err)
+			}
+			content, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(content), "storage: "+tt.want) {
+				t.Errorf("base file =\n%s\nwant storage %s", content, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdaterRewritesClaimNames(t *testing.T) {
+	const workloads = `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  template:
+    spec:
+      volumes:
+      - name: data
+        persistentVolumeClaim:
+          claimName: App_Data # the PVC
+      - name: other
+        persistentVolumeClaim:
+          claimName: other
+---
+apiVersion: batch/v1
+kind: CronJob
+metadata:
+  name: backup
+  namespace: prod
+spec:
+  jobTemplate:
+    spec:
+      template:
+        spec:
+          volumes:
+          - name: data
+            persistentVolumeClaim:
+              claimName: App_Data
+`
+	renamed := &types.PVCInfo{Name: "app-data", OriginalName: "App_Data", Namespace: "default"}
+
+	tests := []struct {
+		name string
+		pvcs []*types.PVCInfo
+		want []string // claimName lines afterwards
+	}{
+		{
+			name: "renamed PVC",
+			pvcs: []*types.PVCInfo{renamed},
+			want: []string{"claimName: app-data # the PVC", "claimName: other", "claimName: App_Data"},
+		},
+		{
+			name: "name kept",
+			pvcs: []*types.PVCInfo{{Name: "App_Data", Namespace: "default", NewSize: "2Gi"}},
+			want: []string{"claimName: App_Data # the PVC", "claimName: other", "claimName: App_Data"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _ := renderTestFile(t, NewUpdater(), workloads, tt.pvcs)
+
+			var claimNames []string
+			for _, line := range strings.Split(got, "\n") {
+				if strings.Contains(line, "claimName:") {
+					claimNames = append(claimNames, strings.TrimSpace(line))
+				}
+			}
+			if !slices.Equal(claimNames, tt.want) {
+				t.Errorf("claimName lines = %v, want %v", claimNames, tt.want)
+			}
+		})
+	}
+
+	// The PVC document and the workload mounting it are renamed together
+	dir := t.TempDir()
+	pvcDocument := "apiVersion: v1\nkind: PersistentVolumeClaim\nmetadata:\n  name: App_Data\nspec:\n  resources:\n    requests:\n      storage: 1Gi\n"
+	for name, content := range map[string]string{"pvc.yaml": pvcDocument, "workloads.yaml": workloads} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := NewUpdater().UpdateYAMLFiles(dir, []*types.PVCInfo{renamed}); err != nil {
+		t.Fatal(err)
+	}
+	for name, want := range map[string]string{"pvc.yaml": "name: app-data", "workloads.yaml": "claimName: app-data"} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(content), want) {
+			t.Errorf("%s =\n%s\nwant %q", name, content, want)
+		}
+	}
+}